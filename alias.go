@@ -0,0 +1,53 @@
+package tsdata
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Aliases maps old column names to current column names, e.g.
+// "sog" -> "speed_over_ground". It allows files created before a column was
+// renamed to be combined with newer files.
+type Aliases map[string]string
+
+// ParseAliases creates Aliases from a list of "old=new" strings.
+func ParseAliases(pairs []string) (Aliases, error) {
+	a := make(Aliases)
+	for _, p := range pairs {
+		parts := strings.SplitN(p, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("bad alias '%v', expected OLD=NEW", p)
+		}
+		old, cur := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if old == "" || cur == "" {
+			return nil, fmt.Errorf("bad alias '%v', expected OLD=NEW", p)
+		}
+		if old == cur {
+			return nil, fmt.Errorf("bad alias '%v', names are the same", p)
+		}
+		a[old] = cur
+	}
+	return a, nil
+}
+
+// Canonical returns the current name for a column, following chains of renames.
+// Names without an alias are returned unchanged.
+func (a Aliases) Canonical(name string) string {
+	seen := map[string]bool{name: true}
+	for {
+		next, ok := a[name]
+		if !ok || seen[next] {
+			return name
+		}
+		seen[next] = true
+		name = next
+	}
+}
+
+// WithAliases sets column aliases used by ParseHeader. Header names which are
+// keys in a are replaced by their canonical name.
+func WithAliases(a Aliases) Option {
+	return func(t *Tsdata) {
+		t.aliases = a
+	}
+}
@@ -0,0 +1,72 @@
+package tsdata
+
+import "testing"
+
+func TestAliases_Canonical(t *testing.T) {
+	a := Aliases{"sog": "speed", "speed": "speed_over_ground", "x": "y", "y": "x"}
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "no alias", in: "salinity", want: "salinity"},
+		{name: "single rename", in: "speed", want: "speed_over_ground"},
+		{name: "chained rename", in: "sog", want: "speed_over_ground"},
+		{name: "cycle", in: "x", want: "y"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.Canonical(tt.in); got != tt.want {
+				t.Errorf("Aliases.Canonical() = %v, expected %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAliases(t *testing.T) {
+	tests := []struct {
+		name    string
+		pairs   []string
+		want    Aliases
+		wantErr bool
+	}{
+		{name: "valid", pairs: []string{"sog=speed_over_ground", " cog = course "}, want: Aliases{"sog": "speed_over_ground", "cog": "course"}},
+		{name: "missing equals", pairs: []string{"sog"}, wantErr: true},
+		{name: "empty new name", pairs: []string{"sog="}, wantErr: true},
+		{name: "same name", pairs: []string{"sog=sog"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAliases(tt.pairs)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseAliases() err %v, expected a non-nil error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("ParseAliases() err %v, expected nil", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("ParseAliases() = %v, expected %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("ParseAliases() = %v, expected %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTsdata_ParseHeader_aliases(t *testing.T) {
+	header := "fileType\nproject\nfile description\n\ntime\tfloat\nNA\tknots\ntime\tsog"
+	d := New(WithAliases(Aliases{"sog": "speed_over_ground"}))
+	if err := d.ParseHeader(header); err != nil {
+		t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+	}
+	want := []string{"time", "speed_over_ground"}
+	if !stringSliceEqual(d.Headers, want) {
+		t.Errorf("Tsdata.ParseHeader() Headers = %v, expected %v", d.Headers, want)
+	}
+}
@@ -0,0 +1,324 @@
+package tsdata
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// BinaryMagic starts a file in the binary TSDATA encoding.
+//
+// The binary encoding keeps the same header metadata as a text file but stores
+// data lines in chunks of rows, with each column of a chunk compressed
+// separately. After BinaryMagic comes the length of the header as a uvarint,
+// then the header text with tab delimiters. Each chunk starts with a uvarint
+// row count, followed by each column as a uvarint length and DEFLATE
+// compressed data. A row count of 0 ends the file. Time values in the first
+// column are stored as varint differences from the previous value in
+// nanoseconds, and other values as a uvarint length followed by the value.
+// Timestamps are UTC when read.
+const BinaryMagic = "TSDATA-BINARY-1\n"
+
+// binaryChunkRows is the number of rows in a full chunk.
+const binaryChunkRows = 1 << 16
+
+// binaryChunkBytes is the number of buffered value bytes which ends a chunk
+// early, so columns stay well under maxBinaryColumn.
+const binaryChunkBytes = 1 << 24
+
+// maxBinaryHeader and maxBinaryColumn are the largest header and column
+// lengths, compressed or not, which readers accept, and maxBinaryCells is the
+// largest number of values in a chunk. Lengths and counts come from the input,
+// so larger ones are treated as corrupt rather than allocated.
+const (
+	maxBinaryHeader = 1 << 20
+	maxBinaryColumn = 1 << 28
+	maxBinaryCells  = 1 << 24
+)
+
+// BinaryWriter writes data lines in the binary TSDATA encoding. Read these
+// files with NewReader.
+type BinaryWriter struct {
+	w        io.Writer
+	cols     int
+	rows     int // rows in a full chunk
+	times    []time.Time
+	fields   [][]string // by column, excluding time
+	size     int        // buffered value bytes
+	lastTime int64
+	closed   bool
+}
+
+// NewBinaryWriter writes BinaryMagic and the header from t to w, and returns a
+// BinaryWriter for data lines.
+func NewBinaryWriter(w io.Writer, t *Tsdata) (*BinaryWriter, error) {
+	s := t.Schema()
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	header := s.header(Delim)
+	var buf bytes.Buffer
+	buf.WriteString(BinaryMagic)
+	putUvarint(&buf, uint64(len(header)))
+	buf.WriteString(header)
+	if _, err := buf.WriteTo(w); err != nil {
+		return nil, err
+	}
+	cols := len(s.Headers)
+	rows := binaryChunkRows
+	if rows*cols > maxBinaryCells {
+		rows = maxBinaryCells / cols
+	}
+	return &BinaryWriter{w: w, cols: cols, rows: rows, fields: make([][]string, cols-1)}, nil
+}
+
+// Write adds a validated data line.
+func (bw *BinaryWriter) Write(data Data) error {
+	if bw.closed {
+		return errors.New("write to closed BinaryWriter")
+	}
+	if len(data.Fields) != bw.cols {
+		return fmt.Errorf("found %v columns, expected %v", len(data.Fields), bw.cols)
+	}
+	bw.times = append(bw.times, data.Time)
+	for i, f := range data.Fields[1:] {
+		bw.fields[i] = append(bw.fields[i], f)
+		bw.size += len(f)
+	}
+	if len(bw.times) >= bw.rows || bw.size >= binaryChunkBytes {
+		return bw.flush()
+	}
+	return nil
+}
+
+// Close writes any buffered lines and the end of the file. It does not close
+// the underlying writer.
+func (bw *BinaryWriter) Close() error {
+	if bw.closed {
+		return nil
+	}
+	if err := bw.flush(); err != nil {
+		return err
+	}
+	bw.closed = true
+	var buf bytes.Buffer
+	putUvarint(&buf, 0)
+	_, err := buf.WriteTo(bw.w)
+	return err
+}
+
+// flush writes buffered rows as a chunk.
+func (bw *BinaryWriter) flush() error {
+	if len(bw.times) == 0 {
+		return nil
+	}
+	var chunk bytes.Buffer
+	putUvarint(&chunk, uint64(len(bw.times)))
+
+	var col bytes.Buffer
+	for _, t := range bw.times {
+		nano := t.UnixNano()
+		putVarint(&col, nano-bw.lastTime)
+		bw.lastTime = nano
+	}
+	if err := writeBinaryColumn(&chunk, col.Bytes()); err != nil {
+		return err
+	}
+	for i, fields := range bw.fields {
+		col.Reset()
+		for _, f := range fields {
+			putUvarint(&col, uint64(len(f)))
+			col.WriteString(f)
+		}
+		if err := writeBinaryColumn(&chunk, col.Bytes()); err != nil {
+			return err
+		}
+		bw.fields[i] = fields[:0]
+	}
+	bw.times = bw.times[:0]
+	bw.size = 0
+	_, err := chunk.WriteTo(bw.w)
+	return err
+}
+
+// writeBinaryColumn compresses b and appends it to chunk with its length.
+func writeBinaryColumn(chunk *bytes.Buffer, b []byte) error {
+	var z bytes.Buffer
+	zw, err := flate.NewWriter(&z, flate.DefaultCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := zw.Write(b); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	putUvarint(chunk, uint64(z.Len()))
+	_, err = z.WriteTo(chunk)
+	return err
+}
+
+// readBinaryHeader reads the header of a binary file from br, which must be
+// positioned at BinaryMagic.
+func readBinaryHeader(br *bufio.Reader) (string, error) {
+	if _, err := br.Discard(len(BinaryMagic)); err != nil {
+		return "", err
+	}
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return "", fmt.Errorf("bad binary header: %v", err)
+	}
+	if n > maxBinaryHeader {
+		return "", fmt.Errorf("bad binary header: length %v, expected <= %v", n, maxBinaryHeader)
+	}
+	header, err := readBinaryBytes(br, n)
+	if err != nil {
+		return "", fmt.Errorf("bad binary header: %v", err)
+	}
+	return string(header), nil
+}
+
+// readBinaryBytes reads exactly n bytes from r. The buffer grows as bytes
+// arrive rather than being allocated from n, so truncated input with a large
+// length fails without a large allocation.
+func readBinaryBytes(r io.Reader, n uint64) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// binaryScanner returns the data lines of a binary file as delimited text.
+// It has the same methods as bufio.Scanner used by Reader.
+type binaryScanner struct {
+	br       *bufio.Reader
+	delim    string
+	cols     int
+	rows     [][]string // current chunk by column
+	row      int
+	lastTime int64
+	done     bool
+	err      error
+	text     string
+}
+
+func newBinaryScanner(br *bufio.Reader, cols int, delim string) *binaryScanner {
+	return &binaryScanner{br: br, cols: cols, delim: delim}
+}
+
+func (s *binaryScanner) Scan() bool {
+	if s.done {
+		return false
+	}
+	if len(s.rows) == 0 || s.row >= len(s.rows[0]) {
+		if err := s.readChunk(); err != nil {
+			s.done = true
+			if err != io.EOF {
+				s.err = err
+			}
+			return false
+		}
+	}
+	fields := make([]string, s.cols)
+	for i := range fields {
+		fields[i] = s.rows[i][s.row]
+	}
+	s.row++
+	s.text = strings.Join(fields, s.delim)
+	return true
+}
+
+func (s *binaryScanner) Text() string {
+	return s.text
+}
+
+func (s *binaryScanner) Err() error {
+	return s.err
+}
+
+// readChunk reads the next chunk. It returns io.EOF at the end of the file.
+func (s *binaryScanner) readChunk() error {
+	n, err := binary.ReadUvarint(s.br)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("bad binary chunk: %v", err)
+	}
+	if n == 0 {
+		return io.EOF
+	}
+	if n > binaryChunkRows {
+		return fmt.Errorf("bad binary chunk: %v rows, expected <= %v", n, binaryChunkRows)
+	}
+	if n*uint64(s.cols) > maxBinaryCells {
+		return fmt.Errorf("bad binary chunk: %v rows of %v columns, expected <= %v values", n, s.cols, maxBinaryCells)
+	}
+	s.rows = make([][]string, s.cols)
+	s.row = 0
+	for i := 0; i < s.cols; i++ {
+		b, err := s.readColumn()
+		if err != nil {
+			return fmt.Errorf("bad binary chunk, column %v: %v", i+1, err)
+		}
+		r := bytes.NewReader(b)
+		col := make([]string, n)
+		for j := range col {
+			if i == 0 {
+				d, err := binary.ReadVarint(r)
+				if err != nil {
+					return fmt.Errorf("bad binary chunk, column %v: %v", i+1, err)
+				}
+				s.lastTime += d
+				col[j] = time.Unix(0, s.lastTime).UTC().Format(time.RFC3339Nano)
+				continue
+			}
+			l, err := binary.ReadUvarint(r)
+			if err != nil || l > uint64(r.Len()) {
+				return fmt.Errorf("bad binary chunk, column %v: truncated value", i+1)
+			}
+			v := make([]byte, l)
+			r.Read(v)
+			col[j] = string(v)
+		}
+		s.rows[i] = col
+	}
+	return nil
+}
+
+// readColumn reads and decompresses one column of a chunk.
+func (s *binaryScanner) readColumn() ([]byte, error) {
+	n, err := binary.ReadUvarint(s.br)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxBinaryColumn {
+		return nil, fmt.Errorf("length %v, expected <= %v", n, maxBinaryColumn)
+	}
+	z, err := readBinaryBytes(s.br, n)
+	if err != nil {
+		return nil, err
+	}
+	zr := flate.NewReader(bytes.NewReader(z))
+	defer zr.Close()
+	b, err := ioutil.ReadAll(io.LimitReader(zr, maxBinaryColumn+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxBinaryColumn {
+		return nil, fmt.Errorf("decompressed length over %v", maxBinaryColumn)
+	}
+	return b, nil
+}
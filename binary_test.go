@@ -0,0 +1,185 @@
+package tsdata
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// readAllLines reads all data lines from r as text.
+func readAllLines(t *testing.T, r *Reader) []string {
+	var lines []string
+	for {
+		data, err := r.Next()
+		if err == io.EOF {
+			return lines
+		}
+		if err != nil {
+			t.Fatalf("Reader.Next() err %v, expected nil", err)
+		}
+		lines = append(lines, strings.Join(data.Fields, Delim))
+	}
+}
+
+func TestBinaryWriter_roundtrip(t *testing.T) {
+	for _, n := range []int{0, 3, binaryChunkRows + 5} {
+		input := indexInput(n)
+		r, err := NewReader(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := readAllLines(t, r)
+
+		r, err = NewReader(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		bw, err := NewBinaryWriter(&buf, r.Tsdata)
+		if err != nil {
+			t.Fatalf("NewBinaryWriter() err %v, expected nil", err)
+		}
+		for {
+			data, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err := bw.Write(data); err != nil {
+				t.Fatalf("BinaryWriter.Write() err %v, expected nil", err)
+			}
+		}
+		if err := bw.Close(); err != nil {
+			t.Fatalf("BinaryWriter.Close() err %v, expected nil", err)
+		}
+		if n > 1000 && buf.Len() >= len(input) {
+			t.Errorf("binary size %v, expected less than text size %v", buf.Len(), len(input))
+		}
+
+		br, err := NewReader(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("NewReader() err %v, expected nil", err)
+		}
+		if br.Tsdata.Header() != r.Tsdata.Header() {
+			t.Errorf("binary header %q, expected %q", br.Tsdata.Header(), r.Tsdata.Header())
+		}
+		got := readAllLines(t, br)
+		if len(got) != len(want) {
+			t.Fatalf("read %v binary lines, expected %v", len(got), len(want))
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("binary line %v = %q, expected %q", i, got[i], want[i])
+			}
+		}
+		if br.Line() != HeaderSize+n {
+			t.Errorf("Reader.Line() = %v, expected %v", br.Line(), HeaderSize+n)
+		}
+	}
+}
+
+func TestNewReader_badBinary(t *testing.T) {
+	r, err := NewReader(strings.NewReader(indexInput(10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	bw, err := NewBinaryWriter(&buf, r.Tsdata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		data, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		bw.Write(data)
+	}
+	bw.Close()
+	b := buf.Bytes()
+
+	_, err = NewReader(bytes.NewReader(b[:len(BinaryMagic)+3]))
+	var herr *HeaderError
+	if !errors.As(err, &herr) {
+		t.Errorf("NewReader() err %v, expected a *HeaderError for a truncated header", err)
+	}
+
+	br, err := NewReader(bytes.NewReader(b[:len(b)-5]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := br.Next(); err == nil || err == io.EOF {
+		t.Errorf("Reader.Next() err %v, expected an error for a truncated chunk", err)
+	}
+}
+
+func TestNewReader_oversizedBinary(t *testing.T) {
+	huge := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}
+	_, err := NewReader(bytes.NewReader(append([]byte(BinaryMagic), huge...)))
+	var herr *HeaderError
+	if !errors.As(err, &herr) {
+		t.Errorf("NewReader() err %v, expected a *HeaderError for an oversized header length", err)
+	}
+
+	// A header length under the limit but past the end of the input
+	_, err = NewReader(bytes.NewReader(append([]byte(BinaryMagic), 0x80, 0x80, 0x20)))
+	if !errors.As(err, &herr) {
+		t.Errorf("NewReader() err %v, expected a *HeaderError for a truncated header", err)
+	}
+
+	r, err := NewReader(strings.NewReader(indexInput(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := NewBinaryWriter(&buf, r.Tsdata); err != nil {
+		t.Fatal(err)
+	}
+	header := buf.Bytes()
+	tests := []struct {
+		name  string
+		chunk []byte
+	}{
+		{"oversized column length", append([]byte{1}, huge...)},
+		{"truncated column", []byte{1, 0x80, 0x80, 0x80, 0x01, 1, 2, 3}},
+		{"too many rows", []byte{0xff, 0xff, 0xff, 0xff, 0x0f}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := append(append([]byte{}, header...), tt.chunk...)
+			br, err := NewReader(bytes.NewReader(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := br.Next(); err == nil || err == io.EOF {
+				t.Errorf("Reader.Next() err %v, expected an error", err)
+			}
+		})
+	}
+}
+
+func TestNewBinaryWriter_wideChunks(t *testing.T) {
+	cols := 1000
+	s := Schema{FileType: "test", Project: "test", FileDescription: "test"}
+	for i := 0; i < cols; i++ {
+		s.Comments = append(s.Comments, NA)
+		s.Units = append(s.Units, NA)
+		if i == 0 {
+			s.Types = append(s.Types, "time")
+			s.Headers = append(s.Headers, "time")
+			continue
+		}
+		s.Types = append(s.Types, "integer")
+		s.Headers = append(s.Headers, fmt.Sprintf("c%v", i))
+	}
+	var buf bytes.Buffer
+	bw, err := NewBinaryWriter(&buf, New(WithSchema(s)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bw.rows*cols > maxBinaryCells {
+		t.Errorf("chunk of %v rows of %v columns, expected <= %v values", bw.rows, cols, maxBinaryCells)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ctberthiaume/tsdata"
+)
+
+const (
+	aliasOldInput = "gps\nproject\nbefore rename\nNA\tNA\ntime\tfloat\nNA\tknots\ntime\tsog\n" +
+		"2020-01-01T00:00:00Z\t1.5\n"
+	aliasNewInput = "gps\nproject\nafter rename\nNA\tNA\ntime\tfloat\nNA\tknots\ntime\tspeed_over_ground\n" +
+		"2020-01-01T00:01:00Z\t2.5\n"
+)
+
+// aliasSetup writes the old and new inputs to a temporary directory and
+// returns their paths and reader options which alias sog to
+// speed_over_ground.
+func aliasSetup(t *testing.T) (string, string, []tsdata.Option) {
+	logger = newLogger(ioutil.Discard)
+	dir := t.TempDir()
+	oldFile := filepath.Join(dir, "old.tsdata")
+	newFile := filepath.Join(dir, "new.tsdata")
+	if err := ioutil.WriteFile(oldFile, []byte(aliasOldInput), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(newFile, []byte(aliasNewInput), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a, err := tsdata.ParseAliases([]string{"sog=speed_over_ground"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return oldFile, newFile, []tsdata.Option{tsdata.WithAliases(a)}
+}
+
+// captureStdout returns what f writes to STDOUT.
+func captureStdout(t *testing.T, f func() error) (string, error) {
+	out, err := ioutil.TempFile(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	stdout := os.Stdout
+	os.Stdout = out
+	ferr := f()
+	os.Stdout = stdout
+	b, err := ioutil.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b), ferr
+}
+
+func TestCatCmd_alias(t *testing.T) {
+	oldFile, newFile, opts := aliasSetup(t)
+	if _, err := captureStdout(t, func() error { return catCmd([]string{oldFile, newFile}, true, true, nil) }); err == nil {
+		t.Errorf("catCmd() without aliases err nil, expected a column mismatch")
+	}
+	got, err := captureStdout(t, func() error { return catCmd([]string{oldFile, newFile}, false, true, opts) })
+	if err != nil {
+		t.Fatalf("catCmd() err %v, expected nil", err)
+	}
+	want := "2020-01-01T00:00:00Z\t1.5\n2020-01-01T00:01:00Z\t2.5\n"
+	if got != want {
+		t.Errorf("catCmd() wrote %q, expected %q", got, want)
+	}
+}
+
+func TestJoinCmd_alias(t *testing.T) {
+	oldFile, newFile, opts := aliasSetup(t)
+	outfile := filepath.Join(t.TempDir(), "out.tsdata")
+	if err := joinCmd(oldFile, newFile, outfile, "time", 0, "", opts); err == nil {
+		t.Errorf("joinCmd() err nil, expected a duplicate column once sog is aliased to speed_over_ground")
+	}
+	if err := joinCmd(oldFile, newFile, outfile, "time", 0, "", nil); err != nil {
+		t.Errorf("joinCmd() without aliases err %v, expected nil", err)
+	}
+}
+
+func TestSchemaCheckCmd_alias(t *testing.T) {
+	oldFile, newFile, opts := aliasSetup(t)
+	if err := schemaCheckCmd([]string{oldFile, newFile}, nil); err == nil {
+		t.Errorf("schemaCheckCmd() without aliases err nil, expected a column mismatch")
+	}
+	if err := schemaCheckCmd([]string{oldFile, newFile}, opts); err != nil {
+		t.Errorf("schemaCheckCmd() err %v, expected nil", err)
+	}
+}
+
+func TestReadSchema_alias(t *testing.T) {
+	oldFile, _, opts := aliasSetup(t)
+	s, err := readSchema(oldFile, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(s.Headers, ","); got != "time,speed_over_ground" {
+		t.Errorf("readSchema() headers %v, expected time,speed_over_ground", got)
+	}
+}
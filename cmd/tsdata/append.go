@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var appendCommand = cli.Command{
+	Name:      "append",
+	Usage:     "Appends data lines to an existing TSDATA file",
+	UsageText: "tsdata append INFILE TARGET",
+	Description: "Appends the data lines of INFILE to the existing file TARGET. Nothing is written unless " +
+		"the fileType, project, column names, types, and units of both headers match, every line of INFILE " +
+		"is valid, and INFILE's timestamps are in order and later than the last timestamp in TARGET. " +
+		"A TARGET ending with .gz is appended to as a new gzip member. " +
+		"Use '-' for STDIN as INFILE.",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE and TARGET arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 2 {
+			err := usageErrorf("missing required TARGET argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 2 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = appendCmd(c.Args().Get(0), c.Args().Get(1), opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+func appendCmd(infile string, target string, opts []tsdata.Option) error {
+	if target == "-" {
+		return usageErrorf("TARGET must be a file")
+	}
+	schema, last, newline, err := lastTime(target, opts)
+	if err != nil {
+		return fmt.Errorf("%v: %w", target, err)
+	}
+
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return fmt.Errorf("%v: %w", infile, err)
+	}
+	err = checkSchema(schema, tr.Tsdata.Schema())
+	if err != nil {
+		return fmt.Errorf("%v: %w", infile, err)
+	}
+
+	// Validate all of INFILE before touching TARGET
+	var buf bytes.Buffer
+	n := 0
+	for {
+		data, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%v: %w", infile, err)
+		}
+		if !data.Time.After(last) {
+			if n == 0 {
+				return dataErrorf("%v: line %v, timestamp %v is not after last timestamp %v in %v",
+					infile, tr.Line(), data.Fields[0], last.Format(time.RFC3339Nano), target)
+			}
+			return dataErrorf("%v: line %v, timestamp %v is earlier than previous line", infile, tr.Line(), data.Fields[0])
+		}
+		last = data.Time
+		buf.WriteString(strings.Join(data.Fields, tsdata.Delim) + "\n")
+		n++
+	}
+	if n == 0 {
+		logger.Println("no data lines to append")
+		return nil
+	}
+
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	var w io.WriteCloser = f
+	if strings.HasSuffix(target, ".gz") {
+		// Concatenated gzip members are read as one stream
+		w = gzipWriteCloser(f)
+	}
+	defer w.Close()
+	if !newline {
+		_, err = io.WriteString(w, "\n")
+		if err != nil {
+			return err
+		}
+	}
+	_, err = buf.WriteTo(w)
+	if err != nil {
+		return err
+	}
+	logger.Printf("appended %v lines to %v\n", n, target)
+	return w.Close()
+}
+
+// lastTime returns the schema and the latest valid timestamp in a TSDATA file,
+// and whether the file ends with a newline.
+func lastTime(path string, opts []tsdata.Option) (tsdata.Schema, time.Time, bool, error) {
+	var last time.Time
+	f, err := openInput(path)
+	if err != nil {
+		return tsdata.Schema{}, last, false, err
+	}
+	defer f.Close()
+	lr := &lastByteReader{r: f}
+	tr, err := tsdata.NewReader(lr, opts...)
+	if err != nil {
+		return tsdata.Schema{}, last, false, err
+	}
+	delim := tr.Tsdata.Delimiter()
+	for {
+		line, err := tr.NextRaw()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return tsdata.Schema{}, last, false, err
+		}
+		t, err := tsdata.ParseTime(strings.TrimSpace(strings.SplitN(line, delim, 2)[0]))
+		if err != nil {
+			continue
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+	return tr.Tsdata.Schema(), last, lr.last == '\n', nil
+}
+
+// lastByteReader remembers the last byte read from r.
+type lastByteReader struct {
+	r    io.Reader
+	last byte
+}
+
+func (l *lastByteReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	if n > 0 {
+		l.last = p[n-1]
+	}
+	return n, err
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var catCommand = cli.Command{
+	Name:      "cat",
+	Usage:     "Prints the header or data lines of TSDATA files",
+	UsageText: "tsdata cat [--no-header | --header-only] [--alias OLD=NEW...] INFILE...",
+	Description: "Prints the header of the first INFILE followed by the data lines of every INFILE to STDOUT. " +
+		"With --no-header only data lines are printed, so output can be piped to tools like sort, cut, and awk, " +
+		"and with --header-only only the header of each INFILE is printed, separated by blank lines. " +
+		"Headers are checked but data lines are printed as they are, without validation. " +
+		"Lines are tab-delimited even if INFILE uses another delimiter or the binary encoding. " +
+		"Without --header-only, every INFILE must have the same FileType, Project, column names, types, and units, " +
+		"after renaming columns with --alias. " +
+		"Use '-' for STDIN.",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "no-header",
+			Usage: "Print only data lines",
+		},
+		cli.BoolFlag{
+			Name:  "header-only",
+			Usage: "Print only the header of each INFILE",
+		},
+		cli.StringSliceFlag{
+			Name:  "alias",
+			Usage: "Treat column `OLD=NEW` as column NEW, may be repeated",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("no-header") && c.Bool("header-only") {
+			err := usageErrorf("--no-header and --header-only can't be used together")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = catCmd(c.Args(), !c.Bool("no-header"), !c.Bool("header-only"), opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+func catCmd(infiles []string, header bool, data bool, opts []tsdata.Option) error {
+	w := bufio.NewWriter(os.Stdout)
+	var first tsdata.Schema
+	for i, infile := range infiles {
+		err := func() error {
+			r, err := openInput(infile)
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			tr, err := tsdata.NewReader(r, opts...)
+			if err != nil {
+				return fmt.Errorf("%v: %w", infile, err)
+			}
+
+			if !data {
+				if i > 0 {
+					w.WriteString("\n")
+				}
+				_, err = w.WriteString(tr.Tsdata.Schema().Header() + "\n")
+				return err
+			}
+			if i == 0 {
+				first = tr.Tsdata.Schema()
+				if header {
+					if _, err := w.WriteString(tr.Tsdata.Schema().Header() + "\n"); err != nil {
+						return err
+					}
+				}
+			} else if err := checkSchema(first, tr.Tsdata.Schema()); err != nil {
+				return fmt.Errorf("%v: %w", infile, err)
+			}
+			delim := tr.Tsdata.Delimiter()
+			for {
+				line, err := tr.NextRaw()
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				if delim != tsdata.Delim {
+					line = strings.ReplaceAll(line, delim, tsdata.Delim)
+				}
+				if _, err := w.WriteString(line + "\n"); err != nil {
+					return err
+				}
+			}
+		}()
+		if err != nil {
+			w.Flush()
+			return err
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var checksumCommand = cli.Command{
+	Name:  "checksum",
+	Usage: "Creates and verifies SHA-256 checksum manifests",
+	Subcommands: []cli.Command{
+		{
+			Name:      "create",
+			Usage:     "Writes a checksum manifest for files",
+			UsageText: "tsdata checksum create [--by-day] [--output MANIFEST] FILE...",
+			Description: "Computes the SHA-256 digest of each FILE and writes one line per file to MANIFEST, " +
+				"in the same format as sha256sum. With --by-day, each TSDATA file also gets one line per UTC day " +
+				"with the digest of its decompressed data lines for that day, written as 'DIGEST  FILE#YYYY-MM-DD'. " +
+				"Day digests show which part of a damaged file has changed. Use '-' for STDOUT.",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "by-day",
+					Usage: "Also compute a digest of each UTC day of data lines",
+				},
+				cli.StringFlag{
+					Name:  "output, o",
+					Value: "-",
+					Usage: "Write the manifest to `MANIFEST`",
+				},
+				cli.BoolFlag{
+					Name:  "quiet, q",
+					Usage: "Suppress logging output",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if c.NArg() == 0 {
+					err := usageErrorf("missing required FILE argument")
+					logger.Error(err)
+					return err
+				}
+				if c.Bool("quiet") {
+					logger.SetOutput(ioutil.Discard)
+				}
+				err := checksumCreateCmd(c.Args(), c.String("output"), c.Bool("by-day"))
+				if err != nil {
+					logger.Error(err)
+				}
+				return err
+			},
+		},
+		{
+			Name:      "verify",
+			Usage:     "Checks files against a checksum manifest",
+			UsageText: "tsdata checksum verify MANIFEST",
+			Description: "Recomputes the digest of every file and day listed in MANIFEST and prints OK or FAILED " +
+				"for each to STDOUT. Days present in a file but not in MANIFEST are also reported as FAILED. " +
+				"Exits with an error if any check fails. Use '-' for STDIN.",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "quiet, q",
+					Usage: "Suppress logging output",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if c.NArg() == 0 {
+					err := usageErrorf("missing required MANIFEST argument")
+					logger.Error(err)
+					return err
+				}
+				if c.NArg() > 1 {
+					err := usageErrorf("too many arguments")
+					logger.Error(err)
+					return err
+				}
+				if c.Bool("quiet") {
+					logger.SetOutput(ioutil.Discard)
+				}
+				err := checksumVerifyCmd(c.Args().Get(0))
+				if err != nil {
+					logger.Error(err)
+				}
+				return err
+			},
+		},
+	},
+}
+
+func checksumCreateCmd(files []string, outfile string, byDay bool) error {
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+
+	for _, file := range files {
+		sum, err := fileDigest(file)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%v  %v\n", sum, file)
+		if !byDay {
+			continue
+		}
+		days, err := dayDigests(file)
+		if err != nil {
+			return err
+		}
+		for _, day := range sortedKeys(days) {
+			fmt.Fprintf(w, "%v  %v#%v\n", days[day], file, day)
+		}
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
+
+func checksumVerifyCmd(manifest string) error {
+	r, err := openInput(manifest)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// Read manifest entries, grouping day digests by file
+	var files []string
+	fileSums := map[string]string{}
+	daySums := map[string]map[string]string{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		parts := strings.SplitN(text, "  ", 2)
+		if len(parts) != 2 || len(parts[0]) != sha256.Size*2 {
+			return usageErrorf("%v: line %v, bad manifest line", manifest, line)
+		}
+		sum, name := parts[0], parts[1]
+		if i := strings.LastIndex(name, "#"); i >= 0 && isDay(name[i+1:]) {
+			file, day := name[:i], name[i+1:]
+			if daySums[file] == nil {
+				daySums[file] = map[string]string{}
+			}
+			daySums[file][day] = sum
+			continue
+		}
+		files = append(files, name)
+		fileSums[name] = sum
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	failed := 0
+	report := func(name string, ok bool, reason string) {
+		if ok {
+			fmt.Printf("%v: OK\n", name)
+			return
+		}
+		failed++
+		if reason != "" {
+			fmt.Printf("%v: FAILED %v\n", name, reason)
+		} else {
+			fmt.Printf("%v: FAILED\n", name)
+		}
+	}
+	for _, file := range files {
+		sum, err := fileDigest(file)
+		if err != nil {
+			logger.Error(err)
+			report(file, false, "open or read")
+			continue
+		}
+		report(file, sum == fileSums[file], "")
+		if daySums[file] == nil {
+			continue
+		}
+		days, err := dayDigests(file)
+		if err != nil {
+			logger.Error(err)
+			report(file+"#*", false, "open or read")
+			continue
+		}
+		for _, day := range sortedKeys(daySums[file]) {
+			got, ok := days[day]
+			if !ok {
+				report(file+"#"+day, false, "missing")
+				continue
+			}
+			report(file+"#"+day, got == daySums[file][day], "")
+		}
+		for _, day := range sortedKeys(days) {
+			if _, ok := daySums[file][day]; !ok {
+				report(file+"#"+day, false, "not in manifest")
+			}
+		}
+	}
+
+	if failed > 0 {
+		return dataErrorf("%v checks failed", failed)
+	}
+	return nil
+}
+
+// fileDigest returns the hex SHA-256 digest of the file at path.
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// dayDigests returns hex SHA-256 digests of the data lines in the TSDATA file
+// at path for each UTC day, keyed by YYYY-MM-DD. Lines without a valid
+// timestamp are keyed by NA.
+func dayDigests(path string) (map[string]string, error) {
+	r, err := openInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	tr, err := tsdata.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	delim := tr.Tsdata.Delimiter()
+	hashes := map[string]hash.Hash{}
+	for {
+		line, err := tr.NextRaw()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", path, err)
+		}
+		day := tsdata.NA
+		t, err := tsdata.ParseTime(strings.TrimSpace(strings.SplitN(line, delim, 2)[0]))
+		if err == nil {
+			day = t.UTC().Format("2006-01-02")
+		}
+		h, ok := hashes[day]
+		if !ok {
+			h = sha256.New()
+			hashes[day] = h
+		}
+		io.WriteString(h, line+"\n")
+	}
+	digests := map[string]string{}
+	for day, h := range hashes {
+		digests[day] = hex.EncodeToString(h.Sum(nil))
+	}
+	return digests, nil
+}
+
+// isDay returns true if s is a day key written by dayDigests.
+func isDay(s string) bool {
+	if s == tsdata.NA {
+		return true
+	}
+	_, err := tsdata.ParseTime(s + "T00:00:00Z")
+	return err == nil && len(s) == 10
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
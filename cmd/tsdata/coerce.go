@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ctberthiaume/tsdata"
+)
+
+// coerceTrue and coerceFalse are boolean spellings rewritten as TRUE and
+// FALSE, compared without case.
+var (
+	coerceTrue  = []string{"true", "t", "1"}
+	coerceFalse = []string{"false", "f", "0"}
+)
+
+// coerceMissing are tokens for missing values in numeric and boolean columns
+// rewritten as NA. Empty fields are also rewritten.
+var coerceMissing = []string{"NaN", "nan", "null", "NULL", "-999"}
+
+// coercer rewrites common spellings of booleans and missing values in data
+// lines and counts each rewrite.
+type coercer struct {
+	counts map[string]int // by "'from' to TO"
+}
+
+func newCoercer() *coercer {
+	return &coercer{counts: map[string]int{}}
+}
+
+// line returns line with fields coerced according to column types in t.
+func (c *coercer) line(t *tsdata.Tsdata, line string) string {
+	fields := strings.Split(line, t.Delimiter())
+	changed := false
+	for i := 1; i < len(fields) && i < len(t.Types); i++ {
+		v := strings.TrimSpace(fields[i])
+		to := c.value(t.Types[i], v)
+		if to == "" || to == v {
+			continue
+		}
+		c.counts[fmt.Sprintf("'%v' to %v", v, to)]++
+		fields[i] = to
+		changed = true
+	}
+	if !changed {
+		return line
+	}
+	return strings.Join(fields, t.Delimiter())
+}
+
+// value returns the coerced form of v in a column of type typ, or "" if v
+// isn't coerced.
+func (c *coercer) value(typ string, v string) string {
+	switch typ {
+	case "boolean":
+		for _, s := range coerceTrue {
+			if strings.EqualFold(v, s) {
+				return "TRUE"
+			}
+		}
+		for _, s := range coerceFalse {
+			if strings.EqualFold(v, s) {
+				return "FALSE"
+			}
+		}
+	case "float", "integer", "latitude", "longitude":
+	default:
+		return ""
+	}
+	if v == "" {
+		return tsdata.NA
+	}
+	for _, s := range coerceMissing {
+		if v == s {
+			return tsdata.NA
+		}
+	}
+	return ""
+}
+
+// summary describes the rewrites made so far, most frequent first, or
+// returns "" if there were none.
+func (c *coercer) summary() string {
+	if len(c.counts) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(c.counts))
+	total := 0
+	for k, n := range c.counts {
+		keys = append(keys, k)
+		total += n
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if c.counts[keys[i]] != c.counts[keys[j]] {
+			return c.counts[keys[i]] > c.counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%v (%v)", k, c.counts[k])
+	}
+	return fmt.Sprintf("coerced %v values: %v", total, strings.Join(parts, ", "))
+}
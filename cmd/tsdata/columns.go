@@ -0,0 +1,535 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var addColumnCommand = cli.Command{
+	Name:      "add-column",
+	Usage:     "Appends a column to a TSDATA file",
+	UsageText: "tsdata add-column --name NAME [--type TYPE] [--unit UNIT] [--comment TEXT] [--default VALUE] INFILE OUTFILE",
+	Description: "Writes INFILE to OUTFILE with a new last column named NAME, setting the new field of every data " +
+		"line to --default. TYPE may be any column type, including category:VALUE|VALUE. --default must be a valid " +
+		"value of TYPE or NA. Data lines are copied in one pass without validation. Lines with missing fields are " +
+		"padded with NA before the new field is added and extra fields are dropped. " +
+		"Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "name",
+			Usage: "`NAME` of the new column",
+		},
+		cli.StringFlag{
+			Name:  "type",
+			Value: "text",
+			Usage: "`TYPE` of the new column",
+		},
+		cli.StringFlag{
+			Name:  "unit",
+			Value: tsdata.NA,
+			Usage: "`UNIT` of the new column",
+		},
+		cli.StringFlag{
+			Name:  "comment",
+			Value: tsdata.NA,
+			Usage: "Column comment `TEXT` of the new column",
+		},
+		cli.StringFlag{
+			Name:  "default",
+			Value: tsdata.NA,
+			Usage: "`VALUE` of the new column in every data line",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 2 {
+			err := usageErrorf("missing required OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 2 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.String("name") == "" {
+			err := usageErrorf("missing required --name")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		col := tsdata.ColumnInfo{
+			Name:    c.String("name"),
+			Type:    c.String("type"),
+			Unit:    c.String("unit"),
+			Comment: c.String("comment"),
+		}
+		err := addColumnCmd(c.Args().Get(0), c.Args().Get(1), col, c.String("default"))
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+func addColumnCmd(infile string, outfile string, col tsdata.ColumnInfo, value string) error {
+	editHeader := func(s tsdata.Schema) (tsdata.Schema, error) {
+		if i := indexOfColumn(s.Headers, col.Name); i != -1 {
+			return s, usageErrorf("column '%v' already exists", col.Name)
+		}
+		if len(s.Comments) == 0 {
+			s.Comments = nas(len(s.Headers))
+		}
+		s.Comments = append(s.Comments, col.Comment)
+		s.Types = append(s.Types, col.Type)
+		s.Units = append(s.Units, col.Unit)
+		s.Headers = append(s.Headers, col.Name)
+		if len(s.Constraints) > 0 {
+			s.Constraints = append(s.Constraints, tsdata.Constraint{})
+		}
+		ns, err := tsdata.ParseSchema(s.Header())
+		if err != nil {
+			return s, usageErrorf("%v", err)
+		}
+		cols := tsdata.New(tsdata.WithSchema(ns)).Columns()
+		if !cols[len(cols)-1].Checker(value) {
+			return s, usageErrorf("--default '%v' is not a valid %v value", value, col.Type)
+		}
+		return ns, nil
+	}
+	return editColumns(infile, outfile, editHeader, func(_ int, fields []string) []string {
+		return append(fields, value)
+	})
+}
+
+var dropColumnCommand = cli.Command{
+	Name:      "drop-column",
+	Usage:     "Removes columns from a TSDATA file",
+	UsageText: "tsdata drop-column NAME... INFILE OUTFILE",
+	Description: "Writes INFILE to OUTFILE without the columns NAME in the header or data lines. The time column " +
+		"can't be dropped, and at least one data column must be left. Data lines are copied in one pass without " +
+		"validation. Lines with missing fields are padded with NA and extra fields are dropped. " +
+		"Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() < 3 {
+			err := usageErrorf("missing required NAME, INFILE, and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		args := c.Args()
+		n := len(args)
+		err := dropColumnCmd(args[n-2], args[n-1], args[:n-2])
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+func dropColumnCmd(infile string, outfile string, names []string) error {
+	var keep []int
+	editHeader := func(s tsdata.Schema) (tsdata.Schema, error) {
+		var err error
+		keep, err = keepColumns(s, names)
+		if err != nil {
+			return s, err
+		}
+		return projectTsdata(tsdata.New(tsdata.WithSchema(s)), keep).Schema(), nil
+	}
+	return editColumns(infile, outfile, editHeader, func(_ int, fields []string) []string {
+		return selectFields(fields, keep)
+	})
+}
+
+var renameColumnCommand = cli.Command{
+	Name:      "rename-column",
+	Usage:     "Renames columns of a TSDATA file",
+	UsageText: "tsdata rename-column OLD=NEW... INFILE OUTFILE",
+	Description: "Writes INFILE to OUTFILE with column OLD renamed to NEW in the Headers row. Renames happen " +
+		"together, so two columns can swap names. New names must be unique and can't be time or NA, and the time " +
+		"column can't be renamed. Data lines are copied as they are. " +
+		"Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() < 3 {
+			err := usageErrorf("missing required OLD=NEW, INFILE, and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		args := c.Args()
+		n := len(args)
+		renames, err := parseColumnArgs(args[:n-2], "OLD=NEW")
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = renameColumnCmd(args[n-2], args[n-1], renames)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+func renameColumnCmd(infile string, outfile string, renames map[string]string) error {
+	editHeader := func(s tsdata.Schema) (tsdata.Schema, error) {
+		for old := range renames {
+			i := indexOfColumn(s.Headers, old)
+			if i == -1 {
+				return s, usageErrorf("no column '%v'", old)
+			}
+			if i == 0 {
+				return s, usageErrorf("can't rename the time column")
+			}
+		}
+		headers := make([]string, len(s.Headers))
+		seen := map[string]string{}
+		for i, h := range s.Headers {
+			name, ok := renames[h]
+			if !ok {
+				name = h
+			}
+			if i > 0 && (name == "time" || name == tsdata.NA) {
+				return s, usageErrorf("can't rename column '%v' to reserved name '%v'", h, name)
+			}
+			if other, ok := seen[name]; ok {
+				return s, usageErrorf("columns '%v' and '%v' would both be named '%v'", other, h, name)
+			}
+			seen[name] = h
+			headers[i] = name
+		}
+		s.Headers = headers
+		return s, nil
+	}
+	return editColumns(infile, outfile, editHeader, nil)
+}
+
+var reorderCommand = cli.Command{
+	Name:      "reorder",
+	Usage:     "Reorders the columns of a TSDATA file",
+	UsageText: "tsdata reorder --columns NAMES INFILE OUTFILE",
+	Description: "Writes INFILE to OUTFILE with columns in the order given by --columns, in the header and every " +
+		"data line, e.g. to give files from different cruises the same column order before concatenation. The " +
+		"time column stays first and may be left out of NAMES. Columns not in NAMES follow in their original order. " +
+		"Data lines are copied in one pass without validation. Lines with missing fields are padded with NA and " +
+		"extra fields are dropped. " +
+		"Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "columns, c",
+			Usage: "Comma-separated column `NAMES` in their new order",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 2 {
+			err := usageErrorf("missing required OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 2 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.String("columns") == "" {
+			err := usageErrorf("missing required --columns")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		names := strings.Split(c.String("columns"), ",")
+		err := reorderCmd(c.Args().Get(0), c.Args().Get(1), names)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+func reorderCmd(infile string, outfile string, names []string) error {
+	var order []int
+	editHeader := func(s tsdata.Schema) (tsdata.Schema, error) {
+		for i, name := range names {
+			if strings.TrimSpace(name) == "time" && i > 0 {
+				return s, usageErrorf("time must be the first column")
+			}
+		}
+		t := tsdata.New(tsdata.WithSchema(s))
+		cols, err := selectColumns(t, names)
+		if err != nil {
+			return s, err
+		}
+		seen := map[int]bool{}
+		for _, i := range cols {
+			seen[i] = true
+		}
+		for i := range s.Headers {
+			if !seen[i] {
+				cols = append(cols, i)
+			}
+		}
+		order = cols
+		return projectTsdata(t, order).Schema(), nil
+	}
+	return editColumns(infile, outfile, editHeader, func(_ int, fields []string) []string {
+		return selectFields(fields, order)
+	})
+}
+
+var setUnitsCommand = cli.Command{
+	Name:      "set-units",
+	Usage:     "Sets column units of a TSDATA file",
+	UsageText: "tsdata set-units NAME=UNIT... INFILE OUTFILE",
+	Description: "Writes INFILE to OUTFILE with the Units row value of each column NAME set to UNIT. Data lines " +
+		"are copied as they are. " +
+		"Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		return setHeaderAction(c, "NAME=UNIT", func(s *tsdata.Schema, i int, unit string) {
+			s.Units[i] = unit
+		})
+	},
+}
+
+var setCommentCommand = cli.Command{
+	Name:      "set-comment",
+	Usage:     "Sets column comments of a TSDATA file",
+	UsageText: "tsdata set-comment NAME=TEXT... INFILE OUTFILE",
+	Description: "Writes INFILE to OUTFILE with the Comments row value of each column NAME set to TEXT. A comment " +
+		"may set a column's range, e.g. range=0..45. Data lines are copied as they are. " +
+		"Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		return setHeaderAction(c, "NAME=TEXT", func(s *tsdata.Schema, i int, comment string) {
+			if len(s.Comments) == 0 {
+				s.Comments = nas(len(s.Headers))
+			}
+			s.Comments[i] = comment
+		})
+	},
+}
+
+// setHeaderAction runs set-units or set-comment, calling set to change the
+// header value of each column given as a NAME=VALUE argument. form describes
+// the argument in errors.
+func setHeaderAction(c *cli.Context, form string, set func(s *tsdata.Schema, i int, value string)) error {
+	if c.NArg() < 3 {
+		err := usageErrorf("missing required %v, INFILE, and OUTFILE arguments", form)
+		logger.Error(err)
+		return err
+	}
+	if c.Bool("quiet") {
+		logger.SetOutput(ioutil.Discard)
+	}
+	args := c.Args()
+	n := len(args)
+	values, err := parseColumnArgs(args[:n-2], form)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	for name, v := range values {
+		if strings.ContainsAny(v, "\t\r\n") {
+			err := usageErrorf("value for column '%v' can't contain tabs or line breaks", name)
+			logger.Error(err)
+			return err
+		}
+	}
+	err = editColumns(args[n-2], args[n-1], func(s tsdata.Schema) (tsdata.Schema, error) {
+		for name, v := range values {
+			i := indexOfColumn(s.Headers, name)
+			if i == -1 {
+				return s, usageErrorf("no column '%v'", name)
+			}
+			set(&s, i, v)
+		}
+		return s, nil
+	}, nil)
+	if err != nil {
+		logger.Error(err)
+	}
+	return err
+}
+
+// parseColumnArgs parses NAME=VALUE arguments into a map from column name to
+// value. form describes the expected argument in errors.
+func parseColumnArgs(args []string, form string) (map[string]string, error) {
+	m := map[string]string{}
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, usageErrorf("bad argument '%v', expected %v", arg, form)
+		}
+		if _, ok := m[parts[0]]; ok {
+			return nil, usageErrorf("column '%v' given more than once", parts[0])
+		}
+		m[parts[0]] = parts[1]
+	}
+	return m, nil
+}
+
+// keepColumns returns the indexes of the columns of s other than names. The
+// time column and at least one data column must be kept.
+func keepColumns(s tsdata.Schema, names []string) ([]int, error) {
+	drop := map[int]bool{}
+	for _, name := range names {
+		i := indexOfColumn(s.Headers, name)
+		if i == -1 {
+			return nil, usageErrorf("no column '%v'", name)
+		}
+		if i == 0 {
+			return nil, usageErrorf("can't drop the time column")
+		}
+		drop[i] = true
+	}
+	var keep []int
+	for i := range s.Headers {
+		if !drop[i] {
+			keep = append(keep, i)
+		}
+	}
+	if len(keep) < 2 {
+		return nil, usageErrorf("can't drop every data column")
+	}
+	return keep, nil
+}
+
+// selectFields returns fields in columns cols.
+func selectFields(fields []string, cols []int) []string {
+	p := make([]string, len(cols))
+	for j, i := range cols {
+		p[j] = fields[i]
+	}
+	return p
+}
+
+// nas returns n NA values.
+func nas(n int) []string {
+	s := make([]string, n)
+	for i := range s {
+		s[i] = tsdata.NA
+	}
+	return s
+}
+
+// editColumns writes INFILE to OUTFILE with the header changed by editHeader
+// and the fields of each data line changed by editFields, in one pass without
+// validating data lines. editFields gets the line number and fields padded
+// with NA or truncated to the number of columns in INFILE and the new header,
+// and may be nil to copy data lines as they are. Errors in the edited header
+// are usage errors.
+func editColumns(infile string, outfile string, editHeader func(tsdata.Schema) (tsdata.Schema, error), editFields func(line int, fields []string) []string) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	tr, err := tsdata.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("%v: %w", infile, err)
+	}
+
+	s, err := editHeader(tr.Tsdata.Schema())
+	if err != nil {
+		return err
+	}
+	s, err = tsdata.ParseSchema(s.Header())
+	if err != nil {
+		return usageErrorf("%v", err)
+	}
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+	if _, err := w.WriteString(s.Header() + "\n"); err != nil {
+		return err
+	}
+
+	delim := tr.Tsdata.Delimiter()
+	cols := len(tr.Tsdata.Headers)
+	for {
+		line, err := tr.NextRaw()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if editFields != nil && line != "" {
+			fields := strings.Split(line, delim)
+			if len(fields) < cols {
+				logger.Warnf(tr.Line(), "found %v columns, expected %v, padded with NA", len(fields), cols)
+				fields = append(fields, nas(cols-len(fields))...)
+			} else if len(fields) > cols {
+				logger.Warnf(tr.Line(), "found %v columns, expected %v, extra fields dropped", len(fields), cols)
+			}
+			line = strings.Join(editFields(tr.Line(), fields[:cols]), tsdata.Delim)
+		} else if delim != tsdata.Delim {
+			line = strings.ReplaceAll(line, delim, tsdata.Delim)
+		}
+		if _, err := w.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return outf.Close()
+}
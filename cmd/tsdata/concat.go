@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var concatCommand = cli.Command{
+	Name:      "concat",
+	Usage:     "Concatenates TSDATA files with compatible headers",
+	UsageText: "tsdata concat [--sort] INFILE1 INFILE2 ... OUTFILE",
+	Description: "Appends data lines from each INFILE to OUTFILE after a single header. " +
+		"All inputs must have the same FileType, Project, column names, types, and units. " +
+		"The header of INFILE1 is used for OUTFILE. Use '-' for STDOUT.",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "sort",
+			Usage: "Sort output lines by time. Lines are held in memory.",
+		},
+		cli.StringSliceFlag{
+			Name:  "alias",
+			Usage: "Treat column `OLD=NEW` as column NEW, may be repeated",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() < 2 {
+			err := usageErrorf("expected at least one INFILE argument and one OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		args := c.Args()
+		err = concatCmd(args[:len(args)-1], args[len(args)-1], c.Bool("sort"), opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+func concatCmd(infiles []string, outfile string, sortLines bool, opts []tsdata.Option) error {
+	readers := make([]*tsdata.Reader, len(infiles))
+	for i, infile := range infiles {
+		r, err := openInput(infile)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		tr, err := tsdata.NewReader(r, opts...)
+		if err != nil {
+			return fmt.Errorf("%v: %w", infile, err)
+		}
+		tr.Strict = false
+		readers[i] = tr
+	}
+	// Check all headers before writing anything
+	first := readers[0].Tsdata
+	for i, tr := range readers[1:] {
+		err := checkSchema(first.Schema(), tr.Tsdata.Schema())
+		if err != nil {
+			return fmt.Errorf("%v: %w", infiles[i+1], err)
+		}
+	}
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+	_, err = w.WriteString(first.Header() + "\n")
+	if err != nil {
+		return err
+	}
+
+	var lines []tsdata.Data
+	for i, tr := range readers {
+		err = eachFileLine(infiles[i], tr, func(data tsdata.Data) error {
+			if sortLines {
+				lines = append(lines, data)
+				return nil
+			}
+			_, err := w.WriteString(strings.Join(data.Fields, tsdata.Delim) + "\n")
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if sortLines {
+		sort.SliceStable(lines, func(i, j int) bool { return lines[i].Time.Before(lines[j].Time) })
+		for _, data := range lines {
+			_, err = w.WriteString(strings.Join(data.Fields, tsdata.Delim) + "\n")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
+
+// checkSchema returns an error describing the first difference between
+// schemas a and b which would prevent their data lines being combined.
+func checkSchema(a tsdata.Schema, b tsdata.Schema) error {
+	if a.Equal(b) {
+		return nil
+	}
+	if a.FileType != b.FileType {
+		return headerErrorf("FileType '%v' does not match '%v'", b.FileType, a.FileType)
+	}
+	if a.Project != b.Project {
+		return headerErrorf("Project '%v' does not match '%v'", b.Project, a.Project)
+	}
+	return checkColumns(a, b)
+}
+
+// checkColumns returns an error describing the first difference in column
+// names, types, or units between a and b.
+func checkColumns(a tsdata.Schema, b tsdata.Schema) error {
+	if len(a.Headers) != len(b.Headers) {
+		return headerErrorf("found %v columns, expected %v", len(b.Headers), len(a.Headers))
+	}
+	for i := range a.Headers {
+		if a.Headers[i] != b.Headers[i] {
+			return headerErrorf("column %v name '%v' does not match '%v'", i+1, b.Headers[i], a.Headers[i])
+		}
+		if a.Types[i] != b.Types[i] {
+			return headerErrorf("column %v (%v) type '%v' does not match '%v'", i+1, a.Headers[i], b.Types[i], a.Types[i])
+		}
+		if a.Units[i] != b.Units[i] {
+			return headerErrorf("column %v (%v) unit '%v' does not match '%v'", i+1, a.Headers[i], b.Units[i], a.Units[i])
+		}
+	}
+	return nil
+}
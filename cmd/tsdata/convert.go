@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/ctberthiaume/tsdata/internal/parquet"
+	"github.com/urfave/cli"
+)
+
+var convertCommand = cli.Command{
+	Name:      "convert",
+	Usage:     "Converts a TSDATA file to another format",
+	UsageText: "tsdata convert --to csv|json|parquet|influx-lp [--compression gzip|none] [--time-format FORMAT] [--columns NAMES] INFILE OUTFILE",
+	Description: "Validates INFILE and writes its valid data lines to OUTFILE in the format given by --to: " +
+		"csv, json for newline-delimited JSON objects, parquet, or influx-lp for InfluxDB line protocol with " +
+		"the fileType as the measurement. NA values are written as NA in csv, null in json and parquet, " +
+		"and left out of influx-lp lines. " +
+		"Every format shares the same reader and flags. --columns selects and orders data columns; the first " +
+		"time column is always included. --time-format formats timestamps in csv and json output as with csv. " +
+		"--compression gzip compresses csv, json, and influx-lp output and Parquet data pages. Text output is " +
+		"also compressed if OUTFILE ends with .gz, and Parquet pages are compressed by default. " +
+		"The csv, json, and parquet commands remain for compatibility. " +
+		"Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "to, t",
+			Usage: "Output `FORMAT`, csv, json, parquet, or influx-lp",
+		},
+		cli.StringFlag{
+			Name:  "compression",
+			Usage: "Output compression `CODEC`, gzip or none",
+		},
+		cli.StringFlag{
+			Name:  "time-format",
+			Value: "rfc3339",
+			Usage: "Write timestamps in csv and json output in `FORMAT`, rfc3339, epoch, epoch_ms, or a Go time layout",
+		},
+		cli.StringFlag{
+			Name:  "columns, c",
+			Usage: "Write only the comma-separated columns `NAMES` in this order, after the time column",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 2 {
+			err := usageErrorf("missing required OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 2 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		conf := convertConfig{to: c.String("to"), compression: c.String("compression")}
+		switch conf.to {
+		case "csv", "json", "parquet", "influx-lp":
+		case "":
+			err := usageErrorf("missing required --to")
+			logger.Error(err)
+			return err
+		default:
+			err := usageErrorf("bad format '%v', expected csv, json, parquet, or influx-lp", conf.to)
+			logger.Error(err)
+			return err
+		}
+		if conf.compression != "" && conf.compression != "gzip" && conf.compression != "none" {
+			err := usageErrorf("bad compression '%v', expected gzip or none", conf.compression)
+			logger.Error(err)
+			return err
+		}
+		if c.IsSet("time-format") && conf.to != "csv" && conf.to != "json" {
+			err := usageErrorf("--time-format only applies to csv and json output")
+			logger.Error(err)
+			return err
+		}
+		if c.String("columns") != "" {
+			conf.columns = strings.Split(c.String("columns"), ",")
+		}
+		var err error
+		conf.timeFormat, err = parseTimeLayout(c.String("time-format"))
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = convertCmd(c.Args().Get(0), c.Args().Get(1), conf, opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// convertConfig holds convert command settings.
+type convertConfig struct {
+	to          string        // output format
+	compression string        // gzip, none, or "" for the format's default
+	timeFormat  timeFormatter // time column format for text output, nil for RFC3339
+	columns     []string      // data columns to write, nil for all
+}
+
+// recordWriter writes data lines in one output format.
+type recordWriter interface {
+	write(data tsdata.Data) error
+	// close writes any buffered output but doesn't close the underlying writer
+	close() error
+}
+
+func convertCmd(infile string, outfile string, conf convertConfig, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	cols, err := selectColumns(tr.Tsdata, conf.columns)
+	if err != nil {
+		return err
+	}
+	t := projectTsdata(tr.Tsdata, cols)
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	var out io.WriteCloser = outf
+	if conf.compression == "gzip" && conf.to != "parquet" && !strings.HasSuffix(outfile, ".gz") {
+		out = gzipWriteCloser(outf)
+		defer out.Close()
+	}
+	bw := bufio.NewWriter(out)
+
+	var rw recordWriter
+	switch conf.to {
+	case "csv":
+		rw, err = newCSVRecordWriter(bw, t, conf.timeFormat)
+	case "json":
+		rw = &jsonRecordWriter{w: bw, t: t, format: conf.timeFormat}
+	case "parquet":
+		codec := parquet.Gzip
+		if conf.compression == "none" {
+			codec = parquet.Uncompressed
+		}
+		pw := parquet.NewWriter(bw, parquetColumns(t))
+		pw.Codec = codec
+		pw.SetMetadata("tsdata.header", t.Header())
+		rw = parquetRecordWriter{pw}
+	case "influx-lp":
+		rw = &influxRecordWriter{
+			w:           bw,
+			t:           t,
+			measurement: influxEscape(t.FileType, ", "),
+			project:     influxEscape(t.Project, ",= "),
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	err = eachLine(tr, func(data tsdata.Data) error {
+		return rw.write(projectData(data, cols))
+	})
+	if err != nil {
+		return err
+	}
+	err = rw.close()
+	if err != nil {
+		return err
+	}
+	err = bw.Flush()
+	if err != nil {
+		return err
+	}
+	if out != outf {
+		if err := out.Close(); err != nil {
+			return err
+		}
+	}
+	return outf.Close()
+}
+
+// selectColumns returns the indexes of the first time column and the named
+// data columns in t, or of every column if names is empty.
+func selectColumns(t *tsdata.Tsdata, names []string) ([]int, error) {
+	if len(names) == 0 {
+		cols := make([]int, len(t.Headers))
+		for i := range cols {
+			cols[i] = i
+		}
+		return cols, nil
+	}
+	cols := []int{0}
+	seen := map[int]bool{0: true}
+	for _, name := range names {
+		i := t.Index(strings.TrimSpace(name))
+		if i == -1 {
+			return nil, usageErrorf("no column '%v'", name)
+		}
+		if seen[i] {
+			continue
+		}
+		seen[i] = true
+		cols = append(cols, i)
+	}
+	return cols, nil
+}
+
+// projectTsdata returns metadata for columns cols of t.
+func projectTsdata(t *tsdata.Tsdata, cols []int) *tsdata.Tsdata {
+	s := t.Schema()
+	p := tsdata.Schema{FileType: s.FileType, Project: s.Project, FileDescription: s.FileDescription}
+	for _, i := range cols {
+		if i < len(s.Comments) {
+			p.Comments = append(p.Comments, s.Comments[i])
+		}
+		p.Types = append(p.Types, s.Types[i])
+		p.Units = append(p.Units, s.Units[i])
+		p.Headers = append(p.Headers, s.Headers[i])
+		if i < len(s.Constraints) {
+			p.Constraints = append(p.Constraints, s.Constraints[i])
+		}
+	}
+	return tsdata.New(tsdata.WithSchema(p))
+}
+
+// projectData returns the values of data in columns cols.
+func projectData(data tsdata.Data, cols []int) tsdata.Data {
+	if len(cols) == len(data.Fields) {
+		return data
+	}
+	p := tsdata.Data{Time: data.Time, Line: data.Line, Warnings: data.Warnings}
+	p.Fields = make([]string, len(cols))
+	p.Values = make([]interface{}, len(cols))
+	for j, i := range cols {
+		p.Fields[j] = data.Fields[i]
+		p.Values[j] = data.Values[i]
+	}
+	return p
+}
+
+type csvRecordWriter struct {
+	w      *csv.Writer
+	t      *tsdata.Tsdata
+	format timeFormatter
+}
+
+func newCSVRecordWriter(w io.Writer, t *tsdata.Tsdata, format timeFormatter) (*csvRecordWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Headers); err != nil {
+		return nil, err
+	}
+	return &csvRecordWriter{w: cw, t: t, format: format}, nil
+}
+
+func (c *csvRecordWriter) write(data tsdata.Data) error {
+	return c.w.Write(formatTimes(c.t, data, c.format))
+}
+
+func (c *csvRecordWriter) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonRecordWriter struct {
+	w      io.Writer
+	t      *tsdata.Tsdata
+	format timeFormatter
+	buf    bytes.Buffer
+}
+
+func (j *jsonRecordWriter) write(data tsdata.Data) error {
+	if j.format != nil {
+		values := make([]interface{}, len(data.Values))
+		copy(values, data.Values)
+		for i, v := range values {
+			if tm, ok := v.(time.Time); ok && j.t.Types[i] == "time" {
+				values[i] = j.format(tm)
+			}
+		}
+		data.Values = values
+	}
+	j.buf.Reset()
+	if err := appendJSONObject(&j.buf, j.t.Headers, data); err != nil {
+		return err
+	}
+	j.buf.WriteByte('\n')
+	_, err := j.w.Write(j.buf.Bytes())
+	return err
+}
+
+func (j *jsonRecordWriter) close() error {
+	return nil
+}
+
+type parquetRecordWriter struct {
+	w *parquet.Writer
+}
+
+func (p parquetRecordWriter) write(data tsdata.Data) error {
+	return p.w.Write(data.Values)
+}
+
+func (p parquetRecordWriter) close() error {
+	return p.w.Close()
+}
+
+type influxRecordWriter struct {
+	w           io.Writer
+	t           *tsdata.Tsdata
+	measurement string
+	project     string
+	buf         bytes.Buffer
+}
+
+func (f *influxRecordWriter) write(data tsdata.Data) error {
+	f.buf.Reset()
+	if !appendInfluxLine(&f.buf, f.measurement, f.project, f.t, data) {
+		return nil
+	}
+	_, err := f.w.Write(f.buf.Bytes())
+	return err
+}
+
+func (f *influxRecordWriter) close() error {
+	return nil
+}
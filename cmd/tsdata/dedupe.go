@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"math/big"
+	"strings"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var dedupeCommand = cli.Command{
+	Name:      "dedupe",
+	Usage:     "Removes lines with duplicate timestamps",
+	UsageText: "tsdata dedupe [--keep first|last|mean|error] INFILE OUTFILE",
+	Description: "Writes INFILE to OUTFILE keeping only one line for each timestamp. " +
+		"--keep first keeps the first line seen for each timestamp, --keep last keeps the last line, " +
+		"--keep mean writes the mean of the non-NA values in float, integer, latitude, and longitude columns and " +
+		"the first line's value in other columns, and --keep error exits with an error at the first duplicate. " +
+		"Lines without duplicates are written as they are. --keep last and mean hold lines in memory. The policy is logged with the number of lines removed. " +
+		"Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "keep",
+			Value: "first",
+			Usage: "How to resolve duplicate lines, `POLICY` is first, last, mean, or error",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 2 {
+			err := usageErrorf("missing required OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		keep := c.String("keep")
+		if keep != "first" && keep != "last" && keep != "mean" && keep != "error" {
+			err := usageErrorf("bad --keep value '%v', expected first, last, mean, or error", keep)
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = dedupeCmd(c.Args().Get(0), c.Args().Get(1), keep, opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+func dedupeCmd(infile string, outfile string, keep string, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+	_, err = w.WriteString(tr.Tsdata.Header() + "\n")
+	if err != nil {
+		return err
+	}
+
+	removed := 0
+	seen := map[int64]int{} // time in ns -> line number, or index for keep last and mean
+	var lines []tsdata.Data
+	var groups [][]tsdata.Data // lines for each timestamp for keep mean
+	err = eachLine(tr, func(data tsdata.Data) error {
+		key := data.Time.UnixNano()
+		if keep == "mean" {
+			if i, ok := seen[key]; ok {
+				removed++
+				groups[i] = append(groups[i], data)
+				return nil
+			}
+			seen[key] = len(groups)
+			groups = append(groups, []tsdata.Data{data})
+			return nil
+		}
+		if keep == "last" {
+			if _, ok := seen[key]; ok {
+				removed++
+			}
+			seen[key] = len(lines)
+			lines = append(lines, data)
+			return nil
+		}
+		if first, ok := seen[key]; ok {
+			if keep == "error" {
+				return dataErrorf("line %v, duplicate timestamp %v first seen on line %v", tr.Line(), data.Fields[0], first)
+			}
+			removed++
+			return nil
+		}
+		seen[key] = tr.Line()
+		_, err := w.WriteString(strings.Join(data.Fields, tsdata.Delim) + "\n")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for i, data := range lines {
+		if seen[data.Time.UnixNano()] != i {
+			continue
+		}
+		_, err = w.WriteString(strings.Join(data.Fields, tsdata.Delim) + "\n")
+		if err != nil {
+			return err
+		}
+	}
+	for _, group := range groups {
+		_, err = w.WriteString(strings.Join(meanLines(tr.Tsdata, group), tsdata.Delim) + "\n")
+		if err != nil {
+			return err
+		}
+	}
+	if removed > 0 {
+		logger.Printf("removed %v duplicate lines with --keep %v\n", removed, keep)
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
+
+// meanLines returns the fields of lines, which have the same timestamp, with
+// the mean of the non-NA values in each numeric column and the fields of the
+// first line in other columns. Fields are copied as they are where there is
+// only one line or one non-NA value, and integer means are exact.
+func meanLines(t *tsdata.Tsdata, lines []tsdata.Data) []string {
+	out := make([]string, len(lines[0].Fields))
+	copy(out, lines[0].Fields)
+	if len(lines) == 1 {
+		return out
+	}
+	for i := 1; i < len(out); i++ {
+		var floats []float64
+		var ints []int64
+		field := ""
+		for _, data := range lines {
+			switch x := data.Values[i].(type) {
+			case float64:
+				floats = append(floats, x)
+				field = data.Fields[i]
+			case int64:
+				ints = append(ints, x)
+				field = data.Fields[i]
+			}
+		}
+		switch {
+		case len(floats)+len(ints) == 1:
+			out[i] = field
+		case len(floats) > 0:
+			out[i] = formatNumber(t.Types[i], mean(floats))
+		case len(ints) > 0:
+			out[i] = meanInt(ints)
+		}
+	}
+	return out
+}
+
+// meanInt returns the mean of v rounded half away from zero, like
+// formatNumber, computed without overflow or loss of precision.
+func meanInt(v []int64) string {
+	sum := new(big.Int)
+	for _, x := range v {
+		sum.Add(sum, big.NewInt(x))
+	}
+	n := big.NewInt(int64(len(v)))
+	q, r := new(big.Int).QuoRem(sum, n, new(big.Int))
+	if r.Abs(r).Lsh(r, 1).Cmp(n) >= 0 {
+		q.Add(q, big.NewInt(int64(sum.Sign())))
+	}
+	return q.String()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ctberthiaume/tsdata"
+)
+
+func TestMeanLines(t *testing.T) {
+	tsd := tsdata.New()
+	header := "a\nb\nc\nNA\tNA\tNA\tNA\ntime\tfloat\tinteger\ttext\nNA\tNA\tNA\tNA\ntime\tf\ti\ts"
+	if err := tsd.ParseHeader(header); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{"single line", []string{"2020-01-01T00:00:00Z\t3.10\t9007199254740993\tx"}, "2020-01-01T00:00:00Z\t3.10\t9007199254740993\tx"},
+		{"one value", []string{"2020-01-01T00:00:00Z\tNA\tNA\tx", "2020-01-01T00:00:00Z\t1.5e3\t7\ty"}, "2020-01-01T00:00:00Z\t1.5e3\t7\tx"},
+		{"exact integers", []string{"2020-01-01T00:00:00Z\t1\t9007199254740993\tx", "2020-01-01T00:00:00Z\t2\t9007199254740995\ty"}, "2020-01-01T00:00:00Z\t1.5\t9007199254740994\tx"},
+		{"no overflow", []string{"2020-01-01T00:00:00Z\t1\t9223372036854775807\tx", "2020-01-01T00:00:00Z\t2\t9223372036854775806\tx"}, "2020-01-01T00:00:00Z\t1.5\t9223372036854775807\tx"},
+		{"round half away from zero", []string{"2020-01-01T00:00:00Z\t1\t-1\tx", "2020-01-01T00:00:00Z\t2\t-2\tx"}, "2020-01-01T00:00:00Z\t1.5\t-2\tx"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines []tsdata.Data
+			for _, line := range tt.lines {
+				data, err := tsd.ValidateLine(line, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				lines = append(lines, data)
+			}
+			if got := strings.Join(meanLines(tsd, lines), tsdata.Delim); got != tt.want {
+				t.Errorf("meanLines() = %q, expected %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ctberthiaume/tsdata"
+)
+
+// parseFromDelimiter returns the delimiter named by the clean --from-delimiter
+// flag, a single character or "space".
+func parseFromDelimiter(flag string) (string, error) {
+	if flag == "space" {
+		return " ", nil
+	}
+	if utf8.RuneCountInString(flag) != 1 || flag == tsdata.Delim || flag == "\n" {
+		return "", usageErrorf("bad delimiter '%v', expected a single character other than tab or \"space\"", flag)
+	}
+	return flag, nil
+}
+
+// delimReader converts lines of a TSDATA file which were written with the
+// wrong delimiter to tab-delimited lines. The first three header lines hold
+// single values and are left alone, as are later lines which already have a
+// tab-delimited field for every column. A space delimiter matches runs of
+// spaces.
+type delimReader struct {
+	br    *bufio.Reader
+	delim string
+	line  int
+	cols  int    // columns in the header, once read
+	buf   []byte // converted text not yet read
+	err   error
+}
+
+func newDelimReader(r io.Reader, delim string) *delimReader {
+	return &delimReader{br: bufio.NewReader(r), delim: delim}
+}
+
+func (d *delimReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		var line string
+		line, d.err = d.br.ReadString('\n')
+		if line != "" {
+			d.buf = []byte(d.convert(line))
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// convert returns line with its delimiters replaced by tabs if needed.
+func (d *delimReader) convert(line string) string {
+	d.line++
+	if d.line <= 3 {
+		return line
+	}
+	body := strings.TrimRight(line, "\r\n")
+	end := line[len(body):]
+	if d.line <= tsdata.HeaderSize {
+		if !strings.Contains(body, tsdata.Delim) {
+			body = d.split(body)
+		}
+		if d.line == tsdata.HeaderSize {
+			d.cols = strings.Count(body, tsdata.Delim) + 1
+		}
+		return body + end
+	}
+	if strings.Count(body, tsdata.Delim)+1 < d.cols {
+		body = d.split(body)
+	}
+	return body + end
+}
+
+// split rewrites a line delimited by d.delim as a tab-delimited line.
+func (d *delimReader) split(line string) string {
+	if d.delim == " " {
+		return strings.Join(strings.Fields(line), tsdata.Delim)
+	}
+	return strings.Replace(line, d.delim, tsdata.Delim, -1)
+}
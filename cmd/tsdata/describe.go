@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var describeCommand = cli.Command{
+	Name:      "describe",
+	Usage:     "Prints summary statistics for each column",
+	UsageText: "tsdata describe [--format table|json] INFILE",
+	Description: "Computes per-column summaries of INFILE in a single pass and prints them to STDOUT. " +
+		"All columns report value and NA counts. Numeric columns add min, max, mean, and standard deviation. " +
+		"Category and boolean columns add distinct value counts. Time columns add first, last, and coverage duration. " +
+		"Values which fail validation are counted as NA. Use '-' for STDIN.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "format",
+			Value: "table",
+			Usage: "Output `FORMAT`, table or json",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 1 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		format := c.String("format")
+		if format != "table" && format != "json" {
+			err := usageErrorf("bad format '%v', expected table or json", format)
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = describeCmd(c.Args().Get(0), format, opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// columnStats is the summary of one column written by describe.
+type columnStats struct {
+	Name     string         `json:"name"`
+	Type     string         `json:"type"`
+	Count    int            `json:"count"`
+	NA       int            `json:"na"`
+	Min      *float64       `json:"min,omitempty"`
+	Max      *float64       `json:"max,omitempty"`
+	Mean     *float64       `json:"mean,omitempty"`
+	Stddev   *float64       `json:"stddev,omitempty"`
+	Distinct map[string]int `json:"distinct,omitempty"`
+	First    *time.Time     `json:"first,omitempty"`
+	Last     *time.Time     `json:"last,omitempty"`
+	Coverage string         `json:"coverage,omitempty"`
+}
+
+// describeStats converts accumulated statistics to column summaries.
+func describeStats(st *tsdata.Stats) []*columnStats {
+	stats := make([]*columnStats, len(st.Columns))
+	for i := range st.Columns {
+		c := &st.Columns[i]
+		s := &columnStats{Name: c.Name, Type: c.Type, Count: c.Count, NA: c.NA, Distinct: c.Distinct}
+		if c.Numeric() {
+			min, max, mean, stddev := c.Min(), c.Max(), c.Mean(), c.Stddev()
+			s.Min, s.Max, s.Mean, s.Stddev = &min, &max, &mean, &stddev
+		}
+		if c.Type == "time" && c.Count > 0 {
+			first, last := c.First(), c.Last()
+			s.First, s.Last = &first, &last
+			s.Coverage = last.Sub(first).String()
+		}
+		stats[i] = s
+	}
+	return stats
+}
+
+func describeCmd(infile string, format string, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+	tr.Stats = tsdata.NewStats(tr.Tsdata.Schema())
+
+	err = eachLine(tr, func(data tsdata.Data) error {
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	stats := describeStats(tr.Stats)
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	}
+	return writeStatsTable(os.Stdout, stats)
+}
+
+// writeStatsTable writes column statistics as a text table to w.
+func writeStatsTable(w io.Writer, stats []*columnStats) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "name\ttype\tcount\tNA\tmin\tmax\tmean\tstddev\tdistinct\n")
+	for _, s := range stats {
+		min, max, mean, stddev, distinct := "", "", "", "", ""
+		if s.Min != nil {
+			min = formatStat(*s.Min)
+			max = formatStat(*s.Max)
+			mean = formatStat(*s.Mean)
+			stddev = formatStat(*s.Stddev)
+		}
+		if s.First != nil {
+			min = s.First.Format(time.RFC3339Nano)
+			max = s.Last.Format(time.RFC3339Nano)
+		}
+		if s.Distinct != nil {
+			distinct = formatDistinct(s.Distinct)
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", s.Name, s.Type, s.Count, s.NA, min, max, mean, stddev, distinct)
+	}
+	return tw.Flush()
+}
+
+func formatStat(f float64) string {
+	return strconv.FormatFloat(f, 'g', 6, 64)
+}
+
+// formatDistinct formats distinct value counts, most common first, listing at
+// most 5 values.
+func formatDistinct(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	parts := []string{}
+	for i, k := range keys {
+		if i == 5 {
+			parts = append(parts, "...")
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%v=%v", k, counts[k]))
+	}
+	return fmt.Sprintf("%v (%v)", len(keys), strings.Join(parts, ", "))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/ctberthiaume/tsdata/internal/objstore"
+)
+
+// Exit codes
+const (
+	exitOK     = 0
+	exitError  = 1 // errors not in another class
+	exitUsage  = 2 // bad arguments or flags
+	exitHeader = 3 // missing, invalid, or mismatched header metadata
+	exitData   = 4 // data lines failed validation or checks
+	exitIO     = 5 // file, network, or compression errors
+)
+
+// cmdError is an error with an exit code.
+type cmdError struct {
+	code int
+	err  error
+}
+
+func (e *cmdError) Error() string {
+	return e.err.Error()
+}
+
+func (e *cmdError) Unwrap() error {
+	return e.err
+}
+
+// usageErrorf returns an error for bad command-line arguments.
+func usageErrorf(format string, a ...interface{}) error {
+	return &cmdError{code: exitUsage, err: fmt.Errorf(format, a...)}
+}
+
+// dataErrorf returns an error for data which fails a check.
+func dataErrorf(format string, a ...interface{}) error {
+	return &cmdError{code: exitData, err: fmt.Errorf(format, a...)}
+}
+
+// ioErrorf returns an error for a failed read or write.
+func ioErrorf(format string, a ...interface{}) error {
+	return &cmdError{code: exitIO, err: fmt.Errorf(format, a...)}
+}
+
+// headerErrorf returns an error for bad or mismatched header metadata.
+func headerErrorf(format string, a ...interface{}) error {
+	return &tsdata.HeaderError{Err: fmt.Errorf(format, a...)}
+}
+
+// exitCode returns the process exit code for err.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var cerr *cmdError
+	var herr *tsdata.HeaderError
+	var lerr *tsdata.LineError
+	var ferr *tsdata.FieldError
+	switch {
+	case errors.As(err, &cerr):
+		return cerr.code
+	case errors.As(err, &herr):
+		return exitHeader
+	case errors.As(err, &lerr), errors.As(err, &ferr):
+		return exitData
+	case isIOError(err):
+		return exitIO
+	}
+	return exitError
+}
+
+// isIOError returns true if err comes from the file system, the network, an
+// object store, or decompression.
+func isIOError(err error) bool {
+	var perr *os.PathError
+	var lerr *os.LinkError
+	var serr *os.SyscallError
+	var nerr net.Error
+	var oerr *objstore.Error
+	return errors.As(err, &perr) || errors.As(err, &lerr) || errors.As(err, &serr) || errors.As(err, &nerr) ||
+		errors.As(err, &oerr) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, gzip.ErrChecksum) || errors.Is(err, gzip.ErrHeader) ||
+		errors.Is(err, bufio.ErrTooLong)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var fillCommand = cli.Command{
+	Name:      "fill",
+	Usage:     "Inserts NA lines where timestamps are missing",
+	UsageText: "tsdata fill --interval DURATION [--align minute|hour|day] INFILE OUTFILE",
+	Description: "Writes INFILE to OUTFILE with a line of NA data values inserted at every --interval step " +
+		"where a gap between consecutive timestamps is missing data. A step is filled when it falls more than " +
+		"half an interval before the next line, so small timing jitter does not create extra lines. " +
+		"Inserted lines are an interval apart from the line before the gap, or with --align on a grid of the " +
+		"interval which restarts at every UTC minute, hour, or day boundary, like resample --align. " +
+		"INFILE should be sorted by time. Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.DurationFlag{
+			Name:  "interval, i",
+			Usage: "Expected time between lines as a `DURATION`, e.g. 1m",
+		},
+		alignFlag,
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 2 {
+			err := usageErrorf("missing required OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 2 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		if c.Duration("interval") <= 0 {
+			err := usageErrorf("--interval must be a positive duration")
+			logger.Error(err)
+			return err
+		}
+		grid, err := parseTimeGrid(c.Duration("interval"), c.String("align"))
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = fillCmd(c.Args().Get(0), c.Args().Get(1), grid, opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+func fillCmd(infile string, outfile string, grid timeGrid, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+	_, err = w.WriteString(tr.Tsdata.Header() + "\n")
+	if err != nil {
+		return err
+	}
+
+	na := strings.Repeat(tsdata.Delim+tsdata.NA, len(tr.Tsdata.Headers)-1)
+	filled := 0
+	var last time.Time
+	err = eachLine(tr, func(data tsdata.Data) error {
+		if !last.IsZero() {
+			limit := data.Time.Add(-grid.interval / 2)
+			t := last.Add(grid.interval)
+			if grid.unit != 0 {
+				t = grid.next(grid.floor(last.Add(grid.interval / 2)))
+			}
+			for ; t.Before(limit); t = grid.next(t) {
+				_, err := w.WriteString(t.Format(time.RFC3339Nano) + na + "\n")
+				if err != nil {
+					return err
+				}
+				filled++
+			}
+		}
+		if data.Time.After(last) {
+			last = data.Time
+		}
+		_, err := w.WriteString(strings.Join(data.Fields, tsdata.Delim) + "\n")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if filled > 0 {
+		logger.Printf("inserted %v NA lines\n", filled)
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
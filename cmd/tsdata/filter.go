@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var filterCommand = cli.Command{
+	Name:      "filter",
+	Usage:     "Selects lines in a time range",
+	UsageText: "tsdata filter [--start TIME] [--end TIME] [--use-index] INFILE OUTFILE",
+	Description: "Writes lines of INFILE with timestamps at or after --start and before --end to OUTFILE. " +
+		"TIME is an RFC3339 timestamp. Without an index every line is read. With --use-index reading starts " +
+		"close to --start using the index in INFILE.idx created by the index command, and stops at the first " +
+		"line at or after --end. Use '-' for STDIN and STDOUT, except with --use-index.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "start",
+			Usage: "Keep lines at or after `TIME`",
+		},
+		cli.StringFlag{
+			Name:  "end",
+			Usage: "Keep lines before `TIME`",
+		},
+		cli.BoolFlag{
+			Name:  "use-index",
+			Usage: "Seek to --start using INFILE.idx",
+		},
+		cli.StringFlag{
+			Name:  "index",
+			Usage: "Read the index from `FILE` instead of INFILE.idx, implies --use-index",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 2 {
+			err := usageErrorf("missing required OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 2 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		start, err := timeFlag(c, "start")
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		end, err := timeFlag(c, "end")
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		infile := c.Args().Get(0)
+		index := c.String("index")
+		if index == "" && c.Bool("use-index") {
+			index = indexPath(infile)
+		}
+		if index != "" && infile == "-" {
+			err := usageErrorf("can't use an index with STDIN")
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = filterCmd(infile, c.Args().Get(1), start, end, index, opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// timeFlag parses the RFC3339 timestamp in flag name, or returns a zero time
+// if the flag is not set.
+func timeFlag(c *cli.Context, name string) (time.Time, error) {
+	if c.String(name) == "" {
+		return time.Time{}, nil
+	}
+	t, err := tsdata.ParseTime(c.String(name))
+	if err != nil {
+		return time.Time{}, usageErrorf("bad --%v time '%v'", name, c.String(name))
+	}
+	return t, nil
+}
+
+// filterCmd writes lines of infile in [start, end) to outfile. A zero start
+// or end is unbounded. If index is not empty it's the path of an index file
+// used to seek to start.
+func filterCmd(infile string, outfile string, start time.Time, end time.Time, index string, opts []tsdata.Option) error {
+	var tr *tsdata.Reader
+	if index != "" {
+		idx, err := readIndexFile(index)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(infile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		tr, err = tsdata.NewReader(f, opts...)
+		if err != nil {
+			return err
+		}
+		if err := tr.Seek(idx, start); err != nil {
+			return err
+		}
+	} else {
+		r, err := openInput(infile)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		tr, err = tsdata.NewReader(r, opts...)
+		if err != nil {
+			return err
+		}
+	}
+	tr.Strict = false
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+	_, err = w.WriteString(tr.Tsdata.Header() + "\n")
+	if err != nil {
+		return err
+	}
+
+	errStop := errors.New("stop")
+	err = eachLine(tr, func(data tsdata.Data) error {
+		if !start.IsZero() && data.Time.Before(start) {
+			return nil
+		}
+		if !end.IsZero() && !data.Time.Before(end) {
+			if index != "" {
+				// Indexed files are sorted, no more lines are in range
+				return errStop
+			}
+			return nil
+		}
+		_, err := w.WriteString(strings.Join(data.Fields, tr.Tsdata.Delimiter()) + "\n")
+		return err
+	})
+	if err != nil && err != errStop {
+		return err
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
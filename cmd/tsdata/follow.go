@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// followInterval is how long a followReader waits before checking for new
+// data after reaching the end of its input.
+var followInterval = 500 * time.Millisecond
+
+// followReader reads from r like tail -f. Instead of returning io.EOF at the
+// end of r it waits for more data to be written. io.EOF is returned once
+// input is exhausted after an interrupt signal has been received, so commands
+// can finish normally.
+type followReader struct {
+	r    io.Reader
+	done chan os.Signal
+}
+
+// newFollowReader returns a followReader for r which stops on interrupt.
+func newFollowReader(r io.Reader) *followReader {
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt)
+	return &followReader{r: r, done: done}
+}
+
+func (f *followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := f.r.Read(p)
+		if err != io.EOF {
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+		select {
+		case <-f.done:
+			signal.Stop(f.done)
+			return 0, io.EOF
+		case <-time.After(followInterval):
+		}
+	}
+}
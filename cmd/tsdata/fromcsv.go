@@ -0,0 +1,379 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var fromCSVCommand = cli.Command{
+	Name:      "fromcsv",
+	Usage:     "Converts a CSV or TSV file to TSDATA",
+	UsageText: "tsdata fromcsv [--schema FILE | --time-column NAME[,NAME]] [--time-layout LAYOUT] [--time-zone ZONE] [--delimiter DELIM] INFILE OUTFILE",
+	Description: "Converts a delimited text file with a column header line at INFILE to a TSDATA file at OUTFILE. " +
+		"Column types are guessed from up to --sample data lines as with infer-schema, and units and comments are NA. " +
+		"Timestamps are read from the first column unless --time-column names another column, or two columns " +
+		"such as date,time whose values are joined with a space before parsing. Time columns named by " +
+		"--time-column become the first column. --time-layout is rfc3339, epoch, epoch_ms, or a Go time layout " +
+		"such as '01/02/2006 15:04:05', and --time-zone is the zone of timestamps without a UTC offset, e.g. " +
+		"America/Los_Angeles or -08:00. Timestamps are written in UTC. Lines with a bad timestamp are dropped, " +
+		"other values which fail validation become NA, and empty values are NA. " +
+		"With --schema, the header of the TSDATA file FILE is used instead of a guessed header, e.g. to bring a file " +
+		"written by tsdata csv and edited in a spreadsheet back to TSDATA. CSV columns are matched to schema " +
+		"columns by name, so they may be reordered, every schema column must be present, and other columns are ignored. " +
+		"Values are validated against the schema's types and --time-layout applies to every time column. " +
+		"The delimiter is a tab for .tsv and .tab files and a comma otherwise unless set with --delimiter. " +
+		"Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "schema",
+			Usage: "Use the header of TSDATA `FILE` instead of guessing column names and types",
+		},
+		cli.StringFlag{
+			Name:  "time-column",
+			Usage: "Read timestamps from column `NAME`, or from two comma-separated columns such as date,time",
+		},
+		cli.StringFlag{
+			Name:  "time-layout",
+			Value: "rfc3339",
+			Usage: "Parse timestamps in `LAYOUT`, rfc3339, epoch, epoch_ms, or a Go time layout",
+		},
+		cli.StringFlag{
+			Name:  "time-zone",
+			Value: "UTC",
+			Usage: "Time `ZONE` of timestamps without a UTC offset, a name such as America/Los_Angeles or an offset such as -08:00",
+		},
+		cli.IntFlag{
+			Name:  "sample",
+			Value: 1000,
+			Usage: "Number of data lines to examine when guessing column types, `N` <= 0 examines all lines",
+		},
+		cli.StringFlag{
+			Name:  "delimiter, d",
+			Usage: "Field delimiter `DELIM`, a single character or \"tab\"",
+		},
+		cli.StringFlag{
+			Name:  "file-type",
+			Usage: "fileType `VALUE` for the header, defaults to INFILE's base name",
+		},
+		cli.StringFlag{
+			Name:  "project",
+			Value: tsdata.NA,
+			Usage: "project `VALUE` for the header",
+		},
+		cli.StringFlag{
+			Name:  "description",
+			Value: tsdata.NA,
+			Usage: "file description `VALUE` for the header",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 2 {
+			err := usageErrorf("missing required OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 2 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		infile := c.Args().Get(0)
+		conf := fromCSVConfig{sample: c.Int("sample")}
+		var err error
+		conf.comma, err = inputDelimiter(infile, c.String("delimiter"))
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		conf.parseTime, err = parseInputTimeLayout(c.String("time-layout"), c.String("time-zone"))
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		if c.String("time-column") != "" {
+			for _, name := range strings.Split(c.String("time-column"), ",") {
+				conf.timeColumns = append(conf.timeColumns, strings.TrimSpace(name))
+			}
+			if len(conf.timeColumns) > 2 {
+				err := usageErrorf("--time-column takes one column or two columns such as date,time")
+				logger.Error(err)
+				return err
+			}
+		}
+		if c.String("schema") != "" {
+			for _, name := range []string{"time-column", "sample", "file-type", "project", "description"} {
+				if c.IsSet(name) {
+					err := usageErrorf("--%v can't be used with --schema", name)
+					logger.Error(err)
+					return err
+				}
+			}
+			s, err := readSchema(c.String("schema"))
+			if err != nil {
+				err = fmt.Errorf("%v: %w", c.String("schema"), err)
+				logger.Error(err)
+				return err
+			}
+			conf.schema = &s
+		}
+		fileType := c.String("file-type")
+		if fileType == "" {
+			fileType = strings.TrimSuffix(filepath.Base(infile), filepath.Ext(infile))
+			if infile == "-" {
+				fileType = tsdata.NA
+			}
+		}
+		conf.meta = tsdata.Schema{
+			FileType:        fileType,
+			Project:         c.String("project"),
+			FileDescription: c.String("description"),
+		}
+		err = fromCSVCmd(infile, c.Args().Get(1), conf)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// fromCSVConfig holds fromcsv command settings.
+type fromCSVConfig struct {
+	comma       rune
+	sample      int            // data lines used to guess types, <= 0 for all
+	meta        tsdata.Schema  // fileType, project, and description of the output header
+	timeColumns []string       // columns joined to make timestamps, nil for the first column
+	schema      *tsdata.Schema // header to use instead of guessing one, or nil
+	parseTime   timeParser
+}
+
+func fromCSVCmd(infile string, outfile string, conf fromCSVConfig) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	cr := csv.NewReader(r)
+	cr.Comma = conf.comma
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	headers, err := cr.Read()
+	if err == io.EOF {
+		return dataErrorf("%v is empty", infile)
+	}
+	if err != nil {
+		return dataErrorf("%v: %v", infile, err)
+	}
+	for i, h := range headers {
+		headers[i] = strings.TrimSpace(h)
+		if headers[i] == "" {
+			headers[i] = fmt.Sprintf("column%v", i+1)
+		}
+	}
+
+	// Buffer lines to guess column types
+	var buf [][]string
+	var bufLines []int
+	for conf.schema == nil && (conf.sample <= 0 || len(buf) < conf.sample) {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return dataErrorf("%v: %v", infile, err)
+		}
+		line, _ := cr.FieldPos(0)
+		buf = append(buf, rec)
+		bufLines = append(bufLines, line)
+	}
+
+	var meta tsdata.Schema
+	var src [][]int
+	if conf.schema != nil {
+		meta, src, err = schemaColumns(*conf.schema, headers)
+	} else {
+		meta, src, err = guessColumns(conf, headers, buf)
+	}
+	if err != nil {
+		return fmt.Errorf("%v: %w", infile, err)
+	}
+	t := tsdata.New(tsdata.WithSchema(meta))
+	if err := t.ValidateMetadata(); err != nil {
+		return err
+	}
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+	if _, err := w.WriteString(t.Header() + "\n"); err != nil {
+		return err
+	}
+
+	written, dropped := 0, 0
+	fields := make([]string, len(meta.Headers))
+	clean := strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+	convert := func(rec []string, line int) error {
+		if len(rec) != len(headers) {
+			logger.Warnf(line, "line %v, found %v fields, expected %v", line, len(rec), len(headers))
+		}
+		for j, cols := range src {
+			parts := make([]string, len(cols))
+			for k, i := range cols {
+				if i < len(rec) {
+					parts[k] = strings.TrimSpace(rec[i])
+				}
+			}
+			v := strings.TrimSpace(strings.Join(parts, " "))
+			if meta.Types[j] == "time" && v != "" && v != tsdata.NA {
+				ts, err := conf.parseTime(v)
+				if err == nil {
+					v = ts.UTC().Format(time.RFC3339Nano)
+				} else if j == 0 {
+					dropped++
+					logger.Error(&tsdata.LineError{Line: line, Err: dataErrorf("bad timestamp '%v'", v)})
+					return nil
+				}
+			}
+			if v == "" {
+				v = tsdata.NA
+			}
+			fields[j] = clean.Replace(v)
+		}
+		data, err := t.ValidateLine(strings.Join(fields, tsdata.Delim), false)
+		if err != nil {
+			dropped++
+			logger.Error(&tsdata.LineError{Line: line, Err: err})
+			return nil
+		}
+		for j := range data.Fields {
+			if data.Fields[j] == tsdata.NA && fields[j] != tsdata.NA {
+				logger.Warnf(line, "line %v, column %v (%v, %v), bad value '%v' written as NA", line, j+1, meta.Headers[j], meta.Types[j], fields[j])
+			}
+		}
+		written++
+		_, err = w.WriteString(strings.Join(data.Fields, tsdata.Delim) + "\n")
+		return err
+	}
+
+	for k, rec := range buf {
+		if err := convert(rec, bufLines[k]); err != nil {
+			return err
+		}
+	}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return dataErrorf("%v: %v", infile, err)
+		}
+		line, _ := cr.FieldPos(0)
+		if err := convert(rec, line); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	logger.Printf("wrote %v lines, dropped %v lines with bad timestamps\n", written, dropped)
+	return outf.Close()
+}
+
+// guessColumns returns a TSDATA schema for a CSV file with column names
+// headers, with types guessed from the lines in sample, and the CSV column
+// indexes of each schema column. The first schema column is the time column
+// from conf.timeColumns, whose values are joined if there are two.
+func guessColumns(conf fromCSVConfig, headers []string, sample [][]string) (tsdata.Schema, [][]int, error) {
+	timeCols := []int{0}
+	if conf.timeColumns != nil {
+		timeCols = nil
+		for _, name := range conf.timeColumns {
+			i := indexOfColumn(headers, name)
+			if i == -1 {
+				return tsdata.Schema{}, nil, usageErrorf("no column '%v'", name)
+			}
+			timeCols = append(timeCols, i)
+		}
+	}
+
+	meta := conf.meta
+	meta.Headers = []string{headers[timeCols[0]]}
+	if len(timeCols) > 1 {
+		meta.Headers[0] = "time"
+	}
+	meta.Types = []string{"time"}
+	src := [][]int{timeCols}
+	for i := range headers {
+		if i == timeCols[0] || (len(timeCols) > 1 && i == timeCols[1]) {
+			continue
+		}
+		g := newTypeGuess()
+		for _, rec := range sample {
+			if i < len(rec) {
+				g.add(rec[i])
+			}
+		}
+		meta.Headers = append(meta.Headers, headers[i])
+		meta.Types = append(meta.Types, g.guess())
+		src = append(src, []int{i})
+	}
+	for range meta.Headers {
+		meta.Units = append(meta.Units, tsdata.NA)
+		meta.Comments = append(meta.Comments, tsdata.NA)
+	}
+	return meta, src, nil
+}
+
+// schemaColumns returns the CSV column index of each column in s, matched by
+// name in headers. CSV columns which aren't in s are logged and ignored.
+func schemaColumns(s tsdata.Schema, headers []string) (tsdata.Schema, [][]int, error) {
+	src := make([][]int, len(s.Headers))
+	for j, name := range s.Headers {
+		i := indexOfColumn(headers, name)
+		if i == -1 {
+			return tsdata.Schema{}, nil, headerErrorf("no column '%v' for schema column %v", name, j+1)
+		}
+		src[j] = []int{i}
+	}
+	for _, name := range headers {
+		if indexOfColumn(s.Headers, name) == -1 {
+			logger.Printf("column '%v' isn't in the schema, ignored\n", name)
+		}
+	}
+	return s, src, nil
+}
+
+// indexOfColumn returns the index of name in headers, or -1.
+func indexOfColumn(headers []string, name string) int {
+	for i, h := range headers {
+		if h == name {
+			return i
+		}
+	}
+	return -1
+}
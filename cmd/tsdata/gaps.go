@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var gapsCommand = cli.Command{
+	Name:      "gaps",
+	Usage:     "Reports gaps between timestamps",
+	UsageText: "tsdata gaps --expected-interval DURATION [--format text|json] INFILE",
+	Description: "Prints a report to STDOUT of each place in INFILE where consecutive timestamps are further apart than --expected-interval, " +
+		"with the timestamps before and after the gap and the gap duration. INFILE should be sorted by time, " +
+		"lines with a timestamp earlier than the previous line are reported as warnings. Use '-' for STDIN.",
+	Flags: []cli.Flag{
+		cli.DurationFlag{
+			Name:  "expected-interval, i",
+			Usage: "Expected time between lines as a `DURATION`, e.g. 1m",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Value: "text",
+			Usage: "Output `FORMAT`, text or json",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 1 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		if c.Duration("expected-interval") <= 0 {
+			err := usageErrorf("--expected-interval must be a positive duration")
+			logger.Error(err)
+			return err
+		}
+		format := c.String("format")
+		if format != "text" && format != "json" {
+			err := usageErrorf("bad format '%v', expected text or json", format)
+			logger.Error(err)
+			return err
+		}
+		err := gapsCmd(c.Args().Get(0), c.Duration("expected-interval"), format)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// gap is a period with no data lines.
+type gap struct {
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Duration time.Duration `json:"-"`
+	Seconds  float64       `json:"seconds"`
+}
+
+func gapsCmd(infile string, interval time.Duration, format string) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, tsdata.WithTimeOrder(tsdata.TimeOrderWarn))
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	gaps := []gap{}
+	var last time.Time
+	err = eachLine(tr, func(data tsdata.Data) error {
+		if !last.IsZero() {
+			d := data.Time.Sub(last)
+			if d > interval {
+				gaps = append(gaps, gap{Start: last, End: data.Time, Duration: d, Seconds: d.Seconds()})
+			}
+		}
+		last = data.Time
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(gaps)
+	}
+	return writeGaps(os.Stdout, gaps)
+}
+
+// writeGaps writes a text table of gaps to w.
+func writeGaps(w io.Writer, gaps []gap) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "start\tend\tduration\n")
+	var total time.Duration
+	for _, g := range gaps {
+		fmt.Fprintf(tw, "%v\t%v\t%v\n", g.Start.Format(time.RFC3339Nano), g.End.Format(time.RFC3339Nano), g.Duration)
+		total += g.Duration
+	}
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "\n%v gaps, %v total\n", len(gaps), total)
+	return err
+}
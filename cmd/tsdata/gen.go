@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var genCommand = cli.Command{
+	Name:      "gen",
+	Usage:     "Generates a synthetic TSDATA file",
+	UsageText: "tsdata gen --schema FILE [--rows N] [--interval DURATION] [--start TIME] [--seed N] [--na-fraction F] OUTFILE",
+	Description: "Writes --rows lines of random data with the header of --schema to OUTFILE, for benchmarks and " +
+		"integration tests of software which reads TSDATA files. Lines start at --start and are --interval apart. " +
+		"Float, integer, latitude, and longitude columns are random walks kept within any range constraints, " +
+		"category columns cycle through their values, boolean columns are random, text columns cycle through a " +
+		"few words, and other time columns repeat the line's timestamp. A --na-fraction of data values are NA. " +
+		"Values which don't satisfy a column's constraints, such as a text pattern, are written as NA. The same " +
+		"--seed produces the same file. Use '-' for STDOUT.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "schema",
+			Usage: "Read the header from TSDATA `FILE`",
+		},
+		cli.StringFlag{
+			Name:  "rows, n",
+			Value: "1000",
+			Usage: "Write `N` data lines, e.g. 1e6",
+		},
+		cli.DurationFlag{
+			Name:  "interval, i",
+			Value: time.Second,
+			Usage: "Time between lines as a `DURATION`",
+		},
+		cli.StringFlag{
+			Name:  "start",
+			Value: "2020-01-01T00:00:00Z",
+			Usage: "RFC3339 `TIME` of the first line",
+		},
+		cli.Int64Flag{
+			Name:  "seed",
+			Value: 1,
+			Usage: "Random number generator `SEED`",
+		},
+		cli.Float64Flag{
+			Name:  "na-fraction",
+			Value: 0.01,
+			Usage: "Write `FRACTION` of data values as NA",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 1 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.String("schema") == "" {
+			err := usageErrorf("missing required --schema")
+			logger.Error(err)
+			return err
+		}
+		rows, err := strconv.ParseFloat(c.String("rows"), 64)
+		if err != nil || rows < 0 || rows != math.Trunc(rows) || rows > math.MaxInt64/2 {
+			err := usageErrorf("bad --rows '%v', expected a whole number such as 1000 or 1e6", c.String("rows"))
+			logger.Error(err)
+			return err
+		}
+		if c.Duration("interval") <= 0 {
+			err := usageErrorf("--interval must be a positive duration")
+			logger.Error(err)
+			return err
+		}
+		if f := c.Float64("na-fraction"); !(f >= 0 && f <= 1) {
+			err := usageErrorf("--na-fraction must be from 0 to 1")
+			logger.Error(err)
+			return err
+		}
+		start, err := timeFlag(c, "start")
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		s, err := readSchema(c.String("schema"))
+		if err != nil {
+			err = fmt.Errorf("%v: %w", c.String("schema"), err)
+			logger.Error(err)
+			return err
+		}
+		conf := genConfig{
+			rows:       int64(rows),
+			interval:   c.Duration("interval"),
+			start:      start,
+			seed:       c.Int64("seed"),
+			naFraction: c.Float64("na-fraction"),
+		}
+		err = genCmd(c.Args().Get(0), s, conf)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// genConfig holds gen command settings.
+type genConfig struct {
+	rows       int64
+	interval   time.Duration
+	start      time.Time
+	seed       int64
+	naFraction float64
+}
+
+// genWords are the values of generated text columns.
+var genWords = []string{"alpha", "bravo", "charlie", "delta", "echo"}
+
+// genColumn generates values for one column.
+type genColumn struct {
+	info     tsdata.ColumnInfo
+	values   []string // values to cycle through for category and text columns
+	min, max float64  // random walk bounds
+	step     float64  // random walk step size
+	walk     float64  // current random walk value
+}
+
+// newGenColumn returns a generator for column col with constraint c.
+func newGenColumn(col tsdata.ColumnInfo, c tsdata.Constraint) *genColumn {
+	g := &genColumn{info: col, min: -1000, max: 1000, step: 1}
+	switch col.Type {
+	case "latitude":
+		g.min, g.max, g.step = -90, 90, 0.001
+	case "longitude":
+		g.min, g.max, g.step = -180, 180, 0.001
+	case "category":
+		g.values = c.Values
+		if len(g.values) == 0 {
+			g.values = genWords
+		}
+	case "text":
+		g.values = genWords
+	}
+	if c.Min != nil {
+		g.min = math.Max(g.min, *c.Min)
+	}
+	if c.Max != nil {
+		g.max = math.Min(g.max, *c.Max)
+	}
+	if g.max < g.min {
+		g.max = g.min
+	}
+	if (g.max - g.min) < 100*g.step {
+		g.step = (g.max - g.min) / 100
+	}
+	g.walk = g.min + (g.max-g.min)/2
+	if col.Type == "integer" {
+		g.min, g.max = math.Ceil(g.min), math.Floor(g.max)
+		g.walk = math.Round(g.walk)
+		g.step = 1
+	}
+	return g
+}
+
+// next returns the value for line i at time t.
+func (g *genColumn) next(rnd *rand.Rand, i int64, t time.Time) string {
+	switch g.info.Type {
+	case "float", "latitude", "longitude", "integer":
+		g.walk += g.step * rnd.NormFloat64()
+		if g.info.Type == "integer" {
+			g.walk = math.Round(g.walk)
+		}
+		// Reflect off the bounds
+		if g.walk > g.max {
+			g.walk = math.Max(g.min, 2*g.max-g.walk)
+		}
+		if g.walk < g.min {
+			g.walk = math.Min(g.max, 2*g.min-g.walk)
+		}
+		if g.info.Type == "integer" {
+			return strconv.FormatInt(int64(g.walk), 10)
+		}
+		return strconv.FormatFloat(g.walk, 'f', 6, 64)
+	case "boolean":
+		if rnd.Intn(2) == 0 {
+			return "FALSE"
+		}
+		return "TRUE"
+	case "category", "text":
+		return g.values[i%int64(len(g.values))]
+	case "time":
+		return t.Format(time.RFC3339Nano)
+	}
+	return tsdata.NA
+}
+
+func genCmd(outfile string, s tsdata.Schema, conf genConfig) error {
+	t := tsdata.New(tsdata.WithSchema(s))
+	cols := t.Columns()
+	gens := make([]*genColumn, len(cols))
+	for i, col := range cols {
+		var c tsdata.Constraint
+		if i < len(s.Constraints) {
+			c = s.Constraints[i]
+		}
+		gens[i] = newGenColumn(col, c)
+	}
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+	_, err = w.WriteString(s.Header() + "\n")
+	if err != nil {
+		return err
+	}
+
+	rnd := rand.New(rand.NewSource(conf.seed))
+	fields := make([]string, len(cols))
+	invalid := make([]int64, len(cols))
+	for i := int64(0); i < conf.rows; i++ {
+		ts := conf.start.Add(time.Duration(i) * conf.interval)
+		fields[0] = ts.Format(time.RFC3339Nano)
+		for j := 1; j < len(cols); j++ {
+			// Always advance generators so NA values don't change other values
+			v := gens[j].next(rnd, i, ts)
+			if rnd.Float64() < conf.naFraction {
+				v = tsdata.NA
+			}
+			if !cols[j].Checker(v) {
+				invalid[j]++
+				v = tsdata.NA
+			}
+			fields[j] = v
+		}
+		_, err = w.WriteString(strings.Join(fields, tsdata.Delim) + "\n")
+		if err != nil {
+			return err
+		}
+	}
+	for j, n := range invalid {
+		if n > 0 {
+			logger.Printf("column %v, wrote %v generated values which don't satisfy its constraints as NA\n", cols[j].Name, n)
+		}
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
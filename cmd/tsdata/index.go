@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var indexCommand = cli.Command{
+	Name:      "index",
+	Usage:     "Creates a time index for a TSDATA file",
+	UsageText: "tsdata index [--interval BYTES] [--output FILE] INFILE",
+	Description: "Writes a sidecar file of timestamp and byte offset checkpoints for INFILE, by default to INFILE.idx. " +
+		"Commands like filter --use-index use the index to start reading close to a timestamp. " +
+		"INFILE must be sorted by time and not compressed. Recreate the index if INFILE is rewritten, " +
+		"an index remains usable after lines are appended.",
+	Flags: []cli.Flag{
+		cli.Int64Flag{
+			Name:  "interval",
+			Value: tsdata.DefaultIndexInterval,
+			Usage: "Add a checkpoint about every `BYTES` bytes",
+		},
+		cli.StringFlag{
+			Name:  "output, o",
+			Usage: "Write the index to `FILE` instead of INFILE.idx",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 1 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		if c.Int64("interval") <= 0 {
+			err := usageErrorf("--interval must be greater than 0")
+			logger.Error(err)
+			return err
+		}
+		infile := c.Args().Get(0)
+		if infile == "-" {
+			err := usageErrorf("can't index STDIN")
+			logger.Error(err)
+			return err
+		}
+		outfile := c.String("output")
+		if outfile == "" {
+			outfile = indexPath(infile)
+		}
+		err := indexCmd(infile, outfile, c.Int64("interval"))
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// indexPath returns the default index file path for infile.
+func indexPath(infile string) string {
+	return infile + ".idx"
+}
+
+func indexCmd(infile string, outfile string, interval int64) error {
+	f, err := os.Open(infile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return usageErrorf("%v is compressed, can't index", infile)
+	}
+	idx, err := tsdata.BuildIndex(br, interval)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(outfile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := idx.WriteTo(out); err != nil {
+		return err
+	}
+	logger.Printf("wrote %v checkpoints to %v\n", len(idx.Checkpoints), outfile)
+	return out.Close()
+}
+
+// readIndexFile reads an index written by the index command.
+func readIndexFile(path string) (*tsdata.Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	idx, err := tsdata.ReadIndex(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return idx, nil
+}
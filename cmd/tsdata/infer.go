@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var inferSchemaCommand = cli.Command{
+	Name:      "infer-schema",
+	Usage:     "Guesses a TSDATA header for a CSV or TSV file",
+	UsageText: "tsdata infer-schema [--sample N] [--delimiter DELIM] INFILE",
+	Description: "Reads the column header line and up to --sample data lines of a delimited text file and prints " +
+		"a draft TSDATA header to STDOUT, guessing time, float, integer, boolean, category, or text for each column. " +
+		"Units and comments are NA and should be edited before use. The delimiter is a tab for .tsv and .tab files " +
+		"and a comma otherwise unless set with --delimiter. Use '-' for STDIN.",
+	Flags: []cli.Flag{
+		cli.IntFlag{
+			Name:  "sample",
+			Value: 1000,
+			Usage: "Number of data lines to examine, `N` <= 0 examines all lines",
+		},
+		cli.StringFlag{
+			Name:  "delimiter, d",
+			Usage: "Field delimiter `DELIM`, a single character or \"tab\"",
+		},
+		cli.StringFlag{
+			Name:  "file-type",
+			Usage: "fileType `VALUE` for the header, defaults to INFILE's base name",
+		},
+		cli.StringFlag{
+			Name:  "project",
+			Value: tsdata.NA,
+			Usage: "project `VALUE` for the header",
+		},
+		cli.StringFlag{
+			Name:  "description",
+			Value: tsdata.NA,
+			Usage: "file description `VALUE` for the header",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 1 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		infile := c.Args().Get(0)
+		comma, err := inputDelimiter(infile, c.String("delimiter"))
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		fileType := c.String("file-type")
+		if fileType == "" {
+			fileType = strings.TrimSuffix(filepath.Base(infile), filepath.Ext(infile))
+			if infile == "-" {
+				fileType = tsdata.NA
+			}
+		}
+		meta := tsdata.Schema{
+			FileType:        fileType,
+			Project:         c.String("project"),
+			FileDescription: c.String("description"),
+		}
+		err = inferSchemaCmd(infile, comma, c.Int("sample"), meta)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// inputDelimiter chooses the field delimiter for a delimited text file,
+// either from flag or from the file extension of path.
+func inputDelimiter(path string, flag string) (rune, error) {
+	switch {
+	case flag == "tab" || flag == `\t`:
+		return '\t', nil
+	case flag != "":
+		if len([]rune(flag)) != 1 {
+			return 0, usageErrorf("bad delimiter '%v', expected a single character or \"tab\"", flag)
+		}
+		return []rune(flag)[0], nil
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".tsv" || ext == ".tab" {
+		return '\t', nil
+	}
+	return ',', nil
+}
+
+// typeGuess tracks which TSDATA types remain possible for a column.
+type typeGuess struct {
+	values   int
+	time     bool
+	integer  bool
+	float    bool
+	boolean  bool
+	distinct map[string]bool
+}
+
+func newTypeGuess() *typeGuess {
+	return &typeGuess{time: true, integer: true, float: true, boolean: true, distinct: map[string]bool{}}
+}
+
+// maxCategories is the largest number of distinct values in a column guessed
+// to be a category.
+const maxCategories = 20
+
+func (g *typeGuess) add(s string) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == tsdata.NA {
+		return
+	}
+	g.values++
+	if g.time {
+		_, err := tsdata.ParseTime(s)
+		g.time = err == nil
+	}
+	if g.integer {
+		_, err := strconv.ParseInt(s, 10, 64)
+		g.integer = err == nil
+	}
+	if g.float {
+		_, err := strconv.ParseFloat(s, 64)
+		g.float = err == nil
+	}
+	if g.boolean {
+		g.boolean = s == "TRUE" || s == "FALSE"
+	}
+	if len(g.distinct) <= maxCategories {
+		g.distinct[s] = true
+	}
+}
+
+// guess returns the most specific type consistent with all values seen.
+func (g *typeGuess) guess() string {
+	switch {
+	case g.values == 0:
+		return "text"
+	case g.time:
+		return "time"
+	case g.boolean:
+		return "boolean"
+	case g.integer:
+		return "integer"
+	case g.float:
+		return "float"
+	case len(g.distinct) <= maxCategories && len(g.distinct)*2 <= g.values:
+		return "category"
+	}
+	return "text"
+}
+
+func inferSchemaCmd(infile string, comma rune, sample int, meta tsdata.Schema) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	headers, err := cr.Read()
+	if err == io.EOF {
+		return dataErrorf("%v is empty", infile)
+	}
+	if err != nil {
+		return err
+	}
+	guesses := make([]*typeGuess, len(headers))
+	for i := range guesses {
+		guesses[i] = newTypeGuess()
+	}
+	for n := 0; sample <= 0 || n < sample; n++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if len(rec) != len(headers) {
+			logger.Warnf(n+2, "line %v, found %v fields, expected %v", n+2, len(rec), len(headers))
+		}
+		for i := 0; i < len(rec) && i < len(guesses); i++ {
+			guesses[i].add(rec[i])
+		}
+	}
+
+	for i, h := range headers {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			h = fmt.Sprintf("column%v", i+1)
+		}
+		meta.Headers = append(meta.Headers, h)
+		meta.Types = append(meta.Types, guesses[i].guess())
+		meta.Units = append(meta.Units, tsdata.NA)
+		meta.Comments = append(meta.Comments, tsdata.NA)
+	}
+	if meta.Types[0] != "time" {
+		logger.Warnf(0, "first column '%v' does not look like RFC3339 timestamps, TSDATA files must start with a time column", meta.Headers[0])
+		meta.Types[0] = "time"
+	}
+	_, err = fmt.Fprintln(os.Stdout, meta.Header())
+	return err
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var pushInfluxCommand = cli.Command{
+	Name:      "influx",
+	Usage:     "Writes TSDATA lines to InfluxDB v2",
+	UsageText: "tsdata push influx --url URL --org ORG --bucket BUCKET [--token TOKEN] INFILE",
+	Description: "Converts each data line of INFILE to InfluxDB line protocol and writes them to an InfluxDB v2 " +
+		"bucket in batches. The measurement is the fileType, project and category columns are tags, and float, " +
+		"integer, boolean, and text columns are fields. NA values are omitted. Failed batches are retried with " +
+		"exponential backoff when the server is unavailable or rate limiting. Use '-' for STDIN.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "url",
+			Usage: "InfluxDB server `URL`, e.g. http://localhost:8086",
+		},
+		cli.StringFlag{
+			Name:  "org",
+			Usage: "InfluxDB organization `NAME`",
+		},
+		cli.StringFlag{
+			Name:  "bucket",
+			Usage: "InfluxDB bucket `NAME`",
+		},
+		cli.StringFlag{
+			Name:   "token",
+			EnvVar: "INFLUX_TOKEN",
+			Usage:  "InfluxDB API `TOKEN`",
+		},
+		cli.IntFlag{
+			Name:  "batch-size",
+			Value: 5000,
+			Usage: "Write `N` lines per request",
+		},
+		cli.IntFlag{
+			Name:  "retries",
+			Value: 5,
+			Usage: "Retry a failed request up to `N` times",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 1 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		for _, f := range []string{"url", "org", "bucket"} {
+			if c.String(f) == "" {
+				err := usageErrorf("missing required --%v flag", f)
+				logger.Error(err)
+				return err
+			}
+		}
+		if c.Int("batch-size") < 1 {
+			err := usageErrorf("--batch-size must be at least 1")
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		u, err := influxWriteURL(c.String("url"), c.String("org"), c.String("bucket"))
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		iw := newPushWriter(u, c.Int("retries"))
+		iw.header.Set("Content-Type", "text/plain; charset=utf-8")
+		if c.String("token") != "" {
+			iw.header.Set("Authorization", "Token "+c.String("token"))
+		}
+		err = pushInfluxCmd(c.Args().Get(0), iw, c.Int("batch-size"), opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// influxWriteURL returns the InfluxDB v2 write endpoint URL for org and bucket.
+func influxWriteURL(server string, org string, bucket string) (string, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", usageErrorf("bad URL '%v'", server)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/v2/write"
+	u.RawQuery = url.Values{"org": {org}, "bucket": {bucket}, "precision": {"ns"}}.Encode()
+	return u.String(), nil
+}
+
+func pushInfluxCmd(infile string, iw *pushWriter, batchSize int, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	measurement := influxEscape(tr.Tsdata.FileType, ", ")
+	project := influxEscape(tr.Tsdata.Project, ",= ")
+	var buf bytes.Buffer
+	lines, written := 0, 0
+	err = eachLine(tr, func(data tsdata.Data) error {
+		if !appendInfluxLine(&buf, measurement, project, tr.Tsdata, data) {
+			return nil
+		}
+		lines++
+		if lines == batchSize {
+			err := iw.write(buf.Bytes())
+			if err != nil {
+				return err
+			}
+			written += lines
+			lines = 0
+			buf.Reset()
+		}
+		return nil
+	})
+	if err == nil && lines > 0 {
+		err = iw.write(buf.Bytes())
+		written += lines
+	}
+	if err != nil {
+		return err
+	}
+	logger.Printf("wrote %v points\n", written)
+	return nil
+}
+
+// appendInfluxLine appends data as one line of InfluxDB line protocol to buf.
+// It returns false and appends nothing if the line has no non-NA fields.
+func appendInfluxLine(buf *bytes.Buffer, measurement string, project string, t *tsdata.Tsdata, data tsdata.Data) bool {
+	var fields []string
+	line := measurement + ",project=" + project
+	for i := 1; i < len(data.Values); i++ {
+		v := data.Values[i]
+		if v == nil {
+			continue
+		}
+		key := influxEscape(t.Headers[i], ",= ")
+		switch t.Types[i] {
+		case "category":
+			line += "," + key + "=" + influxEscape(data.Fields[i], ",= ")
+			continue
+		case "float", "latitude", "longitude":
+			f := v.(float64)
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				continue // NaN and Inf are not valid field values
+			}
+			fields = append(fields, key+"="+strconv.FormatFloat(f, 'g', -1, 64))
+		case "integer":
+			fields = append(fields, key+"="+strconv.FormatInt(v.(int64), 10)+"i")
+		case "boolean":
+			fields = append(fields, key+"="+strconv.FormatBool(v.(bool)))
+		case "time":
+			fields = append(fields, key+"="+strconv.FormatInt(v.(time.Time).UnixNano(), 10)+"i")
+		default:
+			fields = append(fields, key+`="`+influxEscape(data.Fields[i], `"\`)+`"`)
+		}
+	}
+	if len(fields) == 0 {
+		return false
+	}
+	buf.WriteString(line + " " + strings.Join(fields, ",") + " " + strconv.FormatInt(data.Time.UnixNano(), 10) + "\n")
+	return true
+}
+
+// influxEscape backslash escapes each character of chars in s.
+func influxEscape(s string, chars string) string {
+	if !strings.ContainsAny(s, chars) {
+		return s
+	}
+	var sb strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(chars, c) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
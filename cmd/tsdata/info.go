@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var infoCommand = cli.Command{
+	Name:      "info",
+	Usage:     "Prints TSDATA file metadata",
+	UsageText: "tsdata info [--json] INFILE",
+	Description: "Prints header metadata in INFILE to STDOUT, along with the data line count and first and last timestamps. " +
+		"Only the first time column of each line is checked, use validate for full validation. Use '-' for STDIN.",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print metadata as JSON",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 1 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		err := infoCmd(c.Args().Get(0), c.Bool("json"))
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+func infoCmd(infile string, asJSON bool) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r)
+	if err != nil {
+		return err
+	}
+
+	// Count data lines and find first and last timestamps
+	info := fileInfo{}
+	delim := tr.Tsdata.Delimiter()
+	for {
+		line, err := tr.NextRaw()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		info.Rows++
+		field := strings.TrimSpace(strings.SplitN(line, delim, 2)[0])
+		t, err := tsdata.ParseTime(field)
+		if err != nil {
+			ferr := &tsdata.FieldError{Column: 0, Name: tr.Tsdata.Headers[0], Type: tr.Tsdata.Types[0], Value: field}
+			logger.Error(&tsdata.LineError{Line: tr.Line(), Err: ferr})
+			continue
+		}
+		if info.First.IsZero() {
+			info.First = t
+		}
+		info.Last = t
+	}
+
+	if asJSON {
+		return writeInfoJSON(os.Stdout, tr.Tsdata.Schema(), info)
+	}
+	return writeInfo(os.Stdout, tr.Tsdata.Schema(), info)
+}
+
+// fileInfo holds data section information for info.
+type fileInfo struct {
+	Rows  int
+	First time.Time
+	Last  time.Time
+}
+
+// infoJSON is the JSON output of info. It extends the header metadata JSON
+// produced by tsdata.Schema.
+type infoJSON struct {
+	FileType    string          `json:"fileType"`
+	Project     string          `json:"project"`
+	Description string          `json:"description"`
+	Columns     json.RawMessage `json:"columns"`
+	Rows        int             `json:"rows"`
+	FirstTime   *string         `json:"firstTime"`
+	LastTime    *string         `json:"lastTime"`
+}
+
+// writeInfoJSON writes header metadata and data section information as JSON
+// to w.
+func writeInfoJSON(w io.Writer, s tsdata.Schema, info fileInfo) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	out := infoJSON{}
+	err = json.Unmarshal(b, &out)
+	if err != nil {
+		return err
+	}
+	out.Rows = info.Rows
+	if !info.First.IsZero() {
+		first := info.First.Format(time.RFC3339Nano)
+		last := info.Last.Format(time.RFC3339Nano)
+		out.FirstTime, out.LastTime = &first, &last
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// writeInfo writes human-readable header metadata and data section
+// information to w.
+func writeInfo(w io.Writer, s tsdata.Schema, info fileInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "fileType:\t%v\n", s.FileType)
+	fmt.Fprintf(tw, "project:\t%v\n", s.Project)
+	fmt.Fprintf(tw, "description:\t%v\n", s.FileDescription)
+	fmt.Fprintf(tw, "rows:\t%v\n", info.Rows)
+	fmt.Fprintf(tw, "first time:\t%v\n", formatReportTime(info.First))
+	fmt.Fprintf(tw, "last time:\t%v\n", formatReportTime(info.Last))
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "column\tname\ttype\tunit\tcomment\n")
+	for i := range s.Headers {
+		comment := tsdata.NA
+		if i < len(s.Comments) {
+			comment = s.Comments[i]
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\n", i+1, s.Headers[i], s.Types[i], s.Units[i], comment)
+	}
+	return tw.Flush()
+}
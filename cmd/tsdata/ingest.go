@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+)
+
+// schemaExt is the file extension of schemas registered for HTTP ingestion.
+const schemaExt = ".schema"
+
+// ingester appends data lines posted to /ingest/NAME to dated TSDATA files
+// in dir, one file per schema NAME and UTC day.
+type ingester struct {
+	dir     string
+	maxSize int64
+	schemas map[string]tsdata.Schema // by NAME
+
+	mu   sync.Mutex
+	last map[string]time.Time // latest timestamp in each output file by path
+}
+
+// newIngester returns an ingester for the schemas registered in dir. Each
+// NAME.schema file in dir holds a TSDATA header, such as infer-schema output.
+func newIngester(dir string, maxSize int64) (*ingester, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+schemaExt))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, usageErrorf("no %v files in %v", schemaExt, dir)
+	}
+	in := &ingester{
+		dir:     dir,
+		maxSize: maxSize,
+		schemas: map[string]tsdata.Schema{},
+		last:    map[string]time.Time{},
+	}
+	for _, p := range paths {
+		s, err := readSchema(p)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", p, err)
+		}
+		in.schemas[strings.TrimSuffix(filepath.Base(p), schemaExt)] = s
+	}
+	return in, nil
+}
+
+// ServeHTTP validates the data lines in the body of a POST to /ingest/NAME
+// and appends valid lines to the file for NAME and each line's day. The
+// response is a JSON problem object for each invalid line followed by a
+// summary, with status 200 if every line was appended or 422 otherwise. A
+// body larger than maxSize gets status 413.
+func (in *ingester) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	status := func() int {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return http.StatusMethodNotAllowed
+		}
+		name := strings.TrimPrefix(req.URL.Path, "/ingest/")
+		schema, ok := in.schemas[name]
+		if !ok {
+			http.Error(w, "no schema registered for '"+name+"'", http.StatusNotFound)
+			return http.StatusNotFound
+		}
+		opts, err := queryOptions(req.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return http.StatusBadRequest
+		}
+		req.Body = limitBody(w, req.Body, in.maxSize)
+		body, err := maybeGunzip(req.Body)
+		if err != nil {
+			status := bodyStatus(err, http.StatusBadRequest)
+			http.Error(w, err.Error(), status)
+			return status
+		}
+		var buf bytes.Buffer
+		status, err := in.ingest(req.Context(), &buf, name, schema, body, opts)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return status
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(status)
+		buf.WriteTo(w)
+		return status
+	}()
+	logger.Printf("%v %v %v %v\n", req.RemoteAddr, req.Method, req.URL.Path, status)
+}
+
+// ingestSummary is the final object in an /ingest response.
+type ingestSummary struct {
+	Type       string   `json:"type"` // summary
+	File       string   `json:"file"`
+	Lines      int      `json:"lines"`
+	Appended   int      `json:"appended"`
+	ErrorLines int      `json:"errorLines"`
+	Files      []string `json:"files"` // files appended to
+}
+
+// ingestLine is one data line of an /ingest request.
+type ingestLine struct {
+	n    int // line number in the request
+	data tsdata.Data
+	path string // output file for a valid line
+	err  error
+}
+
+// ingest validates the data lines in r, appends valid lines, and writes
+// problems and a summary to out. It returns the response status, and an
+// error to send instead of out if the request failed. Nothing is appended if
+// ctx is done before r is read. The body is read and validated before in.mu
+// is taken, so a slow upload doesn't hold up other requests.
+func (in *ingester) ingest(ctx context.Context, out io.Writer, name string, schema tsdata.Schema, r io.Reader, opts []tsdata.Option) (int, error) {
+	t := tsdata.New(append([]tsdata.Option{tsdata.WithSchema(schema)}, opts...)...)
+	pw := newProblemWriter(out, name)
+	sum := ingestSummary{Type: "summary", File: name}
+
+	var lines []ingestLine
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if err := ctx.Err(); err != nil {
+			return http.StatusServiceUnavailable, err
+		}
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		data, err := t.ValidateLine(line, true)
+		l := ingestLine{n: n, data: data, err: err}
+		if err == nil {
+			l.path = filepath.Join(in.dir, name+"."+data.Time.UTC().Format("2006-01-02")+".tsdata")
+		}
+		lines = append(lines, l)
+	}
+	if err := scanner.Err(); err != nil {
+		return bodyStatus(err, http.StatusBadRequest), err
+	}
+	if err := ctx.Err(); err != nil {
+		return http.StatusServiceUnavailable, err
+	}
+
+	files, err := in.commit(schema, lines, opts)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	sum.Lines = len(lines)
+	sum.Files = files
+	for _, l := range lines {
+		if l.err != nil {
+			sum.ErrorLines++
+			if err := pw.lineError(t, &tsdata.LineError{Line: l.n, Err: l.err}); err != nil {
+				return http.StatusInternalServerError, err
+			}
+			continue
+		}
+		sum.Appended++
+		if err := pw.warnings(l.n, l.data); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+	if err := pw.enc.Encode(sum); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if sum.ErrorLines > 0 {
+		return http.StatusUnprocessableEntity, nil
+	}
+	return http.StatusOK, nil
+}
+
+// commit appends the valid lines to their files and returns the names of the
+// files appended to. Lines which aren't later than the last line of their file
+// get an error. If appending to one file fails, lines already appended to
+// other files are removed, so a request is appended to every file or none,
+// and any file which couldn't be restored is named in the error.
+func (in *ingester) commit(schema tsdata.Schema, lines []ingestLine, opts []tsdata.Option) ([]string, error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	buffers := map[string]*bytes.Buffer{}
+	last := map[string]time.Time{}
+	for i := range lines {
+		l := &lines[i]
+		if l.err != nil {
+			continue
+		}
+		prev, ok := last[l.path]
+		if !ok {
+			var err error
+			prev, err = in.lastTime(l.path, opts)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !l.data.Time.After(prev) {
+			l.err = fmt.Errorf("timestamp %v is not after last timestamp %v in %v",
+				l.data.Fields[0], prev.Format(time.RFC3339Nano), filepath.Base(l.path))
+			continue
+		}
+		last[l.path] = l.data.Time
+		if buffers[l.path] == nil {
+			buffers[l.path] = &bytes.Buffer{}
+		}
+		buffers[l.path].WriteString(strings.Join(l.data.Fields, tsdata.Delim) + "\n")
+	}
+
+	paths := make([]string, 0, len(buffers))
+	for path := range buffers {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var written []appended
+	for _, path := range paths {
+		a, err := in.appendLines(path, schema, buffers[path].Bytes())
+		if err != nil {
+			err = fmt.Errorf("%v: %w", filepath.Base(path), err)
+			if uerr := undoAppends(written); uerr != nil {
+				err = fmt.Errorf("%v, and %w", err, uerr)
+			}
+			logger.Error(err)
+			return nil, err
+		}
+		written = append(written, a)
+	}
+	var files []string
+	for _, path := range paths {
+		in.last[path] = last[path]
+		files = append(files, filepath.Base(path))
+	}
+	return files, nil
+}
+
+// lastTime returns the latest timestamp in the output file at path, or the
+// zero time if it doesn't exist. Must be called with in.mu held.
+func (in *ingester) lastTime(path string, opts []tsdata.Option) (time.Time, error) {
+	if t, ok := in.last[path]; ok {
+		return t, nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	_, t, _, err := lastTime(path, opts)
+	if err != nil {
+		return t, fmt.Errorf("%v: %w", path, err)
+	}
+	in.last[path] = t
+	return t, nil
+}
+
+// appended is a file written by appendLines, so the append can be undone.
+type appended struct {
+	path    string
+	size    int64 // size before the append
+	created bool  // the file didn't exist before the append
+}
+
+// undoAppends restores files written by appendLines to their previous
+// contents. The error names any files which couldn't be restored.
+func undoAppends(written []appended) error {
+	var failed []string
+	for _, a := range written {
+		var err error
+		if a.created {
+			err = os.Remove(a.path)
+		} else {
+			err = os.Truncate(a.path, a.size)
+		}
+		if err != nil {
+			logger.Error(err)
+			failed = append(failed, filepath.Base(a.path))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("lines were left appended to %v", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// appendLines appends data lines to the file at path with a single write. A
+// new file is written with its header to a temporary file and renamed into
+// place, so readers never see a partial file. A failed write to an existing
+// file is truncated to its previous size.
+func (in *ingester) appendLines(path string, schema tsdata.Schema, lines []byte) (appended, error) {
+	a := appended{path: path}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err == nil {
+		var fi os.FileInfo
+		fi, err = f.Stat()
+		if err == nil {
+			a.size = fi.Size()
+			_, err = f.Write(lines)
+			if err != nil {
+				f.Truncate(a.size)
+			}
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return a, err
+	}
+	if !os.IsNotExist(err) {
+		return a, err
+	}
+	a.created = true
+	tmp, err := ioutil.TempFile(in.dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return a, err
+	}
+	defer os.Remove(tmp.Name())
+	meta := tsdata.New(tsdata.WithSchema(schema))
+	_, err = tmp.WriteString(meta.Header() + "\n")
+	if err == nil {
+		_, err = tmp.Write(lines)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err != nil {
+		return a, err
+	}
+	return a, os.Rename(tmp.Name(), path)
+}
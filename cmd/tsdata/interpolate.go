@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var interpolateCommand = cli.Command{
+	Name:      "interpolate",
+	Usage:     "Fills NA numeric values from neighboring lines",
+	UsageText: "tsdata interpolate --max-gap DURATION [--method linear|previous] [--interval DURATION] INFILE OUTFILE",
+	Description: "Writes INFILE to OUTFILE with NA values in float, integer, latitude, and longitude columns " +
+		"filled from the nearest earlier and later values in the same column. With --method linear values are " +
+		"interpolated by time, and with --method previous the earlier value is repeated. Integer results are rounded. " +
+		"Values are only filled when the earlier and later values are at most --max-gap apart, so long outages " +
+		"stay NA. With --interval, lines are first inserted at missing steps of a regular cadence like fill, " +
+		"with other columns NA. INFILE must be sorted by time. Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.DurationFlag{
+			Name:  "max-gap",
+			Usage: "Longest time between known values to fill across as a `DURATION`, e.g. 5m",
+		},
+		cli.StringFlag{
+			Name:  "method",
+			Value: "linear",
+			Usage: "Interpolation `METHOD`, linear or previous",
+		},
+		cli.DurationFlag{
+			Name:  "interval, i",
+			Usage: "Insert lines at missing steps of a regular cadence of `DURATION` before interpolating",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 2 {
+			err := usageErrorf("missing required OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 2 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		if c.Duration("max-gap") <= 0 {
+			err := usageErrorf("--max-gap must be a positive duration")
+			logger.Error(err)
+			return err
+		}
+		if c.Duration("interval") < 0 {
+			err := usageErrorf("--interval must be a positive duration")
+			logger.Error(err)
+			return err
+		}
+		method := c.String("method")
+		if method != "linear" && method != "previous" {
+			err := usageErrorf("bad method '%v', expected linear or previous", method)
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		conf := interpConfig{maxGap: c.Duration("max-gap"), linear: method == "linear", interval: c.Duration("interval")}
+		err = interpolateCmd(c.Args().Get(0), c.Args().Get(1), conf, opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// interpConfig holds interpolate command settings.
+type interpConfig struct {
+	maxGap   time.Duration // longest time between known values to fill across
+	linear   bool          // interpolate linearly rather than repeat the previous value
+	interval time.Duration // cadence of inserted lines, or 0
+}
+
+// interpRow is a buffered output line.
+type interpRow struct {
+	time    time.Time
+	fields  []string
+	pending int // NA values which may still be filled
+}
+
+// interpKnown is the last known value in a column.
+type interpKnown struct {
+	ok    bool
+	time  time.Time
+	value float64
+}
+
+// interpolator fills NA values in numeric columns. Lines are buffered until
+// each of their NA values is either filled or can no longer be filled.
+type interpolator struct {
+	t      *tsdata.Tsdata
+	conf   interpConfig
+	cols   []int
+	known  []interpKnown  // by column
+	wait   [][]*interpRow // rows with an NA value which may be filled, by column
+	rows   []*interpRow   // buffered rows in file order
+	write  func([]string) error
+	last   time.Time // time of the last line added
+	filled int
+}
+
+func newInterpolator(t *tsdata.Tsdata, conf interpConfig, write func([]string) error) *interpolator {
+	ip := &interpolator{
+		t:     t,
+		conf:  conf,
+		known: make([]interpKnown, len(t.Headers)),
+		wait:  make([][]*interpRow, len(t.Headers)),
+		write: write,
+	}
+	for i, typ := range t.Types {
+		switch typ {
+		case "float", "integer", "latitude", "longitude":
+			ip.cols = append(ip.cols, i)
+		}
+	}
+	return ip
+}
+
+// add adds a data line, after inserting NA lines for missing steps if
+// configured with an interval.
+func (ip *interpolator) add(data tsdata.Data) error {
+	if !ip.last.IsZero() && data.Time.Before(ip.last) {
+		return dataErrorf("line %v, timestamp earlier than previous line, INFILE must be sorted by time", data.Line)
+	}
+	if ip.conf.interval > 0 && !ip.last.IsZero() {
+		limit := data.Time.Add(-ip.conf.interval / 2)
+		for t := ip.last.Add(ip.conf.interval); t.Before(limit); t = t.Add(ip.conf.interval) {
+			fields := make([]string, len(data.Fields))
+			fields[0] = t.Format(time.RFC3339Nano)
+			for i := 1; i < len(fields); i++ {
+				fields[i] = tsdata.NA
+			}
+			if err := ip.addRow(t, fields, make([]interface{}, len(fields))); err != nil {
+				return err
+			}
+		}
+	}
+	ip.last = data.Time
+	fields := make([]string, len(data.Fields))
+	copy(fields, data.Fields)
+	return ip.addRow(data.Time, fields, data.Values)
+}
+
+// addRow buffers a row, fills values of earlier rows which it makes known,
+// and writes rows which are complete.
+func (ip *interpolator) addRow(t time.Time, fields []string, values []interface{}) error {
+	row := &interpRow{time: t, fields: fields}
+	for _, col := range ip.cols {
+		k := ip.known[col]
+		// Earlier NA values can't be filled across a gap longer than maxGap
+		if k.ok && t.Sub(k.time) > ip.conf.maxGap {
+			ip.give(col)
+		}
+		var v float64
+		switch x := values[col].(type) {
+		case float64:
+			v = x
+		case int64:
+			v = float64(x)
+		default:
+			if k.ok && t.Sub(k.time) <= ip.conf.maxGap {
+				ip.wait[col] = append(ip.wait[col], row)
+				row.pending++
+			}
+			continue
+		}
+		for _, w := range ip.wait[col] {
+			fv := k.value
+			if ip.conf.linear {
+				frac := float64(w.time.Sub(k.time)) / float64(t.Sub(k.time))
+				fv = k.value + (v-k.value)*frac
+			}
+			w.fields[col] = formatNumber(ip.t.Types[col], fv)
+			w.pending--
+			ip.filled++
+		}
+		ip.wait[col] = ip.wait[col][:0]
+		ip.known[col] = interpKnown{ok: true, time: t, value: v}
+	}
+	ip.rows = append(ip.rows, row)
+	return ip.flush()
+}
+
+// give stops waiting to fill NA values in column col.
+func (ip *interpolator) give(col int) {
+	for _, w := range ip.wait[col] {
+		w.pending--
+	}
+	ip.wait[col] = ip.wait[col][:0]
+}
+
+// flush writes buffered rows up to the first with NA values which may still
+// be filled.
+func (ip *interpolator) flush() error {
+	n := 0
+	for n < len(ip.rows) && ip.rows[n].pending == 0 {
+		if err := ip.write(ip.rows[n].fields); err != nil {
+			return err
+		}
+		n++
+	}
+	if n > 0 {
+		m := copy(ip.rows, ip.rows[n:])
+		ip.rows = ip.rows[:m]
+	}
+	return nil
+}
+
+// finish writes all remaining rows. Values which weren't filled stay NA.
+func (ip *interpolator) finish() error {
+	for _, col := range ip.cols {
+		ip.give(col)
+	}
+	return ip.flush()
+}
+
+func interpolateCmd(infile string, outfile string, conf interpConfig, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+	_, err = w.WriteString(tr.Tsdata.Header() + "\n")
+	if err != nil {
+		return err
+	}
+
+	ip := newInterpolator(tr.Tsdata, conf, func(fields []string) error {
+		_, err := w.WriteString(strings.Join(fields, tsdata.Delim) + "\n")
+		return err
+	})
+	err = eachLine(tr, ip.add)
+	if err != nil {
+		return err
+	}
+	err = ip.finish()
+	if err != nil {
+		return err
+	}
+	if ip.filled > 0 {
+		logger.Printf("filled %v NA values\n", ip.filled)
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var joinCommand = cli.Command{
+	Name:      "join",
+	Usage:     "Joins each line to the nearest line in time of another file",
+	UsageText: "tsdata join [--on COLUMN] [--tolerance DURATION] [--alias OLD=NEW...] LEFT RIGHT OUTFILE",
+	Description: "Performs an as-of join of LEFT and RIGHT and writes the result to OUTFILE. Each line of LEFT is " +
+		"written with the columns of the RIGHT line whose --on time is nearest to its own, or NA if there is no " +
+		"RIGHT line within --tolerance. If two RIGHT lines are equally near the earlier is used. Output columns are " +
+		"the columns of LEFT followed by the columns of RIGHT other than --on, and column names must be unique " +
+		"after renaming columns with --alias. " +
+		"Both inputs must be sorted by the --on column. This attaches positions from a GPS feed to an instrument " +
+		"with an unsynchronized clock, for example. Use '-' for STDOUT.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "on",
+			Value: "time",
+			Usage: "Join on time column `COLUMN`, which must be in both inputs",
+		},
+		cli.DurationFlag{
+			Name:  "tolerance, t",
+			Usage: "Join lines with times within `DURATION` of each other, e.g. 30s. Default is exact matches only.",
+		},
+		cli.StringFlag{
+			Name:  "description",
+			Usage: "FileDescription for OUTFILE",
+		},
+		cli.StringSliceFlag{
+			Name:  "alias",
+			Usage: "Treat column `OLD=NEW` as column NEW, may be repeated",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() < 3 {
+			err := usageErrorf("expected LEFT, RIGHT, and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 3 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		if c.Duration("tolerance") < 0 {
+			err := usageErrorf("--tolerance must not be negative")
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		args := c.Args()
+		err = joinCmd(args[0], args[1], args[2], c.String("on"), c.Duration("tolerance"), c.String("description"), opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// joinSource reads the RIGHT input of join, keeping the last line at or
+// before the current LEFT time and the first line after it.
+type joinSource struct {
+	name       string
+	tr         *tsdata.Reader
+	col        int // index of the join column
+	prev, next tsdata.Data
+	prevT      time.Time
+	nextT      time.Time
+	prevOK     bool
+	nextOK     bool
+}
+
+// advance moves next to prev and reads the next line with a join time into
+// next. Lines which fail validation are logged and skipped, as are lines with
+// an NA join time.
+func (s *joinSource) advance() error {
+	if s.nextOK {
+		s.prev, s.prevT, s.prevOK = s.next, s.nextT, true
+	}
+	for {
+		data, err := s.tr.Next()
+		if err == io.EOF {
+			s.nextOK = false
+			return nil
+		}
+		if err != nil {
+			var lerr *tsdata.LineError
+			if !errors.As(err, &lerr) {
+				return fmt.Errorf("%v: %w", s.name, err)
+			}
+			logger.FileError(s.name, err)
+			continue
+		}
+		t, ok := data.Values[s.col].(time.Time)
+		if !ok {
+			continue
+		}
+		if s.prevOK && t.Before(s.prevT) {
+			return dataErrorf("%v: line %v, input is not sorted by %v", s.name, s.tr.Line(), s.tr.Tsdata.Headers[s.col])
+		}
+		s.next, s.nextT, s.nextOK = data, t, true
+		return nil
+	}
+}
+
+// nearest returns the line nearest to t within tolerance, reading lines as
+// needed. Calls must be made with non-decreasing t.
+func (s *joinSource) nearest(t time.Time, tolerance time.Duration) (tsdata.Data, bool, error) {
+	for s.nextOK && !s.nextT.After(t) {
+		if err := s.advance(); err != nil {
+			return tsdata.Data{}, false, err
+		}
+	}
+	var best tsdata.Data
+	bestDiff := tolerance + 1
+	if s.prevOK {
+		if d := t.Sub(s.prevT); d <= tolerance {
+			best, bestDiff = s.prev, d
+		}
+	}
+	if s.nextOK {
+		if d := s.nextT.Sub(t); d <= tolerance && d < bestDiff {
+			best, bestDiff = s.next, d
+		}
+	}
+	return best, bestDiff <= tolerance, nil
+}
+
+func joinCmd(left string, right string, outfile string, on string, tolerance time.Duration, description string, opts []tsdata.Option) error {
+	lr, err := openInput(left)
+	if err != nil {
+		return err
+	}
+	defer lr.Close()
+	ltr, err := tsdata.NewReader(lr, opts...)
+	if err != nil {
+		return fmt.Errorf("%v: %w", left, err)
+	}
+	ltr.Strict = false
+	rr, err := openInput(right)
+	if err != nil {
+		return err
+	}
+	defer rr.Close()
+	rtr, err := tsdata.NewReader(rr, opts...)
+	if err != nil {
+		return fmt.Errorf("%v: %w", right, err)
+	}
+	rtr.Strict = false
+
+	lcol, err := joinColumn(left, ltr.Tsdata, on)
+	if err != nil {
+		return err
+	}
+	rcol, err := joinColumn(right, rtr.Tsdata, on)
+	if err != nil {
+		return err
+	}
+	meta, err := joinHeaders(left, right, ltr.Tsdata, rtr.Tsdata, rcol)
+	if err != nil {
+		return err
+	}
+	meta.FileDescription = description
+	if meta.FileDescription == "" {
+		meta.FileDescription = fmt.Sprintf("%v joined with %v on %v", left, right, on)
+	}
+	err = meta.ValidateMetadata()
+	if err != nil {
+		return err
+	}
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+	_, err = w.WriteString(meta.Header() + "\n")
+	if err != nil {
+		return err
+	}
+
+	src := &joinSource{name: right, tr: rtr, col: rcol}
+	if err := src.advance(); err != nil {
+		return err
+	}
+	nas := make([]string, len(rtr.Tsdata.Headers)-1)
+	for i := range nas {
+		nas[i] = tsdata.NA
+	}
+	var last time.Time
+	matched := 0
+	row := make([]string, 0, len(meta.Headers))
+	err = eachFileLine(left, ltr, func(data tsdata.Data) error {
+		row = append(row[:0], data.Fields...)
+		t, ok := data.Values[lcol].(time.Time)
+		var match tsdata.Data
+		if ok {
+			if !last.IsZero() && t.Before(last) {
+				return dataErrorf("%v: line %v, input is not sorted by %v", left, ltr.Line(), on)
+			}
+			last = t
+			var err error
+			match, ok, err = src.nearest(t, tolerance)
+			if err != nil {
+				return err
+			}
+		}
+		if ok {
+			matched++
+			for j, f := range match.Fields {
+				if j != rcol {
+					row = append(row, f)
+				}
+			}
+		} else {
+			row = append(row, nas...)
+		}
+		_, err := w.WriteString(strings.Join(row, tsdata.Delim) + "\n")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	logger.Printf("joined %v lines\n", matched)
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
+
+// joinColumn returns the index of time column on in t.
+func joinColumn(name string, t *tsdata.Tsdata, on string) (int, error) {
+	for i, h := range t.Headers {
+		if h == on {
+			if t.Types[i] != "time" {
+				return 0, usageErrorf("%v: --on column %v has type %v, expected time", name, on, t.Types[i])
+			}
+			return i, nil
+		}
+	}
+	return 0, usageErrorf("%v: no --on column %v", name, on)
+}
+
+// joinHeaders returns metadata for the columns of l followed by the columns
+// of r other than column rcol.
+func joinHeaders(left string, right string, l *tsdata.Tsdata, r *tsdata.Tsdata, rcol int) (*tsdata.Tsdata, error) {
+	meta := &tsdata.Tsdata{FileType: l.FileType, Project: l.Project}
+	add := func(t *tsdata.Tsdata, j int) {
+		comment := tsdata.NA
+		if j < len(t.Comments) {
+			comment = t.Comments[j]
+		}
+		meta.Headers = append(meta.Headers, t.Headers[j])
+		meta.Types = append(meta.Types, t.Types[j])
+		meta.Units = append(meta.Units, t.Units[j])
+		meta.Comments = append(meta.Comments, comment)
+	}
+	seen := map[string]bool{}
+	for j, h := range l.Headers {
+		add(l, j)
+		seen[h] = true
+	}
+	for j, h := range r.Headers {
+		if j == rcol {
+			continue
+		}
+		if seen[h] {
+			return nil, headerErrorf("column %v is in both %v and %v", h, left, right)
+		}
+		add(r, j)
+	}
+	return meta, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var jsonCommand = cli.Command{
+	Name:        "json",
+	Usage:       "Converts a TSDATA file to newline-delimited JSON",
+	UsageText:   "tsdata json INFILE OUTFILE",
+	Description: "Validates and converts a TSDATA file at INFILE to newline-delimited JSON at OUTFILE, one object per data line keyed by column name. NA values are written as null. Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 2 {
+			err := usageErrorf("missing required OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = jsonCmd(c.Args().Get(0), c.Args().Get(1), opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+func jsonCmd(infile string, outfile string, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+
+	var buf bytes.Buffer
+	err = eachLine(tr, func(data tsdata.Data) error {
+		buf.Reset()
+		err := appendJSONObject(&buf, tr.Tsdata.Headers, data)
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		_, err = w.Write(buf.Bytes())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
+
+// appendJSONObject writes data as a JSON object to buf with keys in column
+// order.
+func appendJSONObject(buf *bytes.Buffer, headers []string, data tsdata.Data) error {
+	buf.WriteByte('{')
+	for i, h := range headers {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(h)
+		if err != nil {
+			return err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		val, err := json.Marshal(jsonValue(data.Values[i]))
+		if err != nil {
+			return err
+		}
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// jsonValue converts a typed TSDATA value to a value suitable for
+// encoding/json. NA, NaN, and infinite values become nil.
+func jsonValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil
+		}
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	}
+	return v
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var lintCommand = cli.Command{
+	Name:      "lint",
+	Usage:     "Reports style problems in a TSDATA file",
+	UsageText: "tsdata lint [--max N] INFILE\n   tsdata lint --fix INFILE OUTFILE",
+	Description: "Reports problems in INFILE which don't cause validation errors or which clean can repair: " +
+		"trailing whitespace, whitespace around fields, lowercase booleans, space-separated or otherwise " +
+		"non-standard timestamps, inconsistent float precision, constant columns, and column names which aren't " +
+		"safe identifiers for SQL and dataframe tools. Each finding is marked " +
+		"fixable or unfixable. With --fix, INFILE is also written to OUTFILE with the same rewrites as clean, " +
+		"which resolves all fixable findings. Exits with an error if there are findings and --fix is not set. " +
+		"Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "fix",
+			Usage: "Write a fixed copy of INFILE to OUTFILE",
+		},
+		cli.IntFlag{
+			Name:  "max",
+			Value: 10,
+			Usage: "Print at most `N` line findings for each check, N <= 0 prints all",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("fix") && c.NArg() < 2 {
+			err := usageErrorf("missing required OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if (!c.Bool("fix") && c.NArg() > 1) || c.NArg() > 2 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		outfile := ""
+		if c.Bool("fix") {
+			outfile = c.Args().Get(1)
+		}
+		err = lintCmd(c.Args().Get(0), outfile, c.Int("max"), opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// Lint checks
+const (
+	lintTrailingWhitespace = "trailing-whitespace"
+	lintFieldWhitespace    = "field-whitespace"
+	lintLowercaseBoolean   = "lowercase-boolean"
+	lintSpaceTimestamp     = "space-timestamp"
+	lintTimestampFormat    = "timestamp-format"
+	lintFloatPrecision     = "float-precision"
+	lintConstantColumn     = "constant-column"
+	lintColumnName         = "column-name"
+)
+
+// lintFixable records which checks are repaired by clean.
+var lintFixable = map[string]bool{
+	lintTrailingWhitespace: true,
+	lintFieldWhitespace:    true,
+	lintLowercaseBoolean:   true,
+	lintSpaceTimestamp:     true,
+	lintTimestampFormat:    true,
+	lintFloatPrecision:     false,
+	lintConstantColumn:     false,
+	lintColumnName:         false,
+}
+
+// identifierRe matches column names which are safe identifiers in SQL and
+// dataframe tools.
+var identifierRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// headersLine is the line number of column names in the header.
+const headersLine = 7
+
+// linter accumulates lint findings for one file.
+type linter struct {
+	t      *tsdata.Tsdata
+	w      io.Writer
+	max    int
+	counts map[string]int
+	// Per-column state for whole-file checks
+	minDecimals []int
+	maxDecimals []int
+	first       []string
+	constant    []bool
+	lines       int
+}
+
+func newLinter(t *tsdata.Tsdata, w io.Writer, max int) *linter {
+	n := len(t.Headers)
+	l := &linter{
+		t:           t,
+		w:           w,
+		max:         max,
+		counts:      map[string]int{},
+		minDecimals: make([]int, n),
+		maxDecimals: make([]int, n),
+		first:       make([]string, n),
+		constant:    make([]bool, n),
+	}
+	for i := range l.constant {
+		l.constant[i] = true
+		l.minDecimals[i] = -1
+	}
+	return l
+}
+
+// report records a finding. line is 0 for whole-file findings and col is -1
+// for whole-line findings.
+func (l *linter) report(check string, line int, col int, msg string) {
+	l.counts[check]++
+	if line > 0 && l.max > 0 && l.counts[check] > l.max {
+		return
+	}
+	where := ""
+	if line > 0 {
+		where = fmt.Sprintf("line %v, ", line)
+	}
+	if col >= 0 {
+		where += fmt.Sprintf("column %v (%v), ", col+1, l.t.Headers[col])
+	}
+	fix := "unfixable"
+	if lintFixable[check] {
+		fix = "fixable"
+	}
+	fmt.Fprintf(l.w, "%v%v [%v, %v]\n", where, msg, check, fix)
+}
+
+// header checks header metadata.
+func (l *linter) header() {
+	for i, h := range l.t.Headers {
+		if !identifierRe.MatchString(h) {
+			l.report(lintColumnName, headersLine, i,
+				"name should have only letters, digits, and underscores and not start with a digit")
+		}
+	}
+}
+
+// line checks one raw data line.
+func (l *linter) line(n int, line string) {
+	l.lines++
+	if trimmed := strings.TrimRight(line, " \t\r"); trimmed != line {
+		l.report(lintTrailingWhitespace, n, -1, "trailing whitespace")
+		line = trimmed
+	}
+	fields := strings.Split(line, l.t.Delimiter())
+	for i := 0; i < len(fields) && i < len(l.t.Headers); i++ {
+		v := strings.TrimSpace(fields[i])
+		if v != fields[i] {
+			l.report(lintFieldWhitespace, n, i, "whitespace around value")
+		}
+		switch l.t.Types[i] {
+		case "boolean":
+			if v != "TRUE" && v != "FALSE" && (strings.EqualFold(v, "TRUE") || strings.EqualFold(v, "FALSE")) {
+				l.report(lintLowercaseBoolean, n, i, fmt.Sprintf("boolean '%v' is not uppercase", v))
+			}
+		case "time":
+			t, err := tsdata.ParseTime(v)
+			if err == nil && t.Format(time.RFC3339Nano) != v {
+				if strings.Contains(v, " ") {
+					l.report(lintSpaceTimestamp, n, i, fmt.Sprintf("timestamp '%v' uses a space instead of 'T'", v))
+				} else {
+					l.report(lintTimestampFormat, n, i, fmt.Sprintf("timestamp '%v' is not in standard form", v))
+				}
+			}
+		case "float", "latitude", "longitude":
+			if v != tsdata.NA && !strings.ContainsAny(v, "eE") {
+				d := 0
+				if j := strings.Index(v, "."); j >= 0 {
+					d = len(v) - j - 1
+				}
+				if l.minDecimals[i] < 0 || d < l.minDecimals[i] {
+					l.minDecimals[i] = d
+				}
+				if d > l.maxDecimals[i] {
+					l.maxDecimals[i] = d
+				}
+			}
+		}
+		if i > 0 {
+			if l.lines == 1 {
+				l.first[i] = v
+			} else if v != l.first[i] {
+				l.constant[i] = false
+			}
+		}
+	}
+}
+
+// finish runs whole-file checks and writes a summary.
+func (l *linter) finish() error {
+	for i := 1; i < len(l.t.Headers); i++ {
+		if l.minDecimals[i] >= 0 && l.minDecimals[i] != l.maxDecimals[i] {
+			l.report(lintFloatPrecision, 0, i, fmt.Sprintf("float precision varies from %v to %v decimal places",
+				l.minDecimals[i], l.maxDecimals[i]))
+		}
+		if l.lines > 1 && l.constant[i] {
+			l.report(lintConstantColumn, 0, i, fmt.Sprintf("every value is '%v'", l.first[i]))
+		}
+	}
+
+	checks := make([]string, 0, len(l.counts))
+	for c := range l.counts {
+		checks = append(checks, c)
+	}
+	sort.Strings(checks)
+	tw := tabwriter.NewWriter(l.w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "\ncheck\tfixable\tcount\n")
+	for _, c := range checks {
+		fmt.Fprintf(tw, "%v\t%v\t%v\n", c, lintFixable[c], l.counts[c])
+	}
+	return tw.Flush()
+}
+
+// findings returns the total number of findings.
+func (l *linter) findings() int {
+	n := 0
+	for _, c := range l.counts {
+		n += c
+	}
+	return n
+}
+
+func lintCmd(infile string, outfile string, max int, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	var out io.Writer = os.Stdout
+	var outf io.WriteCloser
+	var w *bufio.Writer
+	if outfile != "" {
+		if outfile == "-" {
+			out = os.Stderr
+		}
+		outf, err = createOutput(outfile)
+		if err != nil {
+			return err
+		}
+		defer outf.Close()
+		w = bufio.NewWriter(outf)
+		_, err = w.WriteString(tr.Tsdata.Header() + "\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	l := newLinter(tr.Tsdata, out, max)
+	l.header()
+	err = eachCleanLine(tr, false, nil, func(line string, data tsdata.Data) error {
+		l.line(tr.Line(), line)
+		if w == nil {
+			return nil
+		}
+		_, err := w.WriteString(strings.Join(data.Fields, tsdata.Delim) + "\n")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = l.finish()
+	if err != nil {
+		return err
+	}
+	if w == nil {
+		if l.findings() > 0 {
+			return dataErrorf("%v findings", l.findings())
+		}
+		return nil
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
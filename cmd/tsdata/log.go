@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+)
+
+// Log levels, in increasing order of severity
+const (
+	levelInfo = iota
+	levelWarn
+	levelError
+)
+
+var levelNames = []string{"info", "warn", "error"}
+
+// parseLevel returns the log level named s.
+func parseLevel(s string) (int, error) {
+	for i, name := range levelNames {
+		if s == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("bad log level '%v', expected one of %v", s, strings.Join(levelNames, ", "))
+}
+
+// logRecord is one JSON log record.
+type logRecord struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Command string `json:"command,omitempty"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Class   string `json:"class,omitempty"` // error class, from the exit status of an error
+	Msg     string `json:"msg"`
+}
+
+// cmdLogger writes leveled log messages to STDERR, either as plain text lines
+// or as JSON records for log aggregation. It is safe for concurrent use.
+type cmdLogger struct {
+	mu      sync.Mutex
+	out     io.Writer
+	json    bool
+	level   int    // minimum level written
+	command string // subcommand name
+	file    string // file being processed, if any
+	input   string // input file recorded in JSON records if file is ""
+}
+
+func newLogger(out io.Writer) *cmdLogger {
+	return &cmdLogger{out: out, level: levelInfo}
+}
+
+// SetOutput sets the log destination.
+func (l *cmdLogger) SetOutput(w io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out = w
+}
+
+// SetFile sets the file name added to later messages, or clears it if name
+// is "". Text messages are prefixed with the file name.
+func (l *cmdLogger) SetFile(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.file = name
+}
+
+// SetInput sets the input file name recorded in JSON records which aren't
+// about another file. Unlike SetFile it doesn't change text messages.
+func (l *cmdLogger) SetInput(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.input = name
+}
+
+// Printf logs an informational message.
+func (l *cmdLogger) Printf(format string, a ...interface{}) {
+	l.log(levelInfo, "", 0, "", fmt.Sprintf(format, a...))
+}
+
+// Println logs an informational message.
+func (l *cmdLogger) Println(a ...interface{}) {
+	l.log(levelInfo, "", 0, "", fmt.Sprintln(a...))
+}
+
+// Warnf logs a warning about line, or about the whole input if line is 0.
+func (l *cmdLogger) Warnf(line int, format string, a ...interface{}) {
+	l.log(levelWarn, "", line, "", fmt.Sprintf(format, a...))
+}
+
+// FileWarnf is like Warnf for a warning about file name.
+func (l *cmdLogger) FileWarnf(name string, line int, format string, a ...interface{}) {
+	l.log(levelWarn, name, line, "", fmt.Sprintf(format, a...))
+}
+
+// Error logs err. JSON records include the line number of line errors and
+// the class of err, the name of its exit status.
+func (l *cmdLogger) Error(err error) {
+	l.FileError("", err)
+}
+
+// FileError is like Error for an error in file name.
+func (l *cmdLogger) FileError(name string, err error) {
+	line := 0
+	var lerr *tsdata.LineError
+	if errors.As(err, &lerr) {
+		line = lerr.Line
+	}
+	l.log(levelError, name, line, errorClass(err), err.Error())
+}
+
+// log writes a message. name is the file the message is about, or "" for the
+// file set by SetFile.
+func (l *cmdLogger) log(level int, name string, line int, class string, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if level < l.level {
+		return
+	}
+	if name == "" {
+		name = l.file
+	}
+	msg = strings.TrimSuffix(msg, "\n")
+	if !l.json {
+		if name != "" {
+			msg = name + ": " + msg
+		}
+		io.WriteString(l.out, msg+"\n")
+		return
+	}
+	jsonName := name
+	if jsonName == "" {
+		jsonName = l.input
+	}
+	b, err := json.Marshal(logRecord{
+		Time:    time.Now().UTC().Format(time.RFC3339Nano),
+		Level:   levelNames[level],
+		Command: l.command,
+		File:    jsonName,
+		Line:    line,
+		Class:   class,
+		Msg:     msg,
+	})
+	if err != nil {
+		return
+	}
+	l.out.Write(append(b, '\n'))
+}
+
+// errorClass names the exit status of err.
+func errorClass(err error) string {
+	switch exitCode(err) {
+	case exitUsage:
+		return "usage"
+	case exitHeader:
+		return "header"
+	case exitData:
+		return "data"
+	case exitIO:
+		return "io"
+	}
+	return "error"
+}
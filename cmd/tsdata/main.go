@@ -2,346 +2,1400 @@ package main
 
 import (
 	"bufio"
+	"compress/gzip"
+	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/ctberthiaume/tsdata"
+	"github.com/ctberthiaume/tsdata/internal/objstore"
 	"github.com/urfave/cli"
 )
 
-var logger *log.Logger
+var logger *cmdLogger
 var cmdname string = "tsdata"
 var version string = "v0.3.1"
 
 func main() {
-	logger = log.New(os.Stderr, "", 0)
+	logger = newLogger(os.Stderr)
 	app := cli.NewApp()
 	app.Name = cmdname
 	app.Usage = "process time-series TSDATA files (https://github.com/armbrustlab/tsdataformat)"
 	app.Version = version
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "log-format",
+			Value: "text",
+			Usage: "Log `FORMAT` on STDERR, text lines or json records",
+		},
+		cli.StringFlag{
+			Name:  "log-level",
+			Value: "info",
+			Usage: "Log messages at `LEVEL` or above, info, warn, or error",
+		},
+	}
+	app.Before = configureLogger
 	app.Commands = []cli.Command{
 		{
-			Name:        "validate",
-			Usage:       "Validates a TSDATA file",
-			UsageText:   "tsdata validate INFILE",
-			Description: "Validates metadata and data in INFILE. Prints errors encountered to STDERR. Use '-' for STDIN.",
+			Name:      "validate",
+			Usage:     "Validates TSDATA files",
+			UsageText: "tsdata validate [--follow] [--summary] [--max-errors N] [--sample PERCENT | --every N] INFILE...",
+			Description: "Validates metadata and data in each INFILE. Prints errors encountered to STDERR. Use '-' for STDIN. " +
+				"With --registry, files whose fileType isn't registered or whose columns don't match the registered header fail. " +
+				"--max-errors stops validation after N data lines with errors, and --summary prints a histogram of errors " +
+				"in each column to STDOUT at the end, so large files with many errors can be reviewed without logging every error. " +
+				"For a quick check of a large file, --sample validates the header and a random PERCENT of data lines, " +
+				"and --every validates the header and every Nth data line. Every line is still read, the error rate " +
+				"of validated lines is logged along with an estimate of error lines in the whole file, and --report " +
+				"and --summary count only validated lines. " +
+				"Glob patterns in INFILE are expanded. When more than one file is given each file is validated " +
+				"independently, a PASS or FAIL line is printed to STDOUT for each file, and validation fails if any file fails.",
 			Flags: []cli.Flag{
 				cli.BoolFlag{
 					Name:  "stringent, s",
 					Usage: "Exit after the first data line validation error",
 				},
+				cli.IntFlag{
+					Name:  "max-errors",
+					Usage: "Stop after `N` data lines with validation errors, 0 for no limit",
+				},
+				cli.BoolFlag{
+					Name:  "summary",
+					Usage: "Print the number of validation errors in each column to STDOUT",
+				},
+				cli.StringFlag{
+					Name:  "sample",
+					Usage: "Validate a random `PERCENT` of data lines, e.g. 1%",
+				},
+				cli.IntFlag{
+					Name:  "every",
+					Usage: "Validate every `N`th data line, starting with the first",
+				},
+				cli.BoolFlag{
+					Name:  "report, r",
+					Usage: "Print a validation report with line counts, time range, and per-column error and NA counts to STDOUT",
+				},
+				cli.StringFlag{
+					Name:  "check-order",
+					Value: "off",
+					Usage: "Check that timestamps don't decrease, `MODE` is off, warn, or strict",
+				},
+				cli.DurationFlag{
+					Name:  "expected-interval",
+					Usage: "Warn about lines whose time since the previous line differs from `DURATION`, e.g. 30s, by more than --tolerance",
+				},
+				cli.DurationFlag{
+					Name:  "tolerance",
+					Usage: "Allowed difference from --expected-interval as a `DURATION`",
+				},
+				cli.BoolFlag{
+					Name:  "follow, f",
+					Usage: "Keep reading as lines are appended to INFILE, like tail -f, until interrupted",
+				},
+				cli.BoolFlag{
+					Name:  "progress",
+					Usage: "Periodically print progress to STDERR",
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Value: "text",
+					Usage: "Problem output `FORMAT`, text logged to STDERR or json objects on STDOUT",
+				},
+				cli.IntFlag{
+					Name:  "workers",
+					Usage: "Validate with `N` goroutines, 0 for one per CPU",
+				},
+				cli.StringFlag{
+					Name:  "time-format",
+					Value: "rfc3339",
+					Usage: "Also accept Unix epoch timestamps in time columns, `FORMAT` is rfc3339, epoch, or epoch_ms",
+				},
+				cli.StringFlag{
+					Name:  "float-policy",
+					Value: "accept",
+					Usage: "Handle NaN, +Inf, and -Inf in float, latitude, and longitude columns, `MODE` is accept, reject, or na",
+				},
+				cli.BoolFlag{
+					Name:  "nmea",
+					Usage: "Accept NMEA latitude and longitude values such as 4916.45N and 12311.12W",
+				},
+				cli.BoolFlag{
+					Name:  "check-units",
+					Usage: "Report Units values which aren't in a built-in vocabulary of common UDUNITS symbols",
+				},
+				cli.StringFlag{
+					Name:  "units",
+					Usage: "Check Units values against the vocabulary in `FILE`, one unit per line, implies --check-units",
+				},
+				cli.StringFlag{
+					Name:  "constraints",
+					Usage: "Check column values against constraints in JSON `FILE`, an object keyed by column name",
+				},
+				registryFlag,
 				cli.BoolFlag{
 					Name:  "quiet, q",
 					Usage: "Suppress logging output",
 				},
+				cli.StringSliceFlag{
+					Name:  "na",
+					Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+				},
 			},
 			Action: func(c *cli.Context) error {
 				if c.NArg() == 0 {
-					err := fmt.Errorf("missing required INFILE argument")
-					logger.Println(err)
-					return err
-				}
-				if c.NArg() > 1 {
-					err := fmt.Errorf("too many arguments")
-					logger.Println(err)
+					err := usageErrorf("missing required INFILE argument")
+					logger.Error(err)
 					return err
 				}
 				if c.Bool("quiet") {
 					logger.SetOutput(ioutil.Discard)
 				}
-				err := validateCmd(c.Args().Get(0), c.Bool("stringent"))
+				files, err := expandGlobs(c.Args())
 				if err != nil {
-					logger.Println(err)
+					logger.Error(err)
+					return err
 				}
-				return err
+				if len(files) > 1 && c.Bool("follow") {
+					err := usageErrorf("--follow can only be used with one INFILE")
+					logger.Error(err)
+					return err
+				}
+				if c.Int("max-errors") < 0 {
+					err := usageErrorf("--max-errors must be >= 0")
+					logger.Error(err)
+					return err
+				}
+				if c.String("format") != "text" && c.String("format") != "json" {
+					err := usageErrorf("bad format '%v', expected text or json", c.String("format"))
+					logger.Error(err)
+					return err
+				}
+				opts, err := readerOptions(c)
+				if err == nil {
+					opts, err = inputTimeFormat(c, opts)
+				}
+				if err == nil {
+					opts, err = expectedInterval(c, opts)
+				}
+				if err != nil {
+					logger.Error(err)
+					return err
+				}
+				sample, err := lineSampler(c)
+				if err != nil {
+					logger.Error(err)
+					return err
+				}
+				units, err := unitsVocabulary(c)
+				if err != nil {
+					logger.Error(err)
+					return err
+				}
+				var reg registry
+				if c.String("registry") != "" {
+					reg, err = readRegistry(c.String("registry"), false)
+					if err != nil {
+						logger.Error(err)
+						return err
+					}
+				}
+				conf := validateConfig{
+					stringent: c.Bool("stringent"),
+					report:    c.Bool("report"),
+					summary:   c.Bool("summary"),
+					maxErrors: c.Int("max-errors"),
+					sample:    sample,
+					follow:    c.Bool("follow"),
+					progress:  c.Bool("progress"),
+					workers:   c.Int("workers"),
+					format:    c.String("format"),
+					units:     units,
+					registry:  reg,
+				}
+				if len(files) == 1 {
+					logger.SetInput(files[0])
+					err = validateCmd(context.Background(), files[0], conf, opts)
+					if err != nil {
+						logger.Error(err)
+					}
+					return err
+				}
+				return validateFiles(context.Background(), files, conf, opts)
 			},
 		},
 		{
-			Name:        "csv",
-			Usage:       "Converts a TSDATA file to CSV",
-			UsageText:   "tsdata csv INFILE OUTFILE",
-			Description: "Validates and converts a TSDATA file at INFILE to a CSV file at OUTFILE. Use '-' for STDIN and STDOUT.",
+			Name:      "csv",
+			Usage:     "Converts a TSDATA file to CSV",
+			UsageText: "tsdata csv [--follow] [--time-format FORMAT] [--transform COMMAND] INFILE OUTFILE",
+			Description: "Validates and converts a TSDATA file at INFILE to a CSV file at OUTFILE. " +
+				"Timestamps in time columns are written in RFC3339 form unless --time-format is epoch for Unix epoch seconds, " +
+				"epoch_ms for Unix epoch milliseconds, or a Go time layout such as '2006-01-02 15:04:05'. " +
+				"--transform passes valid data lines through COMMAND before conversion, see clean. " +
+				"Use '-' for STDIN and STDOUT.",
 			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "time-format",
+					Value: "rfc3339",
+					Usage: "Write timestamps in `FORMAT`, rfc3339, epoch, epoch_ms, or a Go time layout",
+				},
+				cli.StringFlag{
+					Name:  "transform",
+					Usage: "Pass data lines through shell `COMMAND`, which must write and flush one line for each line it reads",
+				},
+				cli.BoolFlag{
+					Name:  "follow, f",
+					Usage: "Keep reading as lines are appended to INFILE, like tail -f, until interrupted",
+				},
+				cli.BoolFlag{
+					Name:  "progress",
+					Usage: "Periodically print progress to STDERR",
+				},
 				cli.BoolFlag{
 					Name:  "quiet, q",
 					Usage: "Suppress logging output",
 				},
+				cli.StringSliceFlag{
+					Name:  "na",
+					Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+				},
 			},
 			Action: func(c *cli.Context) error {
 				if c.NArg() == 0 {
-					err := fmt.Errorf("missing required INFILE and OUTIFLE arguments")
-					logger.Println(err)
+					err := usageErrorf("missing required INFILE and OUTIFLE arguments")
+					logger.Error(err)
 					return err
 				}
 				if c.NArg() < 2 {
-					err := fmt.Errorf("missing required OUTFILE argument")
-					logger.Println(err)
+					err := usageErrorf("missing required OUTFILE argument")
+					logger.Error(err)
 					return err
 				}
 				if c.Bool("quiet") {
 					logger.SetOutput(ioutil.Discard)
 				}
-				err := csvCmd(c.Args().Get(0), c.Args().Get(1))
+				opts, err := readerOptions(c)
+				if err != nil {
+					logger.Error(err)
+					return err
+				}
+				format, err := parseTimeLayout(c.String("time-format"))
+				if err != nil {
+					logger.Error(err)
+					return err
+				}
+				err = csvCmd(c.Args().Get(0), c.Args().Get(1), c.Bool("follow"), c.Bool("progress"), format, c.String("transform"), opts)
 				if err != nil {
-					logger.Println(err)
+					logger.Error(err)
 				}
 				return err
 			},
 		},
 		{
-			Name:        "clean",
-			Usage:       "Clean a TSDATA file",
-			UsageText:   "tsdata clean INFILE OUTFILE",
-			Description: "Fix common errors in a TSDATA file at INFILE, write to OUTFILE. Use '-' for STDIN and STDOUT.",
+			Name:      "clean",
+			Usage:     "Clean a TSDATA file",
+			UsageText: "tsdata clean [--sort] [--dedupe] [--to-utc] [--coerce] [--keep-header] [--from-delimiter DELIM] [--transform COMMAND] INFILE OUTFILE",
+			Description: "Fix common errors in a TSDATA file at INFILE, write to OUTFILE. Whitespace around fields is removed, " +
+				"timestamps are written in a standard RFC3339 form, and booleans are written in uppercase. " +
+				"With --nmea, NMEA latitude and longitude values are converted to decimal degrees. " +
+				"Fields which still fail validation become NA, and lines which can't be repaired are dropped. " +
+				"With --stringent, the first line which fails validation stops cleaning instead and OUTFILE is removed. " +
+				"--sort orders lines by time, keeping the original order of lines with the same time, and holds lines in memory. " +
+				"--dedupe keeps only the first line for each timestamp, like dedupe. " +
+				"--to-utc rewrites timestamps with other UTC offsets, such as +02:00, in UTC with a Z suffix. " +
+				"--coerce rewrites true, T, and 1 as TRUE and false, F, and 0 as FALSE in boolean columns, " +
+				"and NaN, null, -999, and empty fields as NA in numeric and boolean columns. " +
+				"With --time-format epoch or epoch_ms, Unix epoch timestamps are rewritten in RFC3339 form. " +
+				"The header is written in a standard form unless --keep-header is given, which copies the header " +
+				"section exactly as read so reviewed metadata wording is never changed. " +
+				"--from-delimiter converts header and data lines written with another delimiter, such as a comma, " +
+				"to tab-delimited lines. Lines which already have a tab-delimited field for every column are left alone. " +
+				"--transform runs COMMAND with sh and passes it the header section and then each valid data line on STDIN, " +
+				"just before output. COMMAND must write and flush one line to STDOUT for each data line it reads, either " +
+				"the transformed line, which is validated again, or an empty line to drop the line. " +
+				"Use '-' for STDIN and STDOUT.",
 			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "stringent, s",
+					Usage: "Exit with an error at the first line which fails validation, without writing OUTFILE",
+				},
+				cli.BoolFlag{
+					Name:  "sort",
+					Usage: "Sort lines by time",
+				},
+				cli.BoolFlag{
+					Name:  "dedupe",
+					Usage: "Remove lines with the same time as an earlier line",
+				},
+				cli.BoolFlag{
+					Name:  "to-utc",
+					Usage: "Convert timestamps to UTC",
+				},
+				cli.BoolFlag{
+					Name:  "keep-header",
+					Usage: "Copy the header section unchanged instead of writing it in a standard form",
+				},
+				cli.BoolFlag{
+					Name:  "coerce",
+					Usage: "Rewrite other spellings of booleans and missing values, and log a count of each rewrite",
+				},
+				cli.StringFlag{
+					Name:  "from-delimiter",
+					Usage: "Convert lines delimited by `DELIM`, a single character or \"space\" for runs of spaces, to tabs",
+				},
+				cli.StringFlag{
+					Name:  "transform",
+					Usage: "Pass data lines through shell `COMMAND`, which must write and flush one line for each line it reads",
+				},
+				cli.BoolFlag{
+					Name:  "progress",
+					Usage: "Periodically print progress to STDERR",
+				},
+				cli.StringFlag{
+					Name:  "time-format",
+					Value: "rfc3339",
+					Usage: "Also accept Unix epoch timestamps in time columns, `FORMAT` is rfc3339, epoch, or epoch_ms",
+				},
+				cli.StringFlag{
+					Name:  "float-policy",
+					Value: "accept",
+					Usage: "Handle NaN, +Inf, and -Inf in float, latitude, and longitude columns, `MODE` is accept, reject, or na",
+				},
+				cli.BoolFlag{
+					Name:  "nmea",
+					Usage: "Accept NMEA latitude and longitude values such as 4916.45N and 12311.12W",
+				},
+				cli.StringFlag{
+					Name:  "constraints",
+					Usage: "Check column values against constraints in JSON `FILE`, an object keyed by column name",
+				},
 				cli.BoolFlag{
 					Name:  "quiet, q",
 					Usage: "Suppress logging output",
 				},
+				cli.StringSliceFlag{
+					Name:  "na",
+					Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+				},
 			},
 			Action: func(c *cli.Context) error {
 				if c.NArg() == 0 {
-					err := fmt.Errorf("missing required INFILE and OUTIFLE arguments")
-					logger.Println(err)
+					err := usageErrorf("missing required INFILE and OUTIFLE arguments")
+					logger.Error(err)
 					return err
 				}
 				if c.NArg() < 2 {
-					err := fmt.Errorf("missing required OUTFILE argument")
-					logger.Println(err)
+					err := usageErrorf("missing required OUTFILE argument")
+					logger.Error(err)
 					return err
 				}
 				if c.Bool("quiet") {
 					logger.SetOutput(ioutil.Discard)
 				}
-				err := cleanCmd(c.Args().Get(0), c.Args().Get(1))
+				opts, err := readerOptions(c)
+				if err == nil {
+					opts, err = inputTimeFormat(c, opts)
+				}
+				if err != nil {
+					logger.Error(err)
+					return err
+				}
+				conf := cleanConfig{
+					stringent:  c.Bool("stringent"),
+					sort:       c.Bool("sort"),
+					dedupe:     c.Bool("dedupe"),
+					toUTC:      c.Bool("to-utc"),
+					coerce:     c.Bool("coerce"),
+					keepHeader: c.Bool("keep-header"),
+					transform:  c.String("transform"),
+					progress:   c.Bool("progress"),
+				}
+				if c.IsSet("from-delimiter") {
+					conf.fromDelim, err = parseFromDelimiter(c.String("from-delimiter"))
+					if err != nil {
+						logger.Error(err)
+						return err
+					}
+				}
+				err = cleanCmd(c.Args().Get(0), c.Args().Get(1), conf, opts)
 				if err != nil {
-					logger.Println(err)
+					logger.Error(err)
 				}
 				return err
 			},
 		},
+		infoCommand,
+		jsonCommand,
+		parquetCommand,
+		mergeCommand,
+		concatCommand,
+		catCommand,
+		convertCommand,
+		splitCommand,
+		describeCommand,
+		dedupeCommand,
+		gapsCommand,
+		fillCommand,
+		smoothCommand,
+		resampleCommand,
+		interpolateCommand,
+		joinCommand,
+		meltCommand,
+		castCommand,
+		appendCommand,
+		inferSchemaCommand,
+		schemaCommand,
+		registryCommand,
+		queryCommand,
+		plotCommand,
+		pushCommand,
+		checksumCommand,
+		lintCommand,
+		watchCommand,
+		serveCommand,
+		viewCommand,
+		indexCommand,
+		filterCommand,
+		packCommand,
+		unpackCommand,
+		xlsxCommand,
+		fromCSVCommand,
+		addColumnCommand,
+		dropColumnCommand,
+		renameColumnCommand,
+		reorderCommand,
+		retypeCommand,
+		setUnitsCommand,
+		setCommentCommand,
+		timeshiftCommand,
+		driftCorrectCommand,
+		subtractCommand,
+		replayCommand,
+		genCommand,
+		anonymizeCommand,
 	}
+	app.OnUsageError = onUsageError
+	setUsageErrors(app.Commands)
 
 	err := app.Run(os.Args)
 	if err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
-func validateCmd(infile string, stringent bool) error {
-	var r *os.File
-	var err error
-	if infile == "-" {
-		r = os.Stdin
-	} else {
-		r, err = os.Open(infile)
-		if err != nil {
-			return err
+// configureLogger sets up logger from global flags. Errors are printed by
+// cli with the app help.
+func configureLogger(c *cli.Context) error {
+	level, err := parseLevel(c.String("log-level"))
+	if err != nil {
+		return usageErrorf("%v", err)
+	}
+	switch c.String("log-format") {
+	case "text":
+	case "json":
+		logger.json = true
+	default:
+		return usageErrorf("bad log format '%v', expected text or json", c.String("log-format"))
+	}
+	logger.level = level
+	logger.command = c.Args().First()
+	return nil
+}
+
+// onUsageError marks flag parsing errors as usage errors.
+func onUsageError(c *cli.Context, err error, isSubcommand bool) error {
+	err = &cmdError{code: exitUsage, err: err}
+	logger.Error(err)
+	return err
+}
+
+// setUsageErrors sets onUsageError for commands and their subcommands.
+func setUsageErrors(cmds []cli.Command) {
+	for i := range cmds {
+		if cmds[i].OnUsageError == nil {
+			cmds[i].OnUsageError = onUsageError
 		}
-		defer r.Close()
+		setUsageErrors(cmds[i].Subcommands)
 	}
+}
+
+// validateConfig holds validate command settings.
+type validateConfig struct {
+	stringent bool           // stop at the first data line error
+	report    bool           // print a report to STDOUT
+	summary   bool           // print per-column error counts to STDOUT
+	maxErrors int            // stop after this many error lines, 0 for no limit
+	sample    func(int) bool // validate only data lines selected by line number if not nil
+	follow    bool           // keep reading as lines are appended
+	progress  bool           // log progress
+	workers   int            // validation goroutines, <= 0 for one per CPU
+	format    string         // problem output format, text or json
+	units     tsdata.Units   // check Units against this vocabulary if not nil
+	registry  registry       // check fileType and columns against this registry if not nil
+}
 
-	ts := tsdata.Tsdata{}
-	scanner := bufio.NewScanner(r)
-	header, err := readHeader(scanner)
+func validateCmd(ctx context.Context, infile string, conf validateConfig, opts []tsdata.Option) error {
+	p := newProgress(infile, conf.progress)
+	r, err := openInputWith(infile, conf.follow, p)
 	if err != nil {
 		return err
 	}
-	err = ts.ParseHeader(header)
+	defer r.Close()
+	p.run()
+	defer p.stop()
+	return validateReader(ctx, r, infile, conf, os.Stdout, opts)
+}
+
+// validateReader validates TSDATA read from r until it's read or ctx is done.
+// infile is the file name used in output. JSON problems and reports are
+// written to out.
+func validateReader(ctx context.Context, r io.Reader, infile string, conf validateConfig, out io.Writer, opts []tsdata.Option) error {
+	var pw *problemWriter
+	if conf.format == "json" {
+		pw = newProblemWriter(out, infile)
+	}
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err == nil && conf.registry != nil {
+		err = conf.registry.check(tr.Tsdata.Schema())
+	}
 	if err != nil {
+		if pw != nil {
+			if err := pw.headerError(err); err != nil {
+				return err
+			}
+			if err := pw.summary(nil, false, false); err != nil {
+				return err
+			}
+		}
 		return err
 	}
 
-	sawError := false
-	i := tsdata.HeaderSize
-	for scanner.Scan() {
-		i++
-		_, err := ts.ValidateLine(scanner.Text(), true)
+	tr.Sample = conf.sample
+
+	unknownUnits := 0
+	if conf.units != nil {
+		for _, col := range conf.units.Unknown(tr.Tsdata.Schema()) {
+			unknownUnits++
+			if pw != nil {
+				if err := pw.unitsError(tr.Tsdata, col); err != nil {
+					return err
+				}
+			} else {
+				logger.Error(&tsdata.LineError{Line: unitsLine, Err: headerErrorf("column %v (%v), %v", col+1, tr.Tsdata.Headers[col], unknownUnitMessage(tr.Tsdata, col))})
+			}
+		}
+	}
+
+	errorLines := 0
+	otherErrors := 0 // error lines without a column, e.g. the wrong number of fields
+	errStop := errors.New("stop")
+	handle := func(data tsdata.Data, err error) error {
 		if err != nil {
-			sawError = true
-			logger.Printf("line %v, %v\n", i, err)
-			if stringent {
+			var lerr *tsdata.LineError
+			if !errors.As(err, &lerr) {
+				return err
+			}
+			errorLines++
+			var ferr *tsdata.FieldError
+			if !errors.As(lerr, &ferr) {
+				otherErrors++
+			}
+			if pw != nil {
+				err = pw.lineError(tr.Tsdata, lerr)
+				if err != nil {
+					return err
+				}
+			} else {
+				logger.Error(err)
+			}
+			if conf.stringent || (conf.maxErrors > 0 && errorLines >= conf.maxErrors) {
+				return errStop
+			}
+			return nil
+		}
+		if pw != nil {
+			return pw.warnings(tr.Line(), data)
+		}
+		logWarnings(tr.Line(), data)
+		return nil
+	}
+	if conf.follow || conf.workers == 1 {
+		// Validate lines as they arrive
+		for {
+			var data tsdata.Data
+			data, err = tr.NextContext(ctx)
+			if err == io.EOF {
+				err = nil
+				break
+			}
+			err = handle(data, err)
+			if err != nil {
 				break
 			}
 		}
+	} else {
+		err = tsdata.ValidateConcurrentContext(ctx, tr, conf.workers, handle)
 	}
-	err = scanner.Err()
-	if err != nil {
+	if err != nil && err != errStop {
 		return err
 	}
+	stopped := err == errStop && !conf.stringent
+	if stopped {
+		logger.Printf("stopped after %v lines with errors at line %v\n", errorLines, tr.Line())
+	}
+	if conf.sample != nil {
+		logSampleEstimate(infile, tr.Report())
+	}
+
+	if pw != nil {
+		err = pw.summary(tr.Report(), errorLines == 0 && unknownUnits == 0, stopped)
+		if err != nil {
+			return err
+		}
+	} else {
+		if conf.report {
+			err = writeReport(out, infile, tr.Report())
+			if err != nil {
+				return err
+			}
+		}
+		if conf.summary {
+			if conf.report {
+				fmt.Fprintln(out)
+			}
+			err = writeErrorSummary(out, infile, tr.Report(), otherErrors, stopped)
+			if err != nil {
+				return err
+			}
+		}
+	}
 
-	if sawError {
-		return fmt.Errorf("%v failed validation", infile)
+	if unknownUnits > 0 {
+		return headerErrorf("%v has unknown units", infile)
+	}
+	if errorLines > 0 {
+		return dataErrorf("%v failed validation", infile)
 	}
 	return nil
 }
 
-func csvCmd(infile string, outfile string) error {
-	var r *os.File
-	var err error
-	if infile == "-" {
-		r = os.Stdin
-	} else {
-		r, err = os.Open(infile)
+// unitsLine is the 1-based line number of the Units row.
+const unitsLine = 6
+
+// unknownUnitMessage describes a Units value in column col which isn't in
+// the units vocabulary.
+func unknownUnitMessage(t *tsdata.Tsdata, col int) string {
+	return fmt.Sprintf("unknown unit '%v'", t.Units[col])
+}
+
+// unitsVocabulary returns the units vocabulary selected by --check-units and
+// --units, or nil if units shouldn't be checked.
+func unitsVocabulary(c *cli.Context) (tsdata.Units, error) {
+	if c.String("units") != "" {
+		f, err := os.Open(c.String("units"))
+		if err != nil {
+			return nil, &cmdError{code: exitIO, err: err}
+		}
+		defer f.Close()
+		units, err := tsdata.ReadUnits(f)
 		if err != nil {
+			return nil, &cmdError{code: exitIO, err: err}
+		}
+		return units, nil
+	}
+	if c.Bool("check-units") {
+		return tsdata.DefaultUnits(), nil
+	}
+	return nil, nil
+}
+
+// validateFiles validates each file independently and prints a PASS or FAIL
+// line for each file. Log messages are prefixed with the file name. The
+// returned error has the exit code of the first file which failed.
+func validateFiles(ctx context.Context, files []string, conf validateConfig, opts []tsdata.Option) error {
+	results := make([]error, len(files))
+	var firstErr error
+	failed := 0
+	for i, f := range files {
+		logger.SetFile(f)
+		results[i] = validateCmd(ctx, f, conf, opts)
+		if results[i] != nil {
+			logger.Error(results[i])
+		}
+		logger.SetFile("")
+		if results[i] != nil {
+			if firstErr == nil {
+				firstErr = results[i]
+			}
+			failed++
+		}
+	}
+
+	if conf.format == "text" {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for i, f := range files {
+			status := "PASS"
+			if results[i] != nil {
+				status = "FAIL"
+			}
+			fmt.Fprintf(w, "%v\t%v\n", status, f)
+		}
+		if err := w.Flush(); err != nil {
 			return err
 		}
-		defer r.Close()
 	}
 
-	ts := tsdata.Tsdata{}
-	scanner := bufio.NewScanner(r)
-	header, err := readHeader(scanner)
+	if failed > 0 {
+		err := &cmdError{code: exitCode(firstErr), err: fmt.Errorf("%v of %v files failed validation", failed, len(files))}
+		logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+// expandGlobs expands glob patterns in args. Arguments without glob
+// characters are returned unchanged. It's an error for a pattern to match no
+// files.
+func expandGlobs(args []string) ([]string, error) {
+	var files []string
+	for _, a := range args {
+		if a == "-" || !strings.ContainsAny(a, "*?[") {
+			files = append(files, a)
+			continue
+		}
+		matches, err := filepath.Glob(a)
+		if err != nil {
+			return nil, usageErrorf("bad pattern '%v': %v", a, err)
+		}
+		if len(matches) == 0 {
+			return nil, usageErrorf("no files match '%v'", a)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+func csvCmd(infile string, outfile string, follow bool, showProgress bool, format timeFormatter, transform string, opts []tsdata.Option) error {
+	p := newProgress(infile, showProgress)
+	r, err := openInputWith(infile, follow, p)
 	if err != nil {
 		return err
 	}
-	err = ts.ParseHeader(header)
+	defer r.Close()
+	p.run()
+	defer p.stop()
+
+	tr, err := tsdata.NewReader(r, opts...)
 	if err != nil {
 		return err
 	}
+	tr.Strict = false
 
-	var outf *os.File
-	if outfile == "-" {
-		outf = os.Stdout
-	} else {
-		outf, err = os.Create(outfile)
-		if err != nil {
-			return err
-		}
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
 	}
+	defer outf.Close()
 	w := csv.NewWriter(outf)
 
 	// Write CSV column headers
-	err = w.Write(ts.Headers)
+	err = w.Write(tr.Tsdata.Headers)
 	if err != nil {
 		return err
 	}
 
 	// Write CSV lines
-	i := tsdata.HeaderSize
-	for scanner.Scan() {
-		i++
-		data, err := ts.ValidateLine(scanner.Text(), false)
-		if err != nil {
-			logger.Printf("line %v, %v\n", i, err)
-			continue
+	fn := func(data tsdata.Data) error {
+		err := w.Write(formatTimes(tr.Tsdata, data, format))
+		if err != nil || !follow {
+			return err
 		}
-		err = w.Write(data.Fields)
+		w.Flush()
+		return w.Error()
+	}
+	var ct *commandTransformer
+	if transform != "" {
+		ct, err = startTransform(transform, tr.Tsdata, opts)
 		if err != nil {
 			return err
 		}
+		defer ct.kill()
+		fn = ct.wrap(tr, false, fn)
 	}
-	err = scanner.Err()
+	err = eachLine(tr, fn)
 	if err != nil {
 		return err
 	}
+	if ct != nil {
+		if err := ct.close(); err != nil {
+			return err
+		}
+	}
 
 	w.Flush()
 	err = w.Error()
 	if err != nil {
 		return err
 	}
-	if outfile == "-" {
-		err = outf.Close()
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return outf.Close()
 }
 
-func cleanCmd(infile string, outfile string) error {
-	var r *os.File
-	var err error
-	if infile == "-" {
-		r = os.Stdin
-	} else {
-		r, err = os.Open(infile)
-		if err != nil {
-			return err
-		}
-		defer r.Close()
-	}
+// cleanConfig holds clean command settings.
+type cleanConfig struct {
+	stringent  bool   // stop at the first invalid line and discard output
+	sort       bool   // sort lines by time
+	dedupe     bool   // keep only the first line for each timestamp
+	toUTC      bool   // convert timestamps to UTC
+	coerce     bool   // rewrite common boolean and missing value spellings
+	keepHeader bool   // write the header exactly as read
+	fromDelim  string // convert lines delimited by fromDelim, if not ""
+	transform  string // shell command to pass lines through, if not ""
+	progress   bool   // periodically log progress
+}
 
-	ts := tsdata.Tsdata{}
-	scanner := bufio.NewScanner(r)
-	header, err := readHeader(scanner)
+func cleanCmd(infile string, outfile string, conf cleanConfig, opts []tsdata.Option) error {
+	p := newProgress(infile, conf.progress)
+	r, err := openInputWith(infile, false, p)
 	if err != nil {
 		return err
 	}
-	err = ts.ParseHeader(header)
+	defer r.Close()
+	p.run()
+	defer p.stop()
+
+	var in io.Reader = r
+	if conf.fromDelim != "" {
+		in = newDelimReader(r, conf.fromDelim)
+	}
+	tr, err := tsdata.NewReader(in, opts...)
 	if err != nil {
 		return err
 	}
+	tr.Strict = false
 
-	var outf *os.File
-	if outfile == "-" {
-		outf = os.Stdout
-	} else {
-		outf, err = os.Create(outfile)
-		if err != nil {
-			return err
-		}
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
 	}
+	defer outf.Close()
 	w := bufio.NewWriter(outf)
 
 	// Write header section
-	_, err = w.WriteString(ts.Header() + "\n")
+	header := tr.Tsdata.Header()
+	if conf.keepHeader {
+		header = tr.Tsdata.RawHeader()
+	}
+	_, err = w.WriteString(header + "\n")
 	if err != nil {
 		return err
 	}
 
 	// Write TSDATA lines
-	i := tsdata.HeaderSize
-	for scanner.Scan() {
-		i++
-		data, err := ts.ValidateLine(scanner.Text(), false)
-		if err != nil {
-			logger.Printf("line %v, %v\n", i, err)
-			continue
+	write := func(data tsdata.Data) error {
+		_, err := w.WriteString(strings.Join(data.Fields, tsdata.Delim) + "\n")
+		return err
+	}
+	seen := map[int64]bool{}
+	removed := 0
+	var lines []tsdata.Data
+	var co *coercer
+	if conf.coerce {
+		co = newCoercer()
+	}
+	fn := func(data tsdata.Data) error {
+		if conf.toUTC {
+			data = utcData(tr.Tsdata, data)
+		}
+		if conf.dedupe {
+			key := data.Time.UnixNano()
+			if seen[key] {
+				removed++
+				return nil
+			}
+			seen[key] = true
+		}
+		if conf.sort {
+			lines = append(lines, data)
+			return nil
 		}
-		_, err = w.WriteString(strings.Join(data.Fields, tsdata.Delim) + "\n")
+		return write(data)
+	}
+	var ct *commandTransformer
+	if conf.transform != "" {
+		ct, err = startTransform(conf.transform, tr.Tsdata, opts)
 		if err != nil {
 			return err
 		}
+		defer ct.kill()
+		fn = ct.wrap(tr, conf.stringent, fn)
+	}
+	err = eachCleanLine(tr, conf.stringent, co, func(line string, data tsdata.Data) error {
+		return fn(data)
+	})
+	if err == nil && ct != nil {
+		err = ct.close()
 	}
-	err = scanner.Err()
 	if err != nil {
+		if conf.stringent {
+			abortOutput(outf)
+		}
 		return err
 	}
+	if conf.sort {
+		sort.SliceStable(lines, func(i, j int) bool { return lines[i].Time.Before(lines[j].Time) })
+		for _, data := range lines {
+			if err := write(data); err != nil {
+				return err
+			}
+		}
+	}
+	if removed > 0 {
+		logger.Printf("removed %v duplicate lines\n", removed)
+	}
+	if co != nil {
+		if s := co.summary(); s != "" {
+			logger.Println(s)
+		}
+	}
 
 	err = w.Flush()
 	if err != nil {
 		return err
 	}
-	if outfile == "-" {
-		err = outf.Close()
+	return outf.Close()
+}
+
+// utcData converts timestamps in time columns of data to UTC.
+func utcData(t *tsdata.Tsdata, data tsdata.Data) tsdata.Data {
+	for i, v := range data.Values {
+		if tm, ok := v.(time.Time); ok && t.Types[i] == "time" {
+			data.Values[i] = tm.UTC()
+			data.Fields[i] = tm.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	data.Time = data.Time.UTC()
+	return data
+}
+
+// eachCleanLine is like eachLine but repairs each line with fixLine before
+// validation, after coercing values with co if it isn't nil. fn is also
+// passed the original line. If strict is true, values which fail validation
+// aren't converted to NA and the first validation error stops iteration and is
+// returned.
+func eachCleanLine(tr *tsdata.Reader, strict bool, co *coercer, fn func(string, tsdata.Data) error) error {
+	for {
+		line, err := tr.NextRaw()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fixed := line
+		if co != nil {
+			fixed = co.line(tr.Tsdata, fixed)
+		}
+		data, err := tr.Tsdata.ValidateLine(fixLine(tr.Tsdata, fixed), strict)
+		if err != nil {
+			lerr := &tsdata.LineError{Line: tr.Line(), Err: err}
+			if strict {
+				return lerr
+			}
+			logger.Error(lerr)
+			continue
+		}
+		logWarnings(tr.Line(), data)
+		err = fn(line, data)
 		if err != nil {
 			return err
 		}
 	}
+}
 
-	return nil
+// fixLine rewrites values in a data line which would otherwise fail
+// validation but have an unambiguous valid form, such as lowercase booleans.
+func fixLine(t *tsdata.Tsdata, line string) string {
+	fields := strings.Split(line, t.Delimiter())
+	changed := false
+	for i := 1; i < len(fields) && i < len(t.Types); i++ {
+		if t.Types[i] != "boolean" {
+			continue
+		}
+		v := strings.TrimSpace(fields[i])
+		if v != "TRUE" && v != "FALSE" && (strings.EqualFold(v, "TRUE") || strings.EqualFold(v, "FALSE")) {
+			fields[i] = strings.ToUpper(v)
+			changed = true
+		}
+	}
+	if !changed {
+		return line
+	}
+	return strings.Join(fields, t.Delimiter())
 }
 
-func readHeader(scanner *bufio.Scanner) (header string, err error) {
-	headerLines := make([]string, 7)
-	var i int
-	for i = 0; i < tsdata.HeaderSize; i++ {
-		if !scanner.Scan() {
-			err := scanner.Err()
-			if err != nil {
-				return "", err
+// writeReport writes a human-readable validation report to w.
+func writeReport(w io.Writer, infile string, rep *tsdata.Report) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "file:\t%v\n", infile)
+	fmt.Fprintf(tw, "lines:\t%v\n", rep.Lines)
+	fmt.Fprintf(tw, "data lines:\t%v\n", rep.DataLines)
+	if rep.SkippedLines > 0 {
+		fmt.Fprintf(tw, "skipped lines:\t%v\n", rep.SkippedLines)
+	}
+	fmt.Fprintf(tw, "error lines:\t%v\n", rep.ErrorLines)
+	fmt.Fprintf(tw, "first time:\t%v\n", formatReportTime(rep.FirstTime))
+	fmt.Fprintf(tw, "last time:\t%v\n", formatReportTime(rep.LastTime))
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "column\tname\ttype\terrors\tNA\n")
+	for i, col := range rep.Columns {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\n", i+1, col.Name, col.Type, col.Errors, col.NA)
+	}
+	return tw.Flush()
+}
+
+// writeErrorSummary writes the number of validation errors in each column of
+// rep as a table with a bar for each count. other is the number of error lines
+// which aren't counted in any column. If stopped is true validation ended
+// early at --max-errors.
+func writeErrorSummary(w io.Writer, infile string, rep *tsdata.Report, other int, stopped bool) error {
+	const width = 40
+	most := other
+	for _, col := range rep.Columns {
+		if col.Errors > most {
+			most = col.Errors
+		}
+	}
+	bar := func(n int) string {
+		if n == 0 {
+			return ""
+		}
+		return strings.Repeat("#", (n*width+most-1)/most)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "file:\t%v\n", infile)
+	fmt.Fprintf(tw, "error lines:\t%v of %v data lines\n", rep.ErrorLines, rep.DataLines)
+	if stopped {
+		fmt.Fprintf(tw, "stopped:\tline %v\n", rep.Lines)
+	}
+	fmt.Fprintf(tw, "\n")
+	fmt.Fprintf(tw, "column\tname\ttype\terrors\t\n")
+	for i, col := range rep.Columns {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\n", i+1, col.Name, col.Type, col.Errors, bar(col.Errors))
+	}
+	fmt.Fprintf(tw, "-\tother lines\t\t%v\t%v\n", other, bar(other))
+	return tw.Flush()
+}
+
+// formatReportTime formats t as RFC3339, or NA for a zero time.
+func formatReportTime(t time.Time) string {
+	if t.IsZero() {
+		return tsdata.NA
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// readerOptions creates tsdata options from command-line flags shared by
+// commands which read TSDATA files.
+func readerOptions(c *cli.Context) ([]tsdata.Option, error) {
+	opts := []tsdata.Option{}
+	if na := c.StringSlice("na"); len(na) > 0 {
+		opts = append(opts, tsdata.WithNATokens(na...))
+	}
+	if aliases := c.StringSlice("alias"); len(aliases) > 0 {
+		a, err := tsdata.ParseAliases(aliases)
+		if err != nil {
+			return nil, &cmdError{code: exitUsage, err: err}
+		}
+		opts = append(opts, tsdata.WithAliases(a))
+	}
+	if c.String("check-order") != "" {
+		order, err := tsdata.ParseTimeOrder(c.String("check-order"))
+		if err != nil {
+			return nil, &cmdError{code: exitUsage, err: err}
+		}
+		opts = append(opts, tsdata.WithTimeOrder(order))
+	}
+	if c.Bool("nmea") {
+		opts = append(opts, tsdata.WithNMEACoordinates())
+	}
+	if c.String("float-policy") != "" {
+		p, err := tsdata.ParseFloatPolicy(c.String("float-policy"))
+		if err != nil {
+			return nil, &cmdError{code: exitUsage, err: err}
+		}
+		opts = append(opts, tsdata.WithFloatPolicy(p))
+	}
+	if c.String("constraints") != "" {
+		f, err := os.Open(c.String("constraints"))
+		if err != nil {
+			return nil, &cmdError{code: exitIO, err: err}
+		}
+		defer f.Close()
+		m, err := tsdata.ReadConstraints(f)
+		if err != nil {
+			return nil, &cmdError{code: exitUsage, err: fmt.Errorf("%v: %w", c.String("constraints"), err)}
+		}
+		opts = append(opts, tsdata.WithConstraints(m))
+	}
+	return opts, nil
+}
+
+// inputTimeFormat returns reader options with the input timestamp format set
+// by --time-format added. csv uses --time-format for output so this isn't part
+// of readerOptions.
+func inputTimeFormat(c *cli.Context, opts []tsdata.Option) ([]tsdata.Option, error) {
+	f, err := tsdata.ParseTimeFormat(c.String("time-format"))
+	if err != nil {
+		return nil, &cmdError{code: exitUsage, err: err}
+	}
+	return append(opts, tsdata.WithTimeFormat(f)), nil
+}
+
+// expectedInterval returns reader options with the cadence check set by
+// --expected-interval and --tolerance added. join uses --tolerance for
+// matching so this isn't part of readerOptions.
+func expectedInterval(c *cli.Context, opts []tsdata.Option) ([]tsdata.Option, error) {
+	if c.Duration("expected-interval") < 0 || c.Duration("tolerance") < 0 {
+		return nil, usageErrorf("--expected-interval and --tolerance must not be negative")
+	}
+	if c.Duration("expected-interval") > 0 {
+		opts = append(opts, tsdata.WithExpectedInterval(c.Duration("expected-interval"), c.Duration("tolerance")))
+	} else if c.Duration("tolerance") > 0 {
+		return nil, usageErrorf("--tolerance requires --expected-interval")
+	}
+	return opts, nil
+}
+
+// logWarnings logs any validation warnings for data at line.
+func logWarnings(line int, data tsdata.Data) {
+	for _, w := range data.Warnings {
+		logger.Warnf(line, "line %v, warning: %v", line, w)
+	}
+}
+
+// eachLine calls fn for every valid data line in tr. Data line validation
+// errors are logged and the line is skipped. Any other error stops iteration
+// and is returned.
+func eachLine(tr *tsdata.Reader, fn func(tsdata.Data) error) error {
+	for {
+		data, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			var lerr *tsdata.LineError
+			if !errors.As(err, &lerr) {
+				return err
+			}
+			logger.Error(err)
+			continue
+		}
+		logWarnings(tr.Line(), data)
+		err = fn(data)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// eachFileLine is like eachLine but includes name in logged error messages.
+func eachFileLine(name string, tr *tsdata.Reader, fn func(tsdata.Data) error) error {
+	for {
+		data, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			var lerr *tsdata.LineError
+			if !errors.As(err, &lerr) {
+				return fmt.Errorf("%v: %w", name, err)
 			}
-			break
+			logger.FileError(name, err)
+			continue
+		}
+		for _, w := range data.Warnings {
+			logger.FileWarnf(name, tr.Line(), "line %v, warning: %v", tr.Line(), w)
+		}
+		err = fn(data)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// openInput opens path for reading, or returns STDIN if path is "-". path may
+// be an s3:// or gs:// object URL, which is streamed from the object store.
+// gzip compressed input is detected and decompressed.
+func openInput(path string) (io.ReadCloser, error) {
+	return openInputWith(path, false, nil)
+}
+
+// openInputWith is like openInput. If follow is true the returned reader
+// waits for more data at the end of input, see followReader. If p is not nil
+// it is updated as input is read.
+func openInputWith(path string, follow bool, p *progress) (io.ReadCloser, error) {
+	loc, isObject, err := objstore.ParseURL(path)
+	if err != nil {
+		return nil, usageErrorf("%v", err)
+	}
+	var f io.ReadCloser
+	switch {
+	case path == "-":
+		f = ioutil.NopCloser(os.Stdin)
+	case isObject:
+		if follow {
+			return nil, usageErrorf("can't follow object store input %v", path)
+		}
+		client, err := objstore.NewClientFromEnv(loc.Scheme)
+		if err != nil {
+			return nil, usageErrorf("%v", err)
+		}
+		f, err = client.Open(context.Background(), loc)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		f, err = os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var r io.Reader = f
+	if p != nil {
+		r = &countReader{r: r, n: &p.bytes}
+	}
+	r, err = maybeGunzip(r)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	if follow {
+		r = newFollowReader(r)
+	}
+	if p != nil {
+		r = &countReader{r: r, n: &p.lines, newlines: true}
+	}
+	return readCloser{r, f.Close}, nil
+}
+
+// maybeGunzip returns a reader which decompresses r if it starts with the
+// gzip magic number, otherwise a reader for r unchanged.
+func maybeGunzip(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+// readCloser combines a reader with a close function.
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r readCloser) Close() error {
+	return r.close()
+}
+
+// createOutput creates path for writing, or returns STDOUT if path is "-".
+// path may be an s3:// or gs:// object URL, which is uploaded as it is
+// written. Output is gzip compressed if path ends with ".gz". Close may be
+// called more than once on the returned io.WriteCloser. See abortOutput to
+// discard output instead.
+func createOutput(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return &onceCloser{w: os.Stdout, close: os.Stdout.Close}, nil
+	}
+	loc, isObject, err := objstore.ParseURL(path)
+	if err != nil {
+		return nil, usageErrorf("%v", err)
+	}
+	var f io.WriteCloser
+	var abort func() error
+	if isObject {
+		client, err := objstore.NewClientFromEnv(loc.Scheme)
+		if err != nil {
+			return nil, usageErrorf("%v", err)
+		}
+		ow := client.Create(context.Background(), loc)
+		f, abort = ow, ow.Abort
+	} else {
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		f = file
+		abort = func() error {
+			file.Close()
+			return os.Remove(path)
+		}
+	}
+	out := &onceCloser{w: f, close: f.Close}
+	if strings.HasSuffix(path, ".gz") {
+		out = gzipWriteCloser(f)
+	}
+	out.abort = abort
+	return out, nil
+}
+
+// abortOutput discards output created by createOutput if it hasn't been
+// closed, removing a partially written file. Output to STDOUT can't be
+// discarded and is closed.
+func abortOutput(w io.WriteCloser) error {
+	o, ok := w.(*onceCloser)
+	if !ok || o.abort == nil {
+		return w.Close()
+	}
+	if o.closed {
+		return nil
+	}
+	o.closed = true
+	return o.abort()
+}
+
+// gzipWriteCloser returns an io.WriteCloser which writes a gzip stream to f.
+// Closing it finishes the stream and closes f. Close may be called more than
+// once.
+func gzipWriteCloser(f io.WriteCloser) *onceCloser {
+	zw := gzip.NewWriter(f)
+	return &onceCloser{w: zw, close: func() error {
+		err := zw.Close()
+		if cerr := f.Close(); err == nil {
+			err = cerr
 		}
-		headerLines[i] = scanner.Text()
+		return err
+	}}
+}
+
+// onceCloser wraps a writer so that only the first call to Close has an
+// effect. This allows a deferred Close alongside an explicit checked Close.
+type onceCloser struct {
+	w      io.Writer
+	close  func() error
+	abort  func() error // discards output, see abortOutput
+	closed bool
+}
+
+func (o *onceCloser) Write(p []byte) (int, error) {
+	return o.w.Write(p)
+}
+
+func (o *onceCloser) Close() error {
+	if o.closed {
+		return nil
 	}
-	header = strings.Join(headerLines, "\n")
-	return header, nil
+	o.closed = true
+	return o.close()
 }
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var mergeCommand = cli.Command{
+	Name:      "merge",
+	Usage:     "Merges TSDATA files on time",
+	UsageText: "tsdata merge [--tolerance DURATION [--keep first|last|mean|error] | --union] INFILE1 INFILE2 ... OUTFILE",
+	Description: "Performs an outer join of TSDATA files by timestamp and writes the result to OUTFILE. " +
+		"Output columns are the union of input columns, with NA for values missing from an input. " +
+		"Lines from different inputs are combined if their timestamps are within --tolerance of the first timestamp in the group. " +
+		"Columns with the same name in more than one input must have the same type, unit, and range, and category " +
+		"columns allow the values listed in any input. When more than one " +
+		"combined line has a value for a column, --keep first keeps the first non-NA value in input order, --keep last " +
+		"the last, --keep mean the mean in float, integer, latitude, and longitude columns and the first value in " +
+		"others, and --keep error exits with an error. The policy is logged with the number of values resolved. " +
+		"With --union, lines aren't combined: every input line is written as its own output line, in time order, " +
+		"with NA in the columns its input lacks. This combines files whose headers differ but overlap, such as " +
+		"files from versions of an instrument which added or removed columns, and the input files for each output " +
+		"column are logged. " +
+		"Each input must be sorted by time. Use '-' for STDOUT.",
+	Flags: []cli.Flag{
+		cli.DurationFlag{
+			Name:  "tolerance, t",
+			Usage: "Combine lines with timestamps within `DURATION` of each other, e.g. 30s. Default is exact matches only.",
+		},
+		cli.StringFlag{
+			Name:  "keep",
+			Value: "first",
+			Usage: "How to resolve values for the same column from more than one input, `POLICY` is first, last, mean, or error",
+		},
+		cli.BoolFlag{
+			Name:  "union",
+			Usage: "Write every input line separately, with NA for columns its input lacks",
+		},
+		cli.StringFlag{
+			Name:  "file-type",
+			Usage: "FileType for OUTFILE, default is the FileType of INFILE1",
+		},
+		cli.StringFlag{
+			Name:  "project",
+			Usage: "Project for OUTFILE, default is the Project of INFILE1",
+		},
+		cli.StringFlag{
+			Name:  "description",
+			Usage: "FileDescription for OUTFILE",
+		},
+		cli.StringSliceFlag{
+			Name:  "alias",
+			Usage: "Treat column `OLD=NEW` as column NEW, may be repeated",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() < 3 {
+			err := usageErrorf("expected at least two INFILE arguments and one OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("union") && (c.IsSet("tolerance") || c.IsSet("keep")) {
+			err := usageErrorf("--union can't be used with --tolerance or --keep")
+			logger.Error(err)
+			return err
+		}
+		keep := c.String("keep")
+		if keep != "first" && keep != "last" && keep != "mean" && keep != "error" {
+			err := usageErrorf("bad --keep value '%v', expected first, last, mean, or error", keep)
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		args := c.Args()
+		meta := tsdata.Tsdata{
+			FileType:        c.String("file-type"),
+			Project:         c.String("project"),
+			FileDescription: c.String("description"),
+		}
+		err = mergeCmd(args[:len(args)-1], args[len(args)-1], c.Duration("tolerance"), keep, c.Bool("union"), meta, opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// mergeSource is one input file for merge.
+type mergeSource struct {
+	name   string
+	tr     *tsdata.Reader
+	cur    tsdata.Data
+	ok     bool  // cur holds a valid line
+	colMap []int // output column index for each input column
+}
+
+// advance reads the next valid line. Lines which fail validation are logged
+// and skipped. An error is returned if the input is not sorted by time.
+func (s *mergeSource) advance() error {
+	last := s.cur.Time
+	for {
+		data, err := s.tr.Next()
+		if err == io.EOF {
+			s.ok = false
+			return nil
+		}
+		if err != nil {
+			var lerr *tsdata.LineError
+			if !errors.As(err, &lerr) {
+				return fmt.Errorf("%v: %w", s.name, err)
+			}
+			logger.FileError(s.name, err)
+			continue
+		}
+		if s.ok && data.Time.Before(last) {
+			return dataErrorf("%v: line %v, input is not sorted by time", s.name, s.tr.Line())
+		}
+		s.cur = data
+		s.ok = true
+		return nil
+	}
+}
+
+func mergeCmd(infiles []string, outfile string, tolerance time.Duration, keep string, union bool, meta tsdata.Tsdata, opts []tsdata.Option) error {
+	sources := make([]*mergeSource, len(infiles))
+	for i, infile := range infiles {
+		r, err := openInput(infile)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		tr, err := tsdata.NewReader(r, opts...)
+		if err != nil {
+			return fmt.Errorf("%v: %w", infile, err)
+		}
+		tr.Strict = false
+		sources[i] = &mergeSource{name: infile, tr: tr}
+	}
+
+	err := mergeHeaders(&meta, sources)
+	if err != nil {
+		return err
+	}
+	if meta.FileDescription == "" {
+		meta.FileDescription = "Merged from " + strings.Join(infiles, ", ")
+	}
+	err = meta.ValidateMetadata()
+	if err != nil {
+		return err
+	}
+	if union {
+		logColumnSources(&meta, sources)
+	}
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+	_, err = w.WriteString(meta.Header() + "\n")
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sources {
+		if err := s.advance(); err != nil {
+			return err
+		}
+	}
+	row := make([]string, len(meta.Headers))
+	set := make([]bool, len(meta.Headers))       // row has a non-NA value
+	vals := make([][]float64, len(meta.Headers)) // numeric values for keep mean
+	resolved := 0
+	used := make([]bool, len(sources))
+	for {
+		for i := range used {
+			used[i] = false
+		}
+		first := nextSource(sources, used, nil)
+		if first == nil {
+			break
+		}
+		for i := range row {
+			row[i] = tsdata.NA
+			set[i] = false
+			vals[i] = vals[i][:0]
+		}
+		row[0] = first.cur.Fields[0]
+		limit := first.cur.Time.Add(tolerance)
+		next := func() *mergeSource {
+			if union {
+				return nil
+			}
+			return nextSource(sources, used, &limit)
+		}
+		for s := first; s != nil; s = next() {
+			for j := 1; j < len(s.cur.Fields); j++ {
+				k := s.colMap[j]
+				v := s.cur.Values[j]
+				if v == nil {
+					continue
+				}
+				if set[k] {
+					if keep == "error" {
+						return dataErrorf("%v: line %v, column %v already has a value from another input at %v",
+							s.name, s.cur.Line, meta.Headers[k], row[0])
+					}
+					resolved++
+				}
+				if keep == "mean" {
+					switch x := v.(type) {
+					case float64:
+						vals[k] = append(vals[k], x)
+					case int64:
+						vals[k] = append(vals[k], float64(x))
+					}
+				}
+				if !set[k] || keep == "last" {
+					row[k] = s.cur.Fields[j]
+				}
+				set[k] = true
+			}
+			for i := range sources {
+				if sources[i] == s {
+					used[i] = true
+				}
+			}
+			if err := s.advance(); err != nil {
+				return err
+			}
+		}
+		for k, v := range vals {
+			if len(v) > 1 {
+				row[k] = formatNumber(meta.Types[k], mean(v))
+			}
+		}
+		_, err = w.WriteString(strings.Join(row, tsdata.Delim) + "\n")
+		if err != nil {
+			return err
+		}
+	}
+	if resolved > 0 {
+		logger.Printf("resolved %v values from more than one input with --keep %v\n", resolved, keep)
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
+
+// nextSource returns the unused source with the earliest current line, or nil
+// if there is none. If limit is not nil the line must not be after limit.
+func nextSource(sources []*mergeSource, used []bool, limit *time.Time) *mergeSource {
+	var best *mergeSource
+	for i, s := range sources {
+		if !s.ok || used[i] {
+			continue
+		}
+		if limit != nil && s.cur.Time.After(*limit) {
+			continue
+		}
+		if best == nil || s.cur.Time.Before(best.cur.Time) {
+			best = s
+		}
+	}
+	return best
+}
+
+// logColumnSources logs the input files with each column of meta, and warns
+// about inputs with a different FileType than the first.
+func logColumnSources(meta *tsdata.Tsdata, sources []*mergeSource) {
+	names := make([][]string, len(meta.Headers))
+	for _, s := range sources {
+		if ft := s.tr.Tsdata.FileType; ft != sources[0].tr.Tsdata.FileType {
+			logger.FileWarnf(s.name, 0, "fileType %v differs from fileType %v of %v", ft, sources[0].tr.Tsdata.FileType, sources[0].name)
+		}
+		for _, k := range s.colMap {
+			names[k] = append(names[k], s.name)
+		}
+	}
+	for k, h := range meta.Headers {
+		if len(names[k]) == len(sources) {
+			logger.Printf("column %v from all inputs\n", h)
+		} else {
+			logger.Printf("column %v from %v\n", h, strings.Join(names[k], ", "))
+		}
+	}
+}
+
+// mergeHeaders fills meta with the union of columns in sources and sets the
+// output column map for each source. FileType and Project default to values
+// from the first source. The allowed values of a category column are the
+// union of its values in each source, and other constraints must match.
+func mergeHeaders(meta *tsdata.Tsdata, sources []*mergeSource) error {
+	first := sources[0].tr.Tsdata
+	if meta.FileType == "" {
+		meta.FileType = first.FileType
+	}
+	if meta.Project == "" {
+		meta.Project = first.Project
+	}
+	index := map[string]int{}
+	for _, s := range sources {
+		t := s.tr.Tsdata
+		s.colMap = make([]int, len(t.Headers))
+		for j, h := range t.Headers {
+			comment := tsdata.NA
+			if j < len(t.Comments) {
+				comment = t.Comments[j]
+			}
+			var c tsdata.Constraint
+			if j < len(t.Constraints) {
+				c = t.Constraints[j]
+			}
+			k, ok := index[h]
+			if !ok {
+				k = len(meta.Headers)
+				index[h] = k
+				meta.Headers = append(meta.Headers, h)
+				meta.Types = append(meta.Types, t.Types[j])
+				meta.Units = append(meta.Units, t.Units[j])
+				meta.Comments = append(meta.Comments, comment)
+				meta.Constraints = append(meta.Constraints, c)
+			} else if meta.Types[k] != t.Types[j] || meta.Units[k] != t.Units[j] {
+				return headerErrorf("%v: column %v has type %v and unit %v, expected type %v and unit %v",
+					s.name, h, t.Types[j], t.Units[j], meta.Types[k], meta.Units[k])
+			} else {
+				merged, err := mergeConstraints(meta.Constraints[k], c)
+				if err != nil {
+					return headerErrorf("%v: column %v, %v", s.name, h, err)
+				}
+				meta.Constraints[k] = merged
+			}
+			s.colMap[j] = k
+		}
+	}
+	return nil
+}
+
+// mergeConstraints returns the constraint for a column with constraint a in
+// one input and b in another. Category values are combined, and a column
+// without values allows any value. Ranges and patterns must be the same.
+func mergeConstraints(a tsdata.Constraint, b tsdata.Constraint) (tsdata.Constraint, error) {
+	if !floatPtrsEqual(a.Min, b.Min) || !floatPtrsEqual(a.Max, b.Max) {
+		return a, fmt.Errorf("ranges %v and %v differ", formatRange(a), formatRange(b))
+	}
+	if a.Pattern != b.Pattern {
+		return a, fmt.Errorf("pattern constraints '%v' and '%v' differ", a.Pattern, b.Pattern)
+	}
+	if len(a.Values) == 0 || len(b.Values) == 0 {
+		a.Values = nil
+		return a, nil
+	}
+	values := append([]string{}, a.Values...)
+	for _, v := range b.Values {
+		if indexOfColumn(values, v) == -1 {
+			values = append(values, v)
+		}
+	}
+	a.Values = values
+	return a, nil
+}
+
+// formatRange formats the range of c like a range comment token.
+func formatRange(c tsdata.Constraint) string {
+	var min, max string
+	if c.Min != nil {
+		min = strconv.FormatFloat(*c.Min, 'g', -1, 64)
+	}
+	if c.Max != nil {
+		max = strconv.FormatFloat(*c.Max, 'g', -1, 64)
+	}
+	return "range=" + min + ".." + max
+}
+
+// floatPtrsEqual reports whether a and b are both nil or point to equal values.
+func floatPtrsEqual(a *float64, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var packCommand = cli.Command{
+	Name:      "pack",
+	Usage:     "Converts a TSDATA file to the binary encoding",
+	UsageText: "tsdata pack INFILE OUTFILE",
+	Description: "Validates INFILE and writes it to OUTFILE in the binary TSDATA encoding, which keeps the same " +
+		"header metadata but stores data in compressed column chunks. Lines which fail validation are logged " +
+		"and skipped. Commands which validate INFILE also read binary files. Timestamps are stored in UTC. " +
+		"Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		return packAction(c, packCmd)
+	},
+}
+
+var unpackCommand = cli.Command{
+	Name:        "unpack",
+	Usage:       "Converts a binary TSDATA file to text",
+	UsageText:   "tsdata unpack INFILE OUTFILE",
+	Description: "Validates a binary TSDATA file created by pack and writes it to OUTFILE as text. Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		return packAction(c, unpackCmd)
+	},
+}
+
+// packAction checks arguments shared by pack and unpack and runs cmd.
+func packAction(c *cli.Context, cmd func(string, string, []tsdata.Option) error) error {
+	if c.NArg() == 0 {
+		err := usageErrorf("missing required INFILE and OUTFILE arguments")
+		logger.Error(err)
+		return err
+	}
+	if c.NArg() < 2 {
+		err := usageErrorf("missing required OUTFILE argument")
+		logger.Error(err)
+		return err
+	}
+	if c.NArg() > 2 {
+		err := usageErrorf("too many arguments")
+		logger.Error(err)
+		return err
+	}
+	if c.Bool("quiet") {
+		logger.SetOutput(ioutil.Discard)
+	}
+	opts, err := readerOptions(c)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	err = cmd(c.Args().Get(0), c.Args().Get(1), opts)
+	if err != nil {
+		logger.Error(err)
+	}
+	return err
+}
+
+func packCmd(infile string, outfile string, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+	bw, err := tsdata.NewBinaryWriter(w, tr.Tsdata)
+	if err != nil {
+		return err
+	}
+	err = eachLine(tr, bw.Write)
+	if err != nil {
+		return err
+	}
+	err = bw.Close()
+	if err != nil {
+		return err
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
+
+func unpackCmd(infile string, outfile string, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+	_, err = w.WriteString(tr.Tsdata.Header() + "\n")
+	if err != nil {
+		return err
+	}
+	err = eachLine(tr, func(data tsdata.Data) error {
+		_, err := w.WriteString(strings.Join(data.Fields, tr.Tsdata.Delimiter()) + "\n")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
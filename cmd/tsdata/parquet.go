@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/ctberthiaume/tsdata/internal/parquet"
+	"github.com/urfave/cli"
+)
+
+var parquetCommand = cli.Command{
+	Name:        "parquet",
+	Usage:       "Converts a TSDATA file to Parquet",
+	UsageText:   "tsdata parquet [--compression gzip|none] INFILE OUTFILE",
+	Description: "Validates and converts a TSDATA file at INFILE to a Parquet file at OUTFILE. Column types are derived from the TSDATA Types row: time as a UTC microsecond timestamp, float as double, integer as int64, boolean as boolean, and all other types as string. The original TSDATA header is stored in the file metadata under the key 'tsdata.header'. Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "compression",
+			Value: "gzip",
+			Usage: "Data page compression `CODEC`, gzip or none",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 2 {
+			err := usageErrorf("missing required OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		var codec int
+		switch c.String("compression") {
+		case "gzip":
+			codec = parquet.Gzip
+		case "none":
+			codec = parquet.Uncompressed
+		default:
+			err := usageErrorf("bad compression '%v', expected gzip or none", c.String("compression"))
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = parquetCmd(c.Args().Get(0), c.Args().Get(1), codec, opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+func parquetCmd(infile string, outfile string, codec int, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	bw := bufio.NewWriter(outf)
+
+	pw := parquet.NewWriter(bw, parquetColumns(tr.Tsdata))
+	pw.Codec = codec
+	pw.SetMetadata("tsdata.header", tr.Tsdata.Header())
+
+	err = eachLine(tr, func(data tsdata.Data) error {
+		return pw.Write(data.Values)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = pw.Close()
+	if err != nil {
+		return err
+	}
+	err = bw.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
+
+// parquetColumns creates Parquet column definitions from TSDATA metadata.
+func parquetColumns(t *tsdata.Tsdata) []parquet.Column {
+	cols := make([]parquet.Column, len(t.Headers))
+	for i, h := range t.Headers {
+		col := parquet.Column{Name: h, Required: i == 0}
+		switch t.Types[i] {
+		case "time":
+			col.Type = parquet.Timestamp
+		case "float", "latitude", "longitude":
+			col.Type = parquet.Double
+		case "integer":
+			col.Type = parquet.Int64
+		case "boolean":
+			col.Type = parquet.Boolean
+		default:
+			col.Type = parquet.String
+		}
+		cols[i] = col
+	}
+	return cols
+}
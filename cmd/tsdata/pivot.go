@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var meltCommand = cli.Command{
+	Name:      "melt",
+	Usage:     "Converts a TSDATA file from wide to long format",
+	UsageText: "tsdata melt [--drop-na] INFILE OUTFILE",
+	Description: "Writes each value in INFILE other than the first time column as its own line in OUTFILE, " +
+		"with columns time, variable, value, and unit holding the line's timestamp, the column name, the value, " +
+		"and the column's Units. This long format is expected by tools such as ggplot and ERDDAP tabledap. " +
+		"See cast for the reverse. Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "drop-na",
+			Usage: "Don't write lines for NA values",
+		},
+		cli.StringFlag{
+			Name:  "description",
+			Usage: "FileDescription for OUTFILE, default is the FileDescription of INFILE",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if err := pivotArgs(c); err != nil {
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = meltCmd(c.Args().Get(0), c.Args().Get(1), c.Bool("drop-na"), c.String("description"), opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+var castCommand = cli.Command{
+	Name:      "cast",
+	Usage:     "Converts a TSDATA file from long to wide format",
+	UsageText: "tsdata cast INFILE OUTFILE",
+	Description: "Reverses melt. INFILE must have variable and value columns and may have a unit column. " +
+		"Lines of INFILE with the same timestamp become one line of OUTFILE with a column for each distinct variable, " +
+		"in order of first appearance. Column types are inferred from the values and Units are taken from the " +
+		"unit column. Variables missing at a timestamp are NA. INFILE must be sorted by time and is held in memory. " +
+		"Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "description",
+			Usage: "FileDescription for OUTFILE, default is the FileDescription of INFILE",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if err := pivotArgs(c); err != nil {
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = castCmd(c.Args().Get(0), c.Args().Get(1), c.String("description"), opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// pivotArgs checks the INFILE and OUTFILE arguments of melt and cast and
+// applies --quiet.
+func pivotArgs(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return usageErrorf("missing required INFILE and OUTFILE arguments")
+	}
+	if c.NArg() < 2 {
+		return usageErrorf("missing required OUTFILE argument")
+	}
+	if c.NArg() > 2 {
+		return usageErrorf("too many arguments")
+	}
+	if c.Bool("quiet") {
+		logger.SetOutput(ioutil.Discard)
+	}
+	return nil
+}
+
+func meltCmd(infile string, outfile string, dropNA bool, description string, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	meta := tsdata.Tsdata{
+		FileType:        tr.Tsdata.FileType,
+		Project:         tr.Tsdata.Project,
+		FileDescription: description,
+		Comments:        []string{tsdata.NA, tsdata.NA, tsdata.NA, tsdata.NA},
+		Types:           []string{"time", "category", "text", "text"},
+		Units:           []string{tsdata.NA, tsdata.NA, tsdata.NA, tsdata.NA},
+		Headers:         []string{tr.Tsdata.Headers[0], "variable", "value", "unit"},
+	}
+	if meta.FileDescription == "" {
+		meta.FileDescription = tr.Tsdata.FileDescription
+	}
+	err = meta.ValidateMetadata()
+	if err != nil {
+		return err
+	}
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+	_, err = w.WriteString(meta.Header() + "\n")
+	if err != nil {
+		return err
+	}
+
+	err = eachLine(tr, func(data tsdata.Data) error {
+		for i := 1; i < len(data.Fields); i++ {
+			if dropNA && data.Values[i] == nil {
+				continue
+			}
+			row := []string{data.Fields[0], tr.Tsdata.Headers[i], data.Fields[i], tr.Tsdata.Units[i]}
+			if _, err := w.WriteString(strings.Join(row, tsdata.Delim) + "\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
+
+// castRow is one output line of cast, with values by output column.
+type castRow struct {
+	time   time.Time
+	fields []string
+}
+
+func castCmd(infile string, outfile string, description string, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	varCol, valCol, unitCol := -1, -1, -1
+	for i, h := range tr.Tsdata.Headers {
+		switch h {
+		case "variable":
+			varCol = i
+		case "value":
+			valCol = i
+		case "unit":
+			unitCol = i
+		}
+	}
+	if varCol == -1 || valCol == -1 {
+		return headerErrorf("%v: expected variable and value columns", infile)
+	}
+
+	meta := tsdata.Tsdata{
+		FileType:        tr.Tsdata.FileType,
+		Project:         tr.Tsdata.Project,
+		FileDescription: description,
+		Comments:        []string{tsdata.NA},
+		Types:           []string{"time"},
+		Units:           []string{tsdata.NA},
+		Headers:         []string{tr.Tsdata.Headers[0]},
+	}
+	if meta.FileDescription == "" {
+		meta.FileDescription = tr.Tsdata.FileDescription
+	}
+	cols := map[string]int{} // output column by variable
+	guesses := []*typeGuess{nil}
+	var rows []*castRow
+	err = eachLine(tr, func(data tsdata.Data) error {
+		if data.Values[varCol] == nil {
+			logger.Warnf(data.Line, "line %v, skipping NA variable", data.Line)
+			return nil
+		}
+		v := data.Fields[varCol]
+		col, ok := cols[v]
+		if !ok {
+			col = len(meta.Headers)
+			cols[v] = col
+			meta.Headers = append(meta.Headers, v)
+			meta.Types = append(meta.Types, "text")
+			meta.Units = append(meta.Units, tsdata.NA)
+			meta.Comments = append(meta.Comments, tsdata.NA)
+			guesses = append(guesses, newTypeGuess())
+		}
+		if unitCol != -1 && meta.Units[col] == tsdata.NA && data.Values[unitCol] != nil {
+			meta.Units[col] = data.Fields[unitCol]
+		}
+
+		var row *castRow
+		if len(rows) > 0 {
+			row = rows[len(rows)-1]
+			if data.Time.Before(row.time) {
+				return dataErrorf("line %v, timestamp earlier than previous line, INFILE must be sorted by time", data.Line)
+			}
+			if !data.Time.Equal(row.time) {
+				row = nil
+			}
+		}
+		if row == nil {
+			row = &castRow{time: data.Time, fields: []string{data.Fields[0]}}
+			rows = append(rows, row)
+		}
+		for len(row.fields) <= col {
+			row.fields = append(row.fields, tsdata.NA)
+		}
+		if row.fields[col] != tsdata.NA {
+			logger.Warnf(data.Line, "line %v, variable %v repeated at %v, keeping the later value", data.Line, v, data.Fields[0])
+		}
+		value := tsdata.NA
+		if data.Values[valCol] != nil {
+			value = data.Fields[valCol]
+			guesses[col].add(value)
+		}
+		row.fields[col] = value
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := 1; i < len(guesses); i++ {
+		meta.Types[i] = guesses[i].guess()
+	}
+	err = meta.ValidateMetadata()
+	if err != nil {
+		return fmt.Errorf("%v: %w", infile, err)
+	}
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+	_, err = w.WriteString(meta.Header() + "\n")
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		for len(row.fields) < len(meta.Headers) {
+			row.fields = append(row.fields, tsdata.NA)
+		}
+		_, err = w.WriteString(strings.Join(row.fields, tsdata.Delim) + "\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
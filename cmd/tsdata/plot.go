@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var plotCommand = cli.Command{
+	Name:      "plot",
+	Usage:     "Plots a numeric column in the terminal",
+	UsageText: "tsdata plot --column NAME [--width N] [--height N] [--ascii] INFILE",
+	Description: "Draws the values of a numeric column of INFILE against time as a Unicode sparkline, " +
+		"or as a bar chart --height lines tall. Time is divided into --width equal bins and each bin shows the mean " +
+		"of its values. Empty bins are left blank. Use --ascii for terminals without Unicode block characters. " +
+		"Use '-' for STDIN.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "column, c",
+			Usage: "`NAME` of the column to plot",
+		},
+		cli.IntFlag{
+			Name:  "width, w",
+			Value: 80,
+			Usage: "Plot width in characters `N`",
+		},
+		cli.IntFlag{
+			Name:  "height",
+			Value: 1,
+			Usage: "Plot height in lines `N`",
+		},
+		cli.BoolFlag{
+			Name:  "ascii",
+			Usage: "Draw with ASCII characters only",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 1 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		if c.String("column") == "" {
+			err := usageErrorf("missing required --column flag")
+			logger.Error(err)
+			return err
+		}
+		if c.Int("width") < 1 || c.Int("height") < 1 {
+			err := usageErrorf("--width and --height must be at least 1")
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		levels := sparkLevels
+		if c.Bool("ascii") {
+			levels = asciiLevels
+		}
+		err = plotCmd(c.Args().Get(0), c.String("column"), c.Int("width"), c.Int("height"), levels, opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// Characters for increasing fractions of one line of plot height. The first
+// character marks a bin with data at the bottom of the range.
+var (
+	sparkLevels = []rune("▁▂▃▄▅▆▇█")
+	asciiLevels = []rune("_.-=+*#@")
+)
+
+func plotCmd(infile string, name string, width int, height int, levels []rune, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+	i := tr.Tsdata.Index(name)
+	if i < 0 {
+		return usageErrorf("no column named '%v'", name)
+	}
+	if typ := tr.Tsdata.Types[i]; !numericType(typ) {
+		return usageErrorf("column '%v' is %v, expected a numeric type", name, typ)
+	}
+
+	var times []time.Time
+	var values []float64
+	err = eachLine(tr, func(data tsdata.Data) error {
+		v, ok := plotValue(data.Values[i])
+		if !ok {
+			return nil
+		}
+		times = append(times, data.Time)
+		values = append(values, v)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return dataErrorf("no values to plot in column '%v'", name)
+	}
+	return writePlot(os.Stdout, name, times, values, width, height, levels)
+}
+
+// numericType returns true for column types with float64 or int64 values.
+func numericType(typ string) bool {
+	switch typ {
+	case "float", "integer", "latitude", "longitude":
+		return true
+	}
+	return false
+}
+
+// plotValue returns a float or integer value as a float64. ok is false for
+// NA, NaN, infinite, and non-numeric values.
+func plotValue(x interface{}) (v float64, ok bool) {
+	switch x := x.(type) {
+	case float64:
+		v = x
+	case int64:
+		v = float64(x)
+	default:
+		return 0, false
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	return v, true
+}
+
+// timeBins holds the means of values in equal width time bins.
+type timeBins struct {
+	first, last time.Time
+	means       []float64
+	counts      []int   // number of values in each bin, means are 0 for empty bins
+	min, max    float64 // range of the means of non-empty bins
+}
+
+// binByTime bins values by time into width bins. times must not be empty.
+func binByTime(times []time.Time, values []float64, width int) timeBins {
+	first, last := times[0], times[0]
+	for _, t := range times {
+		if t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+	span := last.Sub(first)
+	sums := make([]float64, width)
+	counts := make([]int, width)
+	for j, t := range times {
+		b := 0
+		if span > 0 {
+			b = int(float64(t.Sub(first)) / float64(span) * float64(width))
+		}
+		if b >= width {
+			b = width - 1
+		}
+		sums[b] += values[j]
+		counts[b]++
+	}
+	min, max := math.Inf(1), math.Inf(-1)
+	for b := range sums {
+		if counts[b] == 0 {
+			continue
+		}
+		sums[b] /= float64(counts[b])
+		min = math.Min(min, sums[b])
+		max = math.Max(max, sums[b])
+	}
+	return timeBins{first: first, last: last, means: sums, counts: counts, min: min, max: max}
+}
+
+// writePlot bins values by time into width bins and draws the bin means as a
+// chart height lines tall.
+func writePlot(w io.Writer, name string, times []time.Time, values []float64, width int, height int, levels []rune) error {
+	bins := binByTime(times, values, width)
+	sums, counts, min, max := bins.means, bins.counts, bins.min, bins.max
+
+	// Height of each bin in units of one level, from 1 to height*len(levels)
+	steps := height * len(levels)
+	for row := height - 1; row >= 0; row-- {
+		var sb strings.Builder
+		for b := range sums {
+			if counts[b] == 0 {
+				sb.WriteRune(' ')
+				continue
+			}
+			h := 1
+			if max > min {
+				h = 1 + int((sums[b]-min)/(max-min)*float64(steps-1))
+			}
+			h -= row * len(levels)
+			switch {
+			case h <= 0:
+				sb.WriteRune(' ')
+			case h >= len(levels):
+				sb.WriteRune(levels[len(levels)-1])
+			default:
+				sb.WriteRune(levels[h-1])
+			}
+		}
+		_, err := fmt.Fprintln(w, sb.String())
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%v: min %v, max %v, %v values\n%v to %v\n",
+		name, formatStat(min), formatStat(max), len(values),
+		bins.first.Format(time.RFC3339), bins.last.Format(time.RFC3339))
+	return err
+}
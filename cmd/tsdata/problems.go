@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/ctberthiaume/tsdata"
+)
+
+// problem is one validation problem in validate --format json output.
+type problem struct {
+	Type       string `json:"type"` // error or warning
+	File       string `json:"file"`
+	Line       int    `json:"line,omitempty"`
+	Column     int    `json:"column,omitempty"` // 1-based
+	ColumnName string `json:"columnName,omitempty"`
+	ColumnType string `json:"columnType,omitempty"`
+	Value      string `json:"value,omitempty"`
+	Message    string `json:"message"`
+}
+
+// validationSummary is the final object in validate --format json output.
+type validationSummary struct {
+	Type       string          `json:"type"` // summary
+	File       string          `json:"file"`
+	Valid      bool            `json:"valid"`
+	Lines      int             `json:"lines"`
+	DataLines  int             `json:"dataLines"`
+	Skipped    int             `json:"skippedLines,omitempty"` // data lines not validated with --sample or --every
+	ErrorLines int             `json:"errorLines"`
+	Stopped    bool            `json:"stopped,omitempty"` // validation stopped at --max-errors
+	FirstTime  string          `json:"firstTime"`
+	LastTime   string          `json:"lastTime"`
+	Columns    []columnSummary `json:"columns"`
+}
+
+type columnSummary struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Errors int    `json:"errors"`
+	NA     int    `json:"na"`
+}
+
+// problemWriter writes validation problems as one JSON object per line.
+type problemWriter struct {
+	enc  *json.Encoder
+	file string
+}
+
+func newProblemWriter(w io.Writer, file string) *problemWriter {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &problemWriter{enc: enc, file: file}
+}
+
+// headerError writes a problem for a header or metadata error.
+func (pw *problemWriter) headerError(err error) error {
+	return pw.enc.Encode(problem{Type: "error", File: pw.file, Message: err.Error()})
+}
+
+// unitsError writes a problem for a Units row value which isn't in the units
+// vocabulary. col is 0-based.
+func (pw *problemWriter) unitsError(t *tsdata.Tsdata, col int) error {
+	return pw.enc.Encode(problem{
+		Type:       "error",
+		File:       pw.file,
+		Line:       unitsLine,
+		Column:     col + 1,
+		ColumnName: t.Headers[col],
+		ColumnType: t.Types[col],
+		Value:      t.Units[col],
+		Message:    unknownUnitMessage(t, col),
+	})
+}
+
+// lineError writes a problem for a data line error.
+func (pw *problemWriter) lineError(t *tsdata.Tsdata, lerr *tsdata.LineError) error {
+	p := problem{Type: "error", File: pw.file, Line: lerr.Line, Message: lerr.Err.Error()}
+	var ferr *tsdata.FieldError
+	if errors.As(lerr.Err, &ferr) {
+		p.Column = ferr.Column + 1
+		p.Value = ferr.Value
+		p.ColumnName, p.ColumnType = ferr.Name, ferr.Type
+		if p.ColumnName == "" && ferr.Column < len(t.Headers) {
+			p.ColumnName = t.Headers[ferr.Column]
+		}
+	}
+	return pw.enc.Encode(p)
+}
+
+// warnings writes a problem for each warning in data.
+func (pw *problemWriter) warnings(line int, data tsdata.Data) error {
+	for _, w := range data.Warnings {
+		err := pw.enc.Encode(problem{Type: "warning", File: pw.file, Line: line, Message: w})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// summary writes the summary object. rep may be nil if the header could not
+// be read. stopped is true if validation ended early at --max-errors.
+func (pw *problemWriter) summary(rep *tsdata.Report, valid bool, stopped bool) error {
+	s := validationSummary{Type: "summary", File: pw.file, Valid: valid, Stopped: stopped, FirstTime: tsdata.NA, LastTime: tsdata.NA}
+	if rep != nil {
+		s.Lines = rep.Lines
+		s.DataLines = rep.DataLines
+		s.Skipped = rep.SkippedLines
+		s.ErrorLines = rep.ErrorLines
+		s.FirstTime = formatReportTime(rep.FirstTime)
+		s.LastTime = formatReportTime(rep.LastTime)
+		for _, c := range rep.Columns {
+			s.Columns = append(s.Columns, columnSummary{Name: c.Name, Type: c.Type, Errors: c.Errors, NA: c.NA})
+		}
+	}
+	if s.Columns == nil {
+		s.Columns = []columnSummary{}
+	}
+	return pw.enc.Encode(s)
+}
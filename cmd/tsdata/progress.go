@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// progressInterval is how often progress is logged.
+var progressInterval = 2 * time.Second
+
+// progress periodically logs how much of an input file has been read, as a
+// percentage of the file size when it's known, and as lines per second.
+type progress struct {
+	size  int64 // input file size in bytes, 0 if unknown
+	bytes int64 // bytes read from the file, accessed atomically
+	lines int64 // lines read after decompression, accessed atomically
+	start time.Time
+	done  chan struct{}
+	wait  chan struct{}
+}
+
+// newProgress returns a progress for the input at path, or nil if show is
+// false.
+func newProgress(path string, show bool) *progress {
+	if !show {
+		return nil
+	}
+	p := &progress{}
+	if path != "-" {
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			p.size = info.Size()
+		}
+	}
+	return p
+}
+
+// run starts logging progress until stop is called. It's a no-op for a nil
+// progress.
+func (p *progress) run() {
+	if p == nil {
+		return
+	}
+	p.start = time.Now()
+	p.done = make(chan struct{})
+	p.wait = make(chan struct{})
+	go func() {
+		defer close(p.wait)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.log()
+			case <-p.done:
+				p.log()
+				return
+			}
+		}
+	}()
+}
+
+// stop stops logging progress after logging a final update.
+func (p *progress) stop() {
+	if p == nil || p.done == nil {
+		return
+	}
+	close(p.done)
+	<-p.wait
+	p.done = nil
+}
+
+func (p *progress) log() {
+	lines := atomic.LoadInt64(&p.lines)
+	elapsed := time.Since(p.start)
+	rate := float64(lines) / elapsed.Seconds()
+	msg := fmt.Sprintf("%v lines, %.0f lines/s, %v elapsed", lines, rate, elapsed.Round(time.Second))
+	if p.size > 0 {
+		pct := 100 * float64(atomic.LoadInt64(&p.bytes)) / float64(p.size)
+		msg = fmt.Sprintf("%.1f%%, ", pct) + msg
+	}
+	logger.Printf("progress: %v\n", msg)
+}
+
+// countReader adds the number of bytes, or newlines if newlines is true, read
+// from r to n.
+type countReader struct {
+	r        io.Reader
+	n        *int64
+	newlines bool
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if c.newlines {
+		atomic.AddInt64(c.n, int64(bytes.Count(p[:n], []byte{'\n'})))
+	} else {
+		atomic.AddInt64(c.n, int64(n))
+	}
+	return n, err
+}
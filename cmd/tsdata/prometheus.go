@@ -0,0 +1,216 @@
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"net/url"
+	"strings"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/ctberthiaume/tsdata/internal/promwrite"
+	"github.com/urfave/cli"
+)
+
+var pushPrometheusCommand = cli.Command{
+	Name:      "prometheus",
+	Usage:     "Writes TSDATA lines with Prometheus remote-write",
+	UsageText: "tsdata push prometheus --url URL [--prefix PREFIX] INFILE",
+	Description: "Sends each float and integer column of INFILE as a Prometheus time series to a remote-write " +
+		"endpoint in batches. Metric names are PREFIX_column, where PREFIX defaults to the fileType, with " +
+		"characters not allowed in metric names replaced by '_'. Series are labeled with fileType, project, and " +
+		"the values of any category columns. NA, NaN, and Inf values are skipped. Failed batches are retried " +
+		"with exponential backoff when the server is unavailable or rate limiting. The receiver must accept " +
+		"samples older than its current time to load historical files. Use '-' for STDIN.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "url",
+			Usage: "Remote-write endpoint `URL`, e.g. http://localhost:9090/api/v1/write",
+		},
+		cli.StringFlag{
+			Name:  "prefix",
+			Usage: "Metric name `PREFIX`, defaults to the fileType",
+		},
+		cli.IntFlag{
+			Name:  "batch-size",
+			Value: 1000,
+			Usage: "Send `N` lines per request",
+		},
+		cli.IntFlag{
+			Name:  "retries",
+			Value: 5,
+			Usage: "Retry a failed request up to `N` times",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 1 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		if c.String("url") == "" {
+			err := usageErrorf("missing required --url flag")
+			logger.Error(err)
+			return err
+		}
+		if u, err := url.Parse(c.String("url")); err != nil || u.Scheme == "" || u.Host == "" {
+			err := usageErrorf("bad URL '%v'", c.String("url"))
+			logger.Error(err)
+			return err
+		}
+		if c.Int("batch-size") < 1 {
+			err := usageErrorf("--batch-size must be at least 1")
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		pw := newPushWriter(c.String("url"), c.Int("retries"))
+		pw.header.Set("Content-Type", promwrite.ContentType)
+		pw.header.Set("Content-Encoding", promwrite.ContentEncoding)
+		pw.header.Set("X-Prometheus-Remote-Write-Version", promwrite.Version)
+		err = pushPrometheusCmd(c.Args().Get(0), pw, c.String("prefix"), c.Int("batch-size"), opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+func pushPrometheusCmd(infile string, pw *pushWriter, prefix string, batchSize int, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	if prefix == "" {
+		prefix = tr.Tsdata.FileType
+	}
+	var metrics, categories []int
+	names := make([]string, len(tr.Tsdata.Headers))
+	for i, typ := range tr.Tsdata.Types {
+		switch typ {
+		case "float", "integer", "latitude", "longitude":
+			metrics = append(metrics, i)
+			names[i] = promName(prefix + "_" + tr.Tsdata.Headers[i])
+		case "category":
+			categories = append(categories, i)
+			names[i] = promName(tr.Tsdata.Headers[i])
+		}
+	}
+	if len(metrics) == 0 {
+		return headerErrorf("no float or integer columns")
+	}
+	base := []promwrite.Label{
+		{Name: "fileType", Value: tr.Tsdata.FileType},
+		{Name: "project", Value: tr.Tsdata.Project},
+	}
+
+	var series []promwrite.TimeSeries
+	index := map[string]int{} // series key -> index in series
+	lines, samples := 0, 0
+	flush := func() error {
+		if len(series) > 0 {
+			err := pw.write(promwrite.Encode(series))
+			if err != nil {
+				return err
+			}
+		}
+		series = series[:0]
+		index = map[string]int{}
+		lines = 0
+		return nil
+	}
+	err = eachLine(tr, func(data tsdata.Data) error {
+		ts := data.Time.UnixNano() / 1e6
+		labels := base
+		key := ""
+		for _, i := range categories {
+			if data.Values[i] != nil {
+				labels = append(labels[:len(labels):len(labels)], promwrite.Label{Name: names[i], Value: data.Fields[i]})
+				key += names[i] + "=" + data.Fields[i] + "\xff"
+			}
+		}
+		for _, i := range metrics {
+			var v float64
+			switch x := data.Values[i].(type) {
+			case float64:
+				v = x
+			case int64:
+				v = float64(x)
+			default:
+				continue
+			}
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			k := names[i] + "\xff" + key
+			j, ok := index[k]
+			if !ok {
+				j = len(series)
+				index[k] = j
+				l := append([]promwrite.Label{{Name: "__name__", Value: names[i]}}, labels...)
+				series = append(series, promwrite.TimeSeries{Labels: l})
+			}
+			series[j].Samples = append(series[j].Samples, promwrite.Sample{Value: v, Timestamp: ts})
+			samples++
+		}
+		lines++
+		if lines == batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return err
+	}
+	logger.Printf("wrote %v samples\n", samples)
+	return nil
+}
+
+// promName converts s to a valid Prometheus metric or label name by
+// replacing invalid characters with '_'.
+func promName(s string) string {
+	var sb strings.Builder
+	for i, c := range s {
+		valid := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9')
+		if !valid {
+			if i == 0 && c >= '0' && c <= '9' {
+				sb.WriteRune('_')
+				sb.WriteRune(c)
+				continue
+			}
+			c = '_'
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+var pushCommand = cli.Command{
+	Name:  "push",
+	Usage: "Sends TSDATA lines to a time-series database",
+	Subcommands: []cli.Command{
+		pushInfluxCommand,
+		pushPrometheusCommand,
+	},
+}
+
+// pushWriter posts request bodies to a URL.
+type pushWriter struct {
+	client  *http.Client
+	url     string
+	header  http.Header
+	retries int
+}
+
+func newPushWriter(url string, retries int) *pushWriter {
+	return &pushWriter{
+		client:  &http.Client{Timeout: 30 * time.Second},
+		url:     url,
+		header:  http.Header{},
+		retries: retries,
+	}
+}
+
+// write posts body, retrying on network errors, rate limiting, and server
+// errors with exponential backoff.
+func (pw *pushWriter) write(body []byte) error {
+	wait := time.Second
+	for attempt := 0; ; attempt++ {
+		retry, err := pw.post(body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= pw.retries {
+			return err
+		}
+		logger.Warnf(0, "%v, retrying in %v", err, wait)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// post makes one request. retry is true if a failed request may succeed if
+// repeated.
+func (pw *pushWriter) post(body []byte) (retry bool, err error) {
+	req, err := http.NewRequest("POST", pw.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for k, v := range pw.header {
+		req.Header[k] = v
+	}
+	resp, err := pw.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	err = ioErrorf("write failed: %v %v", resp.Status, strings.TrimSpace(string(msg)))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/ctberthiaume/tsdata/internal/expr"
+	"github.com/urfave/cli"
+)
+
+var queryCommand = cli.Command{
+	Name:      "query",
+	Usage:     "Filters lines with an expression",
+	UsageText: "tsdata query [--format tsdata|csv] EXPR INFILE OUTFILE",
+	Description: "Writes lines of INFILE for which EXPR is true to OUTFILE. EXPR compares column values to literals " +
+		"or other columns with ==, !=, <, <=, >, and >=, and combines comparisons with &&, ||, !, and parentheses, " +
+		"e.g. 'sst > 20 && par != NA'. Strings are quoted, timestamps are quoted strings, and TRUE, FALSE, and NA " +
+		"are literals. Comparisons other than == and != with NA values are false. Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "format",
+			Value: "tsdata",
+			Usage: "Output `FORMAT`, tsdata or csv",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required EXPR, INFILE, and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 3 {
+			err := usageErrorf("missing required INFILE or OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 3 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		format := c.String("format")
+		if format != "tsdata" && format != "csv" {
+			err := usageErrorf("bad format '%v', expected tsdata or csv", format)
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = queryCmd(c.Args().Get(0), c.Args().Get(1), c.Args().Get(2), format, opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+func queryCmd(query string, infile string, outfile string, format string, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	types := map[string]string{}
+	for i, h := range tr.Tsdata.Headers {
+		types[h] = tr.Tsdata.Types[i]
+	}
+	e, err := expr.Compile(query, types)
+	if err != nil {
+		return usageErrorf("bad query: %v", err)
+	}
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+
+	var write func(fields []string) error
+	var flush func() error
+	if format == "csv" {
+		w := csv.NewWriter(outf)
+		err = w.Write(tr.Tsdata.Headers)
+		write = w.Write
+		flush = func() error {
+			w.Flush()
+			return w.Error()
+		}
+	} else {
+		w := bufio.NewWriter(outf)
+		_, err = w.WriteString(tr.Tsdata.Header() + "\n")
+		write = func(fields []string) error {
+			_, err := w.WriteString(strings.Join(fields, tsdata.Delim) + "\n")
+			return err
+		}
+		flush = w.Flush
+	}
+	if err != nil {
+		return err
+	}
+
+	err = eachLine(tr, func(data tsdata.Data) error {
+		if !e.Eval(data.Value) {
+			return nil
+		}
+		return write(data.Fields)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
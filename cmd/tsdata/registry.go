@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+// registryFlag selects the fileType registry for validate and registry.
+var registryFlag = cli.StringFlag{
+	Name:   "registry",
+	EnvVar: "TSDATA_REGISTRY",
+	Usage:  "fileType registry `FILE`, a JSON object of canonical TSDATA headers keyed by fileType",
+}
+
+var registryCommand = cli.Command{
+	Name:  "registry",
+	Usage: "Manages a registry of allowed fileTypes",
+	Description: "A registry is a JSON file mapping each allowed fileType to the canonical TSDATA header for that " +
+		"fileType. validate --registry FILE fails files whose fileType isn't registered or whose column names, " +
+		"types, and units don't match the registered header. The registry file may also be set with TSDATA_REGISTRY.",
+	Subcommands: []cli.Command{
+		{
+			Name:      "add",
+			Usage:     "Registers the fileType and header of TSDATA files",
+			UsageText: "tsdata registry add --registry FILE [--replace] INFILE...",
+			Description: "Adds the header of each INFILE to the registry under its fileType, creating the registry " +
+				"if it doesn't exist. Registering a different header for a fileType which is already registered " +
+				"is an error unless --replace is given. The registry is only written if every INFILE can be added.",
+			Flags: []cli.Flag{
+				registryFlag,
+				cli.BoolFlag{
+					Name:  "replace",
+					Usage: "Replace the headers of fileTypes which are already registered",
+				},
+				cli.BoolFlag{
+					Name:  "quiet, q",
+					Usage: "Suppress logging output",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if c.NArg() == 0 {
+					err := usageErrorf("missing required INFILE argument")
+					logger.Error(err)
+					return err
+				}
+				if c.String("registry") == "" {
+					err := usageErrorf("missing required --registry")
+					logger.Error(err)
+					return err
+				}
+				if c.Bool("quiet") {
+					logger.SetOutput(ioutil.Discard)
+				}
+				err := registryAddCmd(c.String("registry"), c.Args(), c.Bool("replace"))
+				if err != nil {
+					logger.Error(err)
+				}
+				return err
+			},
+		},
+		{
+			Name:        "list",
+			Usage:       "Lists registered fileTypes",
+			UsageText:   "tsdata registry list --registry FILE",
+			Description: "Prints each registered fileType with its project and column names to STDOUT.",
+			Flags: []cli.Flag{
+				registryFlag,
+				cli.BoolFlag{
+					Name:  "quiet, q",
+					Usage: "Suppress logging output",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if c.NArg() > 0 {
+					err := usageErrorf("too many arguments")
+					logger.Error(err)
+					return err
+				}
+				if c.String("registry") == "" {
+					err := usageErrorf("missing required --registry")
+					logger.Error(err)
+					return err
+				}
+				if c.Bool("quiet") {
+					logger.SetOutput(ioutil.Discard)
+				}
+				err := registryListCmd(c.String("registry"))
+				if err != nil {
+					logger.Error(err)
+				}
+				return err
+			},
+		},
+	},
+}
+
+// registry maps allowed fileTypes to canonical schemas.
+type registry map[string]tsdata.Schema
+
+// readRegistry reads the registry file at path. If missing is true a missing
+// file is read as an empty registry.
+func readRegistry(path string, missing bool) (registry, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && missing {
+		return registry{}, nil
+	}
+	if err != nil {
+		return nil, &cmdError{code: exitIO, err: err}
+	}
+	var headers map[string]string
+	if err := json.Unmarshal(b, &headers); err != nil {
+		return nil, usageErrorf("%v: %v", path, err)
+	}
+	reg := registry{}
+	for fileType, header := range headers {
+		s, err := tsdata.ParseSchema(header)
+		if err != nil {
+			return nil, fmt.Errorf("%v: fileType %v: %w", path, fileType, err)
+		}
+		if s.FileType != fileType {
+			return nil, headerErrorf("%v: fileType %v has header for fileType '%v'", path, fileType, s.FileType)
+		}
+		reg[fileType] = s
+	}
+	return reg, nil
+}
+
+// write writes the registry to path, replacing any existing file.
+func (reg registry) write(path string) error {
+	headers := map[string]string{}
+	for fileType, s := range reg {
+		headers[fileType] = s.Header()
+	}
+	b, err := json.MarshalIndent(headers, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(append(b, '\n'))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// check returns a *tsdata.HeaderError if the fileType of s isn't registered
+// or its columns don't match the registered schema.
+func (reg registry) check(s tsdata.Schema) error {
+	want, ok := reg[s.FileType]
+	if !ok {
+		return headerErrorf("fileType '%v' is not registered", s.FileType)
+	}
+	if err := checkColumns(want, s); err != nil {
+		return fmt.Errorf("%w in registered fileType %v", err, s.FileType)
+	}
+	return nil
+}
+
+func registryAddCmd(path string, files []string, replace bool) error {
+	reg, err := readRegistry(path, true)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		s, err := readSchema(file)
+		if err != nil {
+			return fmt.Errorf("%v: %w", file, err)
+		}
+		// Registered headers are templates, not descriptions of one file
+		s.FileDescription = ""
+		if old, ok := reg[s.FileType]; ok && !replace {
+			if err := checkColumns(old, s); err != nil {
+				return fmt.Errorf("%v: fileType %v is already registered with different columns, %w", file, s.FileType, err)
+			}
+			continue
+		}
+		reg[s.FileType] = s
+		logger.Printf("registered fileType %v from %v\n", s.FileType, file)
+	}
+	return reg.write(path)
+}
+
+func registryListCmd(path string) error {
+	reg, err := readRegistry(path, false)
+	if err != nil {
+		return err
+	}
+	fileTypes := make([]string, 0, len(reg))
+	for fileType := range reg {
+		fileTypes = append(fileTypes, fileType)
+	}
+	sort.Strings(fileTypes)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "fileType\tproject\tcolumns\n")
+	for _, fileType := range fileTypes {
+		s := reg[fileType]
+		fmt.Fprintf(w, "%v\t%v\t%v\n", fileType, s.Project, strings.Join(s.Headers, ","))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var replayCommand = cli.Command{
+	Name:      "replay",
+	Usage:     "Prints data lines paced by their timestamps",
+	UsageText: "tsdata replay [--speed FACTOR] [--max-wait DURATION] [--no-header] INFILE",
+	Description: "Prints the header of INFILE and then each valid data line to STDOUT at the time given by its " +
+		"timestamp relative to the first line, to test live-ingest software against historical data. --speed " +
+		"replays faster, e.g. 10x waits a tenth of the time between lines, and --max-wait caps the wait for a " +
+		"single line so long outages don't stall the replay. Lines with timestamps earlier than the previous line " +
+		"are printed without waiting. Each line is flushed as it's printed. Lines which fail validation are logged " +
+		"and skipped. Use '-' for STDIN.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "speed",
+			Value: "1x",
+			Usage: "Replay `FACTOR` times faster than real time, e.g. 10x or 0.5x",
+		},
+		cli.DurationFlag{
+			Name:  "max-wait",
+			Usage: "Wait at most `DURATION` between lines, e.g. 10s. Default is no limit.",
+		},
+		cli.BoolFlag{
+			Name:  "no-header",
+			Usage: "Print only data lines",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 1 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		speed, err := strconv.ParseFloat(strings.TrimSuffix(c.String("speed"), "x"), 64)
+		if err != nil || !(speed > 0) || speed > 1e9 {
+			err := usageErrorf("bad --speed '%v', expected a positive factor such as 10x", c.String("speed"))
+			logger.Error(err)
+			return err
+		}
+		if c.Duration("max-wait") < 0 {
+			err := usageErrorf("--max-wait must not be negative")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = replayCmd(c.Args().Get(0), speed, c.Duration("max-wait"), !c.Bool("no-header"), opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+func replayCmd(infile string, speed float64, maxWait time.Duration, header bool, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	w := bufio.NewWriter(os.Stdout)
+	if header {
+		if _, err := w.WriteString(tr.Tsdata.Header() + "\n"); err != nil {
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	var prev time.Time // timestamp of the previous line
+	var due time.Time  // wall clock time the previous line was due
+	return eachLine(tr, func(data tsdata.Data) error {
+		if prev.IsZero() {
+			due = time.Now()
+		} else if data.Time.After(prev) {
+			wait := time.Duration(float64(data.Time.Sub(prev)) / speed)
+			if maxWait > 0 && wait > maxWait {
+				wait = maxWait
+			}
+			due = due.Add(wait)
+			time.Sleep(time.Until(due))
+		}
+		if prev.IsZero() || data.Time.After(prev) {
+			prev = data.Time
+		}
+		if _, err := w.WriteString(strings.Join(data.Fields, tsdata.Delim) + "\n"); err != nil {
+			return err
+		}
+		return w.Flush()
+	})
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var resampleCommand = cli.Command{
+	Name:      "resample",
+	Usage:     "Aggregates lines into fixed time intervals",
+	UsageText: "tsdata resample --interval DURATION [--agg mean|median|min|max|first|last] [--align minute|hour|day] INFILE OUTFILE",
+	Description: "Groups lines of INFILE into bins of --interval, aligned to multiples of the interval since midnight UTC " +
+		"for intervals which divide a day, and writes one line per bin to OUTFILE timestamped with the start of the bin. " +
+		"With --align, bins restart at every UTC minute, hour, or day boundary, so files resampled with the same " +
+		"interval share a time grid even if the interval doesn't divide a day. The last bin before each boundary is " +
+		"shorter if the interval doesn't divide the --align unit. " +
+		"Values in float, integer, latitude, and longitude columns are aggregated with --agg, ignoring NA values. " +
+		"Integer results are rounded. Other columns keep the first value in the bin, or the last with --agg last. " +
+		"Bins without lines are not written, see fill. INFILE must be sorted by time. " +
+		"Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.DurationFlag{
+			Name:  "interval, i",
+			Usage: "Bin width as a `DURATION`, e.g. 1m",
+		},
+		cli.StringFlag{
+			Name:  "agg",
+			Value: "mean",
+			Usage: "Aggregate numeric columns with `AGG`, mean, median, min, max, first, or last",
+		},
+		alignFlag,
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 2 {
+			err := usageErrorf("missing required OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 2 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		if c.Duration("interval") <= 0 {
+			err := usageErrorf("--interval must be a positive duration")
+			logger.Error(err)
+			return err
+		}
+		agg, ok := aggregators[c.String("agg")]
+		if !ok {
+			err := usageErrorf("bad aggregate '%v', expected mean, median, min, max, first, or last", c.String("agg"))
+			logger.Error(err)
+			return err
+		}
+		grid, err := parseTimeGrid(c.Duration("interval"), c.String("align"))
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = resampleCmd(c.Args().Get(0), c.Args().Get(1), grid, agg, c.String("agg") == "last", opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// aggregators are the functions available to resample, by name. Each is
+// passed the non-NA values of a column in a bin in file order.
+var aggregators = map[string]func([]float64) float64{
+	"mean":   mean,
+	"median": median,
+	"min": func(v []float64) float64 {
+		m := math.Inf(1)
+		for _, x := range v {
+			m = math.Min(m, x)
+		}
+		return m
+	},
+	"max": func(v []float64) float64 {
+		m := math.Inf(-1)
+		for _, x := range v {
+			m = math.Max(m, x)
+		}
+		return m
+	},
+	"first": func(v []float64) float64 { return v[0] },
+	"last":  func(v []float64) float64 { return v[len(v)-1] },
+}
+
+// alignFlag sets the time grid boundaries of resample and fill.
+var alignFlag = cli.StringFlag{
+	Name:  "align",
+	Usage: "Restart the time grid at every UTC `UNIT` boundary, minute, hour, or day",
+}
+
+// alignUnits are the --align values.
+var alignUnits = map[string]time.Duration{
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+}
+
+// timeGrid is a sequence of times interval apart which restarts at every
+// multiple of unit since midnight UTC. With no unit the grid is multiples of
+// interval since the zero time.
+type timeGrid struct {
+	interval time.Duration
+	unit     time.Duration
+}
+
+// parseTimeGrid returns a timeGrid for interval and an --align value.
+func parseTimeGrid(interval time.Duration, align string) (timeGrid, error) {
+	if align == "" {
+		return timeGrid{interval: interval}, nil
+	}
+	unit, ok := alignUnits[align]
+	if !ok {
+		return timeGrid{}, usageErrorf("bad --align '%v', expected minute, hour, or day", align)
+	}
+	if interval > unit {
+		return timeGrid{}, usageErrorf("--interval %v is longer than --align %v", interval, align)
+	}
+	return timeGrid{interval: interval, unit: unit}, nil
+}
+
+// floor returns the last grid time at or before t.
+func (g timeGrid) floor(t time.Time) time.Time {
+	if g.unit == 0 {
+		return t.Truncate(g.interval)
+	}
+	b := t.Truncate(g.unit)
+	return b.Add(t.Sub(b) / g.interval * g.interval)
+}
+
+// next returns the grid time after grid time t.
+func (g timeGrid) next(t time.Time) time.Time {
+	n := t.Add(g.interval)
+	if g.unit != 0 {
+		if b := t.Truncate(g.unit).Add(g.unit); n.After(b) {
+			n = b
+		}
+	}
+	return n
+}
+
+// resampler aggregates lines in one time bin at a time.
+type resampler struct {
+	t      *tsdata.Tsdata
+	grid   timeGrid
+	agg    func([]float64) float64
+	last   bool // keep the last rather than first value of other columns
+	write  func([]string) error
+	start  time.Time   // start of the current bin
+	prev   time.Time   // time of the previous line
+	lines  int         // lines in the current bin
+	vals   [][]float64 // non-NA numeric values in the current bin by column
+	fields []string    // values of other columns in the current bin
+}
+
+func newResampler(t *tsdata.Tsdata, grid timeGrid, agg func([]float64) float64, last bool, write func([]string) error) *resampler {
+	return &resampler{
+		t:      t,
+		grid:   grid,
+		agg:    agg,
+		last:   last,
+		write:  write,
+		vals:   make([][]float64, len(t.Headers)),
+		fields: make([]string, len(t.Headers)),
+	}
+}
+
+// add adds a line to the current bin, first writing the current bin if the
+// line starts a new one.
+func (r *resampler) add(data tsdata.Data) error {
+	if r.lines > 0 && data.Time.Before(r.prev) {
+		return dataErrorf("line %v, timestamp earlier than previous line, INFILE must be sorted by time", data.Line)
+	}
+	r.prev = data.Time
+	start := r.grid.floor(data.Time)
+	if r.lines > 0 && !start.Equal(r.start) {
+		if err := r.flush(); err != nil {
+			return err
+		}
+	}
+	r.start = start
+	r.lines++
+	for i := 1; i < len(data.Values); i++ {
+		if data.Values[i] == nil {
+			continue
+		}
+		switch x := data.Values[i].(type) {
+		case float64:
+			r.vals[i] = append(r.vals[i], x)
+			continue
+		case int64:
+			r.vals[i] = append(r.vals[i], float64(x))
+			continue
+		}
+		if r.fields[i] == "" || r.last {
+			r.fields[i] = data.Fields[i]
+		}
+	}
+	return nil
+}
+
+// flush writes the current bin, if it has any lines, and resets it.
+func (r *resampler) flush() error {
+	if r.lines == 0 {
+		return nil
+	}
+	out := make([]string, len(r.fields))
+	out[0] = r.start.Format(time.RFC3339Nano)
+	for i := 1; i < len(out); i++ {
+		switch {
+		case len(r.vals[i]) > 0:
+			out[i] = formatNumber(r.t.Types[i], r.agg(r.vals[i]))
+		case r.fields[i] != "":
+			out[i] = r.fields[i]
+		default:
+			out[i] = tsdata.NA
+		}
+		r.vals[i] = r.vals[i][:0]
+		r.fields[i] = ""
+	}
+	r.lines = 0
+	return r.write(out)
+}
+
+func resampleCmd(infile string, outfile string, grid timeGrid, agg func([]float64) float64, last bool, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+	_, err = w.WriteString(tr.Tsdata.Header() + "\n")
+	if err != nil {
+		return err
+	}
+
+	rs := newResampler(tr.Tsdata, grid, agg, last, func(fields []string) error {
+		_, err := w.WriteString(strings.Join(fields, tsdata.Delim) + "\n")
+		return err
+	})
+	err = eachLine(tr, rs.add)
+	if err != nil {
+		return err
+	}
+	err = rs.flush()
+	if err != nil {
+		return err
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var retypeCommand = cli.Command{
+	Name:      "retype",
+	Usage:     "Changes the types of TSDATA columns",
+	UsageText: "tsdata retype --column NAME=FROM:TO... [--round POLICY] INFILE OUTFILE",
+	Description: "Writes INFILE to OUTFILE with column NAME changed from type FROM to type TO, converting every " +
+		"value to the new type. FROM must be the column's current type, and TO may be any column type, including " +
+		"category:VALUE|VALUE. Values which are already valid for TO are copied as they are, so integer to float " +
+		"always succeeds. Float values are converted to integer with --round: none, which only accepts whole " +
+		"numbers, nearest, even, floor, ceil, or trunc. NA values are kept. If any value can't be converted, the " +
+		"number of failures in each column is logged and OUTFILE isn't written. " +
+		"Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "column, c",
+			Usage: "Change column NAME from type FROM to type TO as `NAME=FROM:TO`, may be repeated",
+		},
+		cli.StringFlag{
+			Name:  "round",
+			Value: "none",
+			Usage: "Float to integer rounding `POLICY`, none, nearest, even, floor, ceil, or trunc",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 2 {
+			err := usageErrorf("missing required OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 2 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if len(c.StringSlice("column")) == 0 {
+			err := usageErrorf("missing required --column")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		round, ok := roundPolicies[c.String("round")]
+		if !ok {
+			err := usageErrorf("bad --round '%v', expected none, nearest, even, floor, ceil, or trunc", c.String("round"))
+			logger.Error(err)
+			return err
+		}
+		specs, err := parseColumnArgs(c.StringSlice("column"), "NAME=FROM:TO")
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		var retypes []retype
+		for name, spec := range specs {
+			parts := strings.SplitN(spec, ":", 2)
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				err := usageErrorf("bad --column '%v=%v', expected NAME=FROM:TO", name, spec)
+				logger.Error(err)
+				return err
+			}
+			retypes = append(retypes, retype{name: name, from: parts[0], to: parts[1]})
+		}
+		err = retypeCmd(c.Args().Get(0), c.Args().Get(1), retypes, round)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// roundPolicies round float values converted to integer. nil only accepts
+// whole numbers.
+var roundPolicies = map[string]func(float64) float64{
+	"none":    nil,
+	"nearest": math.Round,
+	"even":    math.RoundToEven,
+	"floor":   math.Floor,
+	"ceil":    math.Ceil,
+	"trunc":   math.Trunc,
+}
+
+// retype is one column type change and its counts.
+type retype struct {
+	name     string
+	from     string
+	to       string // type with any category values
+	col      int
+	check    func(string) bool // validates values of the new type
+	changed  int               // values rewritten
+	rounded  int               // float values rounded to integer
+	failures int               // values which can't be converted
+}
+
+// convert returns v converted to the new type.
+func (r *retype) convert(v string, round func(float64) float64) (string, bool) {
+	if v == tsdata.NA || r.check(v) {
+		return v, true
+	}
+	if r.to != "integer" {
+		return v, false
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return v, false
+	}
+	i := f
+	if round != nil {
+		i = round(f)
+	}
+	if i != math.Trunc(i) || i < math.MinInt64 || i >= math.MaxInt64 {
+		return v, false
+	}
+	s := strconv.FormatInt(int64(i), 10)
+	if !r.check(s) {
+		return v, false
+	}
+	if i != f {
+		r.rounded++
+	}
+	return s, true
+}
+
+func retypeCmd(infile string, outfile string, retypes []retype, round func(float64) float64) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	tr, err := tsdata.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("%v: %w", infile, err)
+	}
+
+	s := tr.Tsdata.Schema()
+	for i := range retypes {
+		rt := &retypes[i]
+		rt.col = indexOfColumn(s.Headers, rt.name)
+		if rt.col == -1 {
+			return usageErrorf("no column '%v'", rt.name)
+		}
+		if rt.col == 0 {
+			return usageErrorf("can't retype the time column")
+		}
+		if s.Types[rt.col] != rt.from {
+			return usageErrorf("column '%v' has type %v, not %v", rt.name, s.Types[rt.col], rt.from)
+		}
+		s.Types[rt.col] = rt.to
+		if rt.col < len(s.Constraints) {
+			s.Constraints[rt.col] = tsdata.Constraint{}
+		}
+	}
+	sort.Slice(retypes, func(i, j int) bool { return retypes[i].col < retypes[j].col })
+	s, err = tsdata.ParseSchema(s.Header())
+	if err != nil {
+		return usageErrorf("%v", err)
+	}
+	cols := tsdata.New(tsdata.WithSchema(s)).Columns()
+	for i := range retypes {
+		retypes[i].to = s.Types[retypes[i].col]
+		retypes[i].check = cols[retypes[i].col].Checker
+	}
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+	if _, err := w.WriteString(s.Header() + "\n"); err != nil {
+		return err
+	}
+
+	delim := tr.Tsdata.Delimiter()
+	ncols := len(s.Headers)
+	failures := 0
+	for {
+		line, err := tr.NextRaw()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if line == "" {
+			if _, err := w.WriteString("\n"); err != nil {
+				return err
+			}
+			continue
+		}
+		fields := strings.Split(line, delim)
+		if len(fields) < ncols {
+			logger.Warnf(tr.Line(), "found %v columns, expected %v, padded with NA", len(fields), ncols)
+			fields = append(fields, nas(ncols-len(fields))...)
+		}
+		fields = fields[:ncols]
+		for i := range retypes {
+			rt := &retypes[i]
+			v, ok := rt.convert(fields[rt.col], round)
+			if !ok {
+				rt.failures++
+				failures++
+				logger.Warnf(tr.Line(), "column %v (%v), can't convert '%v' from %v to %v", rt.col+1, rt.name, fields[rt.col], rt.from, rt.to)
+				continue
+			}
+			if v != fields[rt.col] {
+				rt.changed++
+				fields[rt.col] = v
+			}
+		}
+		if failures > 0 {
+			continue
+		}
+		if _, err := w.WriteString(strings.Join(fields, tsdata.Delim) + "\n"); err != nil {
+			return err
+		}
+	}
+	for _, rt := range retypes {
+		logger.Printf("column %v (%v), %v to %v: %v values changed, %v rounded, %v failed\n", rt.col+1, rt.name, rt.from, rt.to, rt.changed, rt.rounded, rt.failures)
+	}
+	if failures > 0 {
+		abortOutput(outf)
+		return dataErrorf("%v: %v values can't be converted", infile, failures)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return outf.Close()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+// lineSampler returns a tsdata.Reader Sample function for validate --sample
+// and --every, or nil if every line should be validated.
+func lineSampler(c *cli.Context) (func(line int) bool, error) {
+	if c.IsSet("sample") && c.IsSet("every") {
+		return nil, usageErrorf("--sample and --every can't be used together")
+	}
+	if (c.IsSet("sample") || c.IsSet("every")) && c.Duration("expected-interval") > 0 {
+		return nil, usageErrorf("--expected-interval can't be used with --sample or --every")
+	}
+	if c.IsSet("every") {
+		every := c.Int("every")
+		if every < 1 {
+			return nil, usageErrorf("--every must be >= 1")
+		}
+		return func(line int) bool {
+			return (line-tsdata.HeaderSize-1)%every == 0
+		}, nil
+	}
+	if c.IsSet("sample") {
+		s := strings.TrimSuffix(strings.TrimSpace(c.String("sample")), "%")
+		pct, err := strconv.ParseFloat(s, 64)
+		if err != nil || !(pct > 0 && pct <= 100) {
+			return nil, usageErrorf("bad sample '%v', expected a percentage > 0 and <= 100 such as 1%%", c.String("sample"))
+		}
+		rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+		return func(line int) bool {
+			return rnd.Float64()*100 < pct
+		}, nil
+	}
+	return nil, nil
+}
+
+// logSampleEstimate logs the error rate of lines validated in a sampled
+// report and the number of error lines it predicts for the whole file.
+func logSampleEstimate(infile string, rep *tsdata.Report) {
+	total := rep.DataLines + rep.SkippedLines
+	if rep.DataLines == 0 {
+		logger.Printf("%v: validated 0 of %v data lines, can't estimate an error rate\n", infile, total)
+		return
+	}
+	rate := float64(rep.ErrorLines) / float64(rep.DataLines)
+	logger.Printf(
+		"%v: validated %v of %v data lines, %v with errors, estimated error rate %.3g%% (about %.0f error lines)\n",
+		infile, rep.DataLines, total, rep.ErrorLines, rate*100, rate*float64(total),
+	)
+}
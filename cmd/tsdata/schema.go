@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var schemaCommand = cli.Command{
+	Name:  "schema",
+	Usage: "Works with TSDATA file headers",
+	Subcommands: []cli.Command{
+		{
+			Name:      "check",
+			Usage:     "Checks that files with the same fileType have the same columns",
+			UsageText: "tsdata schema check [--alias OLD=NEW...] FILE...",
+			Description: "Reads the header of each FILE and groups files by fileType. Within each group, every file's " +
+				"column names, types, and units are compared to the first file in the group and the first mismatch " +
+				"for each file is printed to STDERR. Columns are compared after renaming them with --alias.",
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "alias",
+					Usage: "Treat column `OLD=NEW` as column NEW, may be repeated",
+				},
+				cli.BoolFlag{
+					Name:  "quiet, q",
+					Usage: "Suppress logging output",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if c.NArg() == 0 {
+					err := usageErrorf("missing required FILE argument")
+					logger.Error(err)
+					return err
+				}
+				if c.Bool("quiet") {
+					logger.SetOutput(ioutil.Discard)
+				}
+				opts, err := readerOptions(c)
+				if err != nil {
+					logger.Error(err)
+					return err
+				}
+				err = schemaCheckCmd(c.Args(), opts)
+				if err != nil {
+					logger.Error(err)
+				}
+				return err
+			},
+		},
+	},
+}
+
+func schemaCheckCmd(files []string, opts []tsdata.Option) error {
+	type reference struct {
+		file   string
+		schema tsdata.Schema
+	}
+	refs := map[string]reference{}
+	bad := 0
+	for _, file := range files {
+		schema, err := readSchema(file, opts...)
+		if err != nil {
+			logger.FileError(file, err)
+			bad++
+			continue
+		}
+		ref, ok := refs[schema.FileType]
+		if !ok {
+			refs[schema.FileType] = reference{file: file, schema: schema}
+			continue
+		}
+		err = checkColumns(ref.schema, schema)
+		if err != nil {
+			logger.FileError(file, fmt.Errorf("%w in %v", err, ref.file))
+			bad++
+		}
+	}
+	if bad > 0 {
+		return headerErrorf("%v of %v files failed schema check", bad, len(files))
+	}
+	return nil
+}
+
+// readSchema reads and validates the header of the TSDATA file at path,
+// configured by opts.
+func readSchema(path string, opts ...tsdata.Option) (tsdata.Schema, error) {
+	r, err := openInput(path)
+	if err != nil {
+		return tsdata.Schema{}, err
+	}
+	defer r.Close()
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return tsdata.Schema{}, err
+	}
+	return tr.Tsdata.Schema(), nil
+}
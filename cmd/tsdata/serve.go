@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/ctberthiaume/tsdata/internal/ingest"
+	"github.com/urfave/cli"
+	"google.golang.org/grpc"
+)
+
+var serveCommand = cli.Command{
+	Name:      "serve",
+	Usage:     "Runs an HTTP service to validate and convert TSDATA files",
+	UsageText: "tsdata serve [--listen ADDR] [--grpc-listen ADDR] [--ingest-dir DIR]",
+	Description: "Serves HTTP endpoints for uploaded TSDATA files. Files may be sent as the request body or as " +
+		"the 'file' field of a multipart form, and may be gzip compressed. " +
+		"POST /validate returns the same newline-delimited JSON as validate --format json, with status 200 " +
+		"for a valid file or 422 for an invalid file. " +
+		"POST /convert?format=csv|ndjson returns valid data lines as CSV or newline-delimited JSON, with " +
+		"the number of skipped invalid lines in the Tsdata-Error-Lines trailer. " +
+		"POST /describe returns the same JSON column summaries as describe --format json. " +
+		"With --ingest-dir, POST /ingest/NAME validates the data lines in the request body against the header in " +
+		"DIR/NAME.schema and appends valid lines to DIR/NAME.YYYY-MM-DD.tsdata for each line's UTC day. New files " +
+		"are created with the schema header and renamed into place, and lines which aren't later than the last line " +
+		"of their file are rejected. The response is a JSON object for each rejected line followed by a summary, " +
+		"with status 200 if every line was appended or 422 otherwise. " +
+		"All endpoints accept na and check-order query parameters, which work like the flags of the same name. " +
+		"With --grpc-listen the gRPC Ingest service in internal/ingest/ingest.proto is also served, which " +
+		"validates streamed data lines and returns an acknowledgement for each line. Runs until interrupted.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "listen, l",
+			Value: ":8080",
+			Usage: "Listen on `ADDR`",
+		},
+		cli.StringFlag{
+			Name:  "grpc-listen",
+			Usage: "Serve the gRPC Ingest service on `ADDR`",
+		},
+		cli.StringFlag{
+			Name:  "ingest-dir",
+			Usage: "Serve POST /ingest/NAME for the NAME.schema files in `DIR`, appending lines to files in DIR",
+		},
+		cli.Int64Flag{
+			Name:  "max-size",
+			Value: 1024,
+			Usage: "Reject uploads larger than `MB` megabytes",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() > 0 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		err := serveCmd(c.String("listen"), c.String("grpc-listen"), c.String("ingest-dir"), c.Int64("max-size")<<20)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+func serveCmd(addr string, grpcAddr string, ingestDir string, maxSize int64) error {
+	mux := http.NewServeMux()
+	mux.Handle("/validate", uploadHandler(maxSize, serveValidate))
+	mux.Handle("/convert", uploadHandler(maxSize, serveConvert))
+	mux.Handle("/describe", uploadHandler(maxSize, serveDescribe))
+	if ingestDir != "" {
+		in, err := newIngester(ingestDir, maxSize)
+		if err != nil {
+			return err
+		}
+		mux.Handle("/ingest/", in)
+		logger.Printf("ingesting %v schemas into %v\n", len(in.schemas), ingestDir)
+	}
+	// Request contexts are cancelled if shutdown times out, so long-running
+	// validations stop
+	base, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+	srv := &http.Server{Addr: addr, Handler: mux, BaseContext: func(net.Listener) context.Context { return base }}
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(done)
+	errc := make(chan error, 2)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+	logger.Printf("listening on %v\n", addr)
+
+	if grpcAddr != "" {
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			srv.Close()
+			return err
+		}
+		g := grpc.NewServer(grpc.ForceServerCodec(ingest.Codec{}))
+		ingest.NewServer().Register(g)
+		go func() {
+			errc <- g.Serve(lis)
+		}()
+		defer g.GracefulStop()
+		logger.Printf("serving gRPC on %v\n", grpcAddr)
+	}
+
+	select {
+	case err := <-errc:
+		srv.Close()
+		return err
+	case <-done:
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+// uploadFunc handles one uploaded file, returning the response status. ctx is
+// done if the client goes away.
+type uploadFunc func(ctx context.Context, w http.ResponseWriter, body io.Reader, name string, q url.Values) int
+
+// uploadHandler returns a handler for POST requests which calls fn with the
+// decompressed upload.
+func uploadHandler(maxSize int64, fn uploadFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		status := func() int {
+			if req.Method != http.MethodPost {
+				w.Header().Set("Allow", http.MethodPost)
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return http.StatusMethodNotAllowed
+			}
+			req.Body = limitBody(w, req.Body, maxSize)
+			body, name, err := uploadReader(req)
+			if err != nil {
+				status := bodyStatus(err, http.StatusBadRequest)
+				http.Error(w, err.Error(), status)
+				return status
+			}
+			return fn(req.Context(), w, body, name, req.URL.Query())
+		}()
+		logger.Printf("%v %v %v %v\n", req.RemoteAddr, req.Method, req.URL.Path, status)
+	})
+}
+
+// errTooLarge is returned when reading a request body larger than --max-size.
+var errTooLarge = errors.New("request body larger than --max-size")
+
+// limitBody returns body limited to maxSize bytes by http.MaxBytesReader,
+// with errTooLarge returned when the limit is exceeded.
+func limitBody(w http.ResponseWriter, body io.ReadCloser, maxSize int64) io.ReadCloser {
+	return &limitedBody{ReadCloser: http.MaxBytesReader(w, body, maxSize), left: maxSize}
+}
+
+// limitedBody is a request body limited by http.MaxBytesReader.
+type limitedBody struct {
+	io.ReadCloser
+	left int64 // bytes left before the limit
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.left -= int64(n)
+	if err != nil && err != io.EOF && b.left <= 0 {
+		err = errTooLarge
+	}
+	return n, err
+}
+
+// bodyStatus returns the response status for err from reading a request
+// body, 413 if the body was larger than --max-size and status otherwise.
+func bodyStatus(err error, status int) int {
+	if errors.Is(err, errTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return status
+}
+
+// uploadReader returns the uploaded file in req and its name. Multipart form
+// uploads must use the field name "file". Otherwise the request body is the
+// file and its name is the name query parameter.
+func uploadReader(req *http.Request) (io.Reader, string, error) {
+	var r io.Reader = req.Body
+	name := req.URL.Query().Get("name")
+	if mr, err := req.MultipartReader(); err == nil {
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return nil, "", errors.New("missing form field 'file'")
+			}
+			if err != nil {
+				return nil, "", err
+			}
+			if part.FormName() == "file" {
+				r = part
+				name = part.FileName()
+				break
+			}
+		}
+	}
+	if name == "" {
+		name = "upload"
+	}
+	r, err := maybeGunzip(r)
+	if err != nil {
+		return nil, "", err
+	}
+	return r, name, nil
+}
+
+// queryOptions creates tsdata options from query parameters in the same way
+// readerOptions does for flags.
+func queryOptions(q url.Values) ([]tsdata.Option, error) {
+	opts := []tsdata.Option{}
+	if na := q["na"]; len(na) > 0 {
+		opts = append(opts, tsdata.WithNATokens(na...))
+	}
+	if s := q.Get("check-order"); s != "" {
+		order, err := tsdata.ParseTimeOrder(s)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, tsdata.WithTimeOrder(order))
+	}
+	return opts, nil
+}
+
+func serveValidate(ctx context.Context, w http.ResponseWriter, body io.Reader, name string, q url.Values) int {
+	opts, err := queryOptions(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return http.StatusBadRequest
+	}
+	var buf bytes.Buffer
+	err = validateReader(ctx, body, name, validateConfig{format: "json"}, &buf, opts)
+	status := http.StatusOK
+	switch exitCode(err) {
+	case exitOK:
+	case exitHeader, exitData:
+		status = http.StatusUnprocessableEntity
+	default:
+		status = bodyStatus(err, http.StatusBadRequest)
+		http.Error(w, err.Error(), status)
+		return status
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+	return status
+}
+
+func serveConvert(ctx context.Context, w http.ResponseWriter, body io.Reader, name string, q url.Values) int {
+	format := q.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "ndjson" {
+		http.Error(w, "bad format '"+format+"', expected csv or ndjson", http.StatusBadRequest)
+		return http.StatusBadRequest
+	}
+	opts, err := queryOptions(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return http.StatusBadRequest
+	}
+	tr, err := tsdata.NewReader(body, opts...)
+	if err != nil {
+		status := bodyStatus(err, http.StatusBadRequest)
+		if exitCode(err) == exitHeader {
+			status = http.StatusUnprocessableEntity
+		}
+		http.Error(w, err.Error(), status)
+		return status
+	}
+	tr.Strict = false
+
+	w.Header().Set("Trailer", "Tsdata-Error-Lines")
+	bw := bufio.NewWriter(w)
+	var cw *csv.Writer
+	var write func(tsdata.Data) error
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		cw = csv.NewWriter(bw)
+		write = func(data tsdata.Data) error {
+			return cw.Write(data.Fields)
+		}
+		if err := cw.Write(tr.Tsdata.Headers); err != nil {
+			return http.StatusOK
+		}
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		var buf bytes.Buffer
+		write = func(data tsdata.Data) error {
+			buf.Reset()
+			if err := appendJSONObject(&buf, tr.Tsdata.Headers, data); err != nil {
+				return err
+			}
+			buf.WriteByte('\n')
+			_, err := bw.Write(buf.Bytes())
+			return err
+		}
+	}
+
+	errorLines := 0
+	for {
+		data, err := tr.NextContext(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var lerr *tsdata.LineError
+			if !errors.As(err, &lerr) {
+				// Headers have been sent, so the response can only be cut short
+				logger.FileError(name, err)
+				return http.StatusOK
+			}
+			errorLines++
+			continue
+		}
+		if err := write(data); err != nil {
+			return http.StatusOK
+		}
+	}
+	if cw != nil {
+		cw.Flush()
+	}
+	if err := bw.Flush(); err != nil {
+		return http.StatusOK
+	}
+	w.Header().Set("Tsdata-Error-Lines", strconv.Itoa(errorLines))
+	return http.StatusOK
+}
+
+func serveDescribe(ctx context.Context, w http.ResponseWriter, body io.Reader, name string, q url.Values) int {
+	opts, err := queryOptions(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return http.StatusBadRequest
+	}
+	tr, err := tsdata.NewReader(body, opts...)
+	if err != nil {
+		status := bodyStatus(err, http.StatusBadRequest)
+		if exitCode(err) == exitHeader {
+			status = http.StatusUnprocessableEntity
+		}
+		http.Error(w, err.Error(), status)
+		return status
+	}
+	tr.Strict = false
+	tr.Stats = tsdata.NewStats(tr.Tsdata.Schema())
+	for {
+		_, err := tr.NextContext(ctx)
+		if err == io.EOF {
+			break
+		}
+		var lerr *tsdata.LineError
+		if err != nil && !errors.As(err, &lerr) {
+			status := bodyStatus(err, http.StatusBadRequest)
+			http.Error(w, err.Error(), status)
+			return status
+		}
+	}
+	b, err := json.MarshalIndent(describeStats(tr.Stats), "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return http.StatusInternalServerError
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(b, '\n'))
+	return http.StatusOK
+}
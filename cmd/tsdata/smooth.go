@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var smoothCommand = cli.Command{
+	Name:      "smooth",
+	Usage:     "Smooths numeric columns with a rolling window",
+	UsageText: "tsdata smooth --window DURATION [--stat median|mean] INFILE OUTFILE",
+	Description: "Writes INFILE to OUTFILE with each value in float, integer, latitude, and longitude columns " +
+		"replaced by the median or mean of the values in a window of --window centered on its line. " +
+		"NA values are left as NA and are ignored in the window. Integer results are rounded. " +
+		"Only lines within one window are held in memory. INFILE must be sorted by time. " +
+		"Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.DurationFlag{
+			Name:  "window, w",
+			Usage: "Width of the centered window as a `DURATION`, e.g. 5m",
+		},
+		cli.StringFlag{
+			Name:  "stat",
+			Value: "median",
+			Usage: "Window statistic `STAT`, median or mean",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 2 {
+			err := usageErrorf("missing required OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 2 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		if c.Duration("window") <= 0 {
+			err := usageErrorf("--window must be a positive duration")
+			logger.Error(err)
+			return err
+		}
+		stat, ok := windowStats[c.String("stat")]
+		if !ok {
+			err := usageErrorf("bad stat '%v', expected median or mean", c.String("stat"))
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = smoothCmd(c.Args().Get(0), c.Args().Get(1), c.Duration("window"), stat, opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// windowStats are the statistics available to smooth, by name.
+var windowStats = map[string]func([]float64) float64{
+	"median": median,
+	"mean":   mean,
+}
+
+// median returns the median of v, reordering v.
+func median(v []float64) float64 {
+	sort.Float64s(v)
+	n := len(v)
+	if n%2 == 1 {
+		return v[n/2]
+	}
+	return (v[n/2-1] + v[n/2]) / 2
+}
+
+func mean(v []float64) float64 {
+	sum := 0.0
+	for _, x := range v {
+		sum += x
+	}
+	return sum / float64(len(v))
+}
+
+// formatNumber formats a computed value for a numeric column of type typ,
+// rounding values for integer columns.
+func formatNumber(typ string, v float64) string {
+	if typ == "integer" {
+		return strconv.FormatInt(int64(math.Round(v)), 10)
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// smoother replaces numeric values with a statistic of the values in a
+// centered time window. Lines are buffered until every line in their window
+// has been read.
+type smoother struct {
+	t     *tsdata.Tsdata
+	half  time.Duration // half the window width
+	stat  func([]float64) float64
+	cols  []int         // numeric columns
+	buf   []tsdata.Data // lines which may be in a pending line's window
+	next  int           // index in buf of the next line to write
+	write func([]string) error
+	vals  []float64 // scratch space for window values
+}
+
+func newSmoother(t *tsdata.Tsdata, window time.Duration, stat func([]float64) float64, write func([]string) error) *smoother {
+	s := &smoother{t: t, half: window / 2, stat: stat, write: write}
+	for i, typ := range t.Types {
+		switch typ {
+		case "float", "integer", "latitude", "longitude":
+			s.cols = append(s.cols, i)
+		}
+	}
+	return s
+}
+
+// add buffers a line and writes lines whose windows are complete.
+func (s *smoother) add(data tsdata.Data) error {
+	if len(s.buf) > 0 && data.Time.Before(s.buf[len(s.buf)-1].Time) {
+		return dataErrorf("line %v, timestamp earlier than previous line, INFILE must be sorted by time", data.Line)
+	}
+	s.buf = append(s.buf, data)
+	return s.flush(false)
+}
+
+// flush writes pending lines whose windows end before the last line read, or
+// all pending lines if all is true, then drops lines which are no longer in
+// any pending line's window.
+func (s *smoother) flush(all bool) error {
+	if len(s.buf) == 0 {
+		return nil
+	}
+	latest := s.buf[len(s.buf)-1].Time
+	for s.next < len(s.buf) && (all || latest.Sub(s.buf[s.next].Time) > s.half) {
+		if err := s.write(s.smooth(s.next)); err != nil {
+			return err
+		}
+		s.next++
+	}
+	// Later pending lines are no earlier than ref
+	ref := latest
+	if s.next < len(s.buf) {
+		ref = s.buf[s.next].Time
+	}
+	drop := 0
+	for drop < s.next && ref.Sub(s.buf[drop].Time) > s.half {
+		drop++
+	}
+	if drop > 0 {
+		n := copy(s.buf, s.buf[drop:])
+		s.buf = s.buf[:n]
+		s.next -= drop
+	}
+	return nil
+}
+
+// smooth returns the fields of buffered line i with numeric values replaced.
+func (s *smoother) smooth(i int) []string {
+	center := s.buf[i]
+	fields := make([]string, len(center.Fields))
+	copy(fields, center.Fields)
+	for _, col := range s.cols {
+		if center.Values[col] == nil {
+			continue
+		}
+		s.vals = s.vals[:0]
+		for _, d := range s.buf {
+			diff := d.Time.Sub(center.Time)
+			if diff < -s.half || diff > s.half {
+				continue
+			}
+			switch x := d.Values[col].(type) {
+			case float64:
+				s.vals = append(s.vals, x)
+			case int64:
+				s.vals = append(s.vals, float64(x))
+			}
+		}
+		fields[col] = formatNumber(s.t.Types[col], s.stat(s.vals))
+	}
+	return fields
+}
+
+func smoothCmd(infile string, outfile string, window time.Duration, stat func([]float64) float64, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+	_, err = w.WriteString(tr.Tsdata.Header() + "\n")
+	if err != nil {
+		return err
+	}
+
+	s := newSmoother(tr.Tsdata, window, stat, func(fields []string) error {
+		_, err := w.WriteString(strings.Join(fields, tsdata.Delim) + "\n")
+		return err
+	})
+	err = eachLine(tr, s.add)
+	if err != nil {
+		return err
+	}
+	err = s.flush(true)
+	if err != nil {
+		return err
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
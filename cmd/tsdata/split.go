@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var splitCommand = cli.Command{
+	Name:      "split",
+	Usage:     "Splits a TSDATA file by time interval or column value",
+	UsageText: "tsdata split [--by day|hour|month | --by-column NAME] [--gzip] INFILE OUTDIR",
+	Description: "Writes data lines in INFILE to one file per UTC time interval in OUTDIR, each with a copy of the header. " +
+		"Files are named by the start of the interval, e.g. 2020-01-01.tsdata for --by day, 2020-01-01T13.tsdata for --by hour, " +
+		"and 2020-01.tsdata for --by month. With --by-column, lines are split by the value of a category or text " +
+		"column, e.g. per station or cruise leg, and files are named by the value, e.g. ALOHA.tsdata, or NA.tsdata " +
+		"for NA values. Values which can't be file names, such as those containing a path separator, are errors, as " +
+		"are values which differ only in case, e.g. Leg1 and leg1, since they name one file on some file systems. " +
+		"With --gzip files are gzip compressed and end with .tsdata.gz. " +
+		"Existing files are overwritten. OUTDIR is created if it doesn't exist. Use '-' for STDIN.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "by",
+			Value: "day",
+			Usage: "Split by `INTERVAL`, one of day, hour, or month",
+		},
+		cli.StringFlag{
+			Name:  "by-column",
+			Usage: "Split by the values of category or text column `NAME`",
+		},
+		cli.BoolFlag{
+			Name:  "gzip, z",
+			Usage: "Compress output files with gzip",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE and OUTDIR arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 2 {
+			err := usageErrorf("missing required OUTDIR argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 2 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		if c.IsSet("by") && c.IsSet("by-column") {
+			err := usageErrorf("--by and --by-column can't be used together")
+			logger.Error(err)
+			return err
+		}
+		var layout string
+		switch c.String("by") {
+		case "hour":
+			layout = "2006-01-02T15"
+		case "day":
+			layout = "2006-01-02"
+		case "month":
+			layout = "2006-01"
+		default:
+			err := usageErrorf("bad --by value '%v', expected day, hour, or month", c.String("by"))
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		newKeyFn := func(*tsdata.Tsdata) (func(tsdata.Data) (string, error), error) {
+			return func(data tsdata.Data) (string, error) {
+				return data.Time.UTC().Format(layout), nil
+			}, nil
+		}
+		if name := c.String("by-column"); name != "" {
+			newKeyFn = func(t *tsdata.Tsdata) (func(tsdata.Data) (string, error), error) {
+				return columnSplitKey(t, name)
+			}
+		}
+		err = splitCmd(c.Args().Get(0), c.Args().Get(1), newKeyFn, c.Bool("gzip"), opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// splitWriter writes data lines to one of many files in a directory. Only one
+// file is open at a time. If gzip is true files are compressed, with each
+// reopening of a file appending a new gzip member.
+type splitWriter struct {
+	dir     string
+	header  string
+	gzip    bool
+	key     string
+	f       *os.File
+	zw      *gzip.Writer
+	w       *bufio.Writer
+	created map[string]string // lowercase key -> key of files created
+}
+
+// write appends fields to the file for key. Keys which differ only in case
+// are an error, since they name the same file on case-insensitive file
+// systems.
+func (s *splitWriter) write(key string, fields []string) error {
+	if s.f == nil || key != s.key {
+		prev, created := s.created[strings.ToLower(key)]
+		if created && prev != key {
+			return dataErrorf("split keys '%v' and '%v' differ only in case", prev, key)
+		}
+		err := s.close()
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(s.dir, key+".tsdata")
+		if s.gzip {
+			path += ".gz"
+		}
+		if created {
+			s.f, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		} else {
+			s.f, err = os.Create(path)
+		}
+		if err != nil {
+			return err
+		}
+		s.w = bufio.NewWriter(s.f)
+		if s.gzip {
+			s.zw = gzip.NewWriter(s.f)
+			s.w = bufio.NewWriter(s.zw)
+		}
+		if !created {
+			_, err = s.w.WriteString(s.header + "\n")
+			if err != nil {
+				return err
+			}
+			s.created[strings.ToLower(key)] = key
+		}
+		s.key = key
+	}
+	_, err := s.w.WriteString(strings.Join(fields, tsdata.Delim) + "\n")
+	return err
+}
+
+// close flushes and closes the current file, if any.
+func (s *splitWriter) close() error {
+	if s.f == nil {
+		return nil
+	}
+	err := s.w.Flush()
+	if err == nil && s.zw != nil {
+		err = s.zw.Close()
+	}
+	s.zw = nil
+	if err != nil {
+		s.f.Close()
+		s.f = nil
+		return err
+	}
+	err = s.f.Close()
+	s.f = nil
+	return err
+}
+
+// columnSplitKey returns a split key function for the values of column name
+// in t.
+func columnSplitKey(t *tsdata.Tsdata, name string) (func(tsdata.Data) (string, error), error) {
+	i := t.Index(name)
+	if i == -1 {
+		return nil, usageErrorf("no column '%v'", name)
+	}
+	if t.Types[i] != "category" && t.Types[i] != "text" {
+		return nil, usageErrorf("column '%v' has type %v, expected category or text", name, t.Types[i])
+	}
+	return func(data tsdata.Data) (string, error) {
+		v := data.Fields[i]
+		if v == "" || v == "." || v == ".." || strings.ContainsAny(v, `/\`+"\x00") {
+			return "", dataErrorf("column %v value '%v' can't be used as a file name", name, v)
+		}
+		return v, nil
+	}, nil
+}
+
+// splitCmd writes the data lines of infile to files in outdir named by the
+// key newKeyFn returns for each line. newKeyFn is called with the header of
+// infile to create the key function.
+func splitCmd(infile string, outdir string, newKeyFn func(*tsdata.Tsdata) (func(tsdata.Data) (string, error), error), gz bool, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+	keyFn, err := newKeyFn(tr.Tsdata)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(outdir, 0755)
+	if err != nil {
+		return err
+	}
+	sw := &splitWriter{dir: outdir, header: tr.Tsdata.Header(), gzip: gz, created: map[string]string{}}
+	defer sw.close()
+
+	err = eachLine(tr, func(data tsdata.Data) error {
+		key, err := keyFn(data)
+		if err != nil {
+			return fmt.Errorf("line %v, %w", tr.Line(), err)
+		}
+		return sw.write(key, data.Fields)
+	})
+	if err != nil {
+		return err
+	}
+	return sw.close()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var subtractCommand = cli.Command{
+	Name:      "subtract",
+	Usage:     "Writes lines of a file whose timestamps aren't in another file",
+	UsageText: "tsdata subtract [--tolerance DURATION] A B OUTFILE",
+	Description: "Performs an anti-join of A and B and writes the lines of A with no line of B within --tolerance " +
+		"of their timestamp to OUTFILE, with the header of A. This finds lines lost in a processing step, for " +
+		"example. Only timestamps are compared, so A and B may have different columns. Both inputs must be sorted " +
+		"by time. Lines which fail validation are logged and skipped. Use '-' for STDOUT.",
+	Flags: []cli.Flag{
+		cli.DurationFlag{
+			Name:  "tolerance, t",
+			Usage: "Treat timestamps within `DURATION` of each other as the same, e.g. 1s. Default is exact matches only.",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() < 3 {
+			err := usageErrorf("expected A, B, and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 3 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		if c.Duration("tolerance") < 0 {
+			err := usageErrorf("--tolerance must not be negative")
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		args := c.Args()
+		err = subtractCmd(args[0], args[1], args[2], c.Duration("tolerance"), opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+func subtractCmd(a string, b string, outfile string, tolerance time.Duration, opts []tsdata.Option) error {
+	ar, err := openInput(a)
+	if err != nil {
+		return err
+	}
+	defer ar.Close()
+	atr, err := tsdata.NewReader(ar, opts...)
+	if err != nil {
+		return fmt.Errorf("%v: %w", a, err)
+	}
+	atr.Strict = false
+	br, err := openInput(b)
+	if err != nil {
+		return err
+	}
+	defer br.Close()
+	btr, err := tsdata.NewReader(br, opts...)
+	if err != nil {
+		return fmt.Errorf("%v: %w", b, err)
+	}
+	btr.Strict = false
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	w := bufio.NewWriter(outf)
+	_, err = w.WriteString(atr.Tsdata.Header() + "\n")
+	if err != nil {
+		return err
+	}
+
+	src := &joinSource{name: b, tr: btr}
+	if err := src.advance(); err != nil {
+		return err
+	}
+	var last time.Time
+	lines, missing := 0, 0
+	err = eachFileLine(a, atr, func(data tsdata.Data) error {
+		if !last.IsZero() && data.Time.Before(last) {
+			return dataErrorf("%v: line %v, input is not sorted by time", a, atr.Line())
+		}
+		last = data.Time
+		lines++
+		_, ok, err := src.nearest(data.Time, tolerance)
+		if err != nil || ok {
+			return err
+		}
+		missing++
+		_, err = w.WriteString(strings.Join(data.Fields, tsdata.Delim) + "\n")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	logger.Printf("%v of %v lines not in %v\n", missing, lines, b)
+
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+)
+
+// timeFormatter formats output timestamps.
+type timeFormatter func(time.Time) string
+
+// parseTimeLayout returns a timeFormatter for an output time format: epoch
+// seconds, epoch_ms milliseconds, or a Go time layout. It returns nil for
+// rfc3339, which leaves timestamps in their standard TSDATA form.
+func parseTimeLayout(spec string) (timeFormatter, error) {
+	switch spec {
+	case "", "rfc3339":
+		return nil, nil
+	case "epoch":
+		return func(t time.Time) string { return formatEpoch(t.UnixNano(), int64(time.Second)) }, nil
+	case "epoch_ms":
+		return func(t time.Time) string { return formatEpoch(t.UnixNano(), int64(time.Millisecond)) }, nil
+	}
+	if time.Unix(0, 0).Format(spec) == spec {
+		return nil, usageErrorf("bad time format '%v', expected rfc3339, epoch, epoch_ms, or a layout such as '2006-01-02 15:04:05'", spec)
+	}
+	return func(t time.Time) string { return t.Format(spec) }, nil
+}
+
+// formatEpoch formats ns nanoseconds as a decimal count of unit nanoseconds,
+// with only as many fractional digits as needed.
+func formatEpoch(ns int64, unit int64) string {
+	sign := ""
+	if ns < 0 {
+		sign = "-"
+		ns = -ns
+	}
+	s := fmt.Sprintf("%v%v", sign, ns/unit)
+	if frac := ns % unit; frac != 0 {
+		digits := len(fmt.Sprint(unit)) - 1
+		s += "." + strings.TrimRight(fmt.Sprintf("%0*d", digits, frac), "0")
+	}
+	return s
+}
+
+// formatTimes returns the fields of data with timestamps in time columns of t
+// formatted by f.
+func formatTimes(t *tsdata.Tsdata, data tsdata.Data, f timeFormatter) []string {
+	if f == nil {
+		return data.Fields
+	}
+	fields := make([]string, len(data.Fields))
+	copy(fields, data.Fields)
+	for i, v := range data.Values {
+		if tm, ok := v.(time.Time); ok && t.Types[i] == "time" {
+			fields[i] = f(tm)
+		}
+	}
+	return fields
+}
+
+// timeParser parses input timestamps.
+type timeParser func(string) (time.Time, error)
+
+// parseInputTimeLayout returns a timeParser for an input time format: rfc3339,
+// epoch seconds, epoch_ms milliseconds, or a Go time layout. Timestamps
+// parsed with a layout without a UTC offset are in zone, which is UTC, Local,
+// an IANA time zone name such as America/Los_Angeles, or an offset such as
+// -08:00.
+func parseInputTimeLayout(spec string, zone string) (timeParser, error) {
+	loc := time.UTC
+	switch {
+	case zone == "" || zone == "UTC":
+	case strings.HasPrefix(zone, "+") || strings.HasPrefix(zone, "-"):
+		t, err := time.Parse("-07:00", zone)
+		if err != nil {
+			return nil, usageErrorf("bad time zone '%v', expected a name such as America/Los_Angeles or an offset such as -08:00", zone)
+		}
+		_, offset := t.Zone()
+		loc = time.FixedZone(zone, offset)
+	default:
+		var err error
+		loc, err = time.LoadLocation(zone)
+		if err != nil {
+			return nil, usageErrorf("bad time zone '%v': %v", zone, err)
+		}
+	}
+
+	switch spec {
+	case "", "rfc3339":
+		return tsdata.ParseTime, nil
+	case "epoch", "epoch_ms":
+		f, _ := tsdata.ParseTimeFormat(spec)
+		return tsdata.New(tsdata.WithTimeFormat(f)).ParseTime, nil
+	}
+	if time.Unix(0, 0).Format(spec) == spec {
+		return nil, usageErrorf("bad time layout '%v', expected rfc3339, epoch, epoch_ms, or a layout such as '01/02/2006 15:04:05'", spec)
+	}
+	return func(s string) (time.Time, error) {
+		return time.ParseInLocation(spec, s, loc)
+	}, nil
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var timeshiftCommand = cli.Command{
+	Name:      "timeshift",
+	Usage:     "Shifts the timestamps of a TSDATA file",
+	UsageText: "tsdata timeshift --offset DURATION [--all-time-columns] INFILE OUTFILE",
+	Description: "Writes INFILE to OUTFILE with DURATION added to every timestamp in the first time column, e.g. " +
+		"to correct a logger configured in the wrong time zone or with a known clock error. DURATION is a Go " +
+		"duration such as -2h30m or 1.5s. With --all-time-columns every time column is shifted. Timestamps keep " +
+		"their UTC offset. NA values and fields which aren't timestamps are copied as they are, with a warning for " +
+		"bad timestamps. Data lines are copied in one pass without other validation. " +
+		"Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "offset",
+			Usage: "Add `DURATION` to each timestamp, e.g. -2h30m",
+		},
+		cli.BoolFlag{
+			Name:  "all-time-columns",
+			Usage: "Shift every time column, not just the first",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 2 {
+			err := usageErrorf("missing required OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 2 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.String("offset") == "" {
+			err := usageErrorf("missing required --offset")
+			logger.Error(err)
+			return err
+		}
+		offset, err := time.ParseDuration(c.String("offset"))
+		if err != nil {
+			err := usageErrorf("bad --offset '%v', expected a duration such as -2h30m", c.String("offset"))
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		shift := func(_ time.Time) time.Duration { return offset }
+		err = shiftTimesCmd(c.Args().Get(0), c.Args().Get(1), c.Bool("all-time-columns"), shift)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+var driftCorrectCommand = cli.Command{
+	Name:      "driftcorrect",
+	Usage:     "Corrects linear clock drift in a TSDATA file",
+	UsageText: "tsdata driftcorrect --end-offset DURATION [--start-offset DURATION] [--start TIME] [--end TIME] [--all-time-columns] INFILE OUTFILE",
+	Description: "Writes INFILE to OUTFILE with timestamps corrected for a clock which drifted linearly, e.g. over " +
+		"a multi-week deployment. --start-offset is added at --start and --end-offset at --end, and the offset " +
+		"for other timestamps is interpolated between them, or extrapolated before --start and after --end. " +
+		"--start and --end are RFC3339 times on the instrument's clock, such as when it was last synchronized and " +
+		"when its error was measured, and default to the first and last timestamps of INFILE, which is then read " +
+		"twice. Offsets are Go durations such as 95s or -1m30s. With --all-time-columns every time column is " +
+		"corrected, not just the first. " +
+		"Use '-' for STDOUT, or for STDIN if --start and --end are given.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "start-offset",
+			Value: "0s",
+			Usage: "Add `DURATION` to timestamps at --start",
+		},
+		cli.StringFlag{
+			Name:  "end-offset",
+			Usage: "Add `DURATION` to timestamps at --end",
+		},
+		cli.StringFlag{
+			Name:  "start",
+			Usage: "`TIME` of --start-offset, the first timestamp by default",
+		},
+		cli.StringFlag{
+			Name:  "end",
+			Usage: "`TIME` of --end-offset, the last timestamp by default",
+		},
+		cli.BoolFlag{
+			Name:  "all-time-columns",
+			Usage: "Correct every time column, not just the first",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 2 {
+			err := usageErrorf("missing required OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 2 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.String("end-offset") == "" {
+			err := usageErrorf("missing required --end-offset")
+			logger.Error(err)
+			return err
+		}
+		startOffset, err := time.ParseDuration(c.String("start-offset"))
+		if err != nil {
+			err := usageErrorf("bad --start-offset '%v', expected a duration such as 95s", c.String("start-offset"))
+			logger.Error(err)
+			return err
+		}
+		endOffset, err := time.ParseDuration(c.String("end-offset"))
+		if err != nil {
+			err := usageErrorf("bad --end-offset '%v', expected a duration such as 95s", c.String("end-offset"))
+			logger.Error(err)
+			return err
+		}
+		d := drift{startOffset: startOffset, endOffset: endOffset}
+		if d.start, err = timeFlag(c, "start"); err == nil {
+			d.end, err = timeFlag(c, "end")
+		}
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		infile := c.Args().Get(0)
+		if infile == "-" && (d.start.IsZero() || d.end.IsZero()) {
+			err := usageErrorf("--start and --end are required to read STDIN")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		err = driftCorrectCmd(infile, c.Args().Get(1), c.Bool("all-time-columns"), d)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// drift is a clock error which changes linearly from startOffset at start to
+// endOffset at end.
+type drift struct {
+	start       time.Time
+	end         time.Time
+	startOffset time.Duration
+	endOffset   time.Duration
+}
+
+// offset returns the correction for timestamp t.
+func (d drift) offset(t time.Time) time.Duration {
+	frac := float64(t.Sub(d.start)) / float64(d.end.Sub(d.start))
+	return d.startOffset + time.Duration(math.Round(frac*float64(d.endOffset-d.startOffset)))
+}
+
+func driftCorrectCmd(infile string, outfile string, all bool, d drift) error {
+	if d.start.IsZero() || d.end.IsZero() {
+		first, last, err := timeRange(infile)
+		if err != nil {
+			return err
+		}
+		if d.start.IsZero() {
+			d.start = first
+		}
+		if d.end.IsZero() {
+			d.end = last
+		}
+	}
+	if !d.end.After(d.start) {
+		return usageErrorf("drift end %v is not after start %v", d.end.Format(time.RFC3339Nano), d.start.Format(time.RFC3339Nano))
+	}
+	logger.Printf("correcting drift of %v at %v to %v at %v\n", d.startOffset, d.start.Format(time.RFC3339Nano), d.endOffset, d.end.Format(time.RFC3339Nano))
+	return shiftTimesCmd(infile, outfile, all, d.offset)
+}
+
+// timeRange returns the first and last valid timestamps in the first column
+// of infile, reading data lines without other validation.
+func timeRange(infile string) (first time.Time, last time.Time, err error) {
+	r, err := openInput(infile)
+	if err != nil {
+		return first, last, err
+	}
+	defer r.Close()
+	tr, err := tsdata.NewReader(r)
+	if err != nil {
+		return first, last, fmt.Errorf("%v: %w", infile, err)
+	}
+	delim := tr.Tsdata.Delimiter()
+	for {
+		line, err := tr.NextRaw()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return first, last, err
+		}
+		v := line
+		if i := strings.Index(line, delim); i >= 0 {
+			v = line[:i]
+		}
+		t, err := tsdata.ParseTime(strings.TrimSpace(v))
+		if err != nil {
+			continue
+		}
+		if first.IsZero() {
+			first = t
+		}
+		last = t
+	}
+	if first.IsZero() {
+		return first, last, dataErrorf("%v: no valid timestamps", infile)
+	}
+	return first, last, nil
+}
+
+// shiftTimesCmd writes INFILE to OUTFILE with the timestamps in the first
+// time column, or every time column if all is true, moved by the duration
+// shift returns for each timestamp.
+func shiftTimesCmd(infile string, outfile string, all bool, shift func(time.Time) time.Duration) error {
+	cols := []int{0}
+	editHeader := func(s tsdata.Schema) (tsdata.Schema, error) {
+		if all {
+			cols = nil
+			for i, typ := range s.Types {
+				if typ == "time" {
+					cols = append(cols, i)
+				}
+			}
+		}
+		return s, nil
+	}
+	return editColumns(infile, outfile, editHeader, func(line int, fields []string) []string {
+		for _, i := range cols {
+			v := strings.TrimSpace(fields[i])
+			if v == tsdata.NA && i > 0 {
+				continue
+			}
+			t, err := tsdata.ParseTime(v)
+			if err != nil {
+				logger.Warnf(line, "column %v, bad timestamp '%v' not shifted", i+1, fields[i])
+				continue
+			}
+			fields[i] = t.Add(shift(t)).Format(time.RFC3339Nano)
+		}
+		return fields
+	})
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/ctberthiaume/tsdata"
+)
+
+// errDropLine is returned by commandTransformer.Transform for lines the
+// command dropped.
+var errDropLine = errors.New("dropped by transform command")
+
+// commandTransformer is a tsdata.LineTransformer which passes data lines
+// through an external command. The command is sent the header section of the
+// file on STDIN, then one data line at a time, and must write and flush
+// exactly one line to STDOUT for each data line it reads: the transformed
+// line, or an empty line to drop it. Transformed lines are validated against
+// the file's header.
+type commandTransformer struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	w     *bufio.Writer
+	out   *bufio.Scanner
+	check *tsdata.Tsdata // validates transformed lines
+}
+
+// startTransform starts command with sh and sends it the header of t. opts
+// configure validation of transformed lines.
+func startTransform(command string, t *tsdata.Tsdata, opts []tsdata.Option) (*commandTransformer, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("transform command: %w", err)
+	}
+	ct := &commandTransformer{
+		cmd:   cmd,
+		stdin: stdin,
+		w:     bufio.NewWriter(stdin),
+		out:   bufio.NewScanner(stdout),
+		check: tsdata.New(append([]tsdata.Option{tsdata.WithSchema(t.Schema())}, opts...)...),
+	}
+	ct.w.WriteString(t.Header() + "\n")
+	return ct, nil
+}
+
+// Transform sends data to the command and returns the validated line it
+// writes back. Transformed lines which fail validation are returned as a
+// *tsdata.LineError.
+func (ct *commandTransformer) Transform(t *tsdata.Tsdata, data tsdata.Data) (tsdata.Data, error) {
+	ct.w.WriteString(strings.Join(data.Fields, tsdata.Delim) + "\n")
+	if err := ct.w.Flush(); err != nil {
+		return tsdata.Data{}, fmt.Errorf("transform command: %w", err)
+	}
+	if !ct.out.Scan() {
+		err := ct.out.Err()
+		if err == nil {
+			err = errors.New("exited or closed STDOUT before writing a line")
+		}
+		return tsdata.Data{}, fmt.Errorf("transform command: %w", err)
+	}
+	line := ct.out.Text()
+	if line == "" {
+		return tsdata.Data{}, errDropLine
+	}
+	out, err := ct.check.ValidateLine(line, true)
+	if err != nil {
+		return tsdata.Data{}, &tsdata.LineError{Line: data.Line, Err: fmt.Errorf("transformed line, %w", err)}
+	}
+	out.Line = data.Line
+	return out, nil
+}
+
+// wrap returns a function which transforms lines read from tr before passing
+// them to fn. Dropped lines are skipped. Transformed lines which fail
+// validation are returned as errors if strict is true, otherwise they are
+// logged and skipped.
+func (ct *commandTransformer) wrap(tr *tsdata.Reader, strict bool, fn func(tsdata.Data) error) func(tsdata.Data) error {
+	return func(data tsdata.Data) error {
+		out, err := ct.Transform(tr.Tsdata, data)
+		var lerr *tsdata.LineError
+		switch {
+		case err == errDropLine:
+			return nil
+		case errors.As(err, &lerr):
+			lerr.Line = tr.Line()
+			if strict {
+				return lerr
+			}
+			logger.Error(lerr)
+			return nil
+		case err != nil:
+			return err
+		}
+		return fn(out)
+	}
+}
+
+// close closes the command's STDIN and waits for it to exit.
+func (ct *commandTransformer) close() error {
+	ct.stdin.Close()
+	if err := ct.cmd.Wait(); err != nil {
+		return fmt.Errorf("transform command: %w", err)
+	}
+	return nil
+}
+
+// kill stops the command if it hasn't exited, for use with defer alongside a
+// checked close.
+func (ct *commandTransformer) kill() {
+	if ct.cmd.ProcessState == nil {
+		ct.cmd.Process.Kill()
+		ct.cmd.Wait()
+	}
+}
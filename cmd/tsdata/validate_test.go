@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestValidateReader_sequentialMatchesConcurrent(t *testing.T) {
+	logger = newLogger(ioutil.Discard)
+	header := "a\nb\nc\nNA\tNA\ntime\tfloat\nNA\tNA\ntime\tx\n"
+	tests := []struct {
+		name  string
+		input string
+		conf  validateConfig
+	}{
+		{"valid", header + "2020-01-01T00:00:00Z\t1\n", validateConfig{}},
+		{"bad line", header + "2020-01-01T00:00:00Z\tx\n", validateConfig{}},
+		{"line too long", header + "2020-01-01T00:00:00Z\tx\n2020-01-01T00:00:01Z\t" + strings.Repeat("1", 70000) + "\n", validateConfig{}},
+		{"max errors", header + "2020-01-01T00:00:00Z\tx\n2020-01-01T00:00:01Z\ty\n2020-01-01T00:00:02Z\tz\n", validateConfig{maxErrors: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs []error
+			for _, workers := range []int{1, 4} {
+				conf := tt.conf
+				conf.workers = workers
+				conf.format = "json"
+				err := validateReader(context.Background(), strings.NewReader(tt.input), "test", conf, ioutil.Discard, nil)
+				errs = append(errs, err)
+			}
+			if exitCode(errs[0]) != exitCode(errs[1]) {
+				t.Errorf("validateReader() exit code %v with 1 worker, %v with 4 (errors %v, %v)", exitCode(errs[0]), exitCode(errs[1]), errs[0], errs[1])
+			}
+			if (errs[0] == nil) != (errs[1] == nil) || (errs[0] != nil && errs[0].Error() != errs[1].Error()) {
+				t.Errorf("validateReader() err %v with 1 worker, %v with 4", errs[0], errs[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/urfave/cli"
+)
+
+var viewCommand = cli.Command{
+	Name:      "view",
+	Usage:     "Shows a TSDATA file in a web browser",
+	UsageText: "tsdata view [--listen ADDR] INFILE",
+	Description: "Reads INFILE into memory and serves a page on a local web server with the header metadata, " +
+		"a plot of each numeric column, and data lines split into pages. Lines which fail " +
+		"validation are highlighted. Runs until interrupted.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "listen, l",
+			Value: "127.0.0.1:8000",
+			Usage: "Listen on `ADDR`",
+		},
+		cli.IntFlag{
+			Name:  "page-size",
+			Value: 100,
+			Usage: "Show `N` data lines per page",
+		},
+		cli.StringFlag{
+			Name:  "constraints",
+			Usage: "Check column values against constraints in JSON `FILE`, an object keyed by column name",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 1 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		if c.Int("page-size") < 1 {
+			err := usageErrorf("--page-size must be at least 1")
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = viewCmd(c.Args().Get(0), c.String("listen"), c.Int("page-size"), opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// viewPlotWidth is the number of time bins in view plots.
+const viewPlotWidth = 600
+
+// viewPlotHeight is the height of view plots in SVG units.
+const viewPlotHeight = 120
+
+// viewFile is the contents of a file shown by view.
+type viewFile struct {
+	Name       string
+	Meta       tsdata.Schema
+	Rows       []viewRow
+	ErrorLines int
+	First      string
+	Last       string
+	Plots      []viewPlot
+}
+
+// viewRow is one data line.
+type viewRow struct {
+	Line   int
+	Fields []string
+	Err    string // validation error, empty for a valid line
+}
+
+// viewPlot is an SVG plot of one numeric column.
+type viewPlot struct {
+	Name  string
+	Units string
+	Path  string // SVG path data
+	Min   string
+	Max   string
+	First string
+	Last  string
+}
+
+// viewPage is the template data for one page of rows.
+type viewPage struct {
+	*viewFile
+	PageRows []viewRow
+	Page     int
+	Pages    int
+	FirstRow int
+	LastRow  int
+}
+
+func viewCmd(infile string, addr string, pageSize int, opts []tsdata.Option) error {
+	vf, err := readViewFile(infile, opts)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/" {
+			http.NotFound(w, req)
+			return
+		}
+		page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := writeViewPage(w, vf, page, pageSize); err != nil {
+			logger.Error(err)
+		}
+	})
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: mux}
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(done)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(lis)
+	}()
+	logger.Printf("viewing %v at http://%v/\n", infile, lis.Addr())
+
+	select {
+	case err := <-errc:
+		return err
+	case <-done:
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+// readViewFile reads all lines of infile and creates plots for numeric
+// columns.
+func readViewFile(infile string, opts []tsdata.Option) (*viewFile, error) {
+	r, err := openInput(infile)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", infile, err)
+	}
+	t := tr.Tsdata
+	vf := &viewFile{Name: infile, Meta: t.Schema(), First: tsdata.NA, Last: tsdata.NA}
+
+	var times []time.Time
+	values := make([][]float64, len(t.Headers))
+	for {
+		line, err := tr.NextRaw()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", infile, err)
+		}
+		data, err := t.ValidateLine(line, false)
+		if err != nil {
+			vf.ErrorLines++
+			vf.Rows = append(vf.Rows, viewRow{Line: tr.Line(), Fields: strings.Split(line, t.Delimiter()), Err: err.Error()})
+			continue
+		}
+		vf.Rows = append(vf.Rows, viewRow{Line: tr.Line(), Fields: data.Fields})
+		times = append(times, data.Time)
+		for i := range t.Headers {
+			v, ok := plotValue(data.Values[i])
+			if !ok {
+				v = math.NaN()
+			}
+			values[i] = append(values[i], v)
+		}
+	}
+
+	if len(times) > 0 {
+		vf.First = formatReportTime(times[0])
+		vf.Last = formatReportTime(times[len(times)-1])
+	}
+	for i, typ := range t.Types {
+		if !numericType(typ) {
+			continue
+		}
+		if p, ok := newViewPlot(t.Headers[i], t.Units[i], times, values[i]); ok {
+			vf.Plots = append(vf.Plots, p)
+		}
+	}
+	return vf, nil
+}
+
+// newViewPlot creates a line plot of the time bin means of values. NaN values
+// and empty bins are skipped. ok is false if there are no values to plot.
+func newViewPlot(name string, units string, times []time.Time, values []float64) (p viewPlot, ok bool) {
+	var ts []time.Time
+	var vs []float64
+	for j, v := range values {
+		if !math.IsNaN(v) {
+			ts = append(ts, times[j])
+			vs = append(vs, v)
+		}
+	}
+	if len(vs) == 0 {
+		return viewPlot{}, false
+	}
+	bins := binByTime(ts, vs, viewPlotWidth)
+	var sb strings.Builder
+	cmd := "M"
+	for b, mean := range bins.means {
+		if bins.counts[b] == 0 {
+			continue
+		}
+		y := float64(viewPlotHeight) / 2
+		if bins.max > bins.min {
+			y = float64(viewPlotHeight) * (1 - (mean-bins.min)/(bins.max-bins.min))
+		}
+		fmt.Fprintf(&sb, "%v%v %.1f ", cmd, b, y)
+		cmd = "L"
+	}
+	return viewPlot{
+		Name:  name,
+		Units: units,
+		Path:  strings.TrimSpace(sb.String()),
+		Min:   formatStat(bins.min),
+		Max:   formatStat(bins.max),
+		First: formatReportTime(bins.first),
+		Last:  formatReportTime(bins.last),
+	}, true
+}
+
+// writeViewPage writes the page of rows numbered page, starting at 1. Out of
+// range page numbers are clamped.
+func writeViewPage(w io.Writer, vf *viewFile, page int, pageSize int) error {
+	pages := (len(vf.Rows) + pageSize - 1) / pageSize
+	if pages == 0 {
+		pages = 1
+	}
+	if page < 1 {
+		page = 1
+	}
+	if page > pages {
+		page = pages
+	}
+	start := (page - 1) * pageSize
+	end := start + pageSize
+	if end > len(vf.Rows) {
+		end = len(vf.Rows)
+	}
+	return viewTemplate.Execute(w, viewPage{
+		viewFile: vf,
+		PageRows: vf.Rows[start:end],
+		Page:     page,
+		Pages:    pages,
+		FirstRow: start + 1,
+		LastRow:  end,
+	})
+}
+
+var viewTemplate = template.Must(template.New("view").Funcs(template.FuncMap{
+	"add": func(a, b int) int { return a + b },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+body { font-family: sans-serif; margin: 1em 2em; }
+table { border-collapse: collapse; font-size: 90%; }
+th, td { border: 1px solid #ccc; padding: 2px 6px; text-align: left; white-space: nowrap; }
+tr.bad { background: #fdd; }
+.plot { margin-bottom: 1em; }
+.plot svg { width: 600px; height: 120px; border: 1px solid #ccc; }
+.plot path { fill: none; stroke: #2a6fb0; stroke-width: 1; vector-effect: non-scaling-stroke; }
+.range { font-size: 80%; color: #555; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<table>
+<tr><th>fileType</th><td>{{.Meta.FileType}}</td></tr>
+<tr><th>project</th><td>{{.Meta.Project}}</td></tr>
+<tr><th>description</th><td>{{.Meta.FileDescription}}</td></tr>
+<tr><th>data lines</th><td>{{len .Rows}}</td></tr>
+<tr><th>error lines</th><td>{{.ErrorLines}}</td></tr>
+<tr><th>time range</th><td>{{.First}} to {{.Last}}</td></tr>
+</table>
+
+<h2>Columns</h2>
+<table>
+<tr><th>name</th><th>type</th><th>units</th><th>comment</th></tr>
+{{range $i, $h := .Meta.Headers}}<tr><td>{{$h}}</td><td>{{index $.Meta.Types $i}}</td><td>{{index $.Meta.Units $i}}</td><td>{{index $.Meta.Comments $i}}</td></tr>
+{{end}}</table>
+
+{{if .Plots}}<h2>Plots</h2>
+{{range .Plots}}<div class="plot">
+<div>{{.Name}}{{if ne .Units "NA"}} ({{.Units}}){{end}}</div>
+<svg viewBox="0 0 600 120" preserveAspectRatio="none"><path d="{{.Path}}"/></svg>
+<div class="range">min {{.Min}}, max {{.Max}}, {{.First}} to {{.Last}}</div>
+</div>
+{{end}}{{end}}
+<h2>Data lines</h2>
+<p>{{if .PageRows}}Lines {{.FirstRow}} to {{.LastRow}} of{{else}}No lines of{{end}} {{len .Rows}}, page {{.Page}} of {{.Pages}}
+{{if gt .Page 1}}<a href="?page=1">first</a> <a href="?page={{add .Page -1}}">previous</a>{{end}}
+{{if lt .Page .Pages}}<a href="?page={{add .Page 1}}">next</a> <a href="?page={{.Pages}}">last</a>{{end}}</p>
+<table>
+<tr><th>line</th>{{range .Meta.Headers}}<th>{{.}}</th>{{end}}</tr>
+{{range .PageRows}}<tr{{if .Err}} class="bad" title="{{.Err}}"{{end}}><td>{{.Line}}</td>{{range .Fields}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
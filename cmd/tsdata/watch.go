@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/fsnotify/fsnotify"
+	"github.com/urfave/cli"
+)
+
+var watchCommand = cli.Command{
+	Name:      "watch",
+	Usage:     "Validates and routes files as they arrive in a directory",
+	UsageText: "tsdata watch [--on-valid mv-to:DIR2] [--on-invalid mv-to:DIR3] DIR",
+	Description: "Watches DIR for new files, validates each file once it has not been written to for the " +
+		"settle time, then applies the --on-valid or --on-invalid action. Actions are none to leave the file " +
+		"in place or mv-to:DIR to move the file into DIR, which must be on the same file system. Files already " +
+		"in DIR when watching starts are processed too. Hidden files and subdirectories are ignored. " +
+		"Runs until interrupted.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "on-valid",
+			Value: "none",
+			Usage: "`ACTION` for files which pass validation, none or mv-to:DIR",
+		},
+		cli.StringFlag{
+			Name:  "on-invalid",
+			Value: "none",
+			Usage: "`ACTION` for files which fail validation, none or mv-to:DIR",
+		},
+		cli.DurationFlag{
+			Name:  "settle",
+			Value: 2 * time.Second,
+			Usage: "Treat a file as closed after no writes for `DURATION`",
+		},
+		cli.StringFlag{
+			Name:  "check-order",
+			Value: "off",
+			Usage: "Check that timestamps don't decrease, `MODE` is off, warn, or strict",
+		},
+		cli.StringFlag{
+			Name:  "constraints",
+			Usage: "Check column values against constraints in JSON `FILE`, an object keyed by column name",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required DIR argument")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() > 1 {
+			err := usageErrorf("too many arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		onValid, err := parseWatchAction(c.String("on-valid"))
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		onInvalid, err := parseWatchAction(c.String("on-invalid"))
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = watchCmd(c.Args().Get(0), onValid, onInvalid, c.Duration("settle"), opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+// watchAction is what to do with a file after validation. A file is left in
+// place when dir is empty, otherwise it's moved to dir.
+type watchAction struct {
+	dir string
+}
+
+func parseWatchAction(s string) (watchAction, error) {
+	if s == "none" {
+		return watchAction{}, nil
+	}
+	if strings.HasPrefix(s, "mv-to:") && len(s) > len("mv-to:") {
+		dir := s[len("mv-to:"):]
+		fi, err := os.Stat(dir)
+		if err != nil {
+			return watchAction{}, usageErrorf("bad action '%v': %v", s, err)
+		}
+		if !fi.IsDir() {
+			return watchAction{}, usageErrorf("bad action '%v': %v is not a directory", s, dir)
+		}
+		return watchAction{dir: dir}, nil
+	}
+	return watchAction{}, usageErrorf("bad action '%v', expected none or mv-to:DIR", s)
+}
+
+func (a watchAction) apply(path string) error {
+	if a.dir == "" {
+		return nil
+	}
+	dst := filepath.Join(a.dir, filepath.Base(path))
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("can't move to %v, file exists", dst)
+	}
+	if err := os.Rename(path, dst); err != nil {
+		return err
+	}
+	logger.Printf("moved to %v\n", dst)
+	return nil
+}
+
+func watchCmd(dir string, onValid watchAction, onInvalid watchAction, settle time.Duration, opts []tsdata.Option) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err := w.Add(dir); err != nil {
+		return fmt.Errorf("%v: %w", dir, err)
+	}
+
+	// Time of the last write to each file which hasn't been processed yet.
+	// Files already present are processed on the first tick.
+	pending := map[string]time.Time{}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, fi := range entries {
+		if fi.Mode().IsRegular() && !isHidden(fi.Name()) {
+			pending[filepath.Join(dir, fi.Name())] = time.Time{}
+		}
+	}
+
+	// Cancelled on interrupt, which also stops a validation in progress
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+
+	logger.Printf("watching %v\n", dir)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-w.Errors:
+			logger.Error(err)
+		case ev := <-w.Events:
+			if isHidden(filepath.Base(ev.Name)) {
+				continue
+			}
+			if ev.Op&(fsnotify.Create|fsnotify.Write) != 0 {
+				pending[ev.Name] = time.Now()
+			} else if ev.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				delete(pending, ev.Name)
+			}
+		case now := <-ticker.C:
+			for path, last := range pending {
+				if now.Sub(last) < settle {
+					continue
+				}
+				delete(pending, path)
+				watchFile(ctx, path, onValid, onInvalid, opts)
+			}
+		}
+	}
+}
+
+// watchFile validates and routes one file. Errors are logged with the file
+// name. Files which can't be read, or whose validation is interrupted by ctx,
+// are left in place.
+func watchFile(ctx context.Context, path string, onValid watchAction, onInvalid watchAction, opts []tsdata.Option) {
+	logger.SetFile(path)
+	defer logger.SetFile("")
+
+	fi, err := os.Stat(path)
+	if err != nil || !fi.Mode().IsRegular() {
+		return
+	}
+	err = validateCmd(ctx, path, validateConfig{format: "text"}, opts)
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil && exitCode(err) == exitIO {
+		logger.Error(err)
+		return
+	}
+	action := onValid
+	if err != nil {
+		logger.Error(err)
+		action = onInvalid
+	} else {
+		logger.Println("valid")
+	}
+	if err := action.apply(path); err != nil {
+		logger.Error(err)
+	}
+}
+
+// isHidden returns true for dot files, which are often temporary files being
+// written before a rename.
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
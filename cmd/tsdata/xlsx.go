@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+
+	"github.com/ctberthiaume/tsdata"
+	"github.com/ctberthiaume/tsdata/internal/xlsx"
+	"github.com/urfave/cli"
+)
+
+var xlsxCommand = cli.Command{
+	Name:      "xlsx",
+	Usage:     "Converts a TSDATA file to an Excel workbook",
+	UsageText: "tsdata xlsx INFILE OUTFILE",
+	Description: "Validates and converts a TSDATA file at INFILE to an Excel workbook at OUTFILE. " +
+		"The 'metadata' sheet lists the fileType, project, and description, and a table of column names, types, " +
+		"units, and comments. The 'data' sheet has a row of column names followed by data lines. " +
+		"Time columns become date cells in UTC, float, integer, latitude, and longitude become numbers, " +
+		"boolean becomes TRUE or FALSE, and NA values are empty cells. A worksheet holds at most 1048575 data lines. " +
+		"Use '-' for STDIN and STDOUT.",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Suppress logging output",
+		},
+		cli.StringSliceFlag{
+			Name:  "na",
+			Usage: "Treat `TOKEN` as NA in data columns, may be repeated (e.g. --na NaN --na -999)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			err := usageErrorf("missing required INFILE and OUTFILE arguments")
+			logger.Error(err)
+			return err
+		}
+		if c.NArg() < 2 {
+			err := usageErrorf("missing required OUTFILE argument")
+			logger.Error(err)
+			return err
+		}
+		if c.Bool("quiet") {
+			logger.SetOutput(ioutil.Discard)
+		}
+		opts, err := readerOptions(c)
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+		err = xlsxCmd(c.Args().Get(0), c.Args().Get(1), opts)
+		if err != nil {
+			logger.Error(err)
+		}
+		return err
+	},
+}
+
+func xlsxCmd(infile string, outfile string, opts []tsdata.Option) error {
+	r, err := openInput(infile)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr, err := tsdata.NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	tr.Strict = false
+
+	outf, err := createOutput(outfile)
+	if err != nil {
+		return err
+	}
+	defer outf.Close()
+	bw := bufio.NewWriter(outf)
+	wb := xlsx.NewWorkbook(bw)
+
+	err = writeMetadataSheet(wb, tr.Tsdata.Schema())
+	if err != nil {
+		return err
+	}
+	err = wb.AddSheet("data")
+	if err != nil {
+		return err
+	}
+	names := make([]interface{}, len(tr.Tsdata.Headers))
+	for i, h := range tr.Tsdata.Headers {
+		names[i] = h
+	}
+	err = wb.WriteRow(names)
+	if err != nil {
+		return err
+	}
+	err = eachLine(tr, func(data tsdata.Data) error {
+		cells := make([]interface{}, len(data.Values))
+		copy(cells, data.Values)
+		err := wb.WriteRow(cells)
+		if err != nil {
+			return dataErrorf("line %v, %v", tr.Line(), err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = wb.Close()
+	if err != nil {
+		return err
+	}
+	err = bw.Flush()
+	if err != nil {
+		return err
+	}
+	return outf.Close()
+}
+
+// writeMetadataSheet adds a sheet with the header fields and a table of
+// column metadata.
+func writeMetadataSheet(wb *xlsx.Workbook, s tsdata.Schema) error {
+	err := wb.AddSheet("metadata")
+	if err != nil {
+		return err
+	}
+	rows := [][]interface{}{
+		{"fileType", s.FileType},
+		{"project", s.Project},
+		{"description", s.FileDescription},
+		nil,
+		{"name", "type", "units", "comment"},
+	}
+	for i, h := range s.Headers {
+		comment := tsdata.NA
+		if i < len(s.Comments) {
+			comment = s.Comments[i]
+		}
+		rows = append(rows, []interface{}{h, s.Types[i], s.Units[i], comment})
+	}
+	for _, row := range rows {
+		err = wb.WriteRow(row)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package tsdata
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ColumnValue is the set of Go types Column can return. Each matches the
+// values of Data.Values for some column types: float64 for float, latitude,
+// and longitude columns, int64 for integer columns, bool for boolean columns,
+// and time.Time for time columns. string can be used with any column and
+// returns field strings.
+type ColumnValue interface {
+	float64 | int64 | bool | string | time.Time
+}
+
+// Column reads the remaining data lines of r and returns the values in column
+// name as a slice of T, with a parallel slice which is true where the value
+// is NA and the slice holds the zero value. T must match the column type, see
+// ColumnValue. If r.Strict is false, lines which fail validation are skipped,
+// otherwise reading stops at the first *LineError, which is returned with the
+// values read so far.
+func Column[T ColumnValue](r *Reader, name string) ([]T, []bool, error) {
+	i := r.Tsdata.Index(name)
+	if i == -1 {
+		return nil, nil, fmt.Errorf("no column %v", name)
+	}
+	var zero T
+	typ := r.Tsdata.Types[i]
+	ok := false
+	switch interface{}(zero).(type) {
+	case float64:
+		ok = typ == "float" || typ == "latitude" || typ == "longitude"
+	case int64:
+		ok = typ == "integer"
+	case bool:
+		ok = typ == "boolean"
+	case time.Time:
+		ok = typ == "time"
+	case string:
+		ok = true
+	}
+	if !ok {
+		return nil, nil, fmt.Errorf("column %v has type %v, can't be read as %T", name, typ, zero)
+	}
+
+	var values []T
+	var na []bool
+	for {
+		data, err := r.Next()
+		if err == io.EOF {
+			return values, na, nil
+		}
+		if err != nil {
+			var lerr *LineError
+			if errors.As(err, &lerr) && !r.Strict {
+				continue
+			}
+			return values, na, err
+		}
+		v := data.Values[i]
+		if v == nil {
+			values = append(values, zero)
+			na = append(na, true)
+			continue
+		}
+		if _, isString := interface{}(zero).(string); isString {
+			v = data.Fields[i]
+		}
+		values = append(values, v.(T))
+		na = append(na, false)
+	}
+}
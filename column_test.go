@@ -0,0 +1,80 @@
+package tsdata
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const columnInput = `fileType
+project
+file description
+
+time	float	integer	boolean	text
+NA	NA	NA	NA	NA
+time	col1	col2	col3	col4
+2017-05-06T19:00:00Z	2.5	1	TRUE	a
+2017-05-06T20:00:00Z	NA	bad	FALSE	b
+notatime	100	100	TRUE	c
+2017-05-06T21:00:00Z	-1	3	NA	NA
+`
+
+func TestColumn(t *testing.T) {
+	newReader := func() *Reader {
+		r, err := NewReader(strings.NewReader(columnInput))
+		if err != nil {
+			t.Fatalf("NewReader() err %v, expected nil", err)
+		}
+		r.Strict = false
+		return r
+	}
+
+	floats, na, err := Column[float64](newReader(), "col1")
+	if err != nil {
+		t.Fatalf("Column[float64]() err %v, expected nil", err)
+	}
+	if len(floats) != 3 || floats[0] != 2.5 || floats[2] != -1 || !na[1] || na[0] || na[2] {
+		t.Errorf("Column[float64]() = %v, %v, expected [2.5 0 -1] [false true false]", floats, na)
+	}
+
+	ints, na, err := Column[int64](newReader(), "col2")
+	if err != nil {
+		t.Fatalf("Column[int64]() err %v, expected nil", err)
+	}
+	if len(ints) != 3 || ints[0] != 1 || ints[2] != 3 || !na[1] {
+		t.Errorf("Column[int64]() = %v, %v, expected [1 0 3] [false true false]", ints, na)
+	}
+
+	bools, na, err := Column[bool](newReader(), "col3")
+	if err != nil {
+		t.Fatalf("Column[bool]() err %v, expected nil", err)
+	}
+	if len(bools) != 3 || !bools[0] || bools[1] || !na[2] {
+		t.Errorf("Column[bool]() = %v, %v, expected [true false false] [false false true]", bools, na)
+	}
+
+	times, _, err := Column[time.Time](newReader(), "time")
+	want, _ := time.Parse(time.RFC3339, "2017-05-06T21:00:00Z")
+	if err != nil || len(times) != 3 || !times[2].Equal(want) {
+		t.Errorf("Column[time.Time]() = %v, %v, expected 3 times ending %v", times, err, want)
+	}
+
+	strs, na, err := Column[string](newReader(), "col2")
+	if err != nil || len(strs) != 3 || strs[0] != "1" || strs[1] != "" || !na[1] {
+		t.Errorf("Column[string]() = %q, %v, %v, expected [1 '' 3]", strs, na, err)
+	}
+
+	if _, _, err := Column[float64](newReader(), "col3"); err == nil {
+		t.Errorf("Column[float64]() of boolean column err nil, expected an error")
+	}
+	if _, _, err := Column[string](newReader(), "nope"); err == nil {
+		t.Errorf("Column[string]() of missing column err nil, expected an error")
+	}
+
+	r := newReader()
+	r.Strict = true
+	floats, _, err = Column[float64](r, "col1")
+	if err == nil || len(floats) != 1 {
+		t.Errorf("strict Column[float64]() = %v, %v, expected 1 value and a *LineError", floats, err)
+	}
+}
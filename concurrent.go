@@ -0,0 +1,208 @@
+package tsdata
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// concurrentBatchSize is the number of lines sent to a worker at once.
+const concurrentBatchSize = 1024
+
+type lineBatch struct {
+	seq   int
+	first int // line number of lines[0]
+	lines []string
+	skip  []bool // lines skipped by Reader.Sample, nil if none
+}
+
+type parsedLine struct {
+	data      Data
+	fieldErrs []*FieldError
+	err       error
+	skip      bool
+}
+
+type parsedBatch struct {
+	seq    int
+	first  int
+	parsed []parsedLine
+}
+
+// ValidateConcurrent reads and validates the remaining data lines of r using
+// workers goroutines, then calls fn for each line in file order with the same
+// results Next would return: Data for a valid line, or a *LineError. Time
+// order checks and r's Report are applied in file order as well. If workers is
+// < 1 runtime.NumCPU() workers are used.
+//
+// Reading stops when all lines have been read, on a read error, or when fn
+// returns a non-nil error, which is returned by ValidateConcurrent. Lines are
+// read ahead of fn, so r should not be read from again after
+// ValidateConcurrent returns.
+func ValidateConcurrent(r *Reader, workers int, fn func(data Data, err error) error) error {
+	return ValidateConcurrentContext(context.Background(), r, workers, fn)
+}
+
+// ValidateConcurrentContext is like ValidateConcurrent but stops and returns
+// ctx.Err() once ctx is done. fn isn't called after ctx is done.
+func ValidateConcurrentContext(ctx context.Context, r *Reader, workers int, fn func(data Data, err error) error) error {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	r.Tsdata.columnIndex() // build the shared index before parsing concurrently
+
+	done := make(chan struct{})
+	defer close(done)
+	jobs := make(chan lineBatch, workers)
+	results := make(chan parsedBatch, workers)
+	// Limit batches in flight so a slow fn doesn't let reading run far ahead.
+	// Tokens are taken in batch order so the next batch to merge always has one.
+	tokens := make(chan struct{}, workers*4)
+
+	var readErr error
+	go func() {
+		defer close(jobs)
+		seq, line := 0, r.line
+		for {
+			batch := lineBatch{seq: seq, first: line + 1}
+			for len(batch.lines) < concurrentBatchSize && r.scanner.Scan() {
+				line++
+				batch.lines = append(batch.lines, r.scanner.Text())
+				if r.Sample != nil {
+					batch.skip = append(batch.skip, !r.Sample(line))
+				}
+			}
+			if len(batch.lines) == 0 {
+				readErr = r.scanner.Err()
+				return
+			}
+			select {
+			case tokens <- struct{}{}:
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- batch:
+			case <-done:
+				return
+			}
+			seq++
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for batch := range jobs {
+				out := parsedBatch{seq: batch.seq, first: batch.first, parsed: make([]parsedLine, len(batch.lines))}
+				for j, line := range batch.lines {
+					if batch.skip != nil && batch.skip[j] {
+						out.parsed[j].skip = true
+						continue
+					}
+					data, fieldErrs, err := r.Tsdata.parseLine(line)
+					out.parsed[j] = parsedLine{data: data, fieldErrs: fieldErrs, err: err}
+				}
+				select {
+				case results <- out:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Merge results in order
+	pending := map[int]parsedBatch{}
+	next := 0
+	for {
+		var b parsedBatch
+		var ok bool
+		select {
+		case b, ok = <-results:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if !ok {
+			// Reading may have stopped early because ctx is done
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return readErr
+		}
+		pending[b.seq] = b
+		for {
+			batch, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			<-tokens
+			for j, p := range batch.parsed {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				r.line = batch.first + j
+				if p.skip {
+					r.report.Lines++
+					r.report.SkippedLines++
+					continue
+				}
+				err := r.result(&p)
+				if ferr := fn(p.data, err); ferr != nil {
+					return ferr
+				}
+			}
+		}
+	}
+}
+
+// result finishes validation of a parsed line in file order, updating the
+// report and returning the data and error Next would return.
+func (r *Reader) result(p *parsedLine) error {
+	err := p.err
+	if err == nil {
+		err = r.Tsdata.checkOrder(&p.data, p.fieldErrs, r.Strict)
+	}
+	if err != nil {
+		p.data, p.fieldErrs = Data{}, nil
+	}
+	r.report.add(p.data, p.fieldErrs, err, r.Strict)
+	if r.report.FirstError == nil {
+		if err != nil {
+			r.report.FirstError = &LineError{Line: r.line, Err: err}
+		} else if len(p.fieldErrs) > 0 {
+			r.report.FirstError = &LineError{Line: r.line, Err: p.fieldErrs[0]}
+		}
+	}
+	if err == nil && r.Strict && len(p.fieldErrs) > 0 {
+		err = p.fieldErrs[0]
+		p.data = Data{}
+	}
+	if err != nil {
+		return &LineError{Line: r.line, Err: err}
+	}
+	p.data.Line = r.line
+	if r.Transform != nil {
+		data, err := r.Transform.Transform(r.Tsdata, p.data)
+		if err != nil {
+			p.data = Data{}
+			return &LineError{Line: r.line, Err: err}
+		}
+		data.Line = r.line
+		p.data = data
+	}
+	if r.Stats != nil {
+		r.Stats.Add(p.data)
+	}
+	return nil
+}
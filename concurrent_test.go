@@ -0,0 +1,187 @@
+package tsdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// concurrentInput returns a TSDATA file with n data lines, some with bad
+// values, bad timestamps, and timestamps out of order.
+func concurrentInput(n int) string {
+	var sb strings.Builder
+	sb.WriteString(readerHeader)
+	for i := 0; i < n; i++ {
+		sec := i
+		if i%97 == 0 {
+			sec = i - 50 // out of order
+		}
+		ts := "2020-01-01T00:00:00Z"
+		if sec >= 0 {
+			ts = fmt.Sprintf("2020-01-01T%02d:%02d:%02dZ", sec/3600%24, sec/60%60, sec%60)
+		}
+		switch {
+		case i%101 == 0:
+			ts = "bad"
+		case i%89 == 0:
+			fmt.Fprintf(&sb, "%v\n", ts)
+			continue
+		}
+		v := fmt.Sprint(i)
+		if i%13 == 0 {
+			v = "x"
+		}
+		fmt.Fprintf(&sb, "%v\t%v\t%v\n", ts, v, i)
+	}
+	return sb.String()
+}
+
+type lineResult struct {
+	Line int
+	Data []string
+	Warn []string
+	Err  string
+}
+
+func TestValidateConcurrent(t *testing.T) {
+	input := concurrentInput(5000)
+	for _, order := range []TimeOrder{TimeOrderOff, TimeOrderWarn, TimeOrderStrict} {
+		for _, strict := range []bool{true, false} {
+			// Sequential results with Next
+			tr, err := NewReader(strings.NewReader(input), WithTimeOrder(order))
+			if err != nil {
+				t.Fatalf("NewReader() err %v, expected nil", err)
+			}
+			tr.Strict = strict
+			var want []lineResult
+			for {
+				data, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				want = append(want, newLineResult(tr.Line(), data, err))
+			}
+			wantReport := tr.Report()
+
+			for _, workers := range []int{1, 3, 8} {
+				tr, _ := NewReader(strings.NewReader(input), WithTimeOrder(order))
+				tr.Strict = strict
+				var got []lineResult
+				err := ValidateConcurrent(tr, workers, func(data Data, err error) error {
+					got = append(got, newLineResult(tr.Line(), data, err))
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("ValidateConcurrent() err %v, expected nil", err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("ValidateConcurrent(order=%v, strict=%v, workers=%v) results differ from Next", order, strict, workers)
+				}
+				if !reflect.DeepEqual(tr.Report(), wantReport) {
+					t.Errorf("ValidateConcurrent(order=%v, strict=%v, workers=%v) report = %+v, expected %+v",
+						order, strict, workers, tr.Report(), wantReport)
+				}
+			}
+		}
+	}
+}
+
+func newLineResult(line int, data Data, err error) lineResult {
+	r := lineResult{Line: line, Data: data.Fields, Warn: data.Warnings}
+	if err == nil && data.Line != line {
+		r.Err = fmt.Sprintf("bad Data.Line %v", data.Line)
+	}
+	if err != nil {
+		var lerr *LineError
+		if !errors.As(err, &lerr) || lerr.Line != line {
+			r.Err = "bad error type or line: " + err.Error()
+		} else {
+			r.Err = err.Error()
+		}
+	}
+	return r
+}
+
+func TestValidateConcurrentStop(t *testing.T) {
+	tr, _ := NewReader(strings.NewReader(concurrentInput(10000)))
+	stop := errors.New("stop")
+	n := 0
+	err := ValidateConcurrent(tr, 4, func(data Data, err error) error {
+		n++
+		if n == 2000 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("ValidateConcurrent() err %v, expected %v", err, stop)
+	}
+	if n != 2000 {
+		t.Errorf("ValidateConcurrent() called fn %v times, expected 2000", n)
+	}
+}
+
+func TestValidateConcurrentSample(t *testing.T) {
+	input := concurrentInput(5000)
+	sample := func(line int) bool { return line%7 == 0 }
+
+	tr, _ := NewReader(strings.NewReader(input), WithTimeOrder(TimeOrderWarn))
+	tr.Sample = sample
+	var want []lineResult
+	for {
+		data, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if tr.Line()%7 != 0 {
+			t.Fatalf("Reader.Next() returned line %v, expected only multiples of 7", tr.Line())
+		}
+		want = append(want, newLineResult(tr.Line(), data, err))
+	}
+	wantReport := tr.Report()
+	if wantReport.DataLines != len(want) || wantReport.DataLines+wantReport.SkippedLines != 5000 {
+		t.Errorf("Report() data lines %v, skipped %v, expected %v and %v",
+			wantReport.DataLines, wantReport.SkippedLines, len(want), 5000-len(want))
+	}
+
+	tr, _ = NewReader(strings.NewReader(input), WithTimeOrder(TimeOrderWarn))
+	tr.Sample = sample
+	var got []lineResult
+	err := ValidateConcurrent(tr, 4, func(data Data, err error) error {
+		got = append(got, newLineResult(tr.Line(), data, err))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ValidateConcurrent() err %v, expected nil", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateConcurrent() with Sample results differ from Next")
+	}
+	if !reflect.DeepEqual(tr.Report(), wantReport) {
+		t.Errorf("ValidateConcurrent() with Sample report = %+v, expected %+v", tr.Report(), wantReport)
+	}
+}
+
+func TestValidateConcurrentContext(t *testing.T) {
+	tr, _ := NewReader(strings.NewReader(concurrentInput(10000)))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := 0
+	err := ValidateConcurrentContext(ctx, tr, 4, func(data Data, err error) error {
+		n++
+		if n == 2000 {
+			cancel()
+		}
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("ValidateConcurrentContext() err %v, expected %v", err, context.Canceled)
+	}
+	if n != 2000 {
+		t.Errorf("ValidateConcurrentContext() called fn %v times, expected 2000", n)
+	}
+}
@@ -0,0 +1,273 @@
+package tsdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Constraint restricts the values of a column beyond what its type allows. NA
+// is always allowed.
+type Constraint struct {
+	// Values lists the allowed values of a category column. Empty allows any
+	// value.
+	Values []string `json:"values,omitempty"`
+	// Min and Max are inclusive bounds for a float, integer, latitude, or
+	// longitude column. Nil means no bound.
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+	// Pattern is a regular expression which values of a text or category
+	// column must match, e.g. ^[A-Z]{2}\d{4}$. Empty allows any value.
+	Pattern string `json:"pattern,omitempty"`
+
+	re *regexp.Regexp // compiled Pattern, may be nil
+}
+
+// IsZero reports whether c has no restrictions.
+func (c Constraint) IsZero() bool {
+	return len(c.Values) == 0 && c.Min == nil && c.Max == nil && c.Pattern == ""
+}
+
+// check returns a description of why s violates c, or "" if s is allowed.
+func (c Constraint) check(s string) string {
+	if s == NA {
+		return ""
+	}
+	if len(c.Values) > 0 {
+		found := false
+		for _, v := range c.Values {
+			if s == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Sprintf("expected one of %v", strings.Join(c.Values, ", "))
+		}
+	}
+	if c.Min != nil || c.Max != nil {
+		v, err := strconv.ParseFloat(s, 64)
+		if err == nil && ((c.Min != nil && v < *c.Min) || (c.Max != nil && v > *c.Max)) {
+			return fmt.Sprintf("outside range %v", c.formatRange())
+		}
+	}
+	if c.Pattern != "" {
+		re := c.re
+		if re == nil || re.String() != c.Pattern {
+			var err error
+			re, err = regexp.Compile(c.Pattern)
+			if err != nil {
+				return fmt.Sprintf("bad pattern '%v'", c.Pattern)
+			}
+		}
+		if !re.MatchString(s) {
+			return fmt.Sprintf("expected a match for pattern '%v'", c.Pattern)
+		}
+	}
+	return ""
+}
+
+// compile returns c with Pattern compiled, so check doesn't compile it for
+// every value.
+func (c Constraint) compile() (Constraint, error) {
+	c.re = nil
+	if c.Pattern != "" {
+		re, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return c, fmt.Errorf("bad pattern '%v': %v", c.Pattern, err)
+		}
+		c.re = re
+	}
+	return c, nil
+}
+
+// validate checks that c may be used for a column of type typ.
+func (c Constraint) validate(typ string) error {
+	if len(c.Values) > 0 {
+		if typ != "category" {
+			return fmt.Errorf("allowed values for %v column, expected category", typ)
+		}
+		seen := make(map[string]bool, len(c.Values))
+		for _, v := range c.Values {
+			if v == "" || v == NA || strings.TrimSpace(v) != v {
+				return fmt.Errorf("bad category value '%v'", v)
+			}
+			if seen[v] {
+				return fmt.Errorf("duplicate category value '%v'", v)
+			}
+			seen[v] = true
+		}
+	}
+	if c.Min != nil || c.Max != nil {
+		switch typ {
+		case "float", "integer", "latitude", "longitude":
+		default:
+			return fmt.Errorf("range for %v column, expected a numeric type", typ)
+		}
+		if c.Min != nil && c.Max != nil && *c.Min > *c.Max {
+			return fmt.Errorf("bad range %v, min greater than max", c.formatRange())
+		}
+	}
+	if c.Pattern != "" {
+		if typ != "text" && typ != "category" {
+			return fmt.Errorf("pattern for %v column, expected text or category", typ)
+		}
+		if _, err := c.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// merge returns c with restrictions set in o replacing those in c.
+func (c Constraint) merge(o Constraint) Constraint {
+	if len(o.Values) > 0 {
+		c.Values = o.Values
+	}
+	if o.Min != nil {
+		c.Min = o.Min
+	}
+	if o.Max != nil {
+		c.Max = o.Max
+	}
+	if o.Pattern != "" {
+		c.Pattern, c.re = o.Pattern, o.re
+	}
+	return c
+}
+
+func (c Constraint) copy() Constraint {
+	return Constraint{
+		Values:  copyStrings(c.Values),
+		Min:     copyFloat(c.Min),
+		Max:     copyFloat(c.Max),
+		Pattern: c.Pattern,
+		re:      c.re,
+	}
+}
+
+func (c Constraint) equal(o Constraint) bool {
+	return stringsEqual(c.Values, o.Values) && floatsEqual(c.Min, o.Min) && floatsEqual(c.Max, o.Max) && c.Pattern == o.Pattern
+}
+
+// formatRange formats Min and Max in the form used by range comment tokens.
+func (c Constraint) formatRange() string {
+	var min, max string
+	if c.Min != nil {
+		min = strconv.FormatFloat(*c.Min, 'g', -1, 64)
+	}
+	if c.Max != nil {
+		max = strconv.FormatFloat(*c.Max, 'g', -1, 64)
+	}
+	return min + rangeSep + max
+}
+
+func copyFloat(f *float64) *float64 {
+	if f == nil {
+		return nil
+	}
+	v := *f
+	return &v
+}
+
+func floatsEqual(a *float64, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// enumSep separates allowed values in a category type, e.g.
+// category:ok|degraded|failed.
+const enumSep = "|"
+
+// parseType splits a Types row value into a type name and a constraint. A
+// category type may list allowed values after a colon, separated by "|", e.g.
+// category:ok|degraded|failed.
+func parseType(s string) (string, Constraint, error) {
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return s, Constraint{}, nil
+	}
+	typ, values := s[:i], strings.Split(s[i+1:], enumSep)
+	if typ != "category" {
+		return "", Constraint{}, fmt.Errorf("bad Types value '%v', only category columns may list values", s)
+	}
+	c := Constraint{Values: values}
+	if err := c.validate(typ); err != nil {
+		return "", Constraint{}, fmt.Errorf("bad Types value '%v', %v", s, err)
+	}
+	return typ, c, nil
+}
+
+// formatType is the inverse of parseType.
+func formatType(typ string, c Constraint) string {
+	if len(c.Values) == 0 {
+		return typ
+	}
+	return typ + ":" + strings.Join(c.Values, enumSep)
+}
+
+// rangePrefix starts a Comments row token which sets the range of a numeric
+// column, e.g. range=0..45. Either bound may be left out.
+const rangePrefix = "range="
+
+// rangeSep separates the bounds of a range token.
+const rangeSep = ".."
+
+// parseCommentRange returns a Constraint with the range set by a range token
+// in comment, if there is one. Comments are free text, so tokens which start
+// with range= but aren't a well-formed range of finite numbers are ignored.
+func parseCommentRange(comment string) Constraint {
+	for _, tok := range strings.Fields(comment) {
+		if c, ok := parseRangeToken(tok); ok {
+			return c
+		}
+	}
+	return Constraint{}
+}
+
+// parseRangeToken parses a range=MIN..MAX token. ok is false if tok isn't
+// one.
+func parseRangeToken(tok string) (c Constraint, ok bool) {
+	if !strings.HasPrefix(tok, rangePrefix) {
+		return Constraint{}, false
+	}
+	bounds := strings.Split(strings.TrimPrefix(tok, rangePrefix), rangeSep)
+	if len(bounds) != 2 || (bounds[0] == "" && bounds[1] == "") {
+		return Constraint{}, false
+	}
+	for i, b := range bounds {
+		if b == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(b, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return Constraint{}, false
+		}
+		if i == 0 {
+			c.Min = &v
+		} else {
+			c.Max = &v
+		}
+	}
+	return c, true
+}
+
+// ReadConstraints reads a JSON object of constraints keyed by column name from
+// r, for use with WithConstraints. For example,
+//
+//	{"salinity": {"min": 0, "max": 45}, "id": {"pattern": "^[A-Z]{2}\\d{4}$"}}
+func ReadConstraints(r io.Reader) (map[string]Constraint, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var m map[string]Constraint
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("bad constraints: %v", err)
+	}
+	return m, nil
+}
@@ -0,0 +1,275 @@
+package tsdata
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_parseType(t *testing.T) {
+	tests := []struct {
+		in      string
+		typ     string
+		values  []string
+		wantErr bool
+	}{
+		{in: "float", typ: "float"},
+		{in: "category", typ: "category"},
+		{in: "category:ok|degraded|failed", typ: "category", values: []string{"ok", "degraded", "failed"}},
+		{in: "category:ok", typ: "category", values: []string{"ok"}},
+		{in: "text:ok|failed", wantErr: true},
+		{in: "category:", wantErr: true},
+		{in: "category:ok||failed", wantErr: true},
+		{in: "category:ok|NA", wantErr: true},
+		{in: "category:ok|ok", wantErr: true},
+		{in: "category:ok| failed", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			typ, c, err := parseType(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseType() err %v, expected a non-nil error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseType() err %v, expected nil", err)
+			}
+			if typ != tt.typ || !stringsEqual(c.Values, tt.values) {
+				t.Errorf("parseType() = %v, %v, expected %v, %v", typ, c.Values, tt.typ, tt.values)
+			}
+			if s := formatType(typ, c); s != tt.in {
+				t.Errorf("formatType() = %v, expected %v", s, tt.in)
+			}
+		})
+	}
+}
+
+func TestTsdata_ValidateLine_categoryValues(t *testing.T) {
+	header := "fileType\nproject\n\nNA\tNA\tNA\ntime\tcategory:ok|degraded|failed\tcategory\nNA\tNA\tNA\ntime\tstatus\tcolor"
+	d := New()
+	if err := d.ParseHeader(header); err != nil {
+		t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+	}
+	if d.Types[1] != "category" {
+		t.Errorf("Tsdata.Types[1] %v, expected category", d.Types[1])
+	}
+	if got := d.Header(); got != header {
+		t.Errorf("Tsdata.Header() %q, expected %q", got, header)
+	}
+
+	for _, line := range []string{
+		"2017-05-06T19:52:57.601Z\tdegraded\tblue",
+		"2017-05-06T19:52:57.601Z\tNA\tblue",
+	} {
+		if _, err := d.ValidateLine(line, true); err != nil {
+			t.Errorf("Tsdata.ValidateLine(%q) err %v, expected nil", line, err)
+		}
+	}
+
+	_, err := d.ValidateLine("2017-05-06T19:52:57.601Z\tOK\tblue", true)
+	var ferr *FieldError
+	if !errors.As(err, &ferr) {
+		t.Fatalf("Tsdata.ValidateLine() err %v, expected a *FieldError", err)
+	}
+	if ferr.Column != 1 || ferr.Reason == "" {
+		t.Errorf("Tsdata.ValidateLine() err %+v, expected column 1 with a reason", ferr)
+	}
+
+	data, err := d.ValidateLine("2017-05-06T19:52:57.601Z\tOK\tblue", false)
+	if err != nil || data.Fields[1] != NA {
+		t.Errorf("Tsdata.ValidateLine() non-strict = %v, %v, expected NA in column 1", data.Fields, err)
+	}
+}
+
+func TestSchema_Equal_constraints(t *testing.T) {
+	a, err := ParseSchema("fileType\nproject\n\n\ntime\tcategory:a|b\nNA\tNA\ntime\tc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ParseSchema("fileType\nproject\n\n\ntime\tcategory\nNA\tNA\ntime\tc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Equal(b) {
+		t.Errorf("Schema.Equal() true for different category values")
+	}
+	if !b.Equal(New(WithSchema(b)).Schema()) {
+		t.Errorf("Schema.Equal() false for a copy")
+	}
+	if !a.Equal(New(WithSchema(a)).Schema()) {
+		t.Errorf("Schema.Equal() false for a copy with constraints")
+	}
+}
+
+func Test_parseCommentRange(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "NA", want: ".."},
+		{in: "salinity range=0..45", want: "0..45"},
+		{in: "range=-2.5.. degrees C", want: "-2.5.."},
+		{in: "range=..1e3", want: "..1000"},
+		{in: "range=0", want: ".."},
+		{in: "range=..", want: ".."},
+		{in: "range=a..1", want: ".."},
+		{in: "range=0..NaN", want: ".."},
+		{in: "range=unknown, see cruise log", want: ".."},
+		{in: "range=unknown range=0..45", want: "0..45"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := parseCommentRange(tt.in).formatRange(); got != tt.want {
+				t.Errorf("parseCommentRange() = %v, expected %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTsdata_ValidateLine_range(t *testing.T) {
+	header := "fileType\nproject\n\nNA\tPSU range=0..45\tNA\ntime\tfloat\tinteger\nNA\tNA\tNA\ntime\tsalinity\tcount"
+	constraints, err := ReadConstraints(strings.NewReader(`{"count": {"max": 10}, "other": {"min": 1}}`))
+	if err != nil {
+		t.Fatalf("ReadConstraints() err %v, expected nil", err)
+	}
+	d := New(WithConstraints(constraints))
+	if err := d.ParseHeader(header); err != nil {
+		t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+	}
+	if got := d.Header(); got != header {
+		t.Errorf("Tsdata.Header() %q, expected %q", got, header)
+	}
+
+	for _, line := range []string{
+		"2017-05-06T19:52:57.601Z\t0\t10",
+		"2017-05-06T19:52:57.601Z\t45\t-3",
+		"2017-05-06T19:52:57.601Z\tNA\tNA",
+	} {
+		if _, err := d.ValidateLine(line, true); err != nil {
+			t.Errorf("Tsdata.ValidateLine(%q) err %v, expected nil", line, err)
+		}
+	}
+	for line, col := range map[string]int{
+		"2017-05-06T19:52:57.601Z\t300\t1":  1,
+		"2017-05-06T19:52:57.601Z\t-0.1\t1": 1,
+		"2017-05-06T19:52:57.601Z\t1\t11":   2,
+	} {
+		_, err := d.ValidateLine(line, true)
+		var ferr *FieldError
+		if !errors.As(err, &ferr) || ferr.Column != col || ferr.Reason == "" {
+			t.Errorf("Tsdata.ValidateLine(%q) err %v, expected a range error in column %v", line, err, col)
+		}
+	}
+}
+
+func TestTsdata_ParseHeader_badConstraints(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		constraints string
+	}{
+		{
+			name:   "range on text column",
+			header: "fileType\nproject\n\nNA\trange=0..1\ntime\ttext\nNA\tNA\ntime\tnote",
+		},
+		{
+			name:   "min greater than max",
+			header: "fileType\nproject\n\nNA\trange=2..1\ntime\tfloat\nNA\tNA\ntime\tspeed",
+		},
+		{
+			name:        "external values on float column",
+			header:      "fileType\nproject\n\n\ntime\tfloat\nNA\tNA\ntime\tspeed",
+			constraints: `{"speed": {"values": ["a"]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.constraints != "" {
+				c, err := ReadConstraints(strings.NewReader(tt.constraints))
+				if err != nil {
+					t.Fatalf("ReadConstraints() err %v, expected nil", err)
+				}
+				opts = append(opts, WithConstraints(c))
+			}
+			err := New(opts...).ParseHeader(tt.header)
+			var herr *HeaderError
+			if !errors.As(err, &herr) {
+				t.Errorf("Tsdata.ParseHeader() err %v, expected a *HeaderError", err)
+			}
+		})
+	}
+}
+
+func TestTsdata_ParseHeader_freeTextRange(t *testing.T) {
+	header := "fileType\nproject\n\nNA\trange=unknown until calibrated\ntime\tfloat\nNA\tNA\ntime\tspeed"
+	d := New()
+	if err := d.ParseHeader(header); err != nil {
+		t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+	}
+	if _, err := d.ValidateLine("2017-05-06T19:52:57.601Z\t-1e9", true); err != nil {
+		t.Errorf("Tsdata.ValidateLine() err %v, expected nil", err)
+	}
+}
+
+func TestReadConstraints_errors(t *testing.T) {
+	for _, in := range []string{`[]`, `{"speed": {"minimum": 1}}`, `{"speed": {"min": "a"}}`} {
+		if _, err := ReadConstraints(strings.NewReader(in)); err == nil {
+			t.Errorf("ReadConstraints(%q) err %v, expected a non-nil error", in, err)
+		}
+	}
+}
+
+func TestTsdata_ValidateLine_pattern(t *testing.T) {
+	header := "fileType\nproject\n\n\ntime\ttext\tcategory\nNA\tNA\tNA\ntime\tid\tstation"
+	constraints, err := ReadConstraints(strings.NewReader(`{"id": {"pattern": "^[A-Z]{2}\\d{4}$"}}`))
+	if err != nil {
+		t.Fatalf("ReadConstraints() err %v, expected nil", err)
+	}
+	d := New(WithConstraints(constraints), WithConstraints(map[string]Constraint{"station": {Pattern: "^S"}}))
+	if err := d.ParseHeader(header); err != nil {
+		t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+	}
+
+	for _, line := range []string{
+		"2017-05-06T19:52:57.601Z\tAB1234\tS1",
+		"2017-05-06T19:52:57.601Z\tNA\tNA",
+	} {
+		if _, err := d.ValidateLine(line, true); err != nil {
+			t.Errorf("Tsdata.ValidateLine(%q) err %v, expected nil", line, err)
+		}
+	}
+	for line, col := range map[string]int{
+		"2017-05-06T19:52:57.601Z\tAB123\tS1":  1,
+		"2017-05-06T19:52:57.601Z\tab1234\tS1": 1,
+		"2017-05-06T19:52:57.601Z\tAB1234\tT1": 2,
+	} {
+		_, err := d.ValidateLine(line, true)
+		var ferr *FieldError
+		if !errors.As(err, &ferr) || ferr.Column != col || ferr.Reason == "" {
+			t.Errorf("Tsdata.ValidateLine(%q) err %v, expected a pattern error in column %v", line, err, col)
+		}
+	}
+
+	s := d.Schema()
+	s.Constraints = []Constraint{{}, {Pattern: "^x"}, {}}
+	d = New(WithSchema(s))
+	if _, err := d.ValidateLine("2017-05-06T19:52:57.601Z\ty\tS1", true); err == nil {
+		t.Errorf("Tsdata.ValidateLine() err %v, expected a pattern error for a Schema constraint", err)
+	}
+}
+
+func TestConstraint_validate_pattern(t *testing.T) {
+	if err := (Constraint{Pattern: "^a"}).validate("float"); err == nil {
+		t.Errorf("Constraint.validate() err %v, expected an error for a float column", err)
+	}
+	if err := (Constraint{Pattern: "("}).validate("text"); err == nil {
+		t.Errorf("Constraint.validate() err %v, expected an error for a bad pattern", err)
+	}
+	if err := New(WithConstraints(map[string]Constraint{"id": {Pattern: "("}})).ParseHeader(
+		"fileType\nproject\n\n\ntime\ttext\nNA\tNA\ntime\tid"); err == nil {
+		t.Errorf("Tsdata.ParseHeader() err %v, expected an error for a bad pattern", err)
+	}
+}
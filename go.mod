@@ -1,5 +1,21 @@
 module github.com/ctberthiaume/tsdata
 
-go 1.13
+go 1.18
 
-require github.com/urfave/cli v1.22.1
+require (
+	github.com/fsnotify/fsnotify v1.4.9
+	github.com/urfave/cli v1.22.1
+	google.golang.org/grpc v1.43.0
+	google.golang.org/protobuf v1.25.0
+)
+
+require (
+	github.com/cpuguy83/go-md2man/v2 v2.0.0-20190314233015-f79a8a8ca69d // indirect
+	github.com/golang/protobuf v1.4.3 // indirect
+	github.com/russross/blackfriday/v2 v2.0.1 // indirect
+	github.com/shurcooL/sanitized_anchor_name v1.0.0 // indirect
+	golang.org/x/net v0.0.0-20200822124328-c89045814202 // indirect
+	golang.org/x/sys v0.0.0-20200323222414-85ca7c5b95cd // indirect
+	golang.org/x/text v0.3.0 // indirect
+	google.golang.org/genproto v0.0.0-20200526211855-cb27e3aa2013 // indirect
+)
@@ -0,0 +1,188 @@
+package tsdata
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultIndexInterval is the default number of bytes between Index
+// checkpoints.
+const DefaultIndexInterval = 1 << 20
+
+// indexMagic starts an encoded Index.
+const indexMagic = "TSDATA-INDEX-1\n"
+
+// Checkpoint is the position of a data line in a TSDATA file.
+type Checkpoint struct {
+	Time   time.Time // timestamp of the line
+	Offset int64     // byte offset of the start of the line
+	Line   int       // 1-based line number
+}
+
+// Index holds checkpoints of timestamps and byte offsets for a TSDATA file
+// sorted by time, so reading can start close to a timestamp without scanning
+// the file from the top. See Reader.Seek.
+type Index struct {
+	// Size is the size of the file in bytes when it was indexed.
+	Size int64
+	// Start is the position of the first data line.
+	Start Checkpoint
+	// Checkpoints are in file order, with non-decreasing times.
+	Checkpoints []Checkpoint
+}
+
+// BuildIndex reads a TSDATA file from r and creates an Index with checkpoints
+// about every interval bytes. Lines must be sorted by time. Lines with a bad
+// timestamp are skipped.
+func BuildIndex(r io.Reader, interval int64, opts ...Option) (*Index, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("bad index interval %v, expected > 0", interval)
+	}
+	br := bufio.NewReader(r)
+	var offset int64
+	line := 0
+	readLine := func() (string, int64, error) {
+		b, err := br.ReadBytes('\n')
+		if err == io.EOF && len(b) > 0 {
+			err = nil
+		}
+		start := offset
+		offset += int64(len(b))
+		if err == nil {
+			line++
+		}
+		return strings.TrimRight(string(b), "\r\n"), start, err
+	}
+
+	headerLines := make([]string, 0, HeaderSize)
+	for len(headerLines) < HeaderSize {
+		s, _, err := readLine()
+		if err == io.EOF {
+			return nil, &HeaderError{Err: fmt.Errorf("expected %v lines in header, found %v", HeaderSize, len(headerLines))}
+		}
+		if err != nil {
+			return nil, err
+		}
+		headerLines = append(headerLines, s)
+	}
+	t := New(opts...)
+	if err := t.ParseHeader(strings.Join(headerLines, "\n")); err != nil {
+		return nil, err
+	}
+
+	idx := &Index{Start: Checkpoint{Offset: offset, Line: line + 1}}
+	var last Checkpoint
+	for {
+		s, start, err := readLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := strings.TrimSpace(strings.SplitN(s, t.Delimiter(), 2)[0])
+		tline, err := t.ParseTime(field)
+		if err != nil {
+			continue
+		}
+		if len(idx.Checkpoints) > 0 && tline.Before(last.Time) {
+			return nil, fmt.Errorf("line %v, timestamp less than previous line, file must be sorted by time", line)
+		}
+		if len(idx.Checkpoints) == 0 || start-idx.Checkpoints[len(idx.Checkpoints)-1].Offset >= interval {
+			idx.Checkpoints = append(idx.Checkpoints, Checkpoint{Time: tline, Offset: start, Line: line})
+		}
+		last = Checkpoint{Time: tline, Offset: start, Line: line}
+	}
+	idx.Size = offset
+	return idx, nil
+}
+
+// Find returns the last checkpoint with a time before t, or Start if there is
+// none. Every line with a timestamp at or after t follows this position.
+func (idx *Index) Find(t time.Time) Checkpoint {
+	i := sort.Search(len(idx.Checkpoints), func(i int) bool {
+		return !idx.Checkpoints[i].Time.Before(t)
+	})
+	if i == 0 {
+		return idx.Start
+	}
+	return idx.Checkpoints[i-1]
+}
+
+// WriteTo writes idx in a compact binary encoding.
+func (idx *Index) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	buf.WriteString(indexMagic)
+	putUvarint(&buf, uint64(idx.Size))
+	putUvarint(&buf, uint64(idx.Start.Offset))
+	putUvarint(&buf, uint64(idx.Start.Line))
+	putUvarint(&buf, uint64(len(idx.Checkpoints)))
+	prev := idx.Start
+	var prevNano int64
+	for _, c := range idx.Checkpoints {
+		nano := c.Time.UnixNano()
+		putVarint(&buf, nano-prevNano)
+		putUvarint(&buf, uint64(c.Offset-prev.Offset))
+		putUvarint(&buf, uint64(c.Line-prev.Line))
+		prev, prevNano = c, nano
+	}
+	return buf.WriteTo(w)
+}
+
+// ReadIndex reads an Index written by Index.WriteTo.
+func ReadIndex(r io.Reader) (*Index, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(indexMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != indexMagic {
+		return nil, errors.New("not a TSDATA index")
+	}
+	var err error
+	uvarint := func() int64 {
+		if err != nil {
+			return 0
+		}
+		var v uint64
+		v, err = binary.ReadUvarint(br)
+		return int64(v)
+	}
+	idx := &Index{}
+	idx.Size = uvarint()
+	idx.Start.Offset = uvarint()
+	idx.Start.Line = int(uvarint())
+	n := uvarint()
+	if err != nil {
+		return nil, fmt.Errorf("bad TSDATA index: %v", err)
+	}
+	prev := idx.Start
+	var prevNano int64
+	for i := int64(0); i < n; i++ {
+		var d int64
+		d, err = binary.ReadVarint(br)
+		c := Checkpoint{Offset: prev.Offset + uvarint(), Line: prev.Line + int(uvarint())}
+		if err != nil {
+			return nil, fmt.Errorf("bad TSDATA index: %v", err)
+		}
+		prevNano += d
+		c.Time = time.Unix(0, prevNano).UTC()
+		idx.Checkpoints = append(idx.Checkpoints, c)
+		prev = c
+	}
+	return idx, nil
+}
+
+func putUvarint(buf *bytes.Buffer, v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func putVarint(buf *bytes.Buffer, v int64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutVarint(b[:], v)])
+}
@@ -0,0 +1,111 @@
+package tsdata
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// indexInput returns a file with n lines one minute apart starting at
+// 2017-05-06T00:00:00Z.
+func indexInput(n int) string {
+	var sb strings.Builder
+	sb.WriteString(readerHeader)
+	start := time.Date(2017, 5, 6, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "%v\t%v\n", start.Add(time.Duration(i)*time.Minute).Format(time.RFC3339), i)
+	}
+	return sb.String()
+}
+
+func TestBuildIndex(t *testing.T) {
+	input := indexInput(100)
+	idx, err := BuildIndex(strings.NewReader(input), 200)
+	if err != nil {
+		t.Fatalf("BuildIndex() err %v, expected nil", err)
+	}
+	if idx.Size != int64(len(input)) {
+		t.Errorf("Index.Size = %v, expected %v", idx.Size, len(input))
+	}
+	if idx.Start.Offset != int64(len(readerHeader)) || idx.Start.Line != HeaderSize+1 {
+		t.Errorf("Index.Start = %+v, expected offset %v line %v", idx.Start, len(readerHeader), HeaderSize+1)
+	}
+	if len(idx.Checkpoints) < 2 {
+		t.Fatalf("Index.Checkpoints = %v, expected more than one", idx.Checkpoints)
+	}
+	for _, c := range idx.Checkpoints {
+		line := strings.SplitN(input[c.Offset:], "\n", 2)[0]
+		if !strings.HasPrefix(line, c.Time.Format(time.RFC3339)) {
+			t.Errorf("checkpoint %+v points at line %q", c, line)
+		}
+		if got := strings.Count(input[:c.Offset], "\n") + 1; got != c.Line {
+			t.Errorf("checkpoint %+v line, expected %v", c, got)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := idx.WriteTo(&buf); err != nil {
+		t.Fatalf("Index.WriteTo() err %v, expected nil", err)
+	}
+	got, err := ReadIndex(&buf)
+	if err != nil {
+		t.Fatalf("ReadIndex() err %v, expected nil", err)
+	}
+	if !reflect.DeepEqual(got, idx) {
+		t.Errorf("ReadIndex() = %+v, expected %+v", got, idx)
+	}
+	if _, err := ReadIndex(strings.NewReader("not an index")); err == nil {
+		t.Errorf("ReadIndex() err %v, expected a non-nil error", err)
+	}
+
+	unsorted := readerHeader + "2017-05-06T01:00:00Z\t1\n2017-05-06T00:00:00Z\t2\n"
+	if _, err := BuildIndex(strings.NewReader(unsorted), 1); err == nil {
+		t.Errorf("BuildIndex() err %v, expected a non-nil error for unsorted lines", err)
+	}
+}
+
+func TestReader_Seek(t *testing.T) {
+	input := indexInput(100)
+	idx, err := BuildIndex(strings.NewReader(input), 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, minute := range []int{-10, 0, 1, 37, 99, 200} {
+		target := time.Date(2017, 5, 6, 0, minute, 0, 0, time.UTC)
+		r, err := NewReader(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Seek(idx, target); err != nil {
+			t.Fatalf("Reader.Seek() err %v, expected nil", err)
+		}
+		data, err := r.Next()
+		if minute >= 100 {
+			if err == nil && !data.Time.Before(target) {
+				t.Errorf("Reader.Seek(%v) read %v, expected no line at or after target", target, data.Fields)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Reader.Next() err %v, expected nil", err)
+		}
+		if data.Time.After(target) && minute >= 0 {
+			t.Errorf("Reader.Seek(%v) first line %v, expected a line at or before target", target, data.Time)
+		}
+		wantLine := HeaderSize + 1 + int(data.Time.Sub(time.Date(2017, 5, 6, 0, 0, 0, 0, time.UTC))/time.Minute)
+		if r.Line() != wantLine {
+			t.Errorf("Reader.Line() = %v, expected %v", r.Line(), wantLine)
+		}
+	}
+
+	r, err := NewReader(strings.NewReader(input[:len(input)-10]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Seek(idx, time.Time{}); err == nil {
+		t.Errorf("Reader.Seek() err %v, expected an error for a truncated file", err)
+	}
+}
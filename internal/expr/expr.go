@@ -0,0 +1,501 @@
+// Package expr implements a small boolean expression language for filtering
+// TSDATA lines by column value.
+//
+// An expression is made of comparisons joined by && and ||, negated with !,
+// and grouped with parentheses. A comparison is a column name, an operator
+// (==, !=, <, <=, >, >=), and a literal or another column name, e.g.
+//
+//	sst > 20 && par != NA
+//	(color == "red" || color == "blue") && time >= "2017-05-06T20:00:00Z"
+//
+// Literals are numbers, quoted strings, TRUE, FALSE, and NA. Strings compared
+// to time columns are parsed as timestamps. A boolean column name on its own
+// is true when the column is TRUE. Column names which are not simple
+// identifiers may be quoted with backticks.
+//
+// Comparisons with NA are only defined for == and !=. Any other comparison
+// involving an NA value is false.
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ctberthiaume/tsdata"
+)
+
+// Expr is a compiled expression.
+type Expr struct {
+	root node
+}
+
+// Compile parses s and checks it against column types, a map of column name
+// to TSDATA type.
+func Compile(s string, types map[string]string) (*Expr, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks, types: types}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, p.unexpected()
+	}
+	return &Expr{root: root}, nil
+}
+
+// Eval evaluates the expression. value returns the parsed value for a column
+// name as returned by tsdata.Data.Value, nil for NA.
+func (e *Expr) Eval(value func(name string) interface{}) bool {
+	return e.root.eval(value)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func lex(s string) ([]token, error) {
+	var toks []token
+	r := []rune(s)
+	for i := 0; i < len(r); {
+		c := r[i]
+		start := i
+		switch {
+		case unicode.IsSpace(c):
+			i++
+			continue
+		case c == '(':
+			toks = append(toks, token{tokLParen, "(", start})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")", start})
+			i++
+		case c == '"' || c == '\'' || c == '`':
+			i++
+			for i < len(r) && r[i] != c {
+				i++
+			}
+			if i == len(r) {
+				return nil, fmt.Errorf("position %v, unterminated quote", start+1)
+			}
+			kind := tokString
+			if c == '`' {
+				kind = tokIdent
+			}
+			toks = append(toks, token{kind, string(r[start+1 : i]), start})
+			i++
+		case unicode.IsDigit(c) || ((c == '-' || c == '+' || c == '.') && i+1 < len(r) && (unicode.IsDigit(r[i+1]) || r[i+1] == '.')):
+			i++
+			for i < len(r) && (unicode.IsDigit(r[i]) || r[i] == '.' || r[i] == 'e' || r[i] == 'E' ||
+				((r[i] == '-' || r[i] == '+') && (r[i-1] == 'e' || r[i-1] == 'E'))) {
+				i++
+			}
+			text := string(r[start:i])
+			if _, err := strconv.ParseFloat(text, 64); err != nil {
+				return nil, fmt.Errorf("position %v, bad number '%v'", start+1, text)
+			}
+			toks = append(toks, token{tokNumber, text, start})
+		case unicode.IsLetter(c) || c == '_':
+			for i < len(r) && (unicode.IsLetter(r[i]) || unicode.IsDigit(r[i]) || r[i] == '_' || r[i] == '.') {
+				i++
+			}
+			toks = append(toks, token{tokIdent, string(r[start:i]), start})
+		default:
+			op := ""
+			for _, o := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!"} {
+				if strings.HasPrefix(string(r[i:]), o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("position %v, unexpected '%v'", start+1, string(c))
+			}
+			toks = append(toks, token{tokOp, op, start})
+			i += len(op)
+		}
+	}
+	toks = append(toks, token{tokEOF, "", len(r)})
+	return toks, nil
+}
+
+type parser struct {
+	toks  []token
+	i     int
+	types map[string]string
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t.kind == tokEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("position %v, unexpected '%v'", t.pos+1, t.text)
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "&&" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t := p.peek()
+	if t.kind == tokOp && t.text == "!" {
+		p.next()
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+	if t.kind == tokLParen {
+		p.next()
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, p.unexpected()
+		}
+		p.next()
+		return n, nil
+	}
+	return p.parseComparison()
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+func isLiteral(t token) bool {
+	return t.kind == tokNumber || t.kind == tokString ||
+		(t.kind == tokIdent && (t.text == tsdata.NA || t.text == "TRUE" || t.text == "FALSE"))
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left := p.peek()
+	if left.kind != tokIdent && !isLiteral(left) {
+		return nil, p.unexpected()
+	}
+	p.next()
+	op := p.peek()
+	if op.kind != tokOp || !isComparison(op.text) {
+		// A boolean column on its own
+		if isLiteral(left) {
+			return nil, p.unexpected()
+		}
+		typ, err := p.columnType(left)
+		if err != nil {
+			return nil, err
+		}
+		if typ != "boolean" {
+			return nil, fmt.Errorf("position %v, column '%v' is %v, not boolean", left.pos+1, left.text, typ)
+		}
+		return cmpNode{op: "==", left: column{left.text}, right: literal{true}}, nil
+	}
+	p.next()
+	right := p.peek()
+	if right.kind != tokIdent && !isLiteral(right) {
+		return nil, p.unexpected()
+	}
+	p.next()
+
+	// Put a column on the left
+	if isLiteral(left) {
+		if isLiteral(right) {
+			return nil, fmt.Errorf("position %v, comparison must include a column", left.pos+1)
+		}
+		left, right = right, left
+		op.text = flip(op.text)
+	}
+	typ, err := p.columnType(left)
+	if err != nil {
+		return nil, err
+	}
+	n := cmpNode{op: op.text, left: column{left.text}}
+	if isLiteral(right) {
+		v, err := literalValue(right, typ)
+		if err != nil {
+			return nil, err
+		}
+		n.right = literal{v}
+		if v == nil && op.text != "==" && op.text != "!=" {
+			return nil, fmt.Errorf("position %v, NA can only be compared with == or !=", op.pos+1)
+		}
+	} else {
+		rtyp, err := p.columnType(right)
+		if err != nil {
+			return nil, err
+		}
+		if kind(typ) != kind(rtyp) {
+			return nil, fmt.Errorf("position %v, cannot compare %v column '%v' to %v column '%v'",
+				op.pos+1, typ, left.text, rtyp, right.text)
+		}
+		n.right = column{right.text}
+	}
+	if typ == "boolean" && op.text != "==" && op.text != "!=" {
+		return nil, fmt.Errorf("position %v, boolean column '%v' can only be compared with == or !=", op.pos+1, left.text)
+	}
+	return n, nil
+}
+
+func (p *parser) columnType(t token) (string, error) {
+	typ, ok := p.types[t.text]
+	if !ok {
+		return "", fmt.Errorf("position %v, unknown column '%v'", t.pos+1, t.text)
+	}
+	return typ, nil
+}
+
+// flip returns the operator which gives the same result with operands swapped.
+func flip(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	}
+	return op
+}
+
+// kind groups column types whose values can be compared with each other.
+func kind(typ string) string {
+	switch typ {
+	case "float", "integer", "latitude", "longitude":
+		return "number"
+	case "time", "boolean":
+		return typ
+	}
+	return "text"
+}
+
+// literalValue converts a literal token to a value comparable to values of
+// a column of type typ.
+func literalValue(t token, typ string) (interface{}, error) {
+	if t.kind == tokIdent && t.text == tsdata.NA {
+		return nil, nil
+	}
+	switch kind(typ) {
+	case "number":
+		if t.kind != tokNumber {
+			return nil, fmt.Errorf("position %v, expected a number, found '%v'", t.pos+1, t.text)
+		}
+		v, _ := strconv.ParseFloat(t.text, 64)
+		return v, nil
+	case "time":
+		v, err := tsdata.ParseTime(t.text)
+		if t.kind != tokString || err != nil {
+			return nil, fmt.Errorf("position %v, expected a quoted timestamp, found '%v'", t.pos+1, t.text)
+		}
+		return v, nil
+	case "boolean":
+		if t.kind != tokIdent {
+			return nil, fmt.Errorf("position %v, expected TRUE or FALSE, found '%v'", t.pos+1, t.text)
+		}
+		return t.text == "TRUE", nil
+	}
+	if t.kind == tokIdent {
+		return nil, fmt.Errorf("position %v, expected a quoted string, found '%v'", t.pos+1, t.text)
+	}
+	return t.text, nil
+}
+
+type node interface {
+	eval(value func(string) interface{}) bool
+}
+
+type operand interface {
+	get(value func(string) interface{}) interface{}
+}
+
+type column struct {
+	name string
+}
+
+func (c column) get(value func(string) interface{}) interface{} {
+	v := value(c.name)
+	if i, ok := v.(int64); ok {
+		return float64(i)
+	}
+	return v
+}
+
+type literal struct {
+	v interface{}
+}
+
+func (l literal) get(value func(string) interface{}) interface{} {
+	return l.v
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n andNode) eval(value func(string) interface{}) bool {
+	return n.left.eval(value) && n.right.eval(value)
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n orNode) eval(value func(string) interface{}) bool {
+	return n.left.eval(value) || n.right.eval(value)
+}
+
+type notNode struct {
+	n node
+}
+
+func (n notNode) eval(value func(string) interface{}) bool {
+	return !n.n.eval(value)
+}
+
+type cmpNode struct {
+	op          string
+	left, right operand
+}
+
+func (n cmpNode) eval(value func(string) interface{}) bool {
+	a, b := n.left.get(value), n.right.get(value)
+	if a == nil || b == nil {
+		switch n.op {
+		case "==":
+			return a == nil && b == nil
+		case "!=":
+			return (a == nil) != (b == nil)
+		}
+		return false
+	}
+	c, ok := compare(a, b)
+	if !ok {
+		return n.op == "!="
+	}
+	switch n.op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// compare returns -1, 0, or 1 as a is less than, equal to, or greater than b.
+// ok is false if a and b are not comparable, including NaN floats.
+func compare(a, b interface{}) (c int, ok bool) {
+	switch av := a.(type) {
+	case float64:
+		bv, ok := b.(float64)
+		if !ok || av != av || bv != bv {
+			return 0, false
+		}
+		switch {
+		case av < bv:
+			return -1, true
+		case av > bv:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(av, bv), true
+	case time.Time:
+		bv, ok := b.(time.Time)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case av.Before(bv):
+			return -1, true
+		case av.After(bv):
+			return 1, true
+		}
+		return 0, true
+	case bool:
+		bv, ok := b.(bool)
+		if !ok || av != bv {
+			return 1, ok
+		}
+		return 0, true
+	}
+	return 0, false
+}
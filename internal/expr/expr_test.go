@@ -0,0 +1,103 @@
+package expr
+
+import (
+	"testing"
+	"time"
+)
+
+var testTypes = map[string]string{
+	"time":    "time",
+	"sst":     "float",
+	"count":   "integer",
+	"notes":   "text",
+	"color":   "category",
+	"hasTail": "boolean",
+	"par":     "float",
+	"odd col": "float",
+}
+
+func testValues(values map[string]interface{}) func(string) interface{} {
+	return func(name string) interface{} {
+		return values[name]
+	}
+}
+
+func TestEval(t *testing.T) {
+	t0, _ := time.Parse(time.RFC3339, "2017-05-06T20:00:00Z")
+	values := testValues(map[string]interface{}{
+		"time":    t0,
+		"sst":     21.5,
+		"count":   int64(3),
+		"notes":   "foo",
+		"color":   "red",
+		"hasTail": true,
+		"par":     nil,
+		"odd col": -1.0,
+	})
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"sst > 20", true},
+		{"sst > 20 && par != NA", false},
+		{"sst > 20 && par == NA", true},
+		{"20 < sst", true},
+		{"sst >= 21.5 && sst <= 21.5", true},
+		{"count == 3", true},
+		{"count > 2.5", true},
+		{"count < -1e3", false},
+		{"par > 0", false},
+		{"par < 0", false},
+		{"!(par < 0)", true},
+		{"color == \"red\" || color == 'blue'", true},
+		{"color != \"red\"", false},
+		{"notes < \"goo\"", true},
+		{"hasTail", true},
+		{"!hasTail", false},
+		{"hasTail == FALSE", false},
+		{"time >= \"2017-05-06T20:00:00Z\"", true},
+		{"time < \"2017-05-06 19:00:00+00:00\"", false},
+		{"`odd col` == -1", true},
+		{"sst > count", true},
+		{"sst == NA || count == 3 && color == \"blue\"", false},
+		{"(sst == NA || count == 3) && color == \"red\"", true},
+	}
+	for _, tt := range tests {
+		e, err := Compile(tt.expr, testTypes)
+		if err != nil {
+			t.Errorf("Compile(%q) err %v, expected nil", tt.expr, err)
+			continue
+		}
+		if got := e.Eval(values); got != tt.want {
+			t.Errorf("Compile(%q).Eval() = %v, expected %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"sst >",
+		"sst > 20 &&",
+		"(sst > 20",
+		"sst > 20)",
+		"foo > 1",
+		"sst > \"a\"",
+		"sst < NA",
+		"color == TRUE",
+		"time > 1",
+		"time > \"yesterday\"",
+		"hasTail > TRUE",
+		"sst",
+		"1 < 2",
+		"sst > notes",
+		"sst = 1",
+		"notes == \"open",
+	}
+	for _, s := range tests {
+		_, err := Compile(s, testTypes)
+		if err == nil {
+			t.Errorf("Compile(%q) err nil, expected error", s)
+		}
+	}
+}
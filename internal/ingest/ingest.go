@@ -0,0 +1,144 @@
+// Package ingest implements the gRPC Ingest service in ingest.proto, which
+// validates data lines streamed by clients and acknowledges each line.
+//
+// Messages are encoded without generated code, so servers and clients must
+// use Codec, e.g. with grpc.ForceServerCodec and grpc.ForceCodec.
+package ingest
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/ctberthiaume/tsdata"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ServiceName is the full name of the Ingest service.
+const ServiceName = "tsdata.ingest.v1.Ingest"
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*interface{})(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Validate",
+			Handler:       validateHandler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "ingest.proto",
+}
+
+var validateStreamDesc = &serviceDesc.Streams[0]
+
+// Server implements the Ingest service.
+type Server struct {
+	opts []tsdata.Option
+}
+
+// NewServer returns a Server which creates a Tsdata for each stream with
+// opts. Options set in the first request of a stream are applied after opts.
+func NewServer(opts ...tsdata.Option) *Server {
+	return &Server{opts: opts}
+}
+
+// Register registers the Ingest service with g. g must be created with
+// grpc.ForceServerCodec(Codec{}).
+func (s *Server) Register(g *grpc.Server) {
+	g.RegisterService(&serviceDesc, s)
+}
+
+func validateHandler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(*Server).validate(stream)
+}
+
+func (s *Server) validate(stream grpc.ServerStream) error {
+	req := &ValidateRequest{}
+	if err := stream.RecvMsg(req); err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+
+	opts := append([]tsdata.Option{}, s.opts...)
+	if len(req.NA) > 0 {
+		opts = append(opts, tsdata.WithNATokens(req.NA...))
+	}
+	if req.CheckOrder != "" {
+		order, err := tsdata.ParseTimeOrder(req.CheckOrder)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		opts = append(opts, tsdata.WithTimeOrder(order))
+	}
+	t := tsdata.New(opts...)
+	if err := t.ParseHeader(req.Header); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	line := int64(tsdata.HeaderSize)
+	for {
+		for _, l := range req.Lines {
+			line++
+			if err := stream.SendMsg(validateLine(t, line, l)); err != nil {
+				return err
+			}
+		}
+		req = &ValidateRequest{}
+		if err := stream.RecvMsg(req); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// validateLine returns the Ack for a data line.
+func validateLine(t *tsdata.Tsdata, line int64, s string) *Ack {
+	data, err := t.ValidateLine(s, true)
+	if err != nil {
+		ack := &Ack{Line: line, Error: err.Error()}
+		var ferr *tsdata.FieldError
+		if errors.As(err, &ferr) {
+			ack.Column = int64(ferr.Column + 1)
+		}
+		return ack
+	}
+	return &Ack{Line: line, Valid: true, Warnings: data.Warnings}
+}
+
+// ValidateClient is the client side of a Validate stream.
+type ValidateClient struct {
+	grpc.ClientStream
+}
+
+// Validate starts a Validate stream on cc.
+func Validate(ctx context.Context, cc *grpc.ClientConn, opts ...grpc.CallOption) (*ValidateClient, error) {
+	opts = append([]grpc.CallOption{grpc.ForceCodec(Codec{})}, opts...)
+	stream, err := cc.NewStream(ctx, validateStreamDesc, "/"+ServiceName+"/Validate", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ValidateClient{stream}, nil
+}
+
+// Send sends a request.
+func (c *ValidateClient) Send(req *ValidateRequest) error {
+	return c.SendMsg(req)
+}
+
+// Recv receives the next Ack. It returns io.EOF when the server has finished
+// the stream successfully.
+func (c *ValidateClient) Recv() (*Ack, error) {
+	ack := &Ack{}
+	if err := c.RecvMsg(ack); err != nil {
+		return nil, err
+	}
+	return ack, nil
+}
@@ -0,0 +1,159 @@
+package ingest
+
+import (
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var header = strings.Join([]string{
+	"fileType",
+	"project",
+	"description",
+	"ISO8601 timestamp\tNA\tNA",
+	"time\tfloat\tboolean",
+	"NA\tm/s\tNA",
+	"time\tspeed\tflag",
+}, "\n")
+
+func TestMessageRoundTrip(t *testing.T) {
+	req := &ValidateRequest{Header: header, Lines: []string{"a", "", "b"}, NA: []string{"NaN"}, CheckOrder: "warn"}
+	var gotReq ValidateRequest
+	b, err := Codec{}.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (Codec{}).Unmarshal(b, &gotReq); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&gotReq, req) {
+		t.Errorf("ValidateRequest round trip = %+v, expected %+v", gotReq, *req)
+	}
+
+	ack := &Ack{Line: 9, Error: "bad", Column: 2, Warnings: []string{"w1", "w2"}}
+	var gotAck Ack
+	b, err = Codec{}.Marshal(ack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (Codec{}).Unmarshal(b, &gotAck); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&gotAck, ack) {
+		t.Errorf("Ack round trip = %+v, expected %+v", gotAck, *ack)
+	}
+
+	if err := (Codec{}).Unmarshal([]byte{0x0a, 0x05, 'a'}, &gotReq); err == nil {
+		t.Errorf("Unmarshal of truncated message returned nil error")
+	}
+
+	// Field 2 as a varint and unknown field 9 are skipped.
+	b = []byte{0x10, 0x05, 0x48, 0x01, 0x12, 0x01, 'a'}
+	if err := (Codec{}).Unmarshal(b, &gotReq); err != nil {
+		t.Fatal(err)
+	}
+	if want := (ValidateRequest{Lines: []string{"a"}}); !reflect.DeepEqual(gotReq, want) {
+		t.Errorf("Unmarshal with mismatched fields = %+v, expected %+v", gotReq, want)
+	}
+}
+
+// dial starts a server and returns a connection to it, and a function to
+// stop both.
+func dial(t *testing.T) (*grpc.ClientConn, func()) {
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer(grpc.ForceServerCodec(Codec{}))
+	NewServer().Register(g)
+	go g.Serve(lis)
+	cc, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithInsecure())
+	if err != nil {
+		g.Stop()
+		t.Fatal(err)
+	}
+	return cc, func() {
+		cc.Close()
+		g.Stop()
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cc, stop := dial(t)
+	defer stop()
+	c, err := Validate(context.Background(), cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqs := []*ValidateRequest{
+		{Header: header, CheckOrder: "warn", Lines: []string{
+			"2020-01-01T00:00:00Z\t1.5\tTRUE",
+			"2020-01-01T00:00:01Z\tfast\tTRUE",
+		}},
+		{Lines: []string{
+			"2019-12-31T23:59:59Z\t2\tFALSE",
+			"2020-01-01T00:00:02Z\t2",
+		}},
+	}
+	for _, req := range reqs {
+		if err := c.Send(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+
+	var acks []Ack
+	for {
+		ack, err := c.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		acks = append(acks, *ack)
+	}
+	if len(acks) > 2 {
+		if len(acks[2].Warnings) != 1 {
+			t.Errorf("acks[2].Warnings = %v, expected one time order warning", acks[2].Warnings)
+		}
+		acks[2].Warnings = nil
+	}
+	expected := []Ack{
+		{Line: 8, Valid: true},
+		{Line: 9, Error: "column 2 (speed, float), bad value 'fast'", Column: 2},
+		{Line: 10, Valid: true},
+		{Line: 11, Error: "found 2 columns, expected 3"},
+	}
+	if !reflect.DeepEqual(acks, expected) {
+		t.Errorf("acks = %+v, expected %+v", acks, expected)
+	}
+}
+
+func TestValidateBadHeader(t *testing.T) {
+	cc, stop := dial(t)
+	defer stop()
+	c, err := Validate(context.Background(), cc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Send(&ValidateRequest{Header: "fileType\nproject"}); err != nil {
+		t.Fatal(err)
+	}
+	c.CloseSend()
+	_, err = c.Recv()
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Recv() err = %v, expected code InvalidArgument", err)
+	}
+}
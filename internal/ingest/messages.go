@@ -0,0 +1,188 @@
+package ingest
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ValidateRequest is a message sent by clients of Validate. Header, NA, and
+// CheckOrder are only read from the first request of a stream.
+type ValidateRequest struct {
+	Header     string   // field 1
+	Lines      []string // field 2
+	NA         []string // field 3
+	CheckOrder string   // field 4
+}
+
+// Ack is the validation result for one data line.
+type Ack struct {
+	Line     int64    // field 1
+	Valid    bool     // field 2
+	Error    string   // field 3
+	Column   int64    // field 4, 1-based, 0 if Error is not for a field
+	Warnings []string // field 5
+}
+
+// message is implemented by messages which can be encoded by Codec.
+type message interface {
+	marshal() []byte
+	unmarshal(b []byte) error
+}
+
+func (m *ValidateRequest) marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, m.Header)
+	for _, s := range m.Lines {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendString(b, s)
+	}
+	for _, s := range m.NA {
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendString(b, s)
+	}
+	b = appendString(b, 4, m.CheckOrder)
+	return b
+}
+
+func (m *ValidateRequest) unmarshal(b []byte) error {
+	*m = ValidateRequest{}
+	return eachField(b, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if typ != protowire.BytesType {
+			// Every field is a string, so this is an unknown field.
+			return nil
+		}
+		switch num {
+		case 1:
+			m.Header = string(v)
+		case 2:
+			m.Lines = append(m.Lines, string(v))
+		case 3:
+			m.NA = append(m.NA, string(v))
+		case 4:
+			m.CheckOrder = string(v)
+		}
+		return nil
+	})
+}
+
+func (m *Ack) marshal() []byte {
+	var b []byte
+	b = appendVarint(b, 1, uint64(m.Line))
+	if m.Valid {
+		b = appendVarint(b, 2, 1)
+	}
+	b = appendString(b, 3, m.Error)
+	b = appendVarint(b, 4, uint64(m.Column))
+	for _, s := range m.Warnings {
+		b = protowire.AppendTag(b, 5, protowire.BytesType)
+		b = protowire.AppendString(b, s)
+	}
+	return b
+}
+
+func (m *Ack) unmarshal(b []byte) error {
+	*m = Ack{}
+	return eachField(b, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		switch num {
+		case 1, 2, 4:
+			if typ != protowire.VarintType {
+				return fmt.Errorf("field %v: bad wire type %v", num, typ)
+			}
+			x, n := protowire.ConsumeVarint(v)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			switch num {
+			case 1:
+				m.Line = int64(x)
+			case 2:
+				m.Valid = x != 0
+			case 4:
+				m.Column = int64(x)
+			}
+		case 3:
+			m.Error = string(v)
+		case 5:
+			m.Warnings = append(m.Warnings, string(v))
+		}
+		return nil
+	})
+}
+
+// appendString appends a string field, omitting the proto3 default "".
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+// appendVarint appends a varint field, omitting the proto3 default 0.
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// eachField calls fn for each field in an encoded message. v is the contents
+// of a length-delimited field or the encoded value of other field types.
+func eachField(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		var v []byte
+		if typ == protowire.BytesType {
+			v, n = protowire.ConsumeBytes(b)
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n >= 0 {
+				v = b[:n]
+			}
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if err := fn(num, typ, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Codec is a gRPC codec for the messages in this package. It's named
+// "proto" since messages use the protobuf wire format of ingest.proto.
+type Codec struct{}
+
+// Marshal encodes v, which must be a *ValidateRequest or *Ack.
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(message)
+	if !ok {
+		return nil, fmt.Errorf("ingest: can't marshal %T", v)
+	}
+	return m.marshal(), nil
+}
+
+// Unmarshal decodes data into v, which must be a *ValidateRequest or *Ack.
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(message)
+	if !ok {
+		return fmt.Errorf("ingest: can't unmarshal into %T", v)
+	}
+	if err := m.unmarshal(data); err != nil {
+		return fmt.Errorf("ingest: %w", err)
+	}
+	return nil
+}
+
+// Name returns the codec name used in the content-type header.
+func (Codec) Name() string {
+	return "proto"
+}
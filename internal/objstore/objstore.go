@@ -0,0 +1,484 @@
+// Package objstore reads and writes objects in Amazon S3 and S3 compatible
+// stores, including Google Cloud Storage through its XML API with HMAC keys.
+//
+// Only the parts of the S3 REST API needed to stream objects are implemented:
+// GetObject for reading, and PutObject or a multipart upload for writing.
+// Requests are signed with AWS Signature Version 4.
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinPartSize is the smallest part size allowed in a multipart upload, except
+// for the last part.
+const MinPartSize = 5 << 20
+
+// DefaultPartSize is the default size of multipart upload parts.
+const DefaultPartSize = 16 << 20
+
+// Location is an object named by a URL such as s3://bucket/key or
+// gs://bucket/key.
+type Location struct {
+	Scheme string // s3 or gs
+	Bucket string
+	Key    string
+}
+
+func (l Location) String() string {
+	return l.Scheme + "://" + l.Bucket + "/" + l.Key
+}
+
+// ParseURL parses an s3:// or gs:// object URL. ok is false if s doesn't
+// start with one of these schemes.
+func ParseURL(s string) (loc Location, ok bool, err error) {
+	for _, scheme := range []string{"s3", "gs"} {
+		prefix := scheme + "://"
+		if !strings.HasPrefix(s, prefix) {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(s, prefix), "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return Location{}, true, fmt.Errorf("bad object URL '%v', expected %vBUCKET/KEY", s, prefix)
+		}
+		return Location{Scheme: scheme, Bucket: parts[0], Key: parts[1]}, true, nil
+	}
+	return Location{}, false, nil
+}
+
+// Client makes requests to an S3 compatible object store.
+type Client struct {
+	// Endpoint is the service URL, e.g. https://s3.us-west-2.amazonaws.com.
+	Endpoint string
+	// Region is used to sign requests.
+	Region string
+	// PathStyle puts the bucket in the URL path instead of the host name.
+	PathStyle    bool
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	// PartSize is the size of multipart upload parts, at least MinPartSize.
+	PartSize int
+	// HTTPClient is used for requests, http.DefaultClient if nil.
+	HTTPClient *http.Client
+
+	now func() time.Time // for tests
+}
+
+// NewClientFromEnv creates a Client for scheme configured by environment
+// variables.
+//
+// For s3, credentials are read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+// and AWS_SESSION_TOKEN, and the region from AWS_REGION or
+// AWS_DEFAULT_REGION, which defaults to us-east-1. AWS_ENDPOINT_URL selects
+// another S3 compatible service, which is addressed with path-style URLs.
+//
+// For gs, HMAC credentials are read from GCS_HMAC_ACCESS_KEY_ID and
+// GCS_HMAC_SECRET, and requests are sent to https://storage.googleapis.com.
+func NewClientFromEnv(scheme string) (*Client, error) {
+	c := &Client{PartSize: DefaultPartSize}
+	switch scheme {
+	case "s3":
+		c.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		c.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		c.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		c.Region = os.Getenv("AWS_REGION")
+		if c.Region == "" {
+			c.Region = os.Getenv("AWS_DEFAULT_REGION")
+		}
+		if c.Region == "" {
+			c.Region = "us-east-1"
+		}
+		c.Endpoint = os.Getenv("AWS_ENDPOINT_URL")
+		if c.Endpoint != "" {
+			c.PathStyle = true
+		} else {
+			c.Endpoint = "https://s3." + c.Region + ".amazonaws.com"
+		}
+		if c.AccessKey == "" || c.SecretKey == "" {
+			return nil, errors.New("missing S3 credentials, set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+		}
+	case "gs":
+		c.AccessKey = os.Getenv("GCS_HMAC_ACCESS_KEY_ID")
+		c.SecretKey = os.Getenv("GCS_HMAC_SECRET")
+		c.Region = "auto"
+		c.Endpoint = "https://storage.googleapis.com"
+		c.PathStyle = true
+		if c.AccessKey == "" || c.SecretKey == "" {
+			return nil, errors.New("missing GCS credentials, set GCS_HMAC_ACCESS_KEY_ID and GCS_HMAC_SECRET")
+		}
+	default:
+		return nil, fmt.Errorf("unknown object store scheme '%v'", scheme)
+	}
+	return c, nil
+}
+
+// Open returns the contents of the object at loc. The caller must close the
+// returned reader.
+func (c *Client) Open(ctx context.Context, loc Location) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, loc, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Create returns a Writer which uploads to loc. Data is sent in parts of
+// PartSize bytes with a multipart upload, or with a single request if it is
+// smaller than one part. The object is complete when Close returns nil.
+func (c *Client) Create(ctx context.Context, loc Location) *Writer {
+	size := c.PartSize
+	if size < MinPartSize {
+		size = MinPartSize
+	}
+	return &Writer{c: c, ctx: ctx, loc: loc, size: size}
+}
+
+// Writer uploads an object in parts.
+type Writer struct {
+	c        *Client
+	ctx      context.Context
+	loc      Location
+	size     int
+	buf      []byte
+	uploadID string
+	etags    []string
+	err      error
+	closed   bool
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.closed {
+		return 0, errors.New("write to closed object writer")
+	}
+	n := len(p)
+	for len(p) > 0 {
+		k := w.size - len(w.buf)
+		if k > len(p) {
+			k = len(p)
+		}
+		w.buf = append(w.buf, p[:k]...)
+		p = p[k:]
+		if len(w.buf) == w.size {
+			if err := w.uploadPart(); err != nil {
+				w.abort(err)
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close uploads buffered data and completes the object. Close may be called
+// more than once.
+func (w *Writer) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	if w.err != nil {
+		return w.err
+	}
+	if w.uploadID == "" {
+		resp, err := w.c.do(w.ctx, http.MethodPut, w.loc, nil, w.buf)
+		if err != nil {
+			w.err = err
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+	if len(w.buf) > 0 {
+		if err := w.uploadPart(); err != nil {
+			w.abort(err)
+			return err
+		}
+	}
+	var body bytes.Buffer
+	body.WriteString("<CompleteMultipartUpload>")
+	for i, etag := range w.etags {
+		fmt.Fprintf(&body, "<Part><PartNumber>%v</PartNumber><ETag>", i+1)
+		xml.EscapeText(&body, []byte(etag))
+		body.WriteString("</ETag></Part>")
+	}
+	body.WriteString("</CompleteMultipartUpload>")
+	resp, err := w.c.do(w.ctx, http.MethodPost, w.loc, url.Values{"uploadId": {w.uploadID}}, body.Bytes())
+	if err != nil {
+		w.abort(err)
+		return err
+	}
+	defer resp.Body.Close()
+	// Errors after a 200 status are reported in the response body
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		w.abort(err)
+		return err
+	}
+	if e := parseError(b); e != nil {
+		w.abort(e)
+		return e
+	}
+	return nil
+}
+
+// Abort discards the object without creating it. Parts already uploaded are
+// deleted. Abort does nothing after Close.
+func (w *Writer) Abort() error {
+	if w.closed {
+		return nil
+	}
+	return w.abort(errors.New("write to aborted object writer"))
+}
+
+// uploadPart uploads w.buf as the next part, starting a multipart upload if
+// necessary.
+func (w *Writer) uploadPart() error {
+	if w.uploadID == "" {
+		resp, err := w.c.do(w.ctx, http.MethodPost, w.loc, url.Values{"uploads": {""}}, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		var result struct {
+			UploadID string `xml:"UploadId"`
+		}
+		if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return fmt.Errorf("%v: bad multipart upload response: %v", w.loc, err)
+		}
+		if result.UploadID == "" {
+			return fmt.Errorf("%v: missing multipart upload ID", w.loc)
+		}
+		w.uploadID = result.UploadID
+	}
+	query := url.Values{
+		"partNumber": {strconv.Itoa(len(w.etags) + 1)},
+		"uploadId":   {w.uploadID},
+	}
+	resp, err := w.c.do(w.ctx, http.MethodPut, w.loc, query, w.buf)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	w.etags = append(w.etags, resp.Header.Get("ETag"))
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// abort records err and cancels any multipart upload so that no parts are
+// left in the bucket. It returns an error if the upload can't be cancelled.
+func (w *Writer) abort(err error) error {
+	w.err = err
+	w.closed = true
+	if w.uploadID == "" {
+		return nil
+	}
+	resp, aerr := w.c.do(w.ctx, http.MethodDelete, w.loc, url.Values{"uploadId": {w.uploadID}}, nil)
+	if aerr != nil {
+		return aerr
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Error is an error response from the object store.
+type Error struct {
+	Status  int    // HTTP status code
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("object store error, status %v", e.Status)
+	}
+	return fmt.Sprintf("object store error, status %v, %v: %v", e.Status, e.Code, e.Message)
+}
+
+// parseError returns the error in an XML Error document, or nil if b is not
+// one.
+func parseError(b []byte) *Error {
+	var e Error
+	if err := xml.Unmarshal(b, &e); err != nil || e.Code == "" {
+		return nil
+	}
+	return &e
+}
+
+// do sends a signed request for loc and returns the response if it has a 2xx
+// status.
+func (c *Client) do(ctx context.Context, method string, loc Location, query url.Values, body []byte) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, loc, query, body)
+	if err != nil {
+		return nil, err
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		e := parseError(b)
+		if e == nil {
+			e = &Error{}
+		}
+		e.Status = resp.StatusCode
+		return nil, fmt.Errorf("%v: %w", loc, e)
+	}
+	return resp, nil
+}
+
+// newRequest creates a request for loc signed with Signature Version 4.
+func (c *Client) newRequest(ctx context.Context, method string, loc Location, query url.Values, body []byte) (*http.Request, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("bad endpoint '%v': %v", c.Endpoint, err)
+	}
+	base := strings.TrimSuffix(u.EscapedPath(), "/")
+	if c.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + loc.Bucket + "/" + loc.Key
+		u.RawPath = base + "/" + escapePath(loc.Bucket) + "/" + escapePath(loc.Key)
+	} else {
+		u.Host = loc.Bucket + "." + u.Host
+		u.Path = "/" + loc.Key
+		u.RawPath = "/" + escapePath(loc.Key)
+	}
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.ContentLength = int64(len(body))
+	if body == nil {
+		req.Body = http.NoBody
+	}
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+	sum := sha256.Sum256(body)
+	req.Header.Set("x-amz-content-sha256", hex.EncodeToString(sum[:]))
+	req.Header.Set("x-amz-date", now().UTC().Format("20060102T150405Z"))
+	if c.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", c.SessionToken)
+	}
+	c.sign(req, u.RawPath)
+	return req, nil
+}
+
+// sign adds an Authorization header to req. path is the escaped URL path.
+func (c *Client) sign(req *http.Request, path string) {
+	amzDate := req.Header.Get("x-amz-date")
+	date := amzDate[:8]
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k := range req.Header {
+		lk := strings.ToLower(k)
+		if lk == "range" || lk == "content-md5" || lk == "content-type" || strings.HasPrefix(lk, "x-amz-") {
+			headers[lk] = strings.TrimSpace(req.Header.Get(k))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	canonRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonHeaders.String(),
+		signed,
+		req.Header.Get("x-amz-content-sha256"),
+	}, "\n")
+	scope := date + "/" + c.Region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonRequest))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	k := hmacSHA256([]byte("AWS4"+c.SecretKey), date)
+	k = hmacSHA256(k, c.Region)
+	k = hmacSHA256(k, "s3")
+	k = hmacSHA256(k, "aws4_request")
+	sig := hex.EncodeToString(hmacSHA256(k, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%v/%v, SignedHeaders=%v, Signature=%v",
+		c.AccessKey, scope, signed, sig))
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+// escapePath escapes an object key for a URL path, leaving slashes.
+func escapePath(s string) string {
+	parts := strings.Split(s, "/")
+	for i, p := range parts {
+		parts[i] = escape(p)
+	}
+	return strings.Join(parts, "/")
+}
+
+// escape percent-encodes s as required by Signature Version 4.
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ('A' <= ch && ch <= 'Z') || ('a' <= ch && ch <= 'z') || ('0' <= ch && ch <= '9') ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery encodes query sorted by key, with values escaped as
+// required by Signature Version 4.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, escape(k)+"="+escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
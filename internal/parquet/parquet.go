@@ -0,0 +1,406 @@
+// Package parquet implements a minimal Apache Parquet file writer for flat
+// schemas. Each column chunk is written as a single PLAIN encoded data page,
+// optionally compressed with gzip.
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// ColumnType is the logical type of a column.
+type ColumnType int
+
+const (
+	// Boolean columns hold bool values.
+	Boolean ColumnType = iota
+	// Int64 columns hold int64 values.
+	Int64
+	// Double columns hold float64 values.
+	Double
+	// String columns hold UTF-8 string values.
+	String
+	// Timestamp columns hold time.Time values stored as UTC microseconds.
+	Timestamp
+)
+
+// Compression codecs
+const (
+	Uncompressed = 0
+	Gzip         = 2
+)
+
+// Parquet physical types
+const (
+	physBoolean   = 0
+	physInt64     = 2
+	physDouble    = 5
+	physByteArray = 6
+)
+
+// Parquet converted types
+const (
+	convUTF8            = 0
+	convTimestampMicros = 10
+)
+
+// Parquet encodings
+const (
+	encPlain = 0
+	encRLE   = 3
+)
+
+// Column defines one column in a Parquet file.
+type Column struct {
+	Name     string
+	Type     ColumnType
+	Required bool // required columns may not hold nil values
+}
+
+// DefaultRowGroupSize is the default number of rows buffered in memory before
+// a row group is written.
+const DefaultRowGroupSize = 128 * 1024
+
+// Writer writes rows to a Parquet file.
+type Writer struct {
+	// RowGroupSize is the maximum number of rows in each row group.
+	RowGroupSize int
+	// Codec is the compression codec for data pages, Uncompressed or Gzip.
+	Codec int
+
+	w         io.Writer
+	offset    int64
+	cols      []Column
+	values    [][]interface{}
+	rows      int
+	totalRows int64
+	rowGroups []rowGroup
+	metadata  [][2]string
+	started   bool
+}
+
+type rowGroup struct {
+	chunks    []columnChunk
+	totalSize int64
+	rows      int64
+}
+
+type columnChunk struct {
+	offset           int64
+	numValues        int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+// NewWriter creates a Writer for cols which writes to w.
+func NewWriter(w io.Writer, cols []Column) *Writer {
+	return &Writer{
+		RowGroupSize: DefaultRowGroupSize,
+		Codec:        Gzip,
+		w:            w,
+		cols:         cols,
+		values:       make([][]interface{}, len(cols)),
+	}
+}
+
+// SetMetadata adds a key/value pair to the file metadata.
+func (w *Writer) SetMetadata(key string, value string) {
+	w.metadata = append(w.metadata, [2]string{key, value})
+}
+
+// Write adds one row. Values must be nil or match the column type: bool,
+// int64, float64, string, or time.Time.
+func (w *Writer) Write(row []interface{}) error {
+	if len(row) != len(w.cols) {
+		return fmt.Errorf("row has %v values, expected %v", len(row), len(w.cols))
+	}
+	for i, v := range row {
+		if err := checkValue(w.cols[i], v); err != nil {
+			return err
+		}
+	}
+	for i, v := range row {
+		w.values[i] = append(w.values[i], v)
+	}
+	w.rows++
+	if w.rows >= w.RowGroupSize {
+		return w.flush()
+	}
+	return nil
+}
+
+func checkValue(col Column, v interface{}) error {
+	if v == nil {
+		if col.Required {
+			return fmt.Errorf("column %v, nil value in required column", col.Name)
+		}
+		return nil
+	}
+	ok := false
+	switch col.Type {
+	case Boolean:
+		_, ok = v.(bool)
+	case Int64:
+		_, ok = v.(int64)
+	case Double:
+		_, ok = v.(float64)
+	case String:
+		_, ok = v.(string)
+	case Timestamp:
+		_, ok = v.(time.Time)
+	}
+	if !ok {
+		return fmt.Errorf("column %v, unexpected value type %T", col.Name, v)
+	}
+	return nil
+}
+
+func (w *Writer) write(p []byte) error {
+	n, err := w.w.Write(p)
+	w.offset += int64(n)
+	return err
+}
+
+// flush writes buffered rows as a row group.
+func (w *Writer) flush() error {
+	if !w.started {
+		if err := w.write([]byte("PAR1")); err != nil {
+			return err
+		}
+		w.started = true
+	}
+	if w.rows == 0 {
+		return nil
+	}
+	rg := rowGroup{rows: int64(w.rows)}
+	for i, col := range w.cols {
+		chunk, err := w.writeColumn(col, w.values[i])
+		if err != nil {
+			return err
+		}
+		rg.chunks = append(rg.chunks, chunk)
+		rg.totalSize += chunk.uncompressedSize
+		w.values[i] = w.values[i][:0]
+	}
+	w.rowGroups = append(w.rowGroups, rg)
+	w.totalRows += int64(w.rows)
+	w.rows = 0
+	return nil
+}
+
+// writeColumn writes values as a single data page column chunk.
+func (w *Writer) writeColumn(col Column, values []interface{}) (columnChunk, error) {
+	var page bytes.Buffer
+	if !col.Required {
+		levels := encodeLevels(values)
+		var n [4]byte
+		binary.LittleEndian.PutUint32(n[:], uint32(len(levels)))
+		page.Write(n[:])
+		page.Write(levels)
+	}
+	encodePlain(&page, col.Type, values)
+
+	uncompressed := page.Len()
+	data := page.Bytes()
+	if w.Codec == Gzip {
+		var zbuf bytes.Buffer
+		zw := gzip.NewWriter(&zbuf)
+		if _, err := zw.Write(data); err != nil {
+			return columnChunk{}, err
+		}
+		if err := zw.Close(); err != nil {
+			return columnChunk{}, err
+		}
+		data = zbuf.Bytes()
+	}
+
+	var h compactWriter
+	h.i32(1, 0) // DATA_PAGE
+	h.i32(2, int32(uncompressed))
+	h.i32(3, int32(len(data)))
+	h.beginStruct(5) // DataPageHeader
+	h.i32(1, int32(len(values)))
+	h.i32(2, encPlain)
+	h.i32(3, encRLE)
+	h.i32(4, encRLE)
+	h.endStruct()
+	h.buf.WriteByte(0) // end PageHeader
+
+	chunk := columnChunk{
+		offset:           w.offset,
+		numValues:        int64(len(values)),
+		uncompressedSize: int64(h.buf.Len() + uncompressed),
+		compressedSize:   int64(h.buf.Len() + len(data)),
+	}
+	if err := w.write(h.buf.Bytes()); err != nil {
+		return chunk, err
+	}
+	return chunk, w.write(data)
+}
+
+// encodeLevels encodes definition levels for an optional column using the
+// RLE/bit-packing hybrid encoding with bit width 1.
+func encodeLevels(values []interface{}) []byte {
+	var buf bytes.Buffer
+	var b [binary.MaxVarintLen64]byte
+	for i := 0; i < len(values); {
+		level := byte(1)
+		if values[i] == nil {
+			level = 0
+		}
+		j := i + 1
+		for j < len(values) && (values[j] == nil) == (level == 0) {
+			j++
+		}
+		n := binary.PutUvarint(b[:], uint64(j-i)<<1) // RLE run header
+		buf.Write(b[:n])
+		buf.WriteByte(level)
+		i = j
+	}
+	return buf.Bytes()
+}
+
+// encodePlain writes non-nil values with PLAIN encoding.
+func encodePlain(buf *bytes.Buffer, typ ColumnType, values []interface{}) {
+	var b [8]byte
+	var bits byte
+	nbits := 0
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		switch typ {
+		case Boolean:
+			if v.(bool) {
+				bits |= 1 << uint(nbits)
+			}
+			nbits++
+			if nbits == 8 {
+				buf.WriteByte(bits)
+				bits, nbits = 0, 0
+			}
+		case Int64:
+			binary.LittleEndian.PutUint64(b[:], uint64(v.(int64)))
+			buf.Write(b[:])
+		case Double:
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(v.(float64)))
+			buf.Write(b[:])
+		case Timestamp:
+			t := v.(time.Time)
+			micros := t.Unix()*1e6 + int64(t.Nanosecond()/1e3)
+			binary.LittleEndian.PutUint64(b[:], uint64(micros))
+			buf.Write(b[:])
+		case String:
+			s := v.(string)
+			binary.LittleEndian.PutUint32(b[:4], uint32(len(s)))
+			buf.Write(b[:4])
+			buf.WriteString(s)
+		}
+	}
+	if nbits > 0 {
+		buf.WriteByte(bits)
+	}
+}
+
+// Close writes any buffered rows and the file footer. It does not close the
+// underlying io.Writer.
+func (w *Writer) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	footer := w.footer()
+	if err := w.write(footer); err != nil {
+		return err
+	}
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(len(footer)))
+	if err := w.write(n[:]); err != nil {
+		return err
+	}
+	return w.write([]byte("PAR1"))
+}
+
+// footer encodes FileMetaData.
+func (w *Writer) footer() []byte {
+	var c compactWriter
+	c.i32(1, 1) // version
+	c.listHeader(2, ctStruct, len(w.cols)+1)
+	c.beginStruct(0) // root SchemaElement
+	c.binary(4, "schema")
+	c.i32(5, int32(len(w.cols)))
+	c.endStruct()
+	for _, col := range w.cols {
+		c.beginStruct(0)
+		c.i32(1, physicalType(col.Type))
+		if col.Required {
+			c.i32(3, 0)
+		} else {
+			c.i32(3, 1)
+		}
+		c.binary(4, col.Name)
+		switch col.Type {
+		case String:
+			c.i32(6, convUTF8)
+		case Timestamp:
+			c.i32(6, convTimestampMicros)
+		}
+		c.endStruct()
+	}
+	c.i64(3, w.totalRows)
+	c.listHeader(4, ctStruct, len(w.rowGroups))
+	for _, rg := range w.rowGroups {
+		c.beginStruct(0)
+		c.listHeader(1, ctStruct, len(rg.chunks))
+		for i, chunk := range rg.chunks {
+			col := w.cols[i]
+			c.beginStruct(0) // ColumnChunk
+			c.i64(2, chunk.offset)
+			c.beginStruct(3) // ColumnMetaData
+			c.i32(1, physicalType(col.Type))
+			c.listHeader(2, ctI32, 2)
+			c.varint(encPlain)
+			c.varint(encRLE)
+			c.listHeader(3, ctBinary, 1)
+			c.rawBinary(col.Name)
+			c.i32(4, int32(w.Codec))
+			c.i64(5, chunk.numValues)
+			c.i64(6, chunk.uncompressedSize)
+			c.i64(7, chunk.compressedSize)
+			c.i64(9, chunk.offset)
+			c.endStruct()
+			c.endStruct()
+		}
+		c.i64(2, rg.totalSize)
+		c.i64(3, rg.rows)
+		c.endStruct()
+	}
+	if len(w.metadata) > 0 {
+		c.listHeader(5, ctStruct, len(w.metadata))
+		for _, kv := range w.metadata {
+			c.beginStruct(0)
+			c.binary(1, kv[0])
+			c.binary(2, kv[1])
+			c.endStruct()
+		}
+	}
+	c.binary(6, "github.com/ctberthiaume/tsdata")
+	c.buf.WriteByte(0) // end FileMetaData
+	return c.buf.Bytes()
+}
+
+func physicalType(t ColumnType) int32 {
+	switch t {
+	case Boolean:
+		return physBoolean
+	case Int64, Timestamp:
+		return physInt64
+	case Double:
+		return physDouble
+	}
+	return physByteArray
+}
@@ -0,0 +1,173 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"testing"
+	"time"
+)
+
+// compactReader decodes Thrift compact protocol structs into maps of field id
+// to value for tests.
+type compactReader struct {
+	b []byte
+	i int
+}
+
+func (r *compactReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b[r.i:])
+	r.i += n
+	return v
+}
+
+func (r *compactReader) varint() int64 {
+	u := r.uvarint()
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (r *compactReader) value(typ byte) interface{} {
+	switch typ {
+	case ctBoolTrue:
+		return true
+	case ctBoolFalse:
+		return false
+	case ctI32, ctI64:
+		return r.varint()
+	case ctBinary:
+		n := int(r.uvarint())
+		s := string(r.b[r.i : r.i+n])
+		r.i += n
+		return s
+	case ctList:
+		h := r.b[r.i]
+		r.i++
+		size := int(h >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		list := make([]interface{}, size)
+		for j := range list {
+			list[j] = r.value(h & 0x0f)
+		}
+		return list
+	case ctStruct:
+		return r.structValue()
+	}
+	panic("unsupported type")
+}
+
+func (r *compactReader) structValue() map[int16]interface{} {
+	m := map[int16]interface{}{}
+	var last int16
+	for {
+		h := r.b[r.i]
+		r.i++
+		if h == 0 {
+			return m
+		}
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(r.varint())
+		}
+		m[id] = r.value(h & 0x0f)
+		last = id
+	}
+}
+
+func TestWriter(t *testing.T) {
+	t0, _ := time.Parse(time.RFC3339, "2017-05-06T19:52:57.601Z")
+	cols := []Column{
+		{Name: "time", Type: Timestamp, Required: true},
+		{Name: "speed", Type: Double},
+		{Name: "count", Type: Int64},
+		{Name: "flag", Type: Boolean},
+		{Name: "notes", Type: String},
+	}
+	rows := [][]interface{}{
+		{t0, 6.5, int64(1), true, "foo"},
+		{t0.Add(time.Second), nil, int64(2), false, nil},
+		{t0.Add(2 * time.Second), 7.5, nil, true, "bar"},
+	}
+	for _, codec := range []int{Uncompressed, Gzip} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf, cols)
+		w.Codec = codec
+		w.RowGroupSize = 2
+		w.SetMetadata("fileType", "test")
+		for _, row := range rows {
+			if err := w.Write(row); err != nil {
+				t.Fatalf("Writer.Write() err %v, expected nil", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Writer.Close() err %v, expected nil", err)
+		}
+
+		b := buf.Bytes()
+		if string(b[:4]) != "PAR1" || string(b[len(b)-4:]) != "PAR1" {
+			t.Fatalf("missing PAR1 magic")
+		}
+		flen := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+		fr := &compactReader{b: b[len(b)-8-flen : len(b)-8]}
+		meta := fr.structValue()
+		if meta[3].(int64) != 3 {
+			t.Errorf("num_rows %v, expected 3", meta[3])
+		}
+		schema := meta[2].([]interface{})
+		if len(schema) != len(cols)+1 || schema[2].(map[int16]interface{})[4] != "speed" {
+			t.Errorf("schema %v, expected %v columns", schema, len(cols))
+		}
+		kv := meta[5].([]interface{})[0].(map[int16]interface{})
+		if kv[1] != "fileType" || kv[2] != "test" {
+			t.Errorf("key_value_metadata %v", kv)
+		}
+		rgs := meta[4].([]interface{})
+		if len(rgs) != 2 {
+			t.Fatalf("%v row groups, expected 2", len(rgs))
+		}
+
+		// Decode speed column from the first row group
+		chunk := rgs[0].(map[int16]interface{})[1].([]interface{})[1].(map[int16]interface{})
+		cmeta := chunk[3].(map[int16]interface{})
+		off := int(cmeta[9].(int64))
+		pr := &compactReader{b: b, i: off}
+		ph := pr.structValue()
+		page := b[pr.i : pr.i+int(ph[3].(int64))]
+		if codec == Gzip {
+			zr, err := gzip.NewReader(bytes.NewReader(page))
+			if err != nil {
+				t.Fatalf("gzip.NewReader() err %v", err)
+			}
+			page, _ = ioutil.ReadAll(zr)
+		}
+		if len(page) != int(ph[2].(int64)) {
+			t.Errorf("page size %v, expected %v", len(page), ph[2])
+		}
+		levelLen := int(binary.LittleEndian.Uint32(page))
+		levels := page[4 : 4+levelLen]
+		// One run of 1, one run of 0
+		if !bytes.Equal(levels, []byte{2, 1, 2, 0}) {
+			t.Errorf("definition levels %v, expected [2 1 2 0]", levels)
+		}
+		vals := page[4+levelLen:]
+		if len(vals) != 8 || math.Float64frombits(binary.LittleEndian.Uint64(vals)) != 6.5 {
+			t.Errorf("speed values %v, expected one 6.5", vals)
+		}
+	}
+}
+
+func TestWriter_badValue(t *testing.T) {
+	w := NewWriter(ioutil.Discard, []Column{{Name: "time", Type: Timestamp, Required: true}, {Name: "x", Type: Double}})
+	if err := w.Write([]interface{}{nil, 1.0}); err == nil {
+		t.Errorf("Writer.Write() err nil for nil required value, expected error")
+	}
+	if err := w.Write([]interface{}{time.Now(), "1.0"}); err == nil {
+		t.Errorf("Writer.Write() err nil for wrong value type, expected error")
+	}
+	if err := w.Write([]interface{}{time.Now()}); err == nil {
+		t.Errorf("Writer.Write() err nil for short row, expected error")
+	}
+}
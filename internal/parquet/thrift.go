@@ -0,0 +1,101 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Thrift compact protocol type codes
+const (
+	ctBoolTrue  = 1
+	ctBoolFalse = 2
+	ctI32       = 5
+	ctI64       = 6
+	ctBinary    = 8
+	ctList      = 9
+	ctStruct    = 12
+)
+
+// compactWriter encodes Thrift structs with the compact protocol, which is
+// used for all Parquet metadata.
+type compactWriter struct {
+	buf    bytes.Buffer
+	last   int16   // last field id in the current struct
+	fields []int16 // saved last field ids for enclosing structs
+}
+
+func (c *compactWriter) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	c.buf.Write(b[:n])
+}
+
+func (c *compactWriter) varint(v int64) {
+	c.uvarint(uint64((v << 1) ^ (v >> 63))) // zigzag
+}
+
+func (c *compactWriter) fieldHeader(id int16, typ byte) {
+	delta := id - c.last
+	if delta > 0 && delta <= 15 {
+		c.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		c.buf.WriteByte(typ)
+		c.varint(int64(id))
+	}
+	c.last = id
+}
+
+func (c *compactWriter) i32(id int16, v int32) {
+	c.fieldHeader(id, ctI32)
+	c.varint(int64(v))
+}
+
+func (c *compactWriter) i64(id int16, v int64) {
+	c.fieldHeader(id, ctI64)
+	c.varint(v)
+}
+
+func (c *compactWriter) bool(id int16, v bool) {
+	if v {
+		c.fieldHeader(id, ctBoolTrue)
+	} else {
+		c.fieldHeader(id, ctBoolFalse)
+	}
+}
+
+func (c *compactWriter) binary(id int16, v string) {
+	c.fieldHeader(id, ctBinary)
+	c.rawBinary(v)
+}
+
+func (c *compactWriter) rawBinary(v string) {
+	c.uvarint(uint64(len(v)))
+	c.buf.WriteString(v)
+}
+
+// listHeader starts a list field with size elements of type elemType.
+func (c *compactWriter) listHeader(id int16, elemType byte, size int) {
+	c.fieldHeader(id, ctList)
+	if size < 15 {
+		c.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		c.buf.WriteByte(0xf0 | elemType)
+		c.uvarint(uint64(size))
+	}
+}
+
+// beginStruct starts a struct field. Pass id 0 for a struct which is a list
+// element and so has no field header.
+func (c *compactWriter) beginStruct(id int16) {
+	if id != 0 {
+		c.fieldHeader(id, ctStruct)
+	}
+	c.fields = append(c.fields, c.last)
+	c.last = 0
+}
+
+func (c *compactWriter) endStruct() {
+	c.buf.WriteByte(0) // stop field
+	c.last = c.fields[len(c.fields)-1]
+	c.fields = c.fields[:len(c.fields)-1]
+}
@@ -0,0 +1,123 @@
+// Package promwrite encodes Prometheus remote-write requests: a protobuf
+// WriteRequest message compressed with the snappy block format.
+//
+// Only the parts of the protocol needed to send samples are implemented.
+// Snappy output uses literal elements only. This is valid snappy which any
+// decoder accepts, but it is not smaller than the input.
+package promwrite
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// Label is a time series label.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is one value of a time series. Timestamp is in milliseconds since
+// the Unix epoch.
+type Sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+// TimeSeries is a set of labels and samples in time order.
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+// HTTP headers for remote-write requests
+const (
+	ContentType     = "application/x-protobuf"
+	ContentEncoding = "snappy"
+	Version         = "0.1.0"
+)
+
+// Encode returns a snappy compressed WriteRequest message for series. Labels
+// are sorted by name as required by the protocol.
+func Encode(series []TimeSeries) []byte {
+	var req []byte
+	for _, ts := range series {
+		req = appendBytesField(req, 1, marshalTimeSeries(ts))
+	}
+	return snappyEncode(req)
+}
+
+func marshalTimeSeries(ts TimeSeries) []byte {
+	labels := make([]Label, len(ts.Labels))
+	copy(labels, ts.Labels)
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	var b []byte
+	for _, l := range labels {
+		var lb []byte
+		lb = appendBytesField(lb, 1, []byte(l.Name))
+		lb = appendBytesField(lb, 2, []byte(l.Value))
+		b = appendBytesField(b, 1, lb)
+	}
+	for _, s := range ts.Samples {
+		var sb []byte
+		sb = appendTag(sb, 1, wireFixed64)
+		var f [8]byte
+		binary.LittleEndian.PutUint64(f[:], math.Float64bits(s.Value))
+		sb = append(sb, f[:]...)
+		sb = appendTag(sb, 2, wireVarint)
+		sb = appendUvarint(sb, uint64(s.Timestamp))
+		b = appendBytesField(b, 2, sb)
+	}
+	return b
+}
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func appendTag(b []byte, field int, wire int) []byte {
+	return appendUvarint(b, uint64(field<<3|wire))
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = appendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// maxLiteral is the longest literal element written by snappyEncode.
+const maxLiteral = 1 << 16
+
+// snappyEncode encodes src in the snappy block format using only literal
+// elements.
+func snappyEncode(src []byte) []byte {
+	dst := appendUvarint(nil, uint64(len(src)))
+	for len(src) > 0 {
+		n := len(src)
+		if n > maxLiteral {
+			n = maxLiteral
+		}
+		m := n - 1
+		switch {
+		case m < 60:
+			dst = append(dst, byte(m<<2))
+		case m < 1<<8:
+			dst = append(dst, 60<<2, byte(m))
+		default:
+			dst = append(dst, 61<<2, byte(m), byte(m>>8))
+		}
+		dst = append(dst, src[:n]...)
+		src = src[n:]
+	}
+	return dst
+}
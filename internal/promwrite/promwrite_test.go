@@ -0,0 +1,141 @@
+package promwrite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// snappyDecode decodes literal-only snappy blocks.
+func snappyDecode(src []byte) ([]byte, error) {
+	n, k := binary.Uvarint(src)
+	src = src[k:]
+	var dst []byte
+	for len(src) > 0 {
+		tag := src[0]
+		if tag&3 != 0 {
+			return nil, fmt.Errorf("unexpected copy element")
+		}
+		m := int(tag >> 2)
+		src = src[1:]
+		switch m {
+		case 60:
+			m = int(src[0])
+			src = src[1:]
+		case 61:
+			m = int(src[0]) | int(src[1])<<8
+			src = src[2:]
+		}
+		dst = append(dst, src[:m+1]...)
+		src = src[m+1:]
+	}
+	if uint64(len(dst)) != n {
+		return nil, fmt.Errorf("decoded %v bytes, expected %v", len(dst), n)
+	}
+	return dst, nil
+}
+
+type field struct {
+	num   int
+	wire  int
+	value uint64
+	bytes []byte
+}
+
+func decodeFields(b []byte) ([]field, error) {
+	var fields []field
+	for len(b) > 0 {
+		tag, k := binary.Uvarint(b)
+		if k <= 0 {
+			return nil, fmt.Errorf("bad tag")
+		}
+		b = b[k:]
+		f := field{num: int(tag >> 3), wire: int(tag & 7)}
+		switch f.wire {
+		case wireVarint:
+			f.value, k = binary.Uvarint(b)
+			b = b[k:]
+		case wireFixed64:
+			f.value = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case wireBytes:
+			n, k := binary.Uvarint(b)
+			b = b[k:]
+			f.bytes = b[:n]
+			b = b[n:]
+		default:
+			return nil, fmt.Errorf("bad wire type %v", f.wire)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func TestSnappyEncode(t *testing.T) {
+	for _, n := range []int{0, 1, 60, 61, 256, 257, maxLiteral, maxLiteral*2 + 3} {
+		src := bytes.Repeat([]byte{'a', 'b', 'c'}, n)[:n]
+		got, err := snappyDecode(snappyEncode(src))
+		if err != nil {
+			t.Errorf("snappyEncode(%v bytes) err %v, expected nil", n, err)
+			continue
+		}
+		if !bytes.Equal(got, src) {
+			t.Errorf("snappyEncode(%v bytes) did not round trip", n)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	series := []TimeSeries{
+		{
+			Labels:  []Label{{"project", "p"}, {"__name__", "m_speed"}},
+			Samples: []Sample{{6.5, 1000}, {10, 2000}},
+		},
+		{
+			Labels:  []Label{{"__name__", "m_distance"}},
+			Samples: []Sample{{100, 1000}},
+		},
+	}
+	b, err := snappyDecode(Encode(series))
+	if err != nil {
+		t.Fatalf("Encode() err %v, expected nil", err)
+	}
+	req, err := decodeFields(b)
+	if err != nil {
+		t.Fatalf("decode WriteRequest err %v, expected nil", err)
+	}
+	if len(req) != 2 {
+		t.Fatalf("found %v timeseries, expected 2", len(req))
+	}
+	ts, err := decodeFields(req[0].bytes)
+	if err != nil {
+		t.Fatalf("decode TimeSeries err %v, expected nil", err)
+	}
+	if len(ts) != 4 {
+		t.Fatalf("found %v TimeSeries fields, expected 4", len(ts))
+	}
+	// Labels sorted by name
+	for i, want := range []Label{{"__name__", "m_speed"}, {"project", "p"}} {
+		lf, _ := decodeFields(ts[i].bytes)
+		if ts[i].num != 1 || len(lf) != 2 || string(lf[0].bytes) != want.Name || string(lf[1].bytes) != want.Value {
+			t.Errorf("label %v = %v, expected %v", i, lf, want)
+		}
+	}
+	for i, want := range series[0].Samples {
+		sf, _ := decodeFields(ts[i+2].bytes)
+		if ts[i+2].num != 2 || len(sf) != 2 {
+			t.Fatalf("sample %v bad fields %v", i, sf)
+		}
+		if v := math.Float64frombits(sf[0].value); v != want.Value {
+			t.Errorf("sample %v value = %v, expected %v", i, v, want.Value)
+		}
+		if int64(sf[1].value) != want.Timestamp {
+			t.Errorf("sample %v timestamp = %v, expected %v", i, sf[1].value, want.Timestamp)
+		}
+	}
+	if series[0].Labels[0].Name != "project" {
+		t.Errorf("Encode() modified input labels")
+	}
+}
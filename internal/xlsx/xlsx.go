@@ -0,0 +1,222 @@
+// Package xlsx implements a minimal Office Open XML (Excel) workbook writer.
+// Rows are streamed to worksheets one at a time. Cells may hold strings,
+// numbers, booleans, or dates, and strings are stored inline rather than in a
+// shared strings table.
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxRows is the maximum number of rows in a worksheet.
+const MaxRows = 1048576
+
+// MaxColumns is the maximum number of columns in a worksheet.
+const MaxColumns = 16384
+
+// dateStyle is the index of the date cell format in styles.xml.
+const dateStyle = 1
+
+// epoch is the origin of Excel date serial numbers in the 1900 date system,
+// valid for dates after 1900-03-01.
+var epoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// Workbook writes an xlsx file.
+type Workbook struct {
+	zw     *zip.Writer
+	sheets []string
+	sheet  *bufio.Writer // current worksheet, nil before AddSheet
+	rows   int           // rows written to the current worksheet
+	closed bool
+}
+
+// NewWorkbook creates a Workbook which writes to w.
+func NewWorkbook(w io.Writer) *Workbook {
+	return &Workbook{zw: zip.NewWriter(w)}
+}
+
+// AddSheet finishes the current worksheet and starts a new one named name.
+// Rows written after AddSheet go to the new worksheet.
+func (wb *Workbook) AddSheet(name string) error {
+	if wb.closed {
+		return errors.New("add sheet to closed workbook")
+	}
+	if name == "" || len(name) > 31 || strings.ContainsAny(name, `[]:*?/\`) {
+		return fmt.Errorf("bad sheet name '%v'", name)
+	}
+	for _, s := range wb.sheets {
+		if strings.EqualFold(s, name) {
+			return fmt.Errorf("duplicate sheet name '%v'", name)
+		}
+	}
+	if err := wb.endSheet(); err != nil {
+		return err
+	}
+	wb.sheets = append(wb.sheets, name)
+	f, err := wb.zw.Create(fmt.Sprintf("xl/worksheets/sheet%v.xml", len(wb.sheets)))
+	if err != nil {
+		return err
+	}
+	wb.sheet = bufio.NewWriter(f)
+	wb.rows = 0
+	_, err = wb.sheet.WriteString(xml.Header +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return err
+}
+
+// WriteRow adds a row to the current worksheet. Cells may be nil for an empty
+// cell, string, float64, int64, bool, or time.Time. NaN and infinite numbers
+// are written as strings because Excel can't represent them.
+func (wb *Workbook) WriteRow(cells []interface{}) error {
+	if wb.sheet == nil {
+		return errors.New("write row before AddSheet")
+	}
+	if wb.rows >= MaxRows {
+		return fmt.Errorf("too many rows, maximum is %v", MaxRows)
+	}
+	if len(cells) > MaxColumns {
+		return fmt.Errorf("too many columns, maximum is %v", MaxColumns)
+	}
+	for i, v := range cells {
+		switch v.(type) {
+		case nil, string, float64, int64, bool, time.Time:
+		default:
+			return fmt.Errorf("column %v, unsupported cell type %T", i+1, v)
+		}
+	}
+	wb.rows++
+	w := wb.sheet
+	fmt.Fprintf(w, `<row r="%v">`, wb.rows)
+	for i, v := range cells {
+		ref := cellRef(i, wb.rows)
+		switch v := v.(type) {
+		case nil:
+			continue
+		case string:
+			writeString(w, ref, v)
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				writeString(w, ref, strconv.FormatFloat(v, 'g', -1, 64))
+				continue
+			}
+			fmt.Fprintf(w, `<c r="%v"><v>%v</v></c>`, ref, strconv.FormatFloat(v, 'g', -1, 64))
+		case int64:
+			fmt.Fprintf(w, `<c r="%v"><v>%v</v></c>`, ref, v)
+		case bool:
+			b := 0
+			if v {
+				b = 1
+			}
+			fmt.Fprintf(w, `<c r="%v" t="b"><v>%v</v></c>`, ref, b)
+		case time.Time:
+			serial := float64(v.Sub(epoch)) / float64(24*time.Hour)
+			fmt.Fprintf(w, `<c r="%v" s="%v"><v>%v</v></c>`, ref, dateStyle, strconv.FormatFloat(serial, 'f', -1, 64))
+		}
+	}
+	_, err := w.WriteString("</row>")
+	return err
+}
+
+// Close finishes the workbook. It does not close the underlying writer.
+func (wb *Workbook) Close() error {
+	if wb.closed {
+		return nil
+	}
+	if len(wb.sheets) == 0 {
+		return errors.New("workbook has no sheets")
+	}
+	if err := wb.endSheet(); err != nil {
+		return err
+	}
+	wb.closed = true
+
+	var sheets, rels, overrides strings.Builder
+	for i, name := range wb.sheets {
+		var escaped strings.Builder
+		xml.EscapeText(&escaped, []byte(name))
+		fmt.Fprintf(&sheets, `<sheet name="%v" sheetId="%v" r:id="rId%v"/>`, escaped.String(), i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%v" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%v.xml"/>`, i+1, i+1)
+		fmt.Fprintf(&overrides, `<Override PartName="/xl/worksheets/sheet%v.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	stylesID := len(wb.sheets) + 1
+	fmt.Fprintf(&rels, `<Relationship Id="rId%v" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, stylesID)
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			overrides.String() + `</Types>`},
+		{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+			`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` +
+			sheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			rels.String() + `</Relationships>`},
+		{"xl/styles.xml", `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss.000"/></numFmts>` +
+			`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>` +
+			`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+			`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+			`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+			`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+			`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>` +
+			`</styleSheet>`},
+	}
+	for _, f := range files {
+		w, err := wb.zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, xml.Header+f.content); err != nil {
+			return err
+		}
+	}
+	return wb.zw.Close()
+}
+
+// endSheet finishes the current worksheet, if there is one.
+func (wb *Workbook) endSheet() error {
+	if wb.sheet == nil {
+		return nil
+	}
+	if _, err := wb.sheet.WriteString(`</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	err := wb.sheet.Flush()
+	wb.sheet = nil
+	return err
+}
+
+// writeString writes an inline string cell.
+func writeString(w *bufio.Writer, ref string, s string) {
+	fmt.Fprintf(w, `<c r="%v" t="inlineStr"><is><t xml:space="preserve">`, ref)
+	xml.EscapeText(w, []byte(s))
+	w.WriteString(`</t></is></c>`)
+}
+
+// cellRef returns the A1 style reference for 0-based column col and 1-based
+// row.
+func cellRef(col int, row int) string {
+	name := ""
+	for col >= 0 {
+		name = string(rune('A'+col%26)) + name
+		col = col/26 - 1
+	}
+	return name + strconv.Itoa(row)
+}
@@ -0,0 +1,116 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCellRef(t *testing.T) {
+	tests := []struct {
+		col  int
+		row  int
+		want string
+	}{
+		{0, 1, "A1"},
+		{25, 2, "Z2"},
+		{26, 3, "AA3"},
+		{701, 4, "ZZ4"},
+		{702, 5, "AAA5"},
+	}
+	for _, tt := range tests {
+		if got := cellRef(tt.col, tt.row); got != tt.want {
+			t.Errorf("cellRef(%v, %v) = %v, expected %v", tt.col, tt.row, got, tt.want)
+		}
+	}
+}
+
+func TestWorkbook(t *testing.T) {
+	var buf bytes.Buffer
+	wb := NewWorkbook(&buf)
+	if err := wb.WriteRow([]interface{}{"a"}); err == nil {
+		t.Errorf("WriteRow() before AddSheet err %v, expected a non-nil error", err)
+	}
+	if err := wb.AddSheet("metadata"); err != nil {
+		t.Fatal(err)
+	}
+	if err := wb.WriteRow([]interface{}{"project", "a < b & c"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wb.AddSheet("Metadata"); err == nil {
+		t.Errorf("AddSheet() err %v, expected an error for a duplicate name", err)
+	}
+	if err := wb.AddSheet("data"); err != nil {
+		t.Fatal(err)
+	}
+	tm := time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC)
+	row := []interface{}{tm, 1.5, int64(3), true, nil, "text", math.NaN()}
+	if err := wb.WriteRow(row); err != nil {
+		t.Fatal(err)
+	}
+	if err := wb.WriteRow([]interface{}{struct{}{}}); err == nil {
+		t.Errorf("WriteRow() err %v, expected an error for an unsupported type", err)
+	}
+	if err := wb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader() err %v, expected nil", err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(b)
+		dec := xml.NewDecoder(bytes.NewReader(b))
+		for {
+			_, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%v is not well-formed XML: %v", f.Name, err)
+			}
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels",
+		"xl/styles.xml", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("workbook is missing %v", name)
+		}
+	}
+	if !strings.Contains(files["xl/worksheets/sheet1.xml"], "a &lt; b &amp; c") {
+		t.Errorf("sheet1.xml = %v, expected an escaped string", files["xl/worksheets/sheet1.xml"])
+	}
+	sheet2 := files["xl/worksheets/sheet2.xml"]
+	for _, want := range []string{
+		`<c r="A1" s="1"><v>43832.5</v></c>`,
+		`<c r="B1"><v>1.5</v></c>`,
+		`<c r="C1"><v>3</v></c>`,
+		`<c r="D1" t="b"><v>1</v></c>`,
+		`<c r="F1" t="inlineStr"><is><t xml:space="preserve">text</t></is></c>`,
+		`<c r="G1" t="inlineStr"><is><t xml:space="preserve">NaN</t></is></c>`,
+	} {
+		if !strings.Contains(sheet2, want) {
+			t.Errorf("sheet2.xml = %v, expected it to contain %v", sheet2, want)
+		}
+	}
+	if strings.Contains(sheet2, `r="E1"`) {
+		t.Errorf("sheet2.xml = %v, expected no cell for nil", sheet2)
+	}
+}
@@ -0,0 +1,341 @@
+package tsdata
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// structColumn describes one struct field mapped to a TSDATA column.
+type structColumn struct {
+	index []int
+	name  string
+	typ   string
+	unit  string
+	// constraint holds allowed values from a type option such as
+	// type=category:a|b
+	constraint Constraint
+}
+
+// compatibleType reports whether a tag type option typ may be used for a
+// field whose Go type maps to kindType.
+func compatibleType(kindType string, typ string) bool {
+	switch kindType {
+	case "text":
+		return typ == "category"
+	case "float":
+		return typ == "latitude" || typ == "longitude"
+	}
+	return false
+}
+
+// structColumns returns column definitions for struct type st based on field
+// tags of the form `tsdata:"name,unit=m/s,type=category"`. Fields without a
+// tag use the field name. Fields tagged "-" and unexported fields are skipped.
+func structColumns(st reflect.Type) ([]structColumn, error) {
+	if st.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected struct, got %v", st)
+	}
+	cols := []structColumn{}
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		tag := f.Tag.Get("tsdata")
+		if tag == "-" {
+			continue
+		}
+		col := structColumn{index: f.Index, name: f.Name, unit: NA}
+		parts := strings.Split(tag, ",")
+		if parts[0] != "" {
+			col.name = parts[0]
+		}
+		for _, opt := range parts[1:] {
+			kv := strings.SplitN(opt, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("field %v, bad tag option '%v'", f.Name, opt)
+			}
+			switch kv[0] {
+			case "unit":
+				col.unit = kv[1]
+			case "type":
+				typ, c, err := parseType(kv[1])
+				if err != nil {
+					return nil, fmt.Errorf("field %v, %v", f.Name, err)
+				}
+				col.typ, col.constraint = typ, c
+			default:
+				return nil, fmt.Errorf("field %v, unknown tag option '%v'", f.Name, kv[0])
+			}
+		}
+		kindType, err := columnType(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %v, %v", f.Name, err)
+		}
+		if col.typ == "" {
+			col.typ = kindType
+		} else if col.typ != kindType && !compatibleType(kindType, col.typ) {
+			return nil, fmt.Errorf("field %v, type %v incompatible with %v", f.Name, col.typ, f.Type)
+		}
+		cols = append(cols, col)
+	}
+	if len(cols) == 0 || cols[0].name != "time" || cols[0].typ != "time" {
+		return nil, errors.New("first field should be a time.Time column named 'time'")
+	}
+	return cols, nil
+}
+
+// columnType returns the TSDATA type for Go type ft. Pointer types are
+// dereferenced.
+func columnType(ft reflect.Type) (string, error) {
+	if ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+	}
+	if ft == timeType {
+		return "time", nil
+	}
+	switch ft.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return "float", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer", nil
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.String:
+		return "text", nil
+	}
+	return "", fmt.Errorf("unsupported type %v", ft)
+}
+
+// Marshal writes a TSDATA file to w with one data line for each struct in the
+// slice v. The FileType, Project, and FileDescription from t are used in the
+// header, while Types, Units, and Headers are derived from struct fields. See
+// Unmarshal for struct tag syntax. Nil pointer fields are written as NA. Each
+// line is validated before it's written, and a value which would make an
+// invalid line, such as a string containing the delimiter or a line break, is
+// an error.
+func Marshal(w io.Writer, t *Tsdata, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice {
+		return fmt.Errorf("Marshal expects a slice of structs, got %T", v)
+	}
+	cols, err := structColumns(rv.Type().Elem())
+	if err != nil {
+		return err
+	}
+	meta := &Tsdata{
+		delim:           t.delim,
+		FileType:        t.FileType,
+		Project:         t.Project,
+		FileDescription: t.FileDescription,
+	}
+	for _, col := range cols {
+		meta.Types = append(meta.Types, col.typ)
+		meta.Units = append(meta.Units, col.unit)
+		meta.Headers = append(meta.Headers, col.name)
+		meta.Constraints = append(meta.Constraints, col.constraint)
+	}
+	err = meta.ValidateMetadata()
+	if err != nil {
+		return err
+	}
+	// Validating lines needs the column checkers set by WithSchema
+	meta = New(WithSchema(meta.Schema()))
+	meta.delim = t.delim
+
+	bw := bufio.NewWriter(w)
+	_, err = bw.WriteString(meta.Header() + "\n")
+	if err != nil {
+		return err
+	}
+	delim := meta.Delimiter()
+	fields := make([]string, len(cols))
+	for i := 0; i < rv.Len(); i++ {
+		row := rv.Index(i)
+		for j, col := range cols {
+			fields[j], err = formatValue(row.FieldByIndex(col.index))
+			if err != nil {
+				return fmt.Errorf("element %v, field %v, %v", i, col.name, err)
+			}
+			if strings.Contains(fields[j], delim) || strings.ContainsAny(fields[j], "\r\n") {
+				return fmt.Errorf("element %v, field %v, value %q contains a delimiter or line break", i, col.name, fields[j])
+			}
+			if reason := col.constraint.check(fields[j]); reason != "" {
+				return fmt.Errorf("element %v, field %v, bad value '%v', %v", i, col.name, fields[j], reason)
+			}
+		}
+		if fields[0] == NA {
+			return fmt.Errorf("element %v, missing time", i)
+		}
+		line := strings.Join(fields, delim)
+		if _, err := meta.ValidateLine(line, true); err != nil {
+			return fmt.Errorf("element %v, %v", i, err)
+		}
+		_, err = bw.WriteString(line + "\n")
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// formatValue converts a struct field value to a TSDATA field string. Unsigned
+// values too large for an integer column are an error.
+func formatValue(fv reflect.Value) (string, error) {
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return NA, nil
+		}
+		fv = fv.Elem()
+	}
+	if fv.Type() == timeType {
+		tv := fv.Interface().(time.Time)
+		if tv.IsZero() {
+			return NA, nil
+		}
+		return tv.Format(time.RFC3339Nano), nil
+	}
+	switch fv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(fv.Float(), 'g', -1, fv.Type().Bits()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if fv.Uint() > math.MaxInt64 {
+			return "", fmt.Errorf("value %v is out of range for an integer column", fv.Uint())
+		}
+		return strconv.FormatUint(fv.Uint(), 10), nil
+	case reflect.Bool:
+		if fv.Bool() {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	}
+	return fv.String(), nil
+}
+
+// Unmarshal reads a TSDATA file from r and appends one struct to the slice
+// pointed to by v for each data line. Struct fields are matched to columns by
+// a `tsdata:"name"` tag, or by field name if there is no tag. A tag may also
+// set the column unit and type used by Marshal, e.g.
+// `tsdata:"speed,unit=m/s"` or `tsdata:"color,type=category"`. A category
+// type may list allowed values, e.g. `tsdata:"color,type=category:red|blue"`,
+// and Marshal rejects other values. Fields may be pointers, in which case NA
+// values produce nil. NA values for non-pointer fields produce the zero value.
+// Columns without a matching field are ignored. Unmarshal stops at the first
+// line which fails validation.
+func Unmarshal(r io.Reader, v interface{}, opts ...Option) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("Unmarshal expects a pointer to a slice of structs, got %T", v)
+	}
+	sv := rv.Elem()
+	cols, err := structColumns(sv.Type().Elem())
+	if err != nil {
+		return err
+	}
+	tr, err := NewReader(r, opts...)
+	if err != nil {
+		return err
+	}
+	// Map each struct column to a file column index
+	colIndex := make([]int, len(cols))
+	for i, col := range cols {
+		colIndex[i] = -1
+		for j, h := range tr.Tsdata.Headers {
+			if h == col.name {
+				colIndex[i] = j
+				break
+			}
+		}
+	}
+
+	for {
+		data, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		row := reflect.New(sv.Type().Elem()).Elem()
+		for i, col := range cols {
+			if colIndex[i] < 0 {
+				continue
+			}
+			err = setValue(row.FieldByIndex(col.index), data.Values[colIndex[i]])
+			if err != nil {
+				return &LineError{Line: tr.Line(), Err: fmt.Errorf("column %v, %v", col.name, err)}
+			}
+		}
+		sv.Set(reflect.Append(sv, row))
+	}
+}
+
+// setValue assigns typed value val, as produced by ValidateLine, to fv.
+func setValue(fv reflect.Value, val interface{}) error {
+	if val == nil {
+		return nil // leave zero value or nil pointer
+	}
+	if fv.Kind() == reflect.Ptr {
+		p := reflect.New(fv.Type().Elem())
+		fv.Set(p)
+		fv = p.Elem()
+	}
+	switch x := val.(type) {
+	case time.Time:
+		if fv.Type() != timeType {
+			return fmt.Errorf("cannot assign time to %v", fv.Type())
+		}
+		fv.Set(reflect.ValueOf(x))
+		return nil
+	case float64:
+		switch fv.Kind() {
+		case reflect.Float32, reflect.Float64:
+			fv.SetFloat(x)
+			return nil
+		}
+	case int64:
+		switch fv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if fv.OverflowInt(x) {
+				return fmt.Errorf("value %v overflows %v", x, fv.Type())
+			}
+			fv.SetInt(x)
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if x < 0 || fv.OverflowUint(uint64(x)) {
+				return fmt.Errorf("value %v overflows %v", x, fv.Type())
+			}
+			fv.SetUint(uint64(x))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			fv.SetFloat(float64(x))
+			return nil
+		}
+	case bool:
+		if fv.Kind() == reflect.Bool {
+			fv.SetBool(x)
+			return nil
+		}
+	case string:
+		if fv.Kind() == reflect.String {
+			fv.SetString(x)
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot assign %T to %v", val, fv.Type())
+}
@@ -0,0 +1,149 @@
+package tsdata
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+type marshalRecord struct {
+	Time     time.Time `tsdata:"time"`
+	Speed    *float64  `tsdata:"speed,unit=m/s"`
+	Distance int       `tsdata:"distance,unit=km"`
+	Notes    string    `tsdata:"notes"`
+	Color    string    `tsdata:"color,type=category"`
+	HasTail  bool      `tsdata:"hasTail"`
+	ignored  string
+	Skip     string `tsdata:"-"`
+}
+
+func TestMarshal_roundtrip(t *testing.T) {
+	tline, _ := time.Parse(time.RFC3339, "2017-05-06T19:52:57.601Z")
+	speed := 6.5
+	in := []marshalRecord{
+		{Time: tline, Speed: &speed, Distance: 100, Notes: "foo", Color: "blue", HasTail: true},
+		{Time: tline.Add(time.Hour), Speed: nil, Distance: 200, Notes: "bar", Color: "red"},
+	}
+	var buf bytes.Buffer
+	err := Marshal(&buf, &Tsdata{FileType: "fileType", Project: "project"}, in)
+	if err != nil {
+		t.Fatalf("Marshal() err %v, expected nil", err)
+	}
+	wantHeader := "fileType\nproject\n\nNA\tNA\tNA\tNA\tNA\tNA\ntime\tfloat\tinteger\ttext\tcategory\tboolean\nNA\tm/s\tkm\tNA\tNA\tNA\ntime\tspeed\tdistance\tnotes\tcolor\thasTail\n"
+	if !strings.HasPrefix(buf.String(), wantHeader) {
+		t.Errorf("Marshal() output %q, expected header %q", buf.String(), wantHeader)
+	}
+	if !strings.Contains(buf.String(), "2017-05-06T20:52:57.601Z\tNA\t200\tbar\tred\tFALSE\n") {
+		t.Errorf("Marshal() output %q, expected NA for nil pointer", buf.String())
+	}
+
+	var out []marshalRecord
+	err = Unmarshal(&buf, &out)
+	if err != nil {
+		t.Fatalf("Unmarshal() err %v, expected nil", err)
+	}
+	if len(out) != len(in) {
+		t.Fatalf("Unmarshal() %v records, expected %v", len(out), len(in))
+	}
+	if !out[0].Time.Equal(tline) || out[0].Speed == nil || *out[0].Speed != speed || out[0].Distance != 100 ||
+		out[0].Notes != "foo" || out[0].Color != "blue" || !out[0].HasTail {
+		t.Errorf("Unmarshal() record 0 %+v, expected %+v", out[0], in[0])
+	}
+	if out[1].Speed != nil || out[1].HasTail {
+		t.Errorf("Unmarshal() record 1 %+v, expected %+v", out[1], in[1])
+	}
+}
+
+func TestUnmarshal_errors(t *testing.T) {
+	input := "fileType\nproject\n\n\ntime\tfloat\nNA\tNA\ntime\tspeed\n2017-05-06T19:52:57.601Z\t6.5\n"
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{name: "not a pointer", v: []marshalRecord{}},
+		{name: "no time field", v: &[]struct {
+			Speed float64 `tsdata:"speed"`
+		}{}},
+		{name: "unsupported field type", v: &[]struct {
+			Time  time.Time  `tsdata:"time"`
+			Speed complex128 `tsdata:"speed"`
+		}{}},
+		{name: "mismatched field type", v: &[]struct {
+			Time  time.Time `tsdata:"time"`
+			Speed bool      `tsdata:"speed"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Unmarshal(strings.NewReader(input), tt.v)
+			if err == nil {
+				t.Errorf("Unmarshal() err %v, expected a non-nil error", err)
+			}
+		})
+	}
+}
+
+func TestMarshal_categoryValues(t *testing.T) {
+	type record struct {
+		Time   time.Time `tsdata:"time"`
+		Status string    `tsdata:"status,type=category:ok|failed"`
+	}
+	tline, _ := time.Parse(time.RFC3339, "2017-05-06T19:52:57.601Z")
+	var buf bytes.Buffer
+	err := Marshal(&buf, &Tsdata{FileType: "fileType", Project: "project"}, []record{{Time: tline, Status: "ok"}})
+	if err != nil {
+		t.Fatalf("Marshal() err %v, expected nil", err)
+	}
+	if !strings.Contains(buf.String(), "\ntime\tcategory:ok|failed\n") {
+		t.Errorf("Marshal() output %q, expected category values in Types", buf.String())
+	}
+
+	err = Marshal(&buf, &Tsdata{FileType: "fileType", Project: "project"}, []record{{Time: tline, Status: "unknown"}})
+	if err == nil {
+		t.Errorf("Marshal() err %v, expected a non-nil error for a value outside the set", err)
+	}
+}
+
+func TestMarshal_badValues(t *testing.T) {
+	type record struct {
+		Time  time.Time `tsdata:"time"`
+		Notes string    `tsdata:"notes"`
+		Color string    `tsdata:"color,type=category"`
+		Count uint64    `tsdata:"count"`
+	}
+	tline, _ := time.Parse(time.RFC3339, "2017-05-06T19:52:57.601Z")
+	tests := []struct {
+		name string
+		in   record
+	}{
+		{"tab in text", record{Time: tline, Notes: "a\tb", Color: "red"}},
+		{"newline in text", record{Time: tline, Notes: "x\ny", Color: "red"}},
+		{"carriage return in text", record{Time: tline, Notes: "x\ry", Color: "red"}},
+		{"empty category", record{Time: tline, Notes: "a", Color: ""}},
+		{"uint64 out of range", record{Time: tline, Notes: "a", Color: "red", Count: math.MaxInt64 + 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Marshal(&buf, &Tsdata{FileType: "fileType", Project: "project"}, []record{tt.in})
+			if err == nil {
+				t.Errorf("Marshal() err nil, expected an error, output %q", buf.String())
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	in := []record{{Time: tline, Notes: "a b", Color: "red", Count: math.MaxInt64}}
+	if err := Marshal(&buf, &Tsdata{FileType: "fileType", Project: "project"}, in); err != nil {
+		t.Fatalf("Marshal() err %v, expected nil", err)
+	}
+	var out []record
+	if err := Unmarshal(&buf, &out); err != nil {
+		t.Fatalf("Unmarshal() err %v, expected nil", err)
+	}
+	if len(out) != 1 || out[0].Notes != "a b" || out[0].Count != math.MaxInt64 {
+		t.Errorf("Unmarshal() %+v, expected %+v", out, in)
+	}
+}
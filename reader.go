@@ -0,0 +1,264 @@
+package tsdata
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// HeaderError is returned for a header section which is incomplete or has
+// invalid metadata.
+type HeaderError struct {
+	Err error
+}
+
+func (e *HeaderError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *HeaderError) Unwrap() error {
+	return e.Err
+}
+
+// LineError is returned by Reader for a data line which failed validation.
+// Reading may continue after a LineError.
+type LineError struct {
+	Line int // 1-based line number in the file
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %v, %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying validation error.
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// LineTransformer modifies valid data lines between validation and output.
+type LineTransformer interface {
+	// Transform returns the line to use in place of data, or an error to
+	// reject the line. t holds the file's metadata. The Fields and Values of
+	// the returned line must agree, e.g. by validating new fields with
+	// ValidateLine.
+	Transform(t *Tsdata, data Data) (Data, error)
+}
+
+// LineTransformerFunc adapts a function to a LineTransformer.
+type LineTransformerFunc func(t *Tsdata, data Data) (Data, error)
+
+// Transform returns f(t, data).
+func (f LineTransformerFunc) Transform(t *Tsdata, data Data) (Data, error) {
+	return f(t, data)
+}
+
+// Reader reads validated data lines from a TSDATA file.
+type Reader struct {
+	// Tsdata holds metadata parsed from the file header.
+	Tsdata *Tsdata
+	// Strict controls whether data values which fail validation produce an
+	// error or are converted to NA. NewReader sets Strict to true.
+	Strict bool
+	// Stats accumulates column statistics for valid data lines if it isn't
+	// nil, e.g. after r.Stats = NewStats(r.Tsdata.Schema()).
+	Stats *Stats
+	// Transform, if not nil, is applied to each valid data line before it's
+	// returned, e.g. to apply site-specific corrections.
+	Transform LineTransformer
+	// Sample, if not nil, selects the data lines to validate by their 1-based
+	// line number in the file. Lines for which it returns false are skipped,
+	// and are counted in Report only as SkippedLines. It's called in file
+	// order from one goroutine.
+	Sample  func(line int) bool
+	src     io.Reader
+	scanner lineScanner
+	line    int
+	report  Report
+}
+
+// lineScanner reads lines of a text or binary file.
+type lineScanner interface {
+	Scan() bool
+	Text() string
+	Err() error
+}
+
+// NewReader creates a Reader for r configured by opts. The header section is
+// read and validated before NewReader returns. r may be a text file or a file
+// in the binary encoding described by BinaryMagic, in which case WithDelimiter
+// doesn't apply.
+func NewReader(r io.Reader, opts ...Option) (*Reader, error) {
+	tr := &Reader{
+		Tsdata: New(opts...),
+		Strict: true,
+		src:    r,
+	}
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(len(BinaryMagic)); err == nil && string(magic) == BinaryMagic {
+		return tr.readBinaryHeader(br)
+	}
+	rest, err := tr.Tsdata.ParseHeaderFrom(br)
+	if err != nil {
+		return nil, err
+	}
+	tr.line = HeaderSize
+	tr.scanner = bufio.NewScanner(rest)
+	tr.report = newReport(tr.Tsdata)
+	return tr, nil
+}
+
+// ParseHeaderFrom reads the header section of a text TSDATA file from r and
+// parses it with ParseHeader. It returns a reader for the rest of the input,
+// starting with the first data line, which should be used in place of r since
+// r may have been read past the header. A file with fewer than HeaderSize
+// lines is a *HeaderError. Use NewReader for files in the binary encoding.
+func (t *Tsdata) ParseHeaderFrom(r io.Reader) (remaining io.Reader, err error) {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	var raw strings.Builder
+	headerLines := make([]string, 0, HeaderSize)
+	for len(headerLines) < HeaderSize {
+		line, err := br.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		raw.WriteString(line)
+		headerLines = append(headerLines, strings.TrimRight(line, "\r\n"))
+	}
+	if len(headerLines) < HeaderSize {
+		return nil, &HeaderError{Err: fmt.Errorf("expected %v lines in header, found %v", HeaderSize, len(headerLines))}
+	}
+	if err := t.ParseHeader(strings.Join(headerLines, "\n")); err != nil {
+		return nil, err
+	}
+	t.rawHeader = strings.TrimSuffix(raw.String(), "\n")
+	return br, nil
+}
+
+// Next reads and validates the next data line. It returns io.EOF when there
+// are no more lines. Validation failures are returned as a *LineError, after
+// which Next may be called again to continue reading.
+func (r *Reader) Next() (Data, error) {
+	for {
+		if !r.scanner.Scan() {
+			if err := r.scanner.Err(); err != nil {
+				return Data{}, err
+			}
+			return Data{}, io.EOF
+		}
+		r.line++
+		if !r.skip() {
+			break
+		}
+	}
+	data, fieldErrs, err := r.Tsdata.parseLine(r.scanner.Text())
+	p := parsedLine{data: data, fieldErrs: fieldErrs, err: err}
+	err = r.result(&p)
+	return p.data, err
+}
+
+// skip reports whether the current line is skipped by r.Sample, and counts it
+// in the report if so.
+func (r *Reader) skip() bool {
+	if r.Sample == nil || r.Sample(r.line) {
+		return false
+	}
+	r.report.Lines++
+	r.report.SkippedLines++
+	return true
+}
+
+// NextContext is like Next but returns ctx.Err() without reading if ctx is
+// done, so a loop over NextContext stops at the next line once ctx is
+// cancelled or its deadline passes. A read which is already blocked isn't
+// interrupted.
+func (r *Reader) NextContext(ctx context.Context) (Data, error) {
+	if err := ctx.Err(); err != nil {
+		return Data{}, err
+	}
+	return r.Next()
+}
+
+// readBinaryHeader reads the header of a binary file from br and prepares tr
+// to read data lines.
+func (tr *Reader) readBinaryHeader(br *bufio.Reader) (*Reader, error) {
+	header, err := readBinaryHeader(br)
+	if err != nil {
+		return nil, &HeaderError{Err: err}
+	}
+	tr.Tsdata.delim = ""
+	if err := tr.Tsdata.ParseHeader(header); err != nil {
+		return nil, err
+	}
+	tr.line = HeaderSize
+	tr.scanner = newBinaryScanner(br, len(tr.Tsdata.Headers), tr.Tsdata.Delimiter())
+	tr.report = newReport(tr.Tsdata)
+	return tr, nil
+}
+
+// NextRaw returns the next data line without validation. It returns io.EOF
+// when there are no more lines. Lines read with NextRaw are not included in
+// Report.
+func (r *Reader) NextRaw() (string, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	r.line++
+	return r.scanner.Text(), nil
+}
+
+// Seek moves r to the checkpoint in idx returned by Index.Find for t. The
+// next line read is at or before the first line with a timestamp at or after
+// t, so callers should skip any earlier lines. The reader passed to NewReader
+// must be an io.Seeker for the indexed file. Seek returns an error if the
+// file is smaller than when it was indexed.
+func (r *Reader) Seek(idx *Index, t time.Time) error {
+	if _, ok := r.scanner.(*binaryScanner); ok {
+		return errors.New("seek is not supported for binary files")
+	}
+	rs, ok := r.src.(io.ReadSeeker)
+	if !ok {
+		return errors.New("seek requires an io.ReadSeeker")
+	}
+	size, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if size < idx.Size {
+		return fmt.Errorf("index is out of date, file is %v bytes, expected at least %v", size, idx.Size)
+	}
+	c := idx.Find(t)
+	if _, err := rs.Seek(c.Offset, io.SeekStart); err != nil {
+		return err
+	}
+	r.scanner = bufio.NewScanner(rs)
+	r.line = c.Line - 1
+	return nil
+}
+
+// Report returns a summary of validation results for all lines read so far.
+func (r *Reader) Report() *Report {
+	rep := r.report
+	rep.Columns = make([]ColumnReport, len(r.report.Columns))
+	copy(rep.Columns, r.report.Columns)
+	return &rep
+}
+
+// Line returns the 1-based line number of the last line read.
+func (r *Reader) Line() int {
+	return r.line
+}
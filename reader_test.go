@@ -0,0 +1,165 @@
+package tsdata
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const readerHeader = "fileType\nproject\nfile description\n\ntime\tfloat\nNA\tNA\ntime\tcol1\n"
+
+func TestNewReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "header only", input: readerHeader, wantErr: false},
+		{name: "header with data", input: readerHeader + "2017-05-06T19:52:57.601Z\t6.0\n", wantErr: false},
+		{name: "short header", input: "fileType\nproject\n", wantErr: true},
+		{name: "empty input", input: "", wantErr: true},
+		{name: "bad header", input: strings.Replace(readerHeader, "float", "notfloat", 1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReader(strings.NewReader(tt.input))
+			if tt.wantErr && err == nil {
+				t.Errorf("NewReader() err %v, expected a non-nil error", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("NewReader() err %v, expected nil", err)
+			}
+			var herr *HeaderError
+			if tt.wantErr && !errors.As(err, &herr) {
+				t.Errorf("NewReader() err %T, expected *HeaderError", err)
+			}
+		})
+	}
+}
+
+func TestTsdata_ParseHeaderFrom(t *testing.T) {
+	data := "2017-05-06T19:52:57.601Z\t6.0\n"
+	for _, input := range []string{readerHeader, strings.ReplaceAll(readerHeader, "\n", "\r\n")} {
+		ts := New()
+		rest, err := ts.ParseHeaderFrom(strings.NewReader(input + data))
+		if err != nil {
+			t.Fatalf("Tsdata.ParseHeaderFrom() err %v, expected nil", err)
+		}
+		if ts.FileType != "fileType" || len(ts.Headers) != 2 || ts.Headers[1] != "col1" {
+			t.Errorf("Tsdata.ParseHeaderFrom() parsed %v %v, expected fileType [time col1]", ts.FileType, ts.Headers)
+		}
+		b, _ := io.ReadAll(rest)
+		if string(b) != data {
+			t.Errorf("Tsdata.ParseHeaderFrom() remaining %q, expected %q", b, data)
+		}
+	}
+
+	for _, input := range []string{"", "fileType\nproject\n", strings.TrimSuffix(readerHeader, "time\tcol1\n")} {
+		_, err := New().ParseHeaderFrom(strings.NewReader(input))
+		var herr *HeaderError
+		if !errors.As(err, &herr) {
+			t.Errorf("Tsdata.ParseHeaderFrom(%q) err %v, expected *HeaderError", input, err)
+		}
+	}
+}
+
+func TestReader_Next(t *testing.T) {
+	input := readerHeader + "2017-05-06T19:52:57.601Z\t6.0\n2017-05-06T20:52:57.601Z\tbad\n2017-05-06T21:52:57.601Z\t7.0\n"
+	r, err := NewReader(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("NewReader() err %v, expected nil", err)
+	}
+	var fields [][]string
+	var lines, lineErrs []int
+	for {
+		data, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var lerr *LineError
+			if !errors.As(err, &lerr) {
+				t.Fatalf("Reader.Next() err %v, expected *LineError", err)
+			}
+			lineErrs = append(lineErrs, lerr.Line)
+			continue
+		}
+		fields = append(fields, data.Fields)
+		lines = append(lines, data.Line)
+	}
+	if len(fields) != 2 || fields[1][1] != "7.0" {
+		t.Errorf("Reader.Next() fields %v, expected 2 valid lines", fields)
+	}
+	if len(lineErrs) != 1 || lineErrs[0] != 9 {
+		t.Errorf("Reader.Next() error lines %v, expected [9]", lineErrs)
+	}
+	if len(lines) != 2 || lines[0] != 8 || lines[1] != 10 {
+		t.Errorf("Reader.Next() Data.Line %v, expected [8 10]", lines)
+	}
+}
+
+func TestReader_NextRaw(t *testing.T) {
+	input := readerHeader + "2017-05-06T19:52:57.601Z\tbad\n"
+	r, err := NewReader(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("NewReader() err %v, expected nil", err)
+	}
+	line, err := r.NextRaw()
+	if err != nil || line != "2017-05-06T19:52:57.601Z\tbad" {
+		t.Errorf("Reader.NextRaw() = %q, %v", line, err)
+	}
+	if r.Line() != 8 {
+		t.Errorf("Reader.Line() = %v, expected 8", r.Line())
+	}
+	if _, err = r.NextRaw(); err != io.EOF {
+		t.Errorf("Reader.NextRaw() err %v, expected io.EOF", err)
+	}
+	if r.Report().DataLines != 0 {
+		t.Errorf("Reader.Report() DataLines %v, expected 0", r.Report().DataLines)
+	}
+}
+
+func TestReader_Transform(t *testing.T) {
+	input := readerHeader + "2017-05-06T19:52:57.601Z\t6.0\n2017-05-06T20:52:57.601Z\t-1\n2017-05-06T21:52:57.601Z\t7.0\n"
+	r, err := NewReader(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("NewReader() err %v, expected nil", err)
+	}
+	r.Transform = LineTransformerFunc(func(ts *Tsdata, data Data) (Data, error) {
+		v, _ := data.Float("col1")
+		if v < 0 {
+			return Data{}, errors.New("negative value")
+		}
+		fields := []string{data.Fields[0], strconv.FormatFloat(v*2, 'f', -1, 64)}
+		return ts.ValidateLine(strings.Join(fields, Delim), true)
+	})
+	var fields []string
+	var lines, lineErrs []int
+	for {
+		data, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		var lerr *LineError
+		if errors.As(err, &lerr) {
+			lineErrs = append(lineErrs, lerr.Line)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Reader.Next() err %v, expected *LineError", err)
+		}
+		fields = append(fields, data.Fields[1])
+		lines = append(lines, data.Line)
+	}
+	if len(fields) != 2 || fields[0] != "12" || fields[1] != "14" {
+		t.Errorf("Reader.Next() col1 %v, expected [12 14]", fields)
+	}
+	if len(lineErrs) != 1 || lineErrs[0] != 9 {
+		t.Errorf("Reader.Next() error lines %v, expected [9]", lineErrs)
+	}
+	if len(lines) != 2 || lines[0] != 8 || lines[1] != 10 {
+		t.Errorf("Reader.Next() Data.Line %v, expected [8 10]", lines)
+	}
+}
@@ -0,0 +1,100 @@
+package tsdata
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Report summarizes validation of a TSDATA file.
+type Report struct {
+	Lines     int // total lines read, including the header
+	DataLines int // data lines read and validated
+	// SkippedLines is the number of data lines skipped by Reader.Sample,
+	// which are included in Lines but not DataLines.
+	SkippedLines int
+	ErrorLines   int       // data lines with at least one validation error
+	FirstTime    time.Time // time of the first valid data line
+	LastTime     time.Time // time of the last valid data line
+	// FirstError is the first data line validation error with its line
+	// number, or nil if all data lines are valid.
+	FirstError *LineError
+	Columns    []ColumnReport
+}
+
+// ColumnReport summarizes validation of one column.
+type ColumnReport struct {
+	Name   string
+	Type   string
+	Errors int // fields which failed validation
+	NA     int // NA fields in valid lines
+}
+
+// newReport creates an empty Report for columns defined in t.
+func newReport(t *Tsdata) Report {
+	r := Report{Lines: HeaderSize, Columns: make([]ColumnReport, len(t.Headers))}
+	for i := range t.Headers {
+		r.Columns[i] = ColumnReport{Name: t.Headers[i], Type: t.Types[i]}
+	}
+	return r
+}
+
+// add records the validation result for one data line.
+func (r *Report) add(data Data, fieldErrs []*FieldError, err error, strict bool) {
+	r.Lines++
+	r.DataLines++
+	if err != nil {
+		r.ErrorLines++
+		if ferr, ok := err.(*FieldError); ok {
+			r.Columns[ferr.Column].Errors++
+		}
+		return
+	}
+	if len(fieldErrs) > 0 {
+		r.ErrorLines++
+		for _, ferr := range fieldErrs {
+			r.Columns[ferr.Column].Errors++
+		}
+		if strict {
+			return
+		}
+	}
+	for i, v := range data.Values {
+		if v == nil {
+			r.Columns[i].NA++
+		}
+	}
+	if r.FirstTime.IsZero() {
+		r.FirstTime = data.Time
+	}
+	r.LastTime = data.Time
+}
+
+// ValidateFile reads and validates a complete TSDATA file from r. Data line
+// validation errors are counted in the returned Report, and the first is kept
+// with its line number in Report.FirstError. The returned error is
+// non-nil only for header validation errors or errors reading r.
+func ValidateFile(r io.Reader, opts ...Option) (*Report, error) {
+	return ValidateFileContext(context.Background(), r, opts...)
+}
+
+// ValidateFileContext is like ValidateFile but stops reading and returns
+// ctx.Err() once ctx is done.
+func ValidateFileContext(ctx context.Context, r io.Reader, opts ...Option) (*Report, error) {
+	tr, err := NewReader(r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		_, err := tr.NextContext(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if _, ok := err.(*LineError); !ok {
+				return nil, err
+			}
+		}
+	}
+	return tr.Report(), nil
+}
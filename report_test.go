@@ -0,0 +1,65 @@
+package tsdata
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateFile(t *testing.T) {
+	input := `fileType
+project
+file description
+
+time	float	integer
+NA	NA	NA
+time	col1	col2
+2017-05-06T19:00:00Z	1.0	NA
+2017-05-06T20:00:00Z	bad	bad
+notatime	1.0	1
+2017-05-06T21:00:00Z	NA	3
+2017-05-06T22:00:00Z	1.0
+`
+	rep, err := ValidateFile(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ValidateFile() err %v, expected nil", err)
+	}
+	if rep.Lines != 12 || rep.DataLines != 5 || rep.ErrorLines != 3 {
+		t.Errorf("ValidateFile() Lines %v DataLines %v ErrorLines %v, expected 12 5 3", rep.Lines, rep.DataLines, rep.ErrorLines)
+	}
+	first, _ := time.Parse(time.RFC3339, "2017-05-06T19:00:00Z")
+	last, _ := time.Parse(time.RFC3339, "2017-05-06T21:00:00Z")
+	if !rep.FirstTime.Equal(first) || !rep.LastTime.Equal(last) {
+		t.Errorf("ValidateFile() FirstTime %v LastTime %v, expected %v %v", rep.FirstTime, rep.LastTime, first, last)
+	}
+	if rep.FirstError == nil || rep.FirstError.Line != 9 {
+		t.Errorf("ValidateFile() FirstError %v, expected an error at line 9", rep.FirstError)
+	}
+	want := []ColumnReport{
+		{Name: "time", Type: "time", Errors: 1, NA: 0},
+		{Name: "col1", Type: "float", Errors: 1, NA: 1},
+		{Name: "col2", Type: "integer", Errors: 1, NA: 1},
+	}
+	for i := range want {
+		if rep.Columns[i] != want[i] {
+			t.Errorf("ValidateFile() Columns[%v] %+v, expected %+v", i, rep.Columns[i], want[i])
+		}
+	}
+}
+
+func TestValidateFile_badHeader(t *testing.T) {
+	_, err := ValidateFile(strings.NewReader("fileType\nproject\n"))
+	if err == nil {
+		t.Errorf("ValidateFile() err %v, expected a non-nil error", err)
+	}
+}
+
+func TestValidateFileContext_cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := ValidateFileContext(ctx, strings.NewReader(concurrentInput(10)))
+	if err != context.Canceled {
+		t.Errorf("ValidateFileContext() err %v, expected %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,307 @@
+package tsdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Schema holds the metadata defined in a TSDATA file header. It holds no
+// parsing state, so a single Schema can be shared by many Tsdata values
+// validating different files or used from different goroutines. Create a
+// Tsdata to validate data lines for a Schema with New(WithSchema(s)).
+type Schema struct {
+	FileType        string
+	Project         string
+	FileDescription string
+	Comments        []string
+	Types           []string
+	Units           []string
+	Headers         []string
+	Constraints     []Constraint // per column, empty if there are none
+}
+
+// ParseSchema parses and validates a header section string. See
+// Tsdata.ParseHeader.
+func ParseSchema(header string) (Schema, error) {
+	t := New()
+	if err := t.ParseHeader(header); err != nil {
+		return Schema{}, err
+	}
+	return t.Schema(), nil
+}
+
+// WithSchema sets header metadata from s. Use this to create Tsdata values
+// which share a Schema but keep separate parsing state.
+func WithSchema(s Schema) Option {
+	return func(t *Tsdata) {
+		t.FileType = s.FileType
+		t.Project = s.Project
+		t.FileDescription = s.FileDescription
+		t.Comments = copyStrings(s.Comments)
+		t.Types = copyStrings(s.Types)
+		t.Units = copyStrings(s.Units)
+		t.Headers = copyStrings(s.Headers)
+		t.Constraints = copyConstraints(s.Constraints)
+		for i := range t.Constraints {
+			t.Constraints[i], _ = t.Constraints[i].compile()
+		}
+		t.index = nil
+		t.checkers = make([]func(string) bool, len(t.Types))
+		for i, ty := range t.Types {
+			t.checkers[i], _ = lookupChecker(ty)
+		}
+	}
+}
+
+// Equal reports whether s and o define the same file type, project, and
+// columns. FileDescription and Comments are not compared.
+func (s Schema) Equal(o Schema) bool {
+	return s.FileType == o.FileType &&
+		s.Project == o.Project &&
+		stringsEqual(s.Types, o.Types) &&
+		stringsEqual(s.Units, o.Units) &&
+		stringsEqual(s.Headers, o.Headers) &&
+		constraintsEqual(s.Constraints, o.Constraints)
+}
+
+// Validate checks for errors and inconsistencies in metadata values. Errors
+// are returned as a *HeaderError.
+func (s Schema) Validate() error {
+	if err := s.validate(); err != nil {
+		return &HeaderError{Err: err}
+	}
+	return nil
+}
+
+// reservedHeaders are names which can't be used for data columns. time is
+// only allowed for the first column.
+var reservedHeaders = map[string]bool{"time": true, NA: true}
+
+func (s Schema) validate() error {
+	// FileType
+	if s.FileType == "" {
+		return fmt.Errorf("missing or empty FileType")
+	}
+
+	// Project
+	if s.Project == "" {
+		return fmt.Errorf("missing or empty Project")
+	}
+
+	// Comments
+	colCount := 0
+	// Column comments may be a blank line so allow 0 columns
+	if len(s.Comments) > 0 {
+		colCount = len(s.Comments)
+		for i, com := range s.Comments {
+			if com == "" {
+				return fmt.Errorf("empty comment in column %v", i+1)
+			}
+		}
+	}
+
+	// Types
+	if len(s.Types) == 0 {
+		return fmt.Errorf("missing or empty Types")
+	}
+	if colCount > 0 && len(s.Types) != colCount {
+		return fmt.Errorf("inconsistent Types column count")
+	}
+	for i, ty := range s.Types {
+		_, ok := lookupChecker(ty)
+		if !ok {
+			return fmt.Errorf("bad Types value '%v' in column %v", ty, i+1)
+		}
+	}
+	colCount = len(s.Types)
+
+	// Constraints
+	if len(s.Constraints) > 0 && len(s.Constraints) != colCount {
+		return fmt.Errorf("inconsistent Constraints column count")
+	}
+	for i, c := range s.Constraints {
+		if err := c.validate(s.Types[i]); err != nil {
+			return fmt.Errorf("column %v, %v", i+1, err)
+		}
+	}
+
+	// Units
+	if len(s.Units) == 0 {
+		return fmt.Errorf("missing or empty Units")
+	}
+	if len(s.Units) != colCount {
+		return fmt.Errorf("inconsistent Units column count")
+	}
+	for i, u := range s.Units {
+		if u == "" {
+			return fmt.Errorf("empty Units value in column %v", i+1)
+		}
+	}
+
+	// Headers
+	if len(s.Headers) == 0 {
+		return fmt.Errorf("missing or empty Headers")
+	}
+	if len(s.Headers) != colCount {
+		return fmt.Errorf("inconsistent Headers column count")
+	}
+	if s.Headers[0] != "time" {
+		return fmt.Errorf("first Headers column should be 'time'")
+	}
+	seen := map[string]int{}
+	for i, h := range s.Headers {
+		if h == "" {
+			return fmt.Errorf("empty Headers value in column %v", i+1)
+		}
+		if i > 0 && reservedHeaders[h] {
+			return fmt.Errorf("reserved Headers value '%v' in column %v", h, i+1)
+		}
+		if j, ok := seen[h]; ok {
+			return fmt.Errorf("duplicate Headers value '%v' in columns %v and %v", h, j+1, i+1)
+		}
+		seen[h] = i
+	}
+
+	// Finally column count should be > 1, meaning at least one data column
+	// after the first time column
+	if colCount < 2 {
+		return fmt.Errorf("no data columns after time")
+	}
+
+	return nil
+}
+
+// Header creates a TSData file metadata header paragraph using the default
+// delimiter.
+func (s Schema) Header() string {
+	return s.header(Delim)
+}
+
+// header creates a TSData file metadata header paragraph with fields separated
+// by delim.
+func (s Schema) header(delim string) string {
+	// TODO: should this ever produce a non-conforming TSData header?
+	cols := len(s.Headers)
+	text := s.FileType + "\n"
+	text = text + s.Project + "\n"
+	text = text + s.FileDescription + "\n"
+	if len(s.Comments) == 0 {
+		text = text + nas(cols, delim) + "\n"
+	} else {
+		text = text + strings.Join(s.Comments, delim) + "\n"
+	}
+	text = text + strings.Join(s.typeStrings(), delim) + "\n"
+	text = text + strings.Join(s.Units, delim) + "\n"
+	text = text + strings.Join(s.Headers, delim) // note, doesn't end with blank line
+	return text
+}
+
+// typeStrings returns Types row values, including category values from
+// Constraints.
+func (s Schema) typeStrings() []string {
+	types := make([]string, len(s.Types))
+	for i, typ := range s.Types {
+		types[i] = typ
+		if i < len(s.Constraints) {
+			types[i] = formatType(typ, s.Constraints[i])
+		}
+	}
+	return types
+}
+
+func copyConstraints(a []Constraint) []Constraint {
+	if a == nil {
+		return nil
+	}
+	b := make([]Constraint, len(a))
+	for i := range a {
+		b[i] = a[i].copy()
+	}
+	return b
+}
+
+// constraintsEqual compares per-column constraints. A nil slice is equal to a
+// slice of zero Constraints.
+func constraintsEqual(a []Constraint, b []Constraint) bool {
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		var ca, cb Constraint
+		if i < len(a) {
+			ca = a[i]
+		}
+		if i < len(b) {
+			cb = b[i]
+		}
+		if !ca.equal(cb) {
+			return false
+		}
+	}
+	return true
+}
+
+func copyStrings(a []string) []string {
+	if a == nil {
+		return nil
+	}
+	b := make([]string, len(a))
+	copy(b, a)
+	return b
+}
+
+func stringsEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// schemaJSON is the JSON representation of a Schema.
+type schemaJSON struct {
+	FileType    string       `json:"fileType"`
+	Project     string       `json:"project"`
+	Description string       `json:"description"`
+	Columns     []columnJSON `json:"columns"`
+}
+
+type columnJSON struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Unit    string `json:"unit"`
+	Comment string `json:"comment"`
+}
+
+// MarshalJSON encodes header metadata as a JSON object with fileType,
+// project, description, and a columns array of objects with name, type, unit,
+// and comment.
+func (s Schema) MarshalJSON() ([]byte, error) {
+	js := schemaJSON{
+		FileType:    s.FileType,
+		Project:     s.Project,
+		Description: s.FileDescription,
+		Columns:     make([]columnJSON, len(s.Headers)),
+	}
+	types := s.typeStrings()
+	for i := range s.Headers {
+		col := columnJSON{Name: s.Headers[i], Type: NA, Unit: NA, Comment: NA}
+		if i < len(types) {
+			col.Type = types[i]
+		}
+		if i < len(s.Units) {
+			col.Unit = s.Units[i]
+		}
+		if i < len(s.Comments) {
+			col.Comment = s.Comments[i]
+		}
+		js.Columns[i] = col
+	}
+	return json.Marshal(js)
+}
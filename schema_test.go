@@ -0,0 +1,72 @@
+package tsdata
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const schemaHeader = "fileType\nproject\nfile description\n\ntime\tfloat\nNA\tm/s\ntime\tspeed"
+
+func TestParseSchema(t *testing.T) {
+	s, err := ParseSchema(schemaHeader)
+	if err != nil {
+		t.Fatalf("ParseSchema() err %v, expected nil", err)
+	}
+	if s.FileType != "fileType" || !stringSliceEqual(s.Headers, []string{"time", "speed"}) {
+		t.Errorf("ParseSchema() = %+v", s)
+	}
+	if s.Header() != "fileType\nproject\nfile description\nNA\tNA\ntime\tfloat\nNA\tm/s\ntime\tspeed" {
+		t.Errorf("Schema.Header() = %q", s.Header())
+	}
+	_, err = ParseSchema("fileType\nproject\n")
+	if err == nil {
+		t.Errorf("ParseSchema() err %v, expected a non-nil error", err)
+	}
+}
+
+func TestSchema_Equal(t *testing.T) {
+	s, _ := ParseSchema(schemaHeader)
+	o := s
+	o.FileDescription = "different description"
+	o.Comments = []string{"a", "b"}
+	if !s.Equal(o) {
+		t.Errorf("Schema.Equal() = false, expected true when only description and comments differ")
+	}
+	o.Units = []string{"NA", "knots"}
+	if s.Equal(o) {
+		t.Errorf("Schema.Equal() = true, expected false when units differ")
+	}
+}
+
+func TestWithSchema(t *testing.T) {
+	s, _ := ParseSchema(schemaHeader)
+	a := New(WithSchema(s), WithMonotonicTime())
+	b := New(WithSchema(s), WithMonotonicTime())
+	if _, err := a.ValidateLine("2017-05-06T20:00:00Z\t1.0", true); err != nil {
+		t.Fatalf("Tsdata.ValidateLine() err %v, expected nil", err)
+	}
+	// b has independent time order state
+	if _, err := b.ValidateLine("2017-05-06T19:00:00Z\t1.0", true); err != nil {
+		t.Errorf("Tsdata.ValidateLine() err %v, expected nil", err)
+	}
+	// a owns its own copy of metadata
+	a.Headers[1] = "changed"
+	if s.Headers[1] != "speed" {
+		t.Errorf("WithSchema() shares Headers with Schema, expected a copy")
+	}
+}
+
+func TestSchema_MarshalJSON(t *testing.T) {
+	d := New()
+	if err := d.ParseHeader(schemaHeader); err != nil {
+		t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("json.Marshal() err %v, expected nil", err)
+	}
+	want := `{"fileType":"fileType","project":"project","description":"file description","columns":[{"name":"time","type":"time","unit":"NA","comment":"NA"},{"name":"speed","type":"float","unit":"m/s","comment":"NA"}]}`
+	if string(b) != want {
+		t.Errorf("Tsdata.MarshalJSON() = %v, expected %v", string(b), want)
+	}
+}
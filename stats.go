@@ -0,0 +1,163 @@
+package tsdata
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stats accumulates summary statistics for each column of validated data
+// lines. Statistics are computed with online algorithms so memory use doesn't
+// depend on the number of lines, except for distinct value counts.
+type Stats struct {
+	Columns []ColumnStats
+}
+
+// ColumnStats summarizes the values of one column.
+type ColumnStats struct {
+	Name  string
+	Type  string
+	Count int // values which aren't NA
+	NA    int // NA values, including NaN and infinite floats
+	// Distinct counts each value in category and boolean columns. It is nil
+	// for other column types.
+	Distinct   map[string]int
+	mean, m2   float64 // Welford's running mean and sum of squared differences
+	min, max   float64
+	tmin, tmax time.Time
+}
+
+// NewStats creates an empty Stats for the columns in s.
+func NewStats(s Schema) *Stats {
+	st := &Stats{Columns: make([]ColumnStats, len(s.Headers))}
+	for i := range s.Headers {
+		c := ColumnStats{Name: s.Headers[i], Type: s.Types[i]}
+		if c.Type == "category" || c.Type == "boolean" {
+			c.Distinct = map[string]int{}
+		}
+		st.Columns[i] = c
+	}
+	return st
+}
+
+// Add records the values of one data line, such as one returned by
+// Reader.Next or Tsdata.ValidateLine.
+func (s *Stats) Add(data Data) {
+	for i, v := range data.Values {
+		if i < len(s.Columns) {
+			s.Columns[i].add(v)
+		}
+	}
+}
+
+// add records one value. v is a typed value from Data.Values.
+func (c *ColumnStats) add(v interface{}) {
+	if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		v = nil
+	}
+	if v == nil {
+		c.NA++
+		return
+	}
+	c.Count++
+	switch x := v.(type) {
+	case float64:
+		c.addNumber(x)
+	case int64:
+		c.addNumber(float64(x))
+	case bool:
+		c.Distinct[strings.ToUpper(strconv.FormatBool(x))]++
+	case time.Time:
+		if c.Count == 1 || x.Before(c.tmin) {
+			c.tmin = x
+		}
+		if c.Count == 1 || x.After(c.tmax) {
+			c.tmax = x
+		}
+	case string:
+		if c.Distinct != nil {
+			c.Distinct[x]++
+		}
+	}
+}
+
+// addNumber updates min, max, and the running mean and variance.
+func (c *ColumnStats) addNumber(x float64) {
+	if c.Count == 1 || x < c.min {
+		c.min = x
+	}
+	if c.Count == 1 || x > c.max {
+		c.max = x
+	}
+	delta := x - c.mean
+	c.mean += delta / float64(c.Count)
+	c.m2 += delta * (x - c.mean)
+}
+
+// Numeric returns true if the column holds numbers and has at least one
+// value, so Min, Max, Mean, Variance, and Stddev are defined.
+func (c *ColumnStats) Numeric() bool {
+	if c.Count == 0 {
+		return false
+	}
+	switch c.Type {
+	case "float", "integer", "latitude", "longitude":
+		return true
+	}
+	return false
+}
+
+// Min returns the smallest numeric value, or NaN if Numeric is false.
+func (c *ColumnStats) Min() float64 {
+	if !c.Numeric() {
+		return math.NaN()
+	}
+	return c.min
+}
+
+// Max returns the largest numeric value, or NaN if Numeric is false.
+func (c *ColumnStats) Max() float64 {
+	if !c.Numeric() {
+		return math.NaN()
+	}
+	return c.max
+}
+
+// Mean returns the mean of numeric values, or NaN if Numeric is false.
+func (c *ColumnStats) Mean() float64 {
+	if !c.Numeric() {
+		return math.NaN()
+	}
+	return c.mean
+}
+
+// Variance returns the sample variance of numeric values, 0 for one value,
+// or NaN if Numeric is false.
+func (c *ColumnStats) Variance() float64 {
+	if !c.Numeric() {
+		return math.NaN()
+	}
+	if c.Count < 2 {
+		return 0
+	}
+	return c.m2 / float64(c.Count-1)
+}
+
+// Stddev returns the sample standard deviation of numeric values, 0 for one
+// value, or NaN if Numeric is false.
+func (c *ColumnStats) Stddev() float64 {
+	return math.Sqrt(c.Variance())
+}
+
+// First returns the earliest value in a time column, or the zero time if
+// there are no values.
+func (c *ColumnStats) First() time.Time {
+	return c.tmin
+}
+
+// Last returns the latest value in a time column, or the zero time if there
+// are no values.
+func (c *ColumnStats) Last() time.Time {
+	return c.tmax
+}
@@ -0,0 +1,64 @@
+package tsdata
+
+import (
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	input := `fileType
+project
+file description
+
+time	float	integer	boolean	text
+NA	NA	NA	NA	NA
+time	col1	col2	col3	col4
+2017-05-06T19:00:00Z	2	1	TRUE	a
+2017-05-06T21:00:00Z	4	NA	FALSE	b
+2017-05-06T20:00:00Z	NaN	3	TRUE	c
+notatime	100	100	TRUE	d
+2017-05-06T22:00:00Z	9	bad	NA	e
+`
+	r, err := NewReader(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("NewReader() err %v, expected nil", err)
+	}
+	r.Strict = false
+	r.Stats = NewStats(r.Tsdata.Schema())
+	for {
+		_, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+	}
+
+	c := r.Stats.Columns
+	if len(c) != 5 {
+		t.Fatalf("Stats.Columns len %v, expected 5", len(c))
+	}
+	first, _ := time.Parse(time.RFC3339, "2017-05-06T19:00:00Z")
+	last, _ := time.Parse(time.RFC3339, "2017-05-06T22:00:00Z")
+	if c[0].Count != 4 || !c[0].First().Equal(first) || !c[0].Last().Equal(last) || c[0].Numeric() {
+		t.Errorf("time ColumnStats = %+v, expected 4 values from %v to %v", c[0], first, last)
+	}
+	if c[1].Count != 3 || c[1].NA != 1 || c[1].Min() != 2 || c[1].Max() != 9 || c[1].Mean() != 5 {
+		t.Errorf("float ColumnStats Count %v NA %v Min %v Max %v Mean %v, expected 3 1 2 9 5",
+			c[1].Count, c[1].NA, c[1].Min(), c[1].Max(), c[1].Mean())
+	}
+	if v := c[1].Variance(); math.Abs(v-13) > 1e-9 {
+		t.Errorf("float ColumnStats.Variance() = %v, expected 13", v)
+	}
+	if c[2].Count != 2 || c[2].NA != 2 || c[2].Mean() != 2 || c[2].Stddev() != math.Sqrt2 {
+		t.Errorf("integer ColumnStats Count %v NA %v Mean %v Stddev %v, expected 2 2 2 %v",
+			c[2].Count, c[2].NA, c[2].Mean(), c[2].Stddev(), math.Sqrt2)
+	}
+	if c[3].Distinct["TRUE"] != 2 || c[3].Distinct["FALSE"] != 1 || c[3].NA != 1 {
+		t.Errorf("boolean ColumnStats Distinct %v NA %v, expected TRUE=2 FALSE=1 and 1 NA", c[3].Distinct, c[3].NA)
+	}
+	if c[4].Count != 4 || c[4].Distinct != nil || !math.IsNaN(c[4].Mean()) {
+		t.Errorf("text ColumnStats = %+v, expected 4 values and no distinct counts", c[4])
+	}
+}
@@ -4,9 +4,12 @@
 package tsdata
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -23,6 +26,19 @@ const HeaderSize = 7
 type Tsdata struct {
 	checkers        []func(string) bool
 	lastTime        time.Time
+	offsets         map[int]bool // UTC offsets in seconds of validated timestamps
+	delim           string
+	naTokens        map[string]bool
+	timeOrder       TimeOrder
+	aliases         Aliases
+	nmea            bool
+	floatPolicy     FloatPolicy
+	timeFormat      TimeFormat
+	interval        time.Duration // expected time between lines, or 0
+	tolerance       time.Duration
+	constraints     map[string]Constraint
+	index           map[string]int
+	rawHeader       string // header section as parsed, see RawHeader
 	FileType        string
 	Project         string
 	FileDescription string
@@ -30,67 +46,557 @@ type Tsdata struct {
 	Types           []string
 	Units           []string
 	Headers         []string
+	Constraints     []Constraint // per column, empty if there are none
+}
+
+// Option configures optional Tsdata behavior. Options are applied by New.
+type Option func(*Tsdata)
+
+// New creates a Tsdata configured by opts. The zero value of Tsdata is also
+// ready to use and behaves the same as New() with no options.
+func New(opts ...Option) *Tsdata {
+	t := &Tsdata{}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// WithNATokens adds tokens which should be treated as missing data in data
+// columns. Matching fields are converted to NA by ValidateLine.
+func WithNATokens(tokens ...string) Option {
+	return func(t *Tsdata) {
+		if t.naTokens == nil {
+			t.naTokens = make(map[string]bool)
+		}
+		for _, tok := range tokens {
+			t.naTokens[tok] = true
+		}
+	}
+}
+
+// TimeOrder controls how ValidateLine handles a line with a timestamp earlier
+// than the timestamp of the last line validated.
+type TimeOrder int
+
+const (
+	// TimeOrderOff disables time order checks.
+	TimeOrderOff TimeOrder = iota
+	// TimeOrderWarn adds a warning to Data.Warnings for out-of-order lines.
+	TimeOrderWarn
+	// TimeOrderStrict returns an error for out-of-order lines.
+	TimeOrderStrict
+)
+
+// ParseTimeOrder converts "off", "warn", or "strict" to a TimeOrder.
+func ParseTimeOrder(s string) (TimeOrder, error) {
+	switch s {
+	case "off":
+		return TimeOrderOff, nil
+	case "warn":
+		return TimeOrderWarn, nil
+	case "strict":
+		return TimeOrderStrict, nil
+	}
+	return TimeOrderOff, fmt.Errorf("bad time order '%v', expected off, warn, or strict", s)
+}
+
+func (o TimeOrder) String() string {
+	switch o {
+	case TimeOrderWarn:
+		return "warn"
+	case TimeOrderStrict:
+		return "strict"
+	}
+	return "off"
+}
+
+// WithTimeOrder sets how ValidateLine handles out-of-order timestamps. The
+// default is TimeOrderOff.
+func WithTimeOrder(o TimeOrder) Option {
+	return func(t *Tsdata) {
+		t.timeOrder = o
+	}
+}
+
+// WithMonotonicTime makes ValidateLine return an error for any line with a
+// timestamp earlier than the timestamp of the last line validated. It is the
+// same as WithTimeOrder(TimeOrderStrict).
+func WithMonotonicTime() Option {
+	return WithTimeOrder(TimeOrderStrict)
+}
+
+// FloatPolicy controls how ValidateLine handles NaN, +Inf, and -Inf in float,
+// latitude, and longitude columns. strconv.ParseFloat accepts these values
+// but many CSV and database tools don't.
+type FloatPolicy int
+
+const (
+	// FloatAccept treats NaN and infinite values as valid.
+	FloatAccept FloatPolicy = iota
+	// FloatReject treats NaN and infinite values as validation errors.
+	FloatReject
+	// FloatNA converts NaN and infinite values to NA.
+	FloatNA
+)
+
+// ParseFloatPolicy converts "accept", "reject", or "na" to a FloatPolicy.
+func ParseFloatPolicy(s string) (FloatPolicy, error) {
+	switch s {
+	case "accept":
+		return FloatAccept, nil
+	case "reject":
+		return FloatReject, nil
+	case "na":
+		return FloatNA, nil
+	}
+	return FloatAccept, fmt.Errorf("bad float policy '%v', expected accept, reject, or na", s)
+}
+
+func (p FloatPolicy) String() string {
+	switch p {
+	case FloatReject:
+		return "reject"
+	case FloatNA:
+		return "na"
+	}
+	return "accept"
+}
+
+// WithFloatPolicy sets how ValidateLine handles NaN and infinite values. The
+// default is FloatAccept.
+func WithFloatPolicy(p FloatPolicy) Option {
+	return func(t *Tsdata) {
+		t.floatPolicy = p
+	}
+}
+
+// WithExpectedInterval makes ValidateLine add a warning to Data.Warnings for a
+// line whose timestamp differs from the timestamp of the last line validated
+// by more than tolerance from interval. This catches clock jumps and logger
+// stalls in regularly sampled data.
+func WithExpectedInterval(interval time.Duration, tolerance time.Duration) Option {
+	return func(t *Tsdata) {
+		t.interval = interval
+		t.tolerance = tolerance
+	}
+}
+
+// TimeFormat sets which timestamp forms ValidateLine accepts in time columns
+// in addition to RFC3339.
+type TimeFormat int
+
+const (
+	// TimeRFC3339 accepts only RFC3339 timestamps.
+	TimeRFC3339 TimeFormat = iota
+	// TimeEpoch also accepts Unix epoch seconds, e.g. 1494100377.601.
+	TimeEpoch
+	// TimeEpochMs also accepts Unix epoch milliseconds, e.g. 1494100377601.
+	TimeEpochMs
+)
+
+// ParseTimeFormat converts "rfc3339", "epoch", or "epoch_ms" to a TimeFormat.
+func ParseTimeFormat(s string) (TimeFormat, error) {
+	switch s {
+	case "rfc3339":
+		return TimeRFC3339, nil
+	case "epoch":
+		return TimeEpoch, nil
+	case "epoch_ms":
+		return TimeEpochMs, nil
+	}
+	return TimeRFC3339, fmt.Errorf("bad time format '%v', expected rfc3339, epoch, or epoch_ms", s)
+}
+
+func (f TimeFormat) String() string {
+	switch f {
+	case TimeEpoch:
+		return "epoch"
+	case TimeEpochMs:
+		return "epoch_ms"
+	}
+	return "rfc3339"
+}
+
+// WithTimeFormat makes ValidateLine accept Unix epoch timestamps in time
+// columns. Epoch timestamps are converted to RFC3339 UTC timestamps in
+// Data.Fields. The default is TimeRFC3339.
+func WithTimeFormat(f TimeFormat) Option {
+	return func(t *Tsdata) {
+		t.timeFormat = f
+	}
+}
+
+// WithNMEACoordinates makes ValidateLine accept latitude and longitude values
+// in NMEA degrees and decimal minutes with a hemisphere letter, e.g. 4916.45N
+// or 12311.12W. These values are converted to decimal degrees in Data.Fields
+// and Data.Values.
+func WithNMEACoordinates() Option {
+	return func(t *Tsdata) {
+		t.nmea = true
+	}
+}
+
+// WithConstraints adds constraints for columns named by the keys of m, e.g.
+// from ReadConstraints. These apply in addition to constraints declared in the
+// header, replacing any header restriction of the same kind, and are not
+// written by Header. Keys which don't name a column are ignored.
+func WithConstraints(m map[string]Constraint) Option {
+	return func(t *Tsdata) {
+		if t.constraints == nil {
+			t.constraints = make(map[string]Constraint, len(m))
+		}
+		for name, c := range m {
+			// A bad Pattern is reported by ParseHeader
+			t.constraints[name], _ = c.copy().compile()
+		}
+	}
+}
+
+// WithDelimiter sets the field separator used to parse and create header and
+// data lines. The default is Delim.
+func WithDelimiter(d rune) Option {
+	return func(t *Tsdata) {
+		t.delim = string(d)
+	}
 }
 
 // Data holds validated information for one TSDATA file line, with the original
-// column strings in Fields and time in Time.
+// column strings in Fields and time in Time. Values holds each field converted
+// to a Go value based on its column type: float64 for float, latitude, and
+// longitude, int64 for integer, bool for boolean, time.Time for time, and
+// string for all other types. NA fields are nil in Values. Warnings holds
+// descriptions of non-fatal problems found during validation. Line is the
+// 1-based line number of the data in its source file if it was read by a
+// Reader, otherwise 0.
 type Data struct {
-	Fields []string
-	Time   time.Time
+	Fields   []string
+	Values   []interface{}
+	Time     time.Time
+	Warnings []string
+	Line     int
+	index    map[string]int
+}
+
+// Get returns the field string for column name. ok is false if there is no
+// such column.
+func (d Data) Get(name string) (s string, ok bool) {
+	i, ok := d.index[name]
+	if !ok || i >= len(d.Fields) {
+		return "", false
+	}
+	return d.Fields[i], true
+}
+
+// Value returns the typed value for column name. See Data for value types. nil
+// is returned for NA values or if there is no such column.
+func (d Data) Value(name string) interface{} {
+	i, ok := d.index[name]
+	if !ok || i >= len(d.Values) {
+		return nil
+	}
+	return d.Values[i]
+}
+
+// Float returns the value for float column name. ok is false if there is no
+// such column, the column is not a float column, or the value is NA.
+func (d Data) Float(name string) (v float64, ok bool) {
+	v, ok = d.Value(name).(float64)
+	return v, ok
+}
+
+// Int returns the value for integer column name. ok is false if there is no
+// such column, the column is not an integer column, or the value is NA.
+func (d Data) Int(name string) (v int64, ok bool) {
+	v, ok = d.Value(name).(int64)
+	return v, ok
+}
+
+// Bool returns the value for boolean column name. ok is false if there is no
+// such column, the column is not a boolean column, or the value is NA.
+func (d Data) Bool(name string) (v bool, ok bool) {
+	v, ok = d.Value(name).(bool)
+	return v, ok
+}
+
+// TimeValue returns the value for time column name. ok is false if there is no
+// such column, the column is not a time column, or the value is NA.
+func (d Data) TimeValue(name string) (v time.Time, ok bool) {
+	v, ok = d.Value(name).(time.Time)
+	return v, ok
+}
+
+// Index returns the 0-based index of column name, or -1 if there is no such
+// column.
+func (t *Tsdata) Index(name string) int {
+	i, ok := t.columnIndex()[name]
+	if !ok {
+		return -1
+	}
+	return i
+}
+
+// columnIndex returns a map of column name to column index, building it from
+// Headers if necessary.
+func (t *Tsdata) columnIndex() map[string]int {
+	if t.index == nil {
+		t.index = make(map[string]int, len(t.Headers))
+		for i := len(t.Headers) - 1; i >= 0; i-- {
+			t.index[t.Headers[i]] = i // first column wins for duplicate names
+		}
+	}
+	return t.index
+}
+
+// ColumnInfo describes one column of a TSDATA file.
+type ColumnInfo struct {
+	Index   int // 0-based column index, 0 is the first time column
+	Name    string
+	Type    string
+	Unit    string
+	Comment string // NA if the header's comments row is empty
+	// Checker reports whether a field is a valid value of Type. NA is valid
+	// except in the first time column. Constraints aren't checked.
+	Checker func(string) bool
+}
+
+// Columns returns a ColumnInfo for each column of t in order.
+func (t *Tsdata) Columns() []ColumnInfo {
+	cols := make([]ColumnInfo, len(t.Headers))
+	for i, name := range t.Headers {
+		c := ColumnInfo{Index: i, Name: name, Type: NA, Unit: NA, Comment: NA}
+		if i < len(t.Types) {
+			c.Type = t.Types[i]
+		}
+		if i < len(t.Units) {
+			c.Unit = t.Units[i]
+		}
+		if i < len(t.Comments) {
+			c.Comment = t.Comments[i]
+		}
+		if c.Type == "time" {
+			first := i == 0
+			c.Checker = func(s string) bool {
+				_, err := t.ParseTime(s)
+				return err == nil || (!first && s == NA)
+			}
+		} else if i < len(t.checkers) && t.checkers[i] != nil {
+			c.Checker = t.checkers[i]
+		} else if checker, ok := lookupChecker(c.Type); ok {
+			c.Checker = checker
+		} else {
+			c.Checker = func(string) bool { return false }
+		}
+		cols[i] = c
+	}
+	return cols
+}
+
+// Delimiter returns the field separator string for this Tsdata.
+func (t *Tsdata) Delimiter() string {
+	if t.delim == "" {
+		return Delim
+	}
+	return t.delim
+}
+
+// FieldError describes a data field which failed validation.
+type FieldError struct {
+	Column int    // 0-based column index
+	Name   string // column name from the header, or "" if unknown
+	Type   string // declared column type, or "" if unknown
+	Value  string // original field value
+	Reason string // why a value of the right type failed a Constraint, or ""
+}
+
+func (e *FieldError) Error() string {
+	var s string
+	if e.Column == 0 {
+		s = "first time column"
+	} else {
+		s = fmt.Sprintf("column %v", e.Column+1)
+	}
+	switch {
+	case e.Name != "" && e.Type != "" && e.Column != 0:
+		s += fmt.Sprintf(" (%v, %v)", e.Name, e.Type)
+	case e.Name != "":
+		s += fmt.Sprintf(" (%v)", e.Name)
+	}
+	s += fmt.Sprintf(", bad value '%v'", e.Value)
+	if e.Reason != "" {
+		s += ", " + e.Reason
+	}
+	return s
 }
 
 // ValidateLine checks values in a data line and returns all fields as a slice of
-// strings. It returns an error for the first field that fails validation. It
-// also returns an error if the timestamp in this line is earlier than the
-// timestamp in the last line validated by this struct.
+// strings. It returns an error for the first field that fails validation. If
+// configured with TimeOrderStrict it also returns an error if the timestamp in
+// this line is earlier than the timestamp in the last line validated by this
+// struct. With TimeOrderWarn the same condition produces a warning in
+// Data.Warnings. If strict is false, data fields which fail validation are
+// converted to NA rather than producing an error.
 func (t *Tsdata) ValidateLine(line string, strict bool) (Data, error) {
-	fields := strings.Split(line, Delim)
+	data, fieldErrs, err := t.validate(line, strict)
+	if err != nil {
+		return Data{}, err
+	}
+	if strict && len(fieldErrs) > 0 {
+		return Data{}, fieldErrs[0]
+	}
+	return data, nil
+}
+
+// validate checks values in a data line. Data fields which fail validation are
+// converted to NA and returned as FieldErrors. The returned error is non-nil
+// for problems which prevent the line from being used, such as a bad first
+// time column. lastTime is only updated if strict is false or there are no
+// field errors.
+func (t *Tsdata) validate(line string, strict bool) (Data, []*FieldError, error) {
+	data, fieldErrs, err := t.parseLine(line)
+	if err != nil {
+		return Data{}, nil, err
+	}
+	err = t.checkOrder(&data, fieldErrs, strict)
+	if err != nil {
+		return Data{}, nil, err
+	}
+	return data, fieldErrs, nil
+}
+
+// delimiterHint returns a note for a line with too few fields if it has one
+// field per column when split on commas or runs of spaces, or "" otherwise.
+func (t *Tsdata) delimiterHint(line string) string {
+	n := len(t.Headers)
+	if t.Delimiter() != "," && len(strings.Split(line, ",")) == n {
+		return ", looks comma-delimited"
+	}
+	if t.Delimiter() != " " && len(strings.Fields(line)) == n {
+		return ", looks space-delimited"
+	}
+	return ""
+}
+
+// parseLine is the part of validate which doesn't depend on previous lines.
+// It doesn't modify t so may be called concurrently once t.index is set.
+func (t *Tsdata) parseLine(line string) (Data, []*FieldError, error) {
+	fields := strings.Split(line, t.Delimiter())
 	if len(fields) < 2 {
 		// Need at least time column plus one data column
-		return Data{}, fmt.Errorf("found %v columns, expected >= 2", len(fields))
+		return Data{}, nil, fmt.Errorf("found %v columns, expected >= 2%v", len(fields), t.delimiterHint(line))
 	}
 	if len(fields) < len(t.Headers) {
-		return Data{}, fmt.Errorf("found %v columns, expected %v", len(fields), len(t.Headers))
+		return Data{}, nil, fmt.Errorf("found %v columns, expected %v%v", len(fields), len(t.Headers), t.delimiterHint(line))
 	}
 	fields = fields[:len(t.Headers)] // remove any extra fields
 	// Validate first time column separately here to make sure not NA
 	fields[0] = strings.TrimSpace(fields[0]) // remove leading/trailing whitespace
-	tline, err := parseTime(fields[0])
-	fields[0] = tline.Format(time.RFC3339Nano) // standardize time string
+	tline, err := t.ParseTime(fields[0])
 	if err != nil {
-		return Data{}, fmt.Errorf("first time column, bad value '%v'", fields[0])
+		return Data{}, nil, t.fieldError(0, fields[0], "")
 	}
+	fields[0] = tline.Format(time.RFC3339Nano) // standardize time string
 
-	// Turn off time order check for now, it's sometimes too stringent.
-	//if tline.Sub(t.lastTime) < 0 {
-	//	return Data{}, fmt.Errorf("timestamp less than previous line, %v < %v", tline, t.lastTime)
-	//}
+	var fieldErrs []*FieldError
 	for i := 1; i < len(fields); i++ { // skip first time column
 		// Remove leading/trailing whitespace from each data field
 		fields[i] = strings.TrimSpace(fields[i])
+		if t.naTokens[fields[i]] {
+			fields[i] = NA
+		}
 		if t.Types[i] == "time" {
 			// Validate time fields as a special case to avoid parsing twice and to
 			// convert to a consistent RFC3339 string with 'T'
-			timeField, err := parseTime(fields[i])
+			timeField, err := t.ParseTime(fields[i])
 			if err != nil {
-				if fields[i] != NA && strict {
-					return Data{}, fmt.Errorf("column %v, bad value '%v'", i+1, fields[i])
+				if fields[i] != NA {
+					fieldErrs = append(fieldErrs, t.fieldError(i, fields[i], ""))
 				}
 				fields[i] = NA
 			} else {
 				fields[i] = timeField.Format(time.RFC3339Nano)
 			}
 		} else {
-			if !t.checkers[i](fields[i]) {
-				if strict {
-					return Data{}, fmt.Errorf("column %v, bad value '%v'", i+1, fields[i])
+			if t.floatPolicy != FloatAccept && isNonFinite(t.Types[i], fields[i]) {
+				if t.floatPolicy == FloatReject {
+					fieldErrs = append(fieldErrs, t.fieldError(i, fields[i], "not a finite number"))
 				}
 				fields[i] = NA
+				continue
+			}
+			if t.nmea && !t.checkers[i](fields[i]) {
+				if deg, ok := parseNMEA(t.Types[i], fields[i]); ok {
+					fields[i] = deg
+				}
+			}
+			if !t.checkers[i](fields[i]) {
+				fieldErrs = append(fieldErrs, t.fieldError(i, fields[i], ""))
+				fields[i] = NA
+			} else if reason := t.checkConstraint(i, fields[i]); reason != "" {
+				fieldErrs = append(fieldErrs, t.fieldError(i, fields[i], reason))
+				fields[i] = NA
 			}
 		}
 	}
-	t.lastTime = tline
-	return Data{Fields: fields, Time: tline}, nil
+	values := make([]interface{}, len(fields))
+	values[0] = tline
+	for i := 1; i < len(fields); i++ {
+		values[i] = parseValue(t.Types[i], fields[i])
+	}
+	return Data{Fields: fields, Values: values, Time: tline, index: t.columnIndex()}, fieldErrs, nil
+}
+
+// fieldError returns a FieldError for value s in column i.
+func (t *Tsdata) fieldError(i int, s string, reason string) *FieldError {
+	return &FieldError{Column: i, Name: t.Headers[i], Type: t.Types[i], Value: s, Reason: reason}
+}
+
+// checkConstraint returns a description of why s violates the constraint for
+// column i, or "" if s is allowed.
+func (t *Tsdata) checkConstraint(i int, s string) string {
+	c, ok := t.constraints[t.Headers[i]]
+	if i < len(t.Constraints) {
+		c = t.Constraints[i].merge(c)
+	} else if !ok {
+		return ""
+	}
+	return c.check(s)
+}
+
+// checkOrder is the part of validate which compares data to previous lines.
+// It adds a time order warning to data or returns an error according to
+// t.timeOrder, and updates lastTime. It also warns the first time each new UTC
+// offset appears in a file with timestamps in more than one offset, and for
+// irregular intervals if configured by WithExpectedInterval.
+func (t *Tsdata) checkOrder(data *Data, fieldErrs []*FieldError, strict bool) error {
+	_, offset := data.Time.Zone()
+	if !t.offsets[offset] {
+		if len(t.offsets) > 0 {
+			data.Warnings = append(data.Warnings, fmt.Sprintf("timestamp offset %v differs from earlier lines", data.Time.Format("Z07:00")))
+		}
+		if t.offsets == nil {
+			t.offsets = map[int]bool{}
+		}
+		t.offsets[offset] = true
+	}
+	// Time order check is opt-in, it's sometimes too stringent.
+	if t.timeOrder != TimeOrderOff && !t.lastTime.IsZero() && data.Time.Sub(t.lastTime) < 0 {
+		msg := fmt.Sprintf("timestamp less than previous line, %v < %v", data.Time.Format(time.RFC3339Nano), t.lastTime.Format(time.RFC3339Nano))
+		if t.timeOrder == TimeOrderStrict {
+			return errors.New(msg)
+		}
+		data.Warnings = append(data.Warnings, msg)
+	}
+	if t.interval > 0 && !t.lastTime.IsZero() {
+		d := data.Time.Sub(t.lastTime)
+		if off := d - t.interval; off > t.tolerance || off < -t.tolerance {
+			data.Warnings = append(data.Warnings, fmt.Sprintf("interval %v from previous line, expected %v with tolerance %v", d, t.interval, t.tolerance))
+		}
+	}
+	if !strict || len(fieldErrs) == 0 {
+		t.lastTime = data.Time
+	}
+	return nil
 }
 
 // ParseHeader parses and validates header metadata. Input should a string of
@@ -99,145 +605,128 @@ func (t *Tsdata) ParseHeader(header string) error {
 	header = strings.TrimSuffix(header, "\n")
 	headerLines := strings.Split(header, "\n")
 	if len(headerLines) != HeaderSize {
-		return fmt.Errorf("expected %v lines in header, found %v", HeaderSize, len(headerLines))
+		return &HeaderError{Err: fmt.Errorf("expected %v lines in header, found %v", HeaderSize, len(headerLines))}
 	}
+	t.rawHeader = header
 	// Remove trailing whitespace from each line
 	for i := 0; i < len(headerLines); i++ {
 		headerLines[i] = strings.TrimRight(headerLines[i], " \t\r")
 	}
 
-	t.FileType = strings.Split(headerLines[0], Delim)[0]
-	t.Project = strings.Split(headerLines[1], Delim)[0]
-	t.FileDescription = strings.Split(headerLines[2], Delim)[0]
+	delim := t.Delimiter()
+	t.FileType = strings.Split(headerLines[0], delim)[0]
+	t.Project = strings.Split(headerLines[1], delim)[0]
+	t.FileDescription = strings.Split(headerLines[2], delim)[0]
 	if headerLines[3] != "" {
-		t.Comments = strings.Split(headerLines[3], Delim)
+		t.Comments = strings.Split(headerLines[3], delim)
 		// Remove leading/trailing whitespace from each field
 		for i := 0; i < len(t.Comments); i++ {
 			t.Comments[i] = strings.TrimSpace(t.Comments[i])
 		}
 	}
+	t.Constraints = nil
 	if headerLines[4] != "" {
-		t.Types = strings.Split(headerLines[4], Delim)
+		t.Types = strings.Split(headerLines[4], delim)
 		// Remove leading/trailing whitespace from each field
 		for i := 0; i < len(t.Types); i++ {
 			t.Types[i] = strings.TrimSpace(t.Types[i])
 		}
+		// Move category values to Constraints
+		for i := 0; i < len(t.Types); i++ {
+			typ, c, err := parseType(t.Types[i])
+			if err != nil {
+				return &HeaderError{Err: err}
+			}
+			if !c.IsZero() {
+				if t.Constraints == nil {
+					t.Constraints = make([]Constraint, len(t.Types))
+				}
+				t.Types[i] = typ
+				t.Constraints[i] = c
+			}
+		}
 	}
 	if headerLines[5] != "" {
-		t.Units = strings.Split(headerLines[5], Delim)
+		t.Units = strings.Split(headerLines[5], delim)
 		// Remove leading/trailing whitespace from each field
 		for i := 0; i < len(t.Units); i++ {
 			t.Units[i] = strings.TrimSpace(t.Units[i])
 		}
 	}
 	if headerLines[6] != "" {
-		t.Headers = strings.Split(headerLines[6], Delim)
+		t.Headers = strings.Split(headerLines[6], delim)
 		// Remove leading/trailing whitespace from each field
 		for i := 0; i < len(t.Headers); i++ {
-			t.Headers[i] = strings.TrimSpace(t.Headers[i])
+			t.Headers[i] = t.aliases.Canonical(strings.TrimSpace(t.Headers[i]))
 		}
 	}
 
-	t.checkers = make([]func(string) bool, len(t.Types))
-	for i, ty := range t.Types {
-		t.checkers[i] = typecheckers[ty]
-	}
-	return t.ValidateMetadata()
-}
-
-// ValidateMetadata checks for errors and inconsistencies in metadata values.
-func (t *Tsdata) ValidateMetadata() error {
-	// FileType
-	if t.FileType == "" {
-		return fmt.Errorf("missing or empty FileType")
-	}
-
-	// Project
-	if t.Project == "" {
-		return fmt.Errorf("missing or empty Project")
-	}
-
-	// Comments
-	colCount := 0
-	// Column comments may be a blank line so allow 0 columns
-	if len(t.Comments) > 0 {
-		colCount = len(t.Comments)
-		for i, com := range t.Comments {
-			if com == "" {
-				return fmt.Errorf("empty comment in column %v", i+1)
+	// Add ranges from Comments to Constraints
+	for i := 0; i < len(t.Comments) && i < len(t.Types); i++ {
+		if c := parseCommentRange(t.Comments[i]); !c.IsZero() {
+			if t.Constraints == nil {
+				t.Constraints = make([]Constraint, len(t.Types))
 			}
+			t.Constraints[i] = t.Constraints[i].merge(c)
 		}
 	}
 
-	// Types
-	if len(t.Types) == 0 {
-		return fmt.Errorf("missing or empty Types")
-	}
-	if colCount > 0 && len(t.Types) != colCount {
-		return fmt.Errorf("inconsistent Types column count")
-	}
-	for i, t := range t.Types {
-		_, ok := typecheckers[t]
-		if !ok {
-			return fmt.Errorf("bad Types value '%v' in column %v", t, i+1)
-		}
-	}
-	colCount = len(t.Types)
-
-	// Units
-	if len(t.Units) == 0 {
-		return fmt.Errorf("missing or empty Units")
-	}
-	if len(t.Units) != colCount {
-		return fmt.Errorf("inconsistent Units column count")
-	}
-	for i, u := range t.Units {
-		if u == "" {
-			return fmt.Errorf("empty Units value in column %v", i+1)
-		}
-	}
-
-	// Headers
-	if len(t.Headers) == 0 {
-		return fmt.Errorf("missing or empty Headers")
-	}
-	if len(t.Headers) != colCount {
-		return fmt.Errorf("inconsistent Headers column count")
+	t.index = nil
+	t.columnIndex()
+	t.checkers = make([]func(string) bool, len(t.Types))
+	for i, ty := range t.Types {
+		t.checkers[i], _ = lookupChecker(ty)
 	}
-	if t.Headers[0] != "time" {
-		return fmt.Errorf("first Headers column should be 'time'")
+	if err := t.ValidateMetadata(); err != nil {
+		return err
 	}
 	for i, h := range t.Headers {
-		if h == "" {
-			return fmt.Errorf("empty Headers value in column %v", i+1)
+		if c, ok := t.constraints[h]; ok {
+			if err := c.validate(t.Types[i]); err != nil {
+				return &HeaderError{Err: fmt.Errorf("constraint for column %v, %v", h, err)}
+			}
 		}
 	}
-
-	// Finally column count should be > 1, meaning at least one data column
-	// after the first time column
-	if colCount < 2 {
-		return fmt.Errorf("no data columns after time")
-	}
-
 	return nil
 }
 
+// ValidateMetadata checks for errors and inconsistencies in metadata values.
+func (t *Tsdata) ValidateMetadata() error {
+	return t.Schema().Validate()
+}
+
 // Header creates a TSData file metadata header paragraph.
 func (t *Tsdata) Header() string {
-	// TODO: should this ever produce a non-conforming TSData header?
-	cols := len(t.Headers)
-	text := t.FileType + "\n"
-	text = text + t.Project + "\n"
-	text = text + t.FileDescription + "\n"
-	if len(t.Comments) == 0 {
-		text = text + nas(cols) + "\n"
-	} else {
-		text = text + strings.Join(t.Comments, Delim) + "\n"
+	return t.Schema().header(t.Delimiter())
+}
+
+// RawHeader returns the header section exactly as it was passed to
+// ParseHeader or read by ParseHeaderFrom, without the newline after the last
+// header line, or "" if t wasn't created by parsing a header. Unlike Header,
+// it keeps the original spacing, line endings, and text after a delimiter in
+// the first three lines, and column names before aliases are applied, so
+// writing it preserves the header's wording exactly.
+func (t *Tsdata) RawHeader() string {
+	return t.rawHeader
+}
+
+// MarshalJSON encodes header metadata as JSON. See Schema.MarshalJSON.
+func (t *Tsdata) MarshalJSON() ([]byte, error) {
+	return t.Schema().MarshalJSON()
+}
+
+// Schema returns a copy of the header metadata in t.
+func (t *Tsdata) Schema() Schema {
+	return Schema{
+		FileType:        t.FileType,
+		Project:         t.Project,
+		FileDescription: t.FileDescription,
+		Comments:        copyStrings(t.Comments),
+		Types:           copyStrings(t.Types),
+		Units:           copyStrings(t.Units),
+		Headers:         copyStrings(t.Headers),
+		Constraints:     copyConstraints(t.Constraints),
 	}
-	text = text + strings.Join(t.Types, Delim) + "\n"
-	text = text + strings.Join(t.Units, Delim) + "\n"
-	text = text + strings.Join(t.Headers, Delim) // note, doesn't end with blank line
-	return text
 }
 
 // checkTime always assumes s is a valid RFC3339 timestamp. Must check
@@ -254,6 +743,80 @@ func checkFloat(s string) bool {
 	return true
 }
 
+// isNonFinite returns true if s is a NaN or infinite value in a column of
+// type typ which holds floating point numbers.
+func isNonFinite(typ string, s string) bool {
+	switch typ {
+	case "float", "latitude", "longitude":
+	default:
+		return false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return err == nil && (math.IsNaN(v) || math.IsInf(v, 0))
+}
+
+func checkLatitude(s string) bool {
+	return checkRange(s, 90)
+}
+
+func checkLongitude(s string) bool {
+	return checkRange(s, 180)
+}
+
+// checkRange checks for NA or a number from -limit to limit.
+func checkRange(s string, limit float64) bool {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return s == NA
+	}
+	return v >= -limit && v <= limit
+}
+
+// parseNMEA converts a latitude or longitude in NMEA degrees and decimal
+// minutes followed by a hemisphere letter, e.g. 4916.45N, to decimal degrees
+// with enough decimal places to keep the precision of the minutes.
+func parseNMEA(typ string, s string) (string, bool) {
+	if len(s) < 2 {
+		return "", false
+	}
+	hemi := s[len(s)-1]
+	sign := 1.0
+	switch {
+	case typ == "latitude" && (hemi == 'N' || hemi == 'S'):
+		if hemi == 'S' {
+			sign = -1
+		}
+	case typ == "longitude" && (hemi == 'E' || hemi == 'W'):
+		if hemi == 'W' {
+			sign = -1
+		}
+	default:
+		return "", false
+	}
+	num := strings.TrimSpace(s[:len(s)-1])
+	if num == "" || strings.IndexFunc(num, func(r rune) bool { return (r < '0' || r > '9') && r != '.' }) >= 0 {
+		return "", false
+	}
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return "", false
+	}
+	deg := math.Floor(v / 100)
+	min := v - deg*100
+	if min >= 60 {
+		return "", false
+	}
+	decimals := 2 // minutes to degrees adds about two decimal places
+	if i := strings.Index(num, "."); i >= 0 {
+		decimals += len(num) - i - 1
+	}
+	d := sign * (deg + min/60)
+	if d == 0 {
+		d = 0 // no negative zero
+	}
+	return strconv.FormatFloat(d, 'f', decimals, 64), true
+}
+
 func checkInteger(s string) bool {
 	_, err := strconv.ParseInt(s, 10, 64)
 	if err != nil {
@@ -274,21 +837,133 @@ func checkBoolean(s string) bool {
 	return (s == "TRUE" || s == "FALSE" || s == NA)
 }
 
+var typecheckersMu sync.RWMutex
+
 var typecheckers = map[string]func(string) bool{
-	"time":     checkTime,
-	"float":    checkFloat,
-	"integer":  checkInteger,
-	"text":     checkText,
-	"category": checkCategory,
-	"boolean":  checkBoolean,
+	"time":      checkTime,
+	"float":     checkFloat,
+	"integer":   checkInteger,
+	"text":      checkText,
+	"category":  checkCategory,
+	"boolean":   checkBoolean,
+	"latitude":  checkLatitude,
+	"longitude": checkLongitude,
 }
 
-func nas(size int) string {
+// parseValue converts a validated field string s to a Go value based on column
+// type typ. NA or unparseable fields produce nil.
+func parseValue(typ string, s string) interface{} {
+	if s == NA {
+		return nil
+	}
+	switch typ {
+	case "float", "latitude", "longitude":
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return v
+		}
+		return nil
+	case "integer":
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return v
+		}
+		return nil
+	case "boolean":
+		return s == "TRUE"
+	case "time":
+		if v, err := parseTime(s); err == nil {
+			return v
+		}
+		return nil
+	default:
+		return s
+	}
+}
+
+// RegisterType makes a custom column type available to ParseHeader and
+// ValidateMetadata. checker should return true if a data field string is a
+// valid value for this type, including NA if missing data is allowed. Values
+// for custom types are stored as strings in Data.Values. RegisterType panics if
+// name is empty, checker is nil, or name is already registered.
+func RegisterType(name string, checker func(string) bool) {
+	typecheckersMu.Lock()
+	defer typecheckersMu.Unlock()
+	if name == "" {
+		panic("tsdata: RegisterType name is empty")
+	}
+	if checker == nil {
+		panic("tsdata: RegisterType checker is nil")
+	}
+	if _, dup := typecheckers[name]; dup {
+		panic("tsdata: RegisterType called twice for type " + name)
+	}
+	typecheckers[name] = checker
+}
+
+// lookupChecker returns the checker function for column type name.
+func lookupChecker(name string) (func(string) bool, bool) {
+	typecheckersMu.RLock()
+	defer typecheckersMu.RUnlock()
+	checker, ok := typecheckers[name]
+	return checker, ok
+}
+
+func nas(size int, delim string) string {
 	s := make([]string, size)
 	for i := range s {
 		s[i] = NA
 	}
-	return strings.Join(s, Delim)
+	return strings.Join(s, delim)
+}
+
+// ParseTime parses a TSDATA timestamp, an RFC3339 string with either a 'T' or
+// a space between the date and time.
+func ParseTime(s string) (time.Time, error) {
+	return parseTime(s)
+}
+
+// ParseTime parses a timestamp in a time column of t. It is like the ParseTime
+// function but also accepts epoch timestamps if configured by WithTimeFormat.
+func (t *Tsdata) ParseTime(s string) (time.Time, error) {
+	switch t.timeFormat {
+	case TimeEpoch:
+		if v, err := parseEpoch(s, time.Second); err == nil {
+			return v, nil
+		}
+	case TimeEpochMs:
+		if v, err := parseEpoch(s, time.Millisecond); err == nil {
+			return v, nil
+		}
+	}
+	return parseTime(s)
+}
+
+// parseEpoch parses a decimal count of unit since the Unix epoch as a UTC time.
+func parseEpoch(s string, unit time.Duration) (time.Time, error) {
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	n, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if n > math.MaxInt64/int64(unit) || n < math.MinInt64/int64(unit) {
+		return time.Time{}, fmt.Errorf("epoch timestamp '%v' out of range", s)
+	}
+	d := time.Duration(n) * unit
+	if frac != "" {
+		// Fraction of unit in nanoseconds
+		f, err := strconv.ParseUint((frac + "000000000")[:9], 10, 64)
+		if err != nil || len(frac) > 9 {
+			return time.Time{}, fmt.Errorf("bad epoch timestamp '%v'", s)
+		}
+		fd := time.Duration(f) * unit / time.Second
+		if strings.HasPrefix(whole, "-") {
+			fd = -fd
+		}
+		d += fd
+	}
+	return time.Unix(0, 0).UTC().Add(d), nil
 }
 
 func parseTime(s string) (t time.Time, err error) {
@@ -1,6 +1,8 @@
 package tsdata
 
 import (
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -294,6 +296,30 @@ ISO8601 timestamp	NA	NA
 time	float	integer
 NA	NA	NA
 nottime	col1	col2
+`,
+			wantErr: true,
+		},
+		{
+			name: "duplicate header column",
+			header: `fileType
+project
+file description
+ISO8601 timestamp	NA	NA
+time	float	integer
+NA	NA	NA
+time	col1	col1
+`,
+			wantErr: true,
+		},
+		{
+			name: "reserved header column",
+			header: `fileType
+project
+file description
+ISO8601 timestamp	NA	NA
+time	float	integer
+NA	NA	NA
+time	col1	time
 `,
 			wantErr: true,
 		},
@@ -789,6 +815,69 @@ func TestTsdata_Header(t *testing.T) {
 	}
 }
 
+func TestTsdata_RawHeader(t *testing.T) {
+	header := "fileType\textra\nproject \nfile  description\n ISO8601 timestamp \t NA\ntime\tfloat\nNA\t m/s\ntime\tspeed "
+	d := New()
+	if d.RawHeader() != "" {
+		t.Errorf("New().RawHeader() = %q, expected \"\"", d.RawHeader())
+	}
+	if err := d.ParseHeader(header + "\n"); err != nil {
+		t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+	}
+	if d.RawHeader() != header {
+		t.Errorf("Tsdata.RawHeader() = %q, expected %q", d.RawHeader(), header)
+	}
+	if d.Header() == header {
+		t.Errorf("Tsdata.Header() = %q, expected a normalized header", d.Header())
+	}
+
+	crlf := strings.ReplaceAll(header, "\n", "\r\n") + "\r"
+	d = New()
+	if _, err := d.ParseHeaderFrom(strings.NewReader(crlf + "\n2017-05-06T19:52:57Z\t1\r\n")); err != nil {
+		t.Fatalf("Tsdata.ParseHeaderFrom() err %v, expected nil", err)
+	}
+	if d.RawHeader() != crlf {
+		t.Errorf("Tsdata.RawHeader() after ParseHeaderFrom = %q, expected %q", d.RawHeader(), crlf)
+	}
+}
+
+func TestTsdata_Columns(t *testing.T) {
+	header := "fileType\nproject\nfile description\n\ntime\tfloat\ttime\tboolean\nNA\tm/s\tNA\tNA\ntime\tspeed\tend\tflag"
+	d := New()
+	if err := d.ParseHeader(header); err != nil {
+		t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+	}
+	cols := d.Columns()
+	if len(cols) != 4 {
+		t.Fatalf("Tsdata.Columns() returned %v columns, expected 4", len(cols))
+	}
+	c := cols[1]
+	if c.Index != 1 || c.Name != "speed" || c.Type != "float" || c.Unit != "m/s" || c.Comment != NA {
+		t.Errorf("Tsdata.Columns()[1] = %+v, expected speed float m/s with NA comment", c)
+	}
+	checks := []struct {
+		col   int
+		value string
+		want  bool
+	}{
+		{0, "2017-05-06T19:52:57Z", true},
+		{0, NA, false},
+		{0, "bad", false},
+		{1, "1.5", true},
+		{1, NA, true},
+		{1, "x", false},
+		{2, NA, true},
+		{2, "2017-05-06", false},
+		{3, "TRUE", true},
+		{3, "yes", false},
+	}
+	for _, tt := range checks {
+		if got := cols[tt.col].Checker(tt.value); got != tt.want {
+			t.Errorf("Tsdata.Columns()[%v].Checker(%q) = %v, expected %v", tt.col, tt.value, got, tt.want)
+		}
+	}
+}
+
 func stringSliceEqual(a []string, b []string) bool {
 	if a == nil && b == nil {
 		return true
@@ -803,3 +892,479 @@ func stringSliceEqual(a []string, b []string) bool {
 	}
 	return true
 }
+
+func TestNew_options(t *testing.T) {
+	header := "fileType\nproject\nfile description\nISO8601 timestamp,NA\ntime,float\nNA,NA\ntime,col1"
+	tests := []struct {
+		name       string
+		opts       []Option
+		lines      []string
+		dataFields []string
+		wantErr    bool
+	}{
+		{
+			name:       "NA tokens converted to NA",
+			opts:       []Option{WithDelimiter(','), WithNATokens("NaN", "-999")},
+			lines:      []string{"2017-05-06T19:52:57.601Z,-999"},
+			dataFields: []string{"2017-05-06T19:52:57.601Z", "NA"},
+			wantErr:    false,
+		},
+		{
+			name:       "in-order lines with monotonic time",
+			opts:       []Option{WithDelimiter(','), WithMonotonicTime()},
+			lines:      []string{"2017-05-06T19:00:00Z,1", "2017-05-06T19:52:57.601Z,6.0"},
+			dataFields: []string{"2017-05-06T19:52:57.601Z", "6.0"},
+			wantErr:    false,
+		},
+		{
+			name:    "out-of-order lines with monotonic time",
+			opts:    []Option{WithDelimiter(','), WithMonotonicTime()},
+			lines:   []string{"2017-05-06T20:00:00Z,1", "2017-05-06T19:52:57.601Z,6.0"},
+			wantErr: true,
+		},
+		{
+			name:    "wrong delimiter",
+			opts:    []Option{WithDelimiter(',')},
+			lines:   []string{"2017-05-06T19:52:57.601Z	6.0"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New(tt.opts...)
+			if err := d.ParseHeader(header); err != nil {
+				t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+			}
+			var data Data
+			var err error
+			for _, line := range tt.lines {
+				data, err = d.ValidateLine(line, true)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Tsdata.ValidateLine() err %v, expected a non-nil error", err)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Tsdata.ValidateLine() err %v, expected nil", err)
+				}
+				if !stringSliceEqual(data.Fields, tt.dataFields) {
+					t.Errorf("Tsdata.ValidateLine() fields %v, expected %v", data.Fields, tt.dataFields)
+				}
+			}
+		})
+	}
+}
+
+func TestTsdata_ValidateLine_values(t *testing.T) {
+	tline, _ := time.Parse(time.RFC3339, "2017-05-06T19:52:57.601Z")
+	t2, _ := time.Parse(time.RFC3339, "2017-05-07T00:00:00Z")
+	d := &Tsdata{}
+	header := "fileType\nproject\n\n\ntime\tfloat\tinteger\ttext\tcategory\tboolean\ttime\tfloat\nNA\tNA\tNA\tNA\tNA\tNA\tNA\tNA\ntime\tc1\tc2\tc3\tc4\tc5\tc6\tc7"
+	if err := d.ParseHeader(header); err != nil {
+		t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+	}
+	data, err := d.ValidateLine("2017-05-06T19:52:57.601Z\t6.5\t-3\tfoo\tblue\tTRUE\t2017-05-07T00:00:00Z\tNA", true)
+	if err != nil {
+		t.Fatalf("Tsdata.ValidateLine() err %v, expected nil", err)
+	}
+	want := []interface{}{tline, 6.5, int64(-3), "foo", "blue", true, t2, nil}
+	if len(data.Values) != len(want) {
+		t.Fatalf("Tsdata.ValidateLine() Values %v, expected %v", data.Values, want)
+	}
+	for i := range want {
+		if wt, ok := want[i].(time.Time); ok {
+			if gt, ok := data.Values[i].(time.Time); !ok || !gt.Equal(wt) {
+				t.Errorf("Tsdata.ValidateLine() Values[%v] %v, expected %v", i, data.Values[i], want[i])
+			}
+			continue
+		}
+		if data.Values[i] != want[i] {
+			t.Errorf("Tsdata.ValidateLine() Values[%v] %v, expected %v", i, data.Values[i], want[i])
+		}
+	}
+}
+
+func TestTsdata_ValidateLine_coordinates(t *testing.T) {
+	header := "fileType\nproject\n\n\ntime\tlatitude\tlongitude\nNA\tdegrees\tdegrees\ntime\tlat\tlon"
+	tests := []struct {
+		name       string
+		opts       []Option
+		line       string
+		dataFields []string
+		wantErr    bool
+	}{
+		{
+			name:       "decimal degrees",
+			line:       "2017-05-06T19:52:57.601Z\t-90\t180.0",
+			dataFields: []string{"2017-05-06T19:52:57.601Z", "-90", "180.0"},
+		},
+		{
+			name:    "latitude out of range",
+			line:    "2017-05-06T19:52:57.601Z\t90.1\t0",
+			wantErr: true,
+		},
+		{
+			name:    "longitude out of range",
+			line:    "2017-05-06T19:52:57.601Z\t0\t-180.5",
+			wantErr: true,
+		},
+		{
+			name:    "NMEA without option",
+			line:    "2017-05-06T19:52:57.601Z\t4730.123N\t0",
+			wantErr: true,
+		},
+		{
+			name:       "NMEA with option",
+			opts:       []Option{WithNMEACoordinates()},
+			line:       "2017-05-06T19:52:57.601Z\t4730.123S\t12218W",
+			dataFields: []string{"2017-05-06T19:52:57.601Z", "-47.50205", "-122.30"},
+		},
+		{
+			name:    "NMEA hemisphere for wrong column",
+			opts:    []Option{WithNMEACoordinates()},
+			line:    "2017-05-06T19:52:57.601Z\t4730.123E\t0",
+			wantErr: true,
+		},
+		{
+			name:    "NMEA minutes out of range",
+			opts:    []Option{WithNMEACoordinates()},
+			line:    "2017-05-06T19:52:57.601Z\t4760.0N\t0",
+			wantErr: true,
+		},
+		{
+			name:    "NMEA degrees out of range",
+			opts:    []Option{WithNMEACoordinates()},
+			line:    "2017-05-06T19:52:57.601Z\t0\t18030.0E",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New(tt.opts...)
+			if err := d.ParseHeader(header); err != nil {
+				t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+			}
+			data, err := d.ValidateLine(tt.line, true)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Tsdata.ValidateLine() err %v, expected a non-nil error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Tsdata.ValidateLine() err %v, expected nil", err)
+			}
+			if !stringSliceEqual(data.Fields, tt.dataFields) {
+				t.Errorf("Tsdata.ValidateLine() fields %v, expected %v", data.Fields, tt.dataFields)
+			}
+			if _, ok := data.Values[1].(float64); !ok {
+				t.Errorf("Tsdata.ValidateLine() Values[1] %T, expected float64", data.Values[1])
+			}
+		})
+	}
+}
+
+func TestRegisterType(t *testing.T) {
+	RegisterType("hexcolor", func(s string) bool {
+		if s == NA {
+			return true
+		}
+		if len(s) != 7 || s[0] != '#' {
+			return false
+		}
+		_, err := strconv.ParseUint(s[1:], 16, 32)
+		return err == nil
+	})
+	d := &Tsdata{}
+	err := d.ParseHeader("fileType\nproject\n\n\ntime\thexcolor\nNA\tNA\ntime\tcolor")
+	if err != nil {
+		t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+	}
+	data, err := d.ValidateLine("2017-05-06T19:52:57.601Z\t#00ff00", true)
+	if err != nil {
+		t.Errorf("Tsdata.ValidateLine() err %v, expected nil", err)
+	}
+	if data.Values[1] != "#00ff00" {
+		t.Errorf("Tsdata.ValidateLine() Values[1] %v, expected #00ff00", data.Values[1])
+	}
+	_, err = d.ValidateLine("2017-05-06T19:52:57.601Z\tgreen", true)
+	if err == nil {
+		t.Errorf("Tsdata.ValidateLine() err %v, expected a non-nil error", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("RegisterType() expected panic for duplicate type")
+		}
+	}()
+	RegisterType("float", checkFloat)
+}
+
+func TestTsdata_ValidateLine_timeOrder(t *testing.T) {
+	header := "fileType\nproject\n\n\ntime\tfloat\nNA\tNA\ntime\tcol1"
+	lines := []string{"2017-05-06T19:00:00Z\t1", "2017-05-06T20:00:00Z\t2", "2017-05-06T18:00:00Z\t3"}
+	tests := []struct {
+		name         string
+		order        TimeOrder
+		wantErr      bool
+		wantWarnings int
+	}{
+		{name: "off", order: TimeOrderOff},
+		{name: "warn", order: TimeOrderWarn, wantWarnings: 1},
+		{name: "strict", order: TimeOrderStrict, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New(WithTimeOrder(tt.order))
+			if err := d.ParseHeader(header); err != nil {
+				t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+			}
+			var data Data
+			var err error
+			for _, line := range lines {
+				data, err = d.ValidateLine(line, true)
+			}
+			if tt.wantErr != (err != nil) {
+				t.Errorf("Tsdata.ValidateLine() err %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(data.Warnings) != tt.wantWarnings {
+				t.Errorf("Tsdata.ValidateLine() Warnings %v, expected %v warnings", data.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestParseTimeOrder(t *testing.T) {
+	for _, s := range []string{"off", "warn", "strict"} {
+		o, err := ParseTimeOrder(s)
+		if err != nil || o.String() != s {
+			t.Errorf("ParseTimeOrder(%v) = %v, %v", s, o, err)
+		}
+	}
+	if _, err := ParseTimeOrder("bogus"); err == nil {
+		t.Errorf("ParseTimeOrder(bogus) err nil, expected a non-nil error")
+	}
+}
+
+func TestData_accessors(t *testing.T) {
+	tline, _ := time.Parse(time.RFC3339, "2017-05-06T19:52:57.601Z")
+	d := &Tsdata{}
+	header := "fileType\nproject\n\n\ntime\tfloat\tinteger\tboolean\tfloat\nNA\tNA\tNA\tNA\tNA\ntime\tsalinity\tcount\tflag\tpar"
+	if err := d.ParseHeader(header); err != nil {
+		t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+	}
+	if d.Index("count") != 2 || d.Index("missing") != -1 {
+		t.Errorf("Tsdata.Index() = %v, %v, expected 2, -1", d.Index("count"), d.Index("missing"))
+	}
+	data, err := d.ValidateLine("2017-05-06T19:52:57.601Z\t35.1\t7\tTRUE\tNA", true)
+	if err != nil {
+		t.Fatalf("Tsdata.ValidateLine() err %v, expected nil", err)
+	}
+	if s, ok := data.Get("salinity"); !ok || s != "35.1" {
+		t.Errorf("Data.Get(salinity) = %v, %v, expected 35.1, true", s, ok)
+	}
+	if _, ok := data.Get("missing"); ok {
+		t.Errorf("Data.Get(missing) ok = true, expected false")
+	}
+	if v, ok := data.Float("salinity"); !ok || v != 35.1 {
+		t.Errorf("Data.Float(salinity) = %v, %v, expected 35.1, true", v, ok)
+	}
+	if _, ok := data.Float("par"); ok {
+		t.Errorf("Data.Float(par) ok = true for NA, expected false")
+	}
+	if _, ok := data.Float("count"); ok {
+		t.Errorf("Data.Float(count) ok = true for integer column, expected false")
+	}
+	if v, ok := data.Int("count"); !ok || v != 7 {
+		t.Errorf("Data.Int(count) = %v, %v, expected 7, true", v, ok)
+	}
+	if v, ok := data.Bool("flag"); !ok || !v {
+		t.Errorf("Data.Bool(flag) = %v, %v, expected true, true", v, ok)
+	}
+	if v, ok := data.TimeValue("time"); !ok || !v.Equal(tline) {
+		t.Errorf("Data.TimeValue(time) = %v, %v, expected %v, true", v, ok, tline)
+	}
+}
+
+func TestFieldError_Error(t *testing.T) {
+	header := "fileType\nproject\n\n\ntime\tfloat\ttext\nNA\tNA\tNA\ntime\tsalinity\tnote"
+	d := New()
+	if err := d.ParseHeader(header); err != nil {
+		t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+	}
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"2017-05-06T19:52:57.601Z\t3.a1\tok", "column 2 (salinity, float), bad value '3.a1'"},
+		{"bad\t3.1\tok", "first time column (time), bad value 'bad'"},
+	}
+	for _, tt := range tests {
+		_, err := d.ValidateLine(tt.line, true)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Tsdata.ValidateLine(%q) err %v, expected %v", tt.line, err, tt.want)
+		}
+	}
+	if got := (&FieldError{Column: 2, Value: "x"}).Error(); got != "column 3, bad value 'x'" {
+		t.Errorf("FieldError.Error() = %v, expected a message without a column name", got)
+	}
+}
+
+func TestTsdata_ValidateLine_offsets(t *testing.T) {
+	d := New()
+	if err := d.ParseHeader("fileType\nproject\n\n\ntime\tfloat\nNA\tNA\ntime\tspeed"); err != nil {
+		t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+	}
+	tests := []struct {
+		line     string
+		warnings int
+	}{
+		{"2017-05-06T19:52:57Z\t1", 0},
+		{"2017-05-06T19:52:58+00:00\t1", 0},
+		{"2017-05-06T21:52:59+02:00\t1", 1},
+		{"2017-05-06T21:53:00+02:00\t1", 0},
+		{"2017-05-06T19:53:01Z\t1", 0},
+		{"2017-05-06T18:53:02-01:00\t1", 1},
+	}
+	for _, tt := range tests {
+		data, err := d.ValidateLine(tt.line, true)
+		if err != nil {
+			t.Fatalf("Tsdata.ValidateLine(%q) err %v, expected nil", tt.line, err)
+		}
+		if len(data.Warnings) != tt.warnings {
+			t.Errorf("Tsdata.ValidateLine(%q) warnings %v, expected %v", tt.line, data.Warnings, tt.warnings)
+		}
+	}
+}
+
+func TestTsdata_ValidateLine_delimiterHint(t *testing.T) {
+	d := New()
+	if err := d.ParseHeader("fileType\nproject\n\n\ntime\tfloat\tfloat\nNA\tNA\tNA\ntime\tspeed\tdistance"); err != nil {
+		t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+	}
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"2017-05-06T19:52:57Z,1,2", "found 1 columns, expected >= 2, looks comma-delimited"},
+		{"2017-05-06T19:52:57Z  1   2", "found 1 columns, expected >= 2, looks space-delimited"},
+		{"2017-05-06T19:52:57Z\t1 2", "found 2 columns, expected 3, looks space-delimited"},
+		{"2017-05-06T19:52:57Z\t1", "found 2 columns, expected 3"},
+	}
+	for _, tt := range tests {
+		_, err := d.ValidateLine(tt.line, true)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Tsdata.ValidateLine(%q) err %v, expected %v", tt.line, err, tt.want)
+		}
+	}
+}
+
+func TestTsdata_ValidateLine_floatPolicy(t *testing.T) {
+	header := "fileType\nproject\n\n\ntime\tfloat\tlatitude\ttext\nNA\tNA\tNA\tNA\ntime\tspeed\tlat\tnote"
+	tests := []struct {
+		policy  FloatPolicy
+		line    string
+		wantErr bool
+		want    []string
+	}{
+		{FloatAccept, "2017-05-06T19:52:57Z\tNaN\t1\tNaN", false, []string{"NaN", "1", "NaN"}},
+		{FloatAccept, "2017-05-06T19:52:57Z\t+Inf\t1\tInf", false, []string{"+Inf", "1", "Inf"}},
+		{FloatReject, "2017-05-06T19:52:57Z\tNaN\t1\tx", true, nil},
+		{FloatReject, "2017-05-06T19:52:57Z\t1\tNaN\tx", true, nil},
+		{FloatReject, "2017-05-06T19:52:57Z\t1.5\t1\tNaN", false, []string{"1.5", "1", "NaN"}},
+		{FloatNA, "2017-05-06T19:52:57Z\t-Inf\tnan\tNaN", false, []string{NA, NA, "NaN"}},
+	}
+	for _, tt := range tests {
+		d := New(WithFloatPolicy(tt.policy))
+		if err := d.ParseHeader(header); err != nil {
+			t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+		}
+		data, err := d.ValidateLine(tt.line, true)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%v: Tsdata.ValidateLine(%q) err %v, wantErr %v", tt.policy, tt.line, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !stringSliceEqual(data.Fields[1:], tt.want) {
+			t.Errorf("%v: Tsdata.ValidateLine(%q) Fields %v, expected %v", tt.policy, tt.line, data.Fields[1:], tt.want)
+		}
+	}
+}
+
+func TestParseFloatPolicy(t *testing.T) {
+	for _, s := range []string{"accept", "reject", "na"} {
+		p, err := ParseFloatPolicy(s)
+		if err != nil || p.String() != s {
+			t.Errorf("ParseFloatPolicy(%v) = %v, %v", s, p, err)
+		}
+	}
+	if _, err := ParseFloatPolicy("bogus"); err == nil {
+		t.Errorf("ParseFloatPolicy(bogus) err nil, expected a non-nil error")
+	}
+}
+
+func TestTsdata_ValidateLine_timeFormat(t *testing.T) {
+	header := "fileType\nproject\n\n\ntime\ttime\nNA\tNA\ntime\tstart"
+	tests := []struct {
+		format  TimeFormat
+		line    string
+		wantErr bool
+		want    []string
+	}{
+		{TimeRFC3339, "1494100377\t1494100377", true, nil},
+		{TimeEpoch, "1494100377\t1494100377.601", false, []string{"2017-05-06T19:52:57Z", "2017-05-06T19:52:57.601Z"}},
+		{TimeEpoch, "2017-05-06T19:52:57Z\t-1.5", false, []string{"2017-05-06T19:52:57Z", "1969-12-31T23:59:58.5Z"}},
+		{TimeEpoch, "1494100377x\tNA", true, nil},
+		{TimeEpochMs, "1494100377601\t1494100377601.5", false, []string{"2017-05-06T19:52:57.601Z", "2017-05-06T19:52:57.6015Z"}},
+	}
+	for _, tt := range tests {
+		d := New(WithTimeFormat(tt.format))
+		if err := d.ParseHeader(header); err != nil {
+			t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+		}
+		data, err := d.ValidateLine(tt.line, true)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%v: Tsdata.ValidateLine(%q) err %v, wantErr %v", tt.format, tt.line, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !stringSliceEqual(data.Fields, tt.want) {
+			t.Errorf("%v: Tsdata.ValidateLine(%q) Fields %v, expected %v", tt.format, tt.line, data.Fields, tt.want)
+		}
+	}
+	for _, s := range []string{"rfc3339", "epoch", "epoch_ms"} {
+		f, err := ParseTimeFormat(s)
+		if err != nil || f.String() != s {
+			t.Errorf("ParseTimeFormat(%v) = %v, %v", s, f, err)
+		}
+	}
+	if _, err := ParseTimeFormat("bogus"); err == nil {
+		t.Errorf("ParseTimeFormat(bogus) err nil, expected a non-nil error")
+	}
+}
+
+func TestTsdata_ValidateLine_interval(t *testing.T) {
+	d := New(WithExpectedInterval(30*time.Second, 2*time.Second))
+	if err := d.ParseHeader("fileType\nproject\n\n\ntime\tfloat\nNA\tNA\ntime\tspeed"); err != nil {
+		t.Fatalf("Tsdata.ParseHeader() err %v, expected nil", err)
+	}
+	tests := []struct {
+		line     string
+		warnings int
+	}{
+		{"2017-05-06T19:00:00Z\t1", 0},
+		{"2017-05-06T19:00:30Z\t1", 0},
+		{"2017-05-06T19:01:02Z\t1", 0},
+		{"2017-05-06T19:01:27Z\t1", 1},
+		{"2017-05-06T19:05:00Z\t1", 1},
+		{"2017-05-06T19:05:28Z\t1", 0},
+	}
+	for _, tt := range tests {
+		data, err := d.ValidateLine(tt.line, true)
+		if err != nil {
+			t.Fatalf("Tsdata.ValidateLine(%q) err %v, expected nil", tt.line, err)
+		}
+		if len(data.Warnings) != tt.warnings {
+			t.Errorf("Tsdata.ValidateLine(%q) warnings %v, expected %v", tt.line, data.Warnings, tt.warnings)
+		}
+	}
+}
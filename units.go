@@ -0,0 +1,70 @@
+package tsdata
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Units is a controlled vocabulary for the Units row. Keys are the allowed
+// unit strings, e.g. "m/s", so that variants like "meters/sec" and "ms-1" can
+// be found and replaced.
+type Units map[string]bool
+
+// defaultUnits are common units written as UDUNITS symbols.
+var defaultUnits = []string{
+	"1", "%", "count",
+	"s", "ms", "min", "h", "d",
+	"m", "km", "cm", "mm", "um", "nm", "m2", "m3", "L", "mL", "uL",
+	"m/s", "cm/s", "mm/s", "km/h", "knot", "m/s2", "m3/s",
+	"degree", "degrees_north", "degrees_east", "rad",
+	"degC", "K",
+	"Pa", "hPa", "kPa", "dbar", "bar", "mbar",
+	"kg", "g", "mg", "ug", "ng", "kg/m3", "g/L", "mg/L", "ug/L", "mg/m3", "ug/m3",
+	"mol", "mmol", "umol", "nmol", "mol/kg", "umol/kg", "umol/L", "mmol/m3", "nmol/L",
+	"PSU", "ppm", "ppb", "ppt", "NTU",
+	"S/m", "mS/cm", "uS/cm",
+	"V", "mV", "A", "mA", "W", "W/m2", "J", "Hz",
+	"umol/m2/s", "cells/mL", "cells/uL", "1/m",
+}
+
+// DefaultUnits returns a vocabulary of common units written as UDUNITS
+// symbols, e.g. m/s, degC, and umol/kg.
+func DefaultUnits() Units {
+	u := make(Units, len(defaultUnits))
+	for _, s := range defaultUnits {
+		u[s] = true
+	}
+	return u
+}
+
+// ReadUnits reads a vocabulary with one unit per line from r. Leading and
+// trailing whitespace is removed. Blank lines and lines starting with # are
+// ignored.
+func ReadUnits(r io.Reader) (Units, error) {
+	u := make(Units)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		u[line] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Unknown returns the 0-based indexes of columns in s with a unit which is not
+// NA and not in u.
+func (u Units) Unknown(s Schema) []int {
+	var cols []int
+	for i, unit := range s.Units {
+		if unit != NA && !u[unit] {
+			cols = append(cols, i)
+		}
+	}
+	return cols
+}
@@ -0,0 +1,31 @@
+package tsdata
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadUnits(t *testing.T) {
+	u, err := ReadUnits(strings.NewReader("# project units\nm/s\n\n  degC \nmg/m3\n"))
+	if err != nil {
+		t.Fatalf("ReadUnits() err %v, expected nil", err)
+	}
+	want := Units{"m/s": true, "degC": true, "mg/m3": true}
+	if !reflect.DeepEqual(u, want) {
+		t.Errorf("ReadUnits() = %v, expected %v", u, want)
+	}
+}
+
+func TestUnits_Unknown(t *testing.T) {
+	s, err := ParseSchema("fileType\nproject\n\n\ntime\tfloat\tfloat\tfloat\tfloat\nNA\tm/s\tmeters/sec\tms-1\tdegC\ntime\ta\tb\tc\td")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := DefaultUnits().Unknown(s), []int{2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Units.Unknown() = %v, expected %v", got, want)
+	}
+	if got := (Units{"m/s": true, "meters/sec": true, "ms-1": true, "degC": true}).Unknown(s); got != nil {
+		t.Errorf("Units.Unknown() = %v, expected nil", got)
+	}
+}